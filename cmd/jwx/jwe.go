@@ -31,7 +31,7 @@ func keyEncryptionFlag(required bool) cli.Flag {
 	return &cli.StringFlag{
 		Name:     "key-encryption",
 		Aliases:  []string{"K"},
-		Usage:    "Key encryption algorithm name `NAME` (e.g. RSA-OAEP, ECDH-ES, A128GCMKW, etc)",
+		Usage:    "Key encryption algorithm name `NAME` (e.g. RSA-OAEP-256, RSA-OAEP-512, ECDH-ES, A128GCMKW, etc)",
 		Required: required,
 	}
 }
@@ -54,7 +54,7 @@ func makeJweEncryptCmd() *cli.Command {
 		&cli.StringFlag{
 			Name:     "content-encryption",
 			Aliases:  []string{"C"},
-			Usage:    "Content encryption algorithm name `NAME` (e.g. A128CBC-HS256, A192GCM, A256GCM, etc)",
+			Usage:    "Content encryption algorithm name `NAME` (e.g. A128CBC-HS256, A192GCM, A256GCM, XC20P, etc)",
 			Required: true,
 		},
 		&cli.BoolFlag{
@@ -10,9 +10,11 @@ import (
 	"fmt"
 	"io"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 	"github.com/urfave/cli/v2"
 	"golang.org/x/crypto/ed25519"
 )
@@ -172,14 +174,26 @@ func makeJwkGenerateCmd() *cli.Command {
 					return fmt.Errorf(`failed to generate ed25519 private key: %w`, err)
 				}
 				rawkey = priv
+			case jwa.Ed448:
+				_, priv, err := ed448.GenerateKey(rand.Reader)
+				if err != nil {
+					return fmt.Errorf(`failed to generate ed448 private key: %w`, err)
+				}
+				rawkey = priv
 			case jwa.X25519:
 				_, priv, err := x25519.GenerateKey(rand.Reader)
 				if err != nil {
 					return fmt.Errorf(`failed to generate x25519 private key: %w`, err)
 				}
 				rawkey = priv
+			case jwa.X448:
+				_, priv, err := x448.GenerateKey(rand.Reader)
+				if err != nil {
+					return fmt.Errorf(`failed to generate x448 private key: %w`, err)
+				}
+				rawkey = priv
 			default:
-				return fmt.Errorf(`invalid elliptic curve for OKP: %s (expected %s/%s)`, crvalg, jwa.Ed25519, jwa.X25519)
+				return fmt.Errorf(`invalid elliptic curve for OKP: %s (expected %s/%s/%s/%s)`, crvalg, jwa.Ed25519, jwa.Ed448, jwa.X25519, jwa.X448)
 			}
 		default:
 			return fmt.Errorf(`invalid key type %s`, typ)
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/binary"
 	"fmt"
+	"io"
 )
 
 func Encode(src []byte) []byte {
@@ -22,6 +23,19 @@ func EncodeToString(src []byte) string {
 	return base64.RawURLEncoding.EncodeToString(src)
 }
 
+// NewEncoder returns a stream encoder that writes the raw URL encoded
+// form of the data written to it to `w`. The encoder must be closed
+// in order to flush any partially written blocks.
+func NewEncoder(w io.Writer) io.WriteCloser {
+	return base64.NewEncoder(base64.RawURLEncoding, w)
+}
+
+// NewDecoder returns a stream decoder that decodes raw URL encoded
+// data read from `r`.
+func NewDecoder(r io.Reader) io.Reader {
+	return base64.NewDecoder(base64.RawURLEncoding, r)
+}
+
 func EncodeUint64ToString(v uint64) string {
 	data := make([]byte, 8)
 	binary.BigEndian.PutUint64(data, v)
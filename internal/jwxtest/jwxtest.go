@@ -15,12 +15,14 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/jwx/v2/internal/ecutil"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +44,23 @@ func GenerateRsaJwk() (jwk.Key, error) {
 	return k, nil
 }
 
+// GenerateRsaJwkWithKeyID generates an RSA private key as a jwk.Key,
+// with its "kid" set to `kid` and its "alg" set to RS256
+func GenerateRsaJwkWithKeyID(kid string) (jwk.Key, error) {
+	key, err := GenerateRsaJwk()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := key.Set(jwk.KeyIDKey, kid); err != nil {
+		return nil, fmt.Errorf(`failed to set "kid": %w`, err)
+	}
+	if err := key.Set(jwk.AlgorithmKey, jwa.RS256); err != nil {
+		return nil, fmt.Errorf(`failed to set "alg": %w`, err)
+	}
+	return key, nil
+}
+
 func GenerateRsaPublicJwk() (jwk.Key, error) {
 	key, err := GenerateRsaJwk()
 	if err != nil {
@@ -119,11 +138,49 @@ func GenerateEd25519Jwk() (jwk.Key, error) {
 	return k, nil
 }
 
+func GenerateEd448Key() (ed448.PrivateKey, error) {
+	_, priv, err := ed448.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+func GenerateEd448Jwk() (jwk.Key, error) {
+	key, err := GenerateEd448Key()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate Ed448 private key: %w`, err)
+	}
+
+	k, err := jwk.FromRaw(key)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate jwk.OKPPrivateKey: %w`, err)
+	}
+
+	return k, nil
+}
+
 func GenerateX25519Key() (x25519.PrivateKey, error) {
 	_, priv, err := x25519.GenerateKey(rand.Reader)
 	return priv, err
 }
 
+func GenerateX448Key() (x448.PrivateKey, error) {
+	_, priv, err := x448.GenerateKey(rand.Reader)
+	return priv, err
+}
+
+func GenerateX448Jwk() (jwk.Key, error) {
+	key, err := GenerateX448Key()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate X448 private key: %w`, err)
+	}
+
+	k, err := jwk.FromRaw(key)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate jwk.OKPPrivateKey: %w`, err)
+	}
+
+	return k, nil
+}
+
 func GenerateX25519Jwk() (jwk.Key, error) {
 	key, err := GenerateX25519Key()
 	if err != nil {
@@ -286,7 +343,7 @@ func EncryptJweFile(ctx context.Context, payload []byte, keyalg jwa.KeyEncryptio
 	var keyif interface{}
 
 	switch keyalg {
-	case jwa.RSA1_5, jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+	case jwa.RSA1_5, jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
 		var rawkey rsa.PrivateKey
 		if err := key.Raw(&rawkey); err != nil {
 			return "", nil, fmt.Errorf(`failed to obtain raw key: %w`, err)
@@ -0,0 +1,23 @@
+// Package keyusage holds the global settings that control whether the
+// "use" and "key_ops" fields of keys are enforced by the jws and jwe
+// packages.
+package keyusage
+
+import "sync/atomic"
+
+// Setting is a boolean flag that may be changed while it is being read
+type Setting struct {
+	v uint32
+}
+
+func (s *Setting) Set(v bool) {
+	var u uint32
+	if v {
+		u = 1
+	}
+	atomic.StoreUint32(&s.v, u)
+}
+
+func (s *Setting) Get() bool {
+	return atomic.LoadUint32(&s.v) == 1
+}
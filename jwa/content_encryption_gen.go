@@ -19,6 +19,8 @@ const (
 	A192GCM       ContentEncryptionAlgorithm = "A192GCM"       // AES-GCM (192)
 	A256CBC_HS512 ContentEncryptionAlgorithm = "A256CBC-HS512" // AES-CBC + HMAC-SHA512 (256)
 	A256GCM       ContentEncryptionAlgorithm = "A256GCM"       // AES-GCM (256)
+	C20P          ContentEncryptionAlgorithm = "C20P"          // ChaCha20-Poly1305
+	XC20P         ContentEncryptionAlgorithm = "XC20P"         // XChaCha20-Poly1305
 )
 
 var allContentEncryptionAlgorithms = map[ContentEncryptionAlgorithm]struct{}{
@@ -28,6 +30,8 @@ var allContentEncryptionAlgorithms = map[ContentEncryptionAlgorithm]struct{}{
 	A192GCM:       {},
 	A256CBC_HS512: {},
 	A256GCM:       {},
+	C20P:          {},
+	XC20P:         {},
 }
 
 var listContentEncryptionAlgorithmOnce sync.Once
@@ -20,16 +20,30 @@ const (
 	A256GCMKW          KeyEncryptionAlgorithm = "A256GCMKW"          // AES-GCM key wrap (256)
 	A256KW             KeyEncryptionAlgorithm = "A256KW"             // AES key wrap (256)
 	DIRECT             KeyEncryptionAlgorithm = "dir"                // Direct encryption
+	ECDH_1PU           KeyEncryptionAlgorithm = "ECDH-1PU"           // ECDH-1PU
+	ECDH_1PU_A128KW    KeyEncryptionAlgorithm = "ECDH-1PU+A128KW"    // ECDH-1PU + AES key wrap (128)
+	ECDH_1PU_A192KW    KeyEncryptionAlgorithm = "ECDH-1PU+A192KW"    // ECDH-1PU + AES key wrap (192)
+	ECDH_1PU_A256KW    KeyEncryptionAlgorithm = "ECDH-1PU+A256KW"    // ECDH-1PU + AES key wrap (256)
 	ECDH_ES            KeyEncryptionAlgorithm = "ECDH-ES"            // ECDH-ES
 	ECDH_ES_A128KW     KeyEncryptionAlgorithm = "ECDH-ES+A128KW"     // ECDH-ES + AES key wrap (128)
 	ECDH_ES_A192KW     KeyEncryptionAlgorithm = "ECDH-ES+A192KW"     // ECDH-ES + AES key wrap (192)
 	ECDH_ES_A256KW     KeyEncryptionAlgorithm = "ECDH-ES+A256KW"     // ECDH-ES + AES key wrap (256)
+	HPKE_0             KeyEncryptionAlgorithm = "HPKE-0"             // HPKE DHKEM(P-256, HKDF-SHA256), HKDF-SHA256, AES-128-GCM
+	HPKE_1             KeyEncryptionAlgorithm = "HPKE-1"             // HPKE DHKEM(P-384, HKDF-SHA384), HKDF-SHA384, AES-256-GCM
+	HPKE_2             KeyEncryptionAlgorithm = "HPKE-2"             // HPKE DHKEM(P-521, HKDF-SHA512), HKDF-SHA512, AES-256-GCM
+	HPKE_3             KeyEncryptionAlgorithm = "HPKE-3"             // HPKE DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM
+	HPKE_4             KeyEncryptionAlgorithm = "HPKE-4"             // HPKE DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, ChaCha20Poly1305
+	HPKE_5             KeyEncryptionAlgorithm = "HPKE-5"             // HPKE DHKEM(X448, HKDF-SHA512), HKDF-SHA512, AES-256-GCM
+	HPKE_6             KeyEncryptionAlgorithm = "HPKE-6"             // HPKE DHKEM(X448, HKDF-SHA512), HKDF-SHA512, ChaCha20Poly1305
+	HPKE_7             KeyEncryptionAlgorithm = "HPKE-7"             // HPKE DHKEM(P-256, HKDF-SHA256), HKDF-SHA256, AES-256-GCM
 	PBES2_HS256_A128KW KeyEncryptionAlgorithm = "PBES2-HS256+A128KW" // PBES2 + HMAC-SHA256 + AES key wrap (128)
 	PBES2_HS384_A192KW KeyEncryptionAlgorithm = "PBES2-HS384+A192KW" // PBES2 + HMAC-SHA384 + AES key wrap (192)
 	PBES2_HS512_A256KW KeyEncryptionAlgorithm = "PBES2-HS512+A256KW" // PBES2 + HMAC-SHA512 + AES key wrap (256)
 	RSA1_5             KeyEncryptionAlgorithm = "RSA1_5"             // RSA-PKCS1v1.5
 	RSA_OAEP           KeyEncryptionAlgorithm = "RSA-OAEP"           // RSA-OAEP-SHA1
 	RSA_OAEP_256       KeyEncryptionAlgorithm = "RSA-OAEP-256"       // RSA-OAEP-SHA256
+	RSA_OAEP_384       KeyEncryptionAlgorithm = "RSA-OAEP-384"       // RSA-OAEP-SHA384
+	RSA_OAEP_512       KeyEncryptionAlgorithm = "RSA-OAEP-512"       // RSA-OAEP-SHA512
 )
 
 var allKeyEncryptionAlgorithms = map[KeyEncryptionAlgorithm]struct{}{
@@ -40,16 +54,30 @@ var allKeyEncryptionAlgorithms = map[KeyEncryptionAlgorithm]struct{}{
 	A256GCMKW:          {},
 	A256KW:             {},
 	DIRECT:             {},
+	ECDH_1PU:           {},
+	ECDH_1PU_A128KW:    {},
+	ECDH_1PU_A192KW:    {},
+	ECDH_1PU_A256KW:    {},
 	ECDH_ES:            {},
 	ECDH_ES_A128KW:     {},
 	ECDH_ES_A192KW:     {},
 	ECDH_ES_A256KW:     {},
+	HPKE_0:             {},
+	HPKE_1:             {},
+	HPKE_2:             {},
+	HPKE_3:             {},
+	HPKE_4:             {},
+	HPKE_5:             {},
+	HPKE_6:             {},
+	HPKE_7:             {},
 	PBES2_HS256_A128KW: {},
 	PBES2_HS384_A192KW: {},
 	PBES2_HS512_A256KW: {},
 	RSA1_5:             {},
 	RSA_OAEP:           {},
 	RSA_OAEP_256:       {},
+	RSA_OAEP_384:       {},
+	RSA_OAEP_512:       {},
 }
 
 var listKeyEncryptionAlgorithmOnce sync.Once
@@ -263,6 +263,150 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant ECDH_1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU", jwa.ECDH_1PU.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A128KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A128KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A128KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A128KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A128KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A128KW", jwa.ECDH_1PU_A128KW.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A192KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A192KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A192KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A192KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A192KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A192KW", jwa.ECDH_1PU_A192KW.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant ECDH_1PU_A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.ECDH_1PU_A256KW), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("ECDH-1PU+A256KW"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "ECDH-1PU+A256KW"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.ECDH_1PU_A256KW, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for ECDH-1PU+A256KW`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "ECDH-1PU+A256KW", jwa.ECDH_1PU_A256KW.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant ECDH_ES`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.KeyEncryptionAlgorithm
@@ -407,6 +551,294 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant HPKE_0`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_0), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_0, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-0`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-0"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_0, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-0`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-0"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_0, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-0`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-0", jwa.HPKE_0.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_1), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-1"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-1`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-1"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_1, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-1`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-1", jwa.HPKE_1.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_2`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_2), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_2, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-2`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-2"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_2, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-2`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-2"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_2, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-2`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-2", jwa.HPKE_2.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_3`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_3), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_3, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-3`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-3"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_3, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-3`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-3"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_3, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-3`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-3", jwa.HPKE_3.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_4`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_4), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_4, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-4`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-4"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_4, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-4`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-4"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_4, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-4`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-4", jwa.HPKE_4.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_5`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_5), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_5, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-5`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-5"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_5, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-5`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-5"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_5, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-5`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-5", jwa.HPKE_5.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_6`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_6), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_6, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-6`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-6"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_6, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-6`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-6"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_6, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-6`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-6", jwa.HPKE_6.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant HPKE_7`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.HPKE_7), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_7, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string HPKE-7`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("HPKE-7"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_7, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for HPKE-7`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "HPKE-7"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.HPKE_7, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for HPKE-7`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "HPKE-7", jwa.HPKE_7.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`accept jwa constant PBES2_HS256_A128KW`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.KeyEncryptionAlgorithm
@@ -623,6 +1055,78 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 			return
 		}
 	})
+	t.Run(`accept jwa constant RSA_OAEP_384`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.RSA_OAEP_384), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_384, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string RSA-OAEP-384`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("RSA-OAEP-384"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_384, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for RSA-OAEP-384`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "RSA-OAEP-384"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_384, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for RSA-OAEP-384`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "RSA-OAEP-384", jwa.RSA_OAEP_384.String(), `stringified value matches`) {
+			return
+		}
+	})
+	t.Run(`accept jwa constant RSA_OAEP_512`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(jwa.RSA_OAEP_512), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_512, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept the string RSA-OAEP-512`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept("RSA-OAEP-512"), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_512, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`accept fmt.Stringer for RSA-OAEP-512`, func(t *testing.T) {
+		t.Parallel()
+		var dst jwa.KeyEncryptionAlgorithm
+		if !assert.NoError(t, dst.Accept(stringer{src: "RSA-OAEP-512"}), `accept is successful`) {
+			return
+		}
+		if !assert.Equal(t, jwa.RSA_OAEP_512, dst, `accepted value should be equal to constant`) {
+			return
+		}
+	})
+	t.Run(`stringification for RSA-OAEP-512`, func(t *testing.T) {
+		t.Parallel()
+		if !assert.Equal(t, "RSA-OAEP-512", jwa.RSA_OAEP_512.String(), `stringified value matches`) {
+			return
+		}
+	})
 	t.Run(`bail out on random integer value`, func(t *testing.T) {
 		t.Parallel()
 		var dst jwa.KeyEncryptionAlgorithm
@@ -641,6 +1145,12 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 		t.Parallel()
 		t.Run(`A128GCMKW`, func(t *testing.T) {
 			assert.True(t, jwa.A128GCMKW.IsSymmetric(), `jwa.A128GCMKW should be symmetric`)
+			t.Run(`RSA_OAEP_384`, func(t *testing.T) {
+				assert.False(t, jwa.RSA_OAEP_384.IsSymmetric(), `jwa.RSA_OAEP_384 should NOT be symmetric`)
+				t.Run(`RSA_OAEP_512`, func(t *testing.T) {
+					assert.False(t, jwa.RSA_OAEP_512.IsSymmetric(), `jwa.RSA_OAEP_512 should NOT be symmetric`)
+				})
+			})
 		})
 		t.Run(`A128KW`, func(t *testing.T) {
 			assert.True(t, jwa.A128KW.IsSymmetric(), `jwa.A128KW should be symmetric`)
@@ -660,6 +1170,18 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 		t.Run(`DIRECT`, func(t *testing.T) {
 			assert.True(t, jwa.DIRECT.IsSymmetric(), `jwa.DIRECT should be symmetric`)
 		})
+		t.Run(`ECDH_1PU`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU.IsSymmetric(), `jwa.ECDH_1PU should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A128KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A128KW.IsSymmetric(), `jwa.ECDH_1PU_A128KW should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A192KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A192KW.IsSymmetric(), `jwa.ECDH_1PU_A192KW should NOT be symmetric`)
+		})
+		t.Run(`ECDH_1PU_A256KW`, func(t *testing.T) {
+			assert.False(t, jwa.ECDH_1PU_A256KW.IsSymmetric(), `jwa.ECDH_1PU_A256KW should NOT be symmetric`)
+		})
 		t.Run(`ECDH_ES`, func(t *testing.T) {
 			assert.False(t, jwa.ECDH_ES.IsSymmetric(), `jwa.ECDH_ES should NOT be symmetric`)
 		})
@@ -672,6 +1194,30 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 		t.Run(`ECDH_ES_A256KW`, func(t *testing.T) {
 			assert.False(t, jwa.ECDH_ES_A256KW.IsSymmetric(), `jwa.ECDH_ES_A256KW should NOT be symmetric`)
 		})
+		t.Run(`HPKE_0`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_0.IsSymmetric(), `jwa.HPKE_0 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_1`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_1.IsSymmetric(), `jwa.HPKE_1 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_2`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_2.IsSymmetric(), `jwa.HPKE_2 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_3`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_3.IsSymmetric(), `jwa.HPKE_3 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_4`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_4.IsSymmetric(), `jwa.HPKE_4 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_5`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_5.IsSymmetric(), `jwa.HPKE_5 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_6`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_6.IsSymmetric(), `jwa.HPKE_6 should NOT be symmetric`)
+		})
+		t.Run(`HPKE_7`, func(t *testing.T) {
+			assert.False(t, jwa.HPKE_7.IsSymmetric(), `jwa.HPKE_7 should NOT be symmetric`)
+		})
 		t.Run(`PBES2_HS256_A128KW`, func(t *testing.T) {
 			assert.True(t, jwa.PBES2_HS256_A128KW.IsSymmetric(), `jwa.PBES2_HS256_A128KW should be symmetric`)
 		})
@@ -701,16 +1247,30 @@ func TestKeyEncryptionAlgorithm(t *testing.T) {
 			jwa.A256GCMKW:          {},
 			jwa.A256KW:             {},
 			jwa.DIRECT:             {},
+			jwa.ECDH_1PU:           {},
+			jwa.ECDH_1PU_A128KW:    {},
+			jwa.ECDH_1PU_A192KW:    {},
+			jwa.ECDH_1PU_A256KW:    {},
 			jwa.ECDH_ES:            {},
 			jwa.ECDH_ES_A128KW:     {},
 			jwa.ECDH_ES_A192KW:     {},
 			jwa.ECDH_ES_A256KW:     {},
+			jwa.HPKE_0:             {},
+			jwa.HPKE_1:             {},
+			jwa.HPKE_2:             {},
+			jwa.HPKE_3:             {},
+			jwa.HPKE_4:             {},
+			jwa.HPKE_5:             {},
+			jwa.HPKE_6:             {},
+			jwa.HPKE_7:             {},
 			jwa.PBES2_HS256_A128KW: {},
 			jwa.PBES2_HS384_A192KW: {},
 			jwa.PBES2_HS512_A256KW: {},
 			jwa.RSA1_5:             {},
 			jwa.RSA_OAEP:           {},
 			jwa.RSA_OAEP_256:       {},
+			jwa.RSA_OAEP_384:       {},
+			jwa.RSA_OAEP_512:       {},
 		}
 		for _, v := range jwa.KeyEncryptionAlgorithms() {
 			if _, ok := expected[v]; !assert.True(t, ok, `%s should be in the expected list`, v) {
@@ -1,6 +1,7 @@
 package jwe
 
 import (
+	"crypto"
 	"crypto/aes"
 	cryptocipher "crypto/cipher"
 	"crypto/ecdsa"
@@ -9,6 +10,7 @@ import (
 	"crypto/sha512"
 	"fmt"
 	"hash"
+	"io"
 
 	"golang.org/x/crypto/pbkdf2"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/content_crypt"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keyenc"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 // decrypter is responsible for taking various components to decrypt a message.
@@ -29,6 +32,7 @@ type decrypter struct {
 	apu         []byte
 	apv         []byte
 	computedAad []byte
+	ek          []byte
 	iv          []byte
 	keyiv       []byte
 	keysalt     []byte
@@ -36,6 +40,7 @@ type decrypter struct {
 	tag         []byte
 	privkey     interface{}
 	pubkey      interface{}
+	senderkey   interface{}
 	ctalg       jwa.ContentEncryptionAlgorithm
 	keyalg      jwa.KeyEncryptionAlgorithm
 	cipher      content_crypt.Cipher
@@ -83,6 +88,13 @@ func (d *decrypter) ContentEncryptionAlgorithm(ctalg jwa.ContentEncryptionAlgori
 	return d
 }
 
+// EncapsulatedKey sets the HPKE encapsulated key ("ek") to be used
+// in decoding HPKE based encryptions
+func (d *decrypter) EncapsulatedKey(ek []byte) *decrypter {
+	d.ek = ek
+	return d
+}
+
 func (d *decrypter) InitializationVector(iv []byte) *decrypter {
 	d.iv = iv
 	return d
@@ -115,6 +127,13 @@ func (d *decrypter) PublicKey(pubkey interface{}) *decrypter {
 	return d
 }
 
+// SenderPublicKey sets the static public key of the sender to be used
+// in decoding ECDH-1PU based encryptions. The key must be in its "raw" format
+func (d *decrypter) SenderPublicKey(pubkey interface{}) *decrypter {
+	d.senderkey = pubkey
+	return d
+}
+
 func (d *decrypter) Tag(tag []byte) *decrypter {
 	d.tag = tag
 	return d
@@ -123,8 +142,8 @@ func (d *decrypter) Tag(tag []byte) *decrypter {
 func (d *decrypter) ContentCipher() (content_crypt.Cipher, error) {
 	if d.cipher == nil {
 		switch d.ctalg {
-		case jwa.A128GCM, jwa.A192GCM, jwa.A256GCM, jwa.A128CBC_HS256, jwa.A192CBC_HS384, jwa.A256CBC_HS512:
-			cipher, err := cipher.NewAES(d.ctalg)
+		case jwa.A128GCM, jwa.A192GCM, jwa.A256GCM, jwa.A128CBC_HS256, jwa.A192CBC_HS384, jwa.A256CBC_HS512, jwa.C20P, jwa.XC20P:
+			cipher, err := cipher.New(d.ctalg)
 			if err != nil {
 				return nil, fmt.Errorf(`failed to build content cipher for %s: %w`, d.ctalg, err)
 			}
@@ -150,12 +169,7 @@ func (d *decrypter) Decrypt(recipientKey, ciphertext []byte) (plaintext []byte,
 		return
 	}
 
-	computedAad := d.computedAad
-	if d.aad != nil {
-		computedAad = append(append(computedAad, '.'), d.aad...)
-	}
-
-	plaintext, err = cipher.Decrypt(cek, d.iv, ciphertext, d.tag, computedAad)
+	plaintext, err = cipher.Decrypt(cek, d.iv, ciphertext, d.tag, d.buildAad())
 	if err != nil {
 		err = fmt.Errorf(`failed to decrypt payload: %w`, err)
 		return
@@ -164,6 +178,29 @@ func (d *decrypter) Decrypt(recipientKey, ciphertext []byte) (plaintext []byte,
 	return plaintext, nil
 }
 
+// ContentReader returns an io.Reader that decrypts the ciphertext read
+// from `src` using `cek`. See (cipher.ContentCipher).NewOpener for details
+func (d *decrypter) ContentReader(cek []byte, src io.Reader, tagFunc cipher.TagFunc) (io.Reader, error) {
+	c, err := d.ContentCipher()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to fetch content crypt cipher: %w`, err)
+	}
+
+	rdr, err := c.NewOpener(src, cek, d.iv, d.buildAad(), tagFunc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create content reader: %w`, err)
+	}
+	return rdr, nil
+}
+
+func (d *decrypter) buildAad() []byte {
+	computedAad := d.computedAad
+	if d.aad != nil {
+		computedAad = append(append(computedAad, '.'), d.aad...)
+	}
+	return computedAad
+}
+
 func (d *decrypter) decryptSymmetricKey(recipientKey, cek []byte) ([]byte, error) {
 	switch d.keyalg {
 	case jwa.DIRECT:
@@ -258,19 +295,19 @@ func (d *decrypter) BuildKeyDecrypter() (keyenc.Decrypter, error) {
 
 	switch alg := d.keyalg; alg {
 	case jwa.RSA1_5:
-		var privkey rsa.PrivateKey
-		if err := keyconv.RSAPrivateKey(&privkey, d.privkey); err != nil {
-			return nil, fmt.Errorf(`*rsa.PrivateKey is required as the key to build %s key decrypter: %w`, alg, err)
+		privkey, err := rsaDecrypter(d.privkey)
+		if err != nil {
+			return nil, fmt.Errorf(`*rsa.PrivateKey or crypto.Decrypter is required as the key to build %s key decrypter: %w`, alg, err)
 		}
 
-		return keyenc.NewRSAPKCS15Decrypt(alg, &privkey, cipher.KeySize()/2), nil
-	case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
-		var privkey rsa.PrivateKey
-		if err := keyconv.RSAPrivateKey(&privkey, d.privkey); err != nil {
-			return nil, fmt.Errorf(`*rsa.PrivateKey is required as the key to build %s key decrypter: %w`, alg, err)
+		return keyenc.NewRSAPKCS15Decrypt(alg, privkey, cipher.KeySize()/2), nil
+	case jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
+		privkey, err := rsaDecrypter(d.privkey)
+		if err != nil {
+			return nil, fmt.Errorf(`*rsa.PrivateKey or crypto.Decrypter is required as the key to build %s key decrypter: %w`, alg, err)
 		}
 
-		return keyenc.NewRSAOAEPDecrypt(alg, &privkey)
+		return keyenc.NewRSAOAEPDecrypt(alg, privkey)
 	case jwa.A128KW, jwa.A192KW, jwa.A256KW:
 		sharedkey, ok := d.privkey.([]byte)
 		if !ok {
@@ -278,9 +315,11 @@ func (d *decrypter) BuildKeyDecrypter() (keyenc.Decrypter, error) {
 		}
 
 		return keyenc.NewAES(alg, sharedkey)
+	case jwa.HPKE_0, jwa.HPKE_1, jwa.HPKE_2, jwa.HPKE_3, jwa.HPKE_4, jwa.HPKE_5, jwa.HPKE_6, jwa.HPKE_7:
+		return keyenc.NewHPKEDecrypt(alg, d.ek, d.privkey)
 	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW:
 		switch d.pubkey.(type) {
-		case x25519.PublicKey:
+		case x25519.PublicKey, x448.PublicKey:
 			return keyenc.NewECDHESDecrypt(alg, d.ctalg, d.pubkey, d.apu, d.apv, d.privkey), nil
 		default:
 			var pubkey ecdsa.PublicKey
@@ -288,6 +327,10 @@ func (d *decrypter) BuildKeyDecrypter() (keyenc.Decrypter, error) {
 				return nil, fmt.Errorf(`*ecdsa.PublicKey is required as the key to build %s key decrypter: %w`, alg, err)
 			}
 
+			if agreer, ok := d.privkey.(ECDHPrivateKey); ok {
+				return keyenc.NewECDHESDecrypt(alg, d.ctalg, &pubkey, d.apu, d.apv, agreer), nil
+			}
+
 			var privkey ecdsa.PrivateKey
 			if err := keyconv.ECDSAPrivateKey(&privkey, d.privkey); err != nil {
 				return nil, fmt.Errorf(`*ecdsa.PrivateKey is required as the key to build %s key decrypter: %w`, alg, err)
@@ -295,7 +338,60 @@ func (d *decrypter) BuildKeyDecrypter() (keyenc.Decrypter, error) {
 
 			return keyenc.NewECDHESDecrypt(alg, d.ctalg, &pubkey, d.apu, d.apv, &privkey), nil
 		}
+	case jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		if d.senderkey == nil {
+			return nil, fmt.Errorf(`the sender's public key is required to build %s key decrypter (see jwe.WithSenderKey())`, alg)
+		}
+
+		var tag []byte
+		if alg != jwa.ECDH_1PU {
+			tag = d.tag
+		}
+
+		switch d.pubkey.(type) {
+		case x25519.PublicKey, x448.PublicKey:
+			return keyenc.NewECDH1PUDecrypt(alg, d.ctalg, d.pubkey, d.senderkey, d.apu, d.apv, tag, d.privkey), nil
+		default:
+			var pubkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&pubkey, d.pubkey); err != nil {
+				return nil, fmt.Errorf(`*ecdsa.PublicKey is required as the key to build %s key decrypter: %w`, alg, err)
+			}
+
+			var senderkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&senderkey, d.senderkey); err != nil {
+				return nil, fmt.Errorf(`*ecdsa.PublicKey is required as the sender's key to build %s key decrypter: %w`, alg, err)
+			}
+
+			if agreer, ok := d.privkey.(ECDHPrivateKey); ok {
+				return keyenc.NewECDH1PUDecrypt(alg, d.ctalg, &pubkey, &senderkey, d.apu, d.apv, tag, agreer), nil
+			}
+
+			var privkey ecdsa.PrivateKey
+			if err := keyconv.ECDSAPrivateKey(&privkey, d.privkey); err != nil {
+				return nil, fmt.Errorf(`*ecdsa.PrivateKey is required as the key to build %s key decrypter: %w`, alg, err)
+			}
+
+			return keyenc.NewECDH1PUDecrypt(alg, d.ctalg, &pubkey, &senderkey, d.apu, d.apv, tag, &privkey), nil
+		}
 	default:
 		return nil, fmt.Errorf(`unsupported algorithm for key decryption (%s)`, alg)
 	}
 }
+
+// rsaDecrypter returns the crypto.Decrypter to be used for RSA based key
+// decryption. Keys that are not a crypto.Decrypter themselves (e.g.
+// rsa.PrivateKey) are converted to *rsa.PrivateKey.
+func rsaDecrypter(key interface{}) (crypto.Decrypter, error) {
+	if dec, ok := key.(crypto.Decrypter); ok {
+		if _, ok := dec.Public().(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf(`expected crypto.Decrypter with a *rsa.PublicKey, got %T`, dec.Public())
+		}
+		return dec, nil
+	}
+
+	var privkey rsa.PrivateKey
+	if err := keyconv.RSAPrivateKey(&privkey, key); err != nil {
+		return nil, err
+	}
+	return &privkey, nil
+}
@@ -23,6 +23,7 @@ func (h *stdHeaders) isZero() bool {
 		h.contentEncryption == nil &&
 		h.contentType == nil &&
 		h.critical == nil &&
+		h.encapsulatedKey == nil &&
 		h.ephemeralPublicKey == nil &&
 		h.jwk == nil &&
 		h.jwkSetURL == nil &&
@@ -25,6 +25,7 @@ const (
 	ContentEncryptionKey      = "enc"
 	ContentTypeKey            = "cty"
 	CriticalKey               = "crit"
+	EncapsulatedKeyKey        = "ek"
 	EphemeralPublicKeyKey     = "epk"
 	JWKKey                    = "jwk"
 	JWKSetURLKey              = "jku"
@@ -47,6 +48,7 @@ type Headers interface {
 	ContentEncryption() jwa.ContentEncryptionAlgorithm
 	ContentType() string
 	Critical() []string
+	EncapsulatedKey() []byte
 	EphemeralPublicKey() jwk.Key
 	JWK() jwk.Key
 	JWKSetURL() string
@@ -82,6 +84,7 @@ type stdHeaders struct {
 	contentEncryption      *jwa.ContentEncryptionAlgorithm
 	contentType            *string
 	critical               []string
+	encapsulatedKey        []byte
 	ephemeralPublicKey     jwk.Key
 	jwk                    jwk.Key
 	jwkSetURL              *string
@@ -156,6 +159,12 @@ func (h *stdHeaders) Critical() []string {
 	return h.critical
 }
 
+func (h *stdHeaders) EncapsulatedKey() []byte {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.encapsulatedKey
+}
+
 func (h *stdHeaders) EphemeralPublicKey() jwk.Key {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
@@ -253,6 +262,9 @@ func (h *stdHeaders) makePairs() []*HeaderPair {
 	if h.critical != nil {
 		pairs = append(pairs, &HeaderPair{Key: CriticalKey, Value: h.critical})
 	}
+	if h.encapsulatedKey != nil {
+		pairs = append(pairs, &HeaderPair{Key: EncapsulatedKeyKey, Value: h.encapsulatedKey})
+	}
 	if h.ephemeralPublicKey != nil {
 		pairs = append(pairs, &HeaderPair{Key: EphemeralPublicKeyKey, Value: h.ephemeralPublicKey})
 	}
@@ -331,6 +343,11 @@ func (h *stdHeaders) Get(name string) (interface{}, bool) {
 			return nil, false
 		}
 		return h.critical, true
+	case EncapsulatedKeyKey:
+		if h.encapsulatedKey == nil {
+			return nil, false
+		}
+		return h.encapsulatedKey, true
 	case EphemeralPublicKeyKey:
 		if h.ephemeralPublicKey == nil {
 			return nil, false
@@ -435,6 +452,12 @@ func (h *stdHeaders) setNoLock(name string, value interface{}) error {
 			return nil
 		}
 		return fmt.Errorf(`invalid value for %s key: %T`, CriticalKey, value)
+	case EncapsulatedKeyKey:
+		if v, ok := value.([]byte); ok {
+			h.encapsulatedKey = v
+			return nil
+		}
+		return fmt.Errorf(`invalid value for %s key: %T`, EncapsulatedKeyKey, value)
 	case EphemeralPublicKeyKey:
 		if v, ok := value.(jwk.Key); ok {
 			h.ephemeralPublicKey = v
@@ -516,6 +539,8 @@ func (h *stdHeaders) Remove(key string) error {
 		h.contentType = nil
 	case CriticalKey:
 		h.critical = nil
+	case EncapsulatedKeyKey:
+		h.encapsulatedKey = nil
 	case EphemeralPublicKeyKey:
 		h.ephemeralPublicKey = nil
 	case JWKKey:
@@ -548,6 +573,7 @@ func (h *stdHeaders) UnmarshalJSON(buf []byte) error {
 	h.contentEncryption = nil
 	h.contentType = nil
 	h.critical = nil
+	h.encapsulatedKey = nil
 	h.ephemeralPublicKey = nil
 	h.jwk = nil
 	h.jwkSetURL = nil
@@ -611,6 +637,10 @@ LOOP:
 					return fmt.Errorf(`failed to decode value for key %s: %w`, CriticalKey, err)
 				}
 				h.critical = decoded
+			case EncapsulatedKeyKey:
+				if err := json.AssignNextBytesToken(&h.encapsulatedKey, dec); err != nil {
+					return fmt.Errorf(`failed to decode value for key %s: %w`, EncapsulatedKeyKey, err)
+				}
 			case EphemeralPublicKeyKey:
 				var buf json.RawMessage
 				if err := dec.Decode(&buf); err != nil {
@@ -677,7 +707,7 @@ LOOP:
 
 func (h stdHeaders) MarshalJSON() ([]byte, error) {
 	data := make(map[string]interface{})
-	fields := make([]string, 0, 16)
+	fields := make([]string, 0, 17)
 	for _, pair := range h.makePairs() {
 		fields = append(fields, pair.Key.(string))
 		data[pair.Key.(string)] = pair.Value
@@ -64,6 +64,11 @@ func TestHeaders(t *testing.T) {
 			Value:  []string{"crit blah"},
 			Method: "Critical",
 		},
+		{
+			Key:    jwe.EncapsulatedKeyKey,
+			Value:  []byte("ek foobarbaz"),
+			Method: "EncapsulatedKey",
+		},
 		{
 			Key:    jwe.EphemeralPublicKeyKey,
 			Value:  pubKey,
@@ -1,6 +1,8 @@
 package jwe
 
 import (
+	"crypto"
+
 	"github.com/lestrrat-go/iter/mapiter"
 	"github.com/lestrrat-go/jwx/v2/internal/iter"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
@@ -148,6 +150,25 @@ type Message struct {
 	storeProtectedHeaders bool
 }
 
+// ECDHPrivateKey is implemented by private keys that can perform ECDH
+// key agreement without exposing the private key material, such as keys
+// stored in a hardware security module or a key management service.
+// Such keys can be passed to `jwe.WithKey()` when decrypting messages
+// using the ECDH-ES family of key encryption algorithms.
+type ECDHPrivateKey interface {
+	// Public returns the public key corresponding to the private key.
+	// This is one of *ecdsa.PublicKey, x25519.PublicKey, or x448.PublicKey.
+	Public() crypto.PublicKey
+
+	// ECDH performs the key agreement between the private key and
+	// the given public key, and returns the shared secret Z. The public
+	// key is of the same type as the one returned by Public().
+	//
+	// For NIST curves, Z is the x-coordinate of the shared point,
+	// encoded in the same number of bytes as the curve's field size.
+	ECDH(crypto.PublicKey) ([]byte, error)
+}
+
 // populater is an interface for things that may modify the
 // JWE header. e.g. ByteWithECPrivateKey
 type populater interface {
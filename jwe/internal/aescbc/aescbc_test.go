@@ -1,10 +1,16 @@
 package aescbc
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/rand"
+	"fmt"
+	"io"
 	"testing"
+	"testing/iotest"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestVectorsAESCBC128(t *testing.T) {
@@ -81,3 +87,65 @@ func TestPad(t *testing.T) {
 		}
 	}
 }
+
+func TestStream(t *testing.T) {
+	sizes := []int{0, 1, 15, 16, 17, 100, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5}
+	for _, keysize := range []int{32, 48, 64} {
+		key := make([]byte, keysize)
+		_, err := rand.Read(key)
+		require.NoError(t, err, `rand.Read should succeed`)
+		nonce := make([]byte, NonceSize)
+		_, err = rand.Read(nonce)
+		require.NoError(t, err, `rand.Read should succeed`)
+		aad := []byte("additional authenticated data")
+
+		c, err := New(key, aes.NewCipher)
+		require.NoError(t, err, `aescbc.New should succeed`)
+
+		for _, size := range sizes {
+			keysize := keysize
+			size := size
+			t.Run(fmt.Sprintf("keysize=%d,size=%d", keysize, size), func(t *testing.T) {
+				plaintext := make([]byte, size)
+				_, err := rand.Read(plaintext)
+				require.NoError(t, err, `rand.Read should succeed`)
+
+				expected := c.Seal(nil, nonce, plaintext, aad)
+				tagOffset := len(expected) - c.tagsize
+
+				var ciphertext bytes.Buffer
+				sealer, err := c.NewSealer(&ciphertext, nonce, aad)
+				require.NoError(t, err, `c.NewSealer should succeed`)
+				// write in odd-sized pieces
+				for in := plaintext; len(in) > 0; {
+					n := 7
+					if n > len(in) {
+						n = len(in)
+					}
+					_, err := sealer.Write(in[:n])
+					require.NoError(t, err, `sealer.Write should succeed`)
+					in = in[n:]
+				}
+				tag, err := sealer.Finalize()
+				require.NoError(t, err, `sealer.Finalize should succeed`)
+				require.True(t, bytes.Equal(expected[:tagOffset], ciphertext.Bytes()), `ciphertext should match`)
+				require.Equal(t, expected[tagOffset:], tag, `tag should match`)
+
+				tagFunc := func() ([]byte, error) { return tag, nil }
+				opener, err := c.NewOpener(iotest.HalfReader(bytes.NewReader(ciphertext.Bytes())), nonce, aad, tagFunc)
+				require.NoError(t, err, `c.NewOpener should succeed`)
+				decrypted, err := io.ReadAll(opener)
+				require.NoError(t, err, `io.ReadAll should succeed`)
+				require.True(t, bytes.Equal(plaintext, decrypted), `plaintext should match`)
+
+				badTag := make([]byte, len(tag))
+				copy(badTag, tag)
+				badTag[0] ^= 0x1
+				opener, err = c.NewOpener(bytes.NewReader(ciphertext.Bytes()), nonce, aad, func() ([]byte, error) { return badTag, nil })
+				require.NoError(t, err, `c.NewOpener should succeed`)
+				_, err = io.ReadAll(opener)
+				require.Error(t, err, `io.ReadAll should fail`)
+			})
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package aescbc
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"io"
+)
+
+const chunkSize = 32 * 1024
+
+func (c Hmac) newMAC(nonce, aad []byte) (hash.Hash, error) {
+	if len(nonce) != NonceSize {
+		return nil, fmt.Errorf(`invalid nonce size (expected %d, got %d)`, NonceSize, len(nonce))
+	}
+
+	h := hmac.New(c.hash, c.integrityKey)
+	h.Write(aad)
+	h.Write(nonce)
+	return h, nil
+}
+
+func finalizeMAC(h hash.Hash, aad []byte, tagsize int) []byte {
+	var al [8]byte
+	binary.BigEndian.PutUint64(al[:], uint64(len(aad)*8))
+	h.Write(al[:])
+	return h.Sum(nil)[:tagsize]
+}
+
+// Sealer encrypts the data written to it, and writes the resulting
+// ciphertext to the underlying io.Writer.
+type Sealer struct {
+	dst       io.Writer
+	mode      cipher.BlockMode
+	mac       hash.Hash
+	aad       []byte
+	tagsize   int
+	buf       []byte
+	buflen    int
+	finalized bool
+}
+
+// NewSealer creates a new Sealer. Data written to the Sealer will be
+// encrypted and written to `dst`. Finalize must be called after all
+// of the data has been written in order to flush the last block
+// and obtain the authentication tag.
+func (c Hmac) NewSealer(dst io.Writer, nonce, aad []byte) (*Sealer, error) {
+	mac, err := c.newMAC(nonce, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Sealer{
+		dst:     dst,
+		mode:    cipher.NewCBCEncrypter(c.blockCipher, nonce),
+		mac:     mac,
+		aad:     aad,
+		tagsize: c.tagsize,
+		buf:     make([]byte, chunkSize),
+	}, nil
+}
+
+func (s *Sealer) flush(buf []byte) error {
+	s.mode.CryptBlocks(buf, buf)
+	s.mac.Write(buf)
+	_, err := s.dst.Write(buf)
+	return err
+}
+
+func (s *Sealer) Write(p []byte) (int, error) {
+	if s.finalized {
+		return 0, fmt.Errorf(`aescbc: write after Finalize`)
+	}
+
+	var written int
+	for len(p) > 0 {
+		n := copy(s.buf[s.buflen:], p)
+		s.buflen += n
+		written += n
+		p = p[n:]
+
+		if s.buflen == len(s.buf) {
+			if err := s.flush(s.buf); err != nil {
+				return written, err
+			}
+			s.buflen = 0
+		}
+	}
+	return written, nil
+}
+
+// Finalize pads and encrypts the remaining data, and returns the
+// authentication tag. The Sealer should not be used after calling this method.
+func (s *Sealer) Finalize() ([]byte, error) {
+	if s.finalized {
+		return nil, fmt.Errorf(`aescbc: Finalize called twice`)
+	}
+	s.finalized = true
+
+	if err := s.flush(pad(s.buf[:s.buflen], s.mode.BlockSize())); err != nil {
+		return nil, err
+	}
+	return finalizeMAC(s.mac, s.aad, s.tagsize), nil
+}
+
+// Opener decrypts the ciphertext read from the underlying io.Reader.
+type Opener struct {
+	src     io.Reader
+	mode    cipher.BlockMode
+	mac     hash.Hash
+	aad     []byte
+	tagsize int
+	tagFunc func() ([]byte, error)
+	in      []byte
+	inlen   int
+	out     []byte
+	pending []byte
+	err     error
+}
+
+// NewOpener creates a new Opener. Reading from the Opener yields the
+// plaintext corresponding to the ciphertext read from `src`.
+//
+// Once `src` has been exhausted, `tagFunc` is called to retrieve the
+// authentication tag. If the tag does not match the content, Read returns
+// an error instead of io.EOF. The padding is only checked after the tag
+// has been verified.
+//
+// Note that the plaintext is returned from Read BEFORE the authentication
+// tag is verified. Users must not act on the plaintext until Read has
+// returned io.EOF.
+func (c Hmac) NewOpener(src io.Reader, nonce, aad []byte, tagFunc func() ([]byte, error)) (*Opener, error) {
+	mac, err := c.newMAC(nonce, aad)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Opener{
+		src:     src,
+		mode:    cipher.NewCBCDecrypter(c.blockCipher, nonce),
+		mac:     mac,
+		aad:     aad,
+		tagsize: c.tagsize,
+		tagFunc: tagFunc,
+		in:      make([]byte, chunkSize+c.blockCipher.BlockSize()),
+		out:     make([]byte, chunkSize+c.blockCipher.BlockSize()),
+	}, nil
+}
+
+func (o *Opener) Read(p []byte) (int, error) {
+	for len(o.pending) == 0 {
+		if o.err != nil {
+			return 0, o.err
+		}
+		o.fill()
+	}
+
+	n := copy(p, o.pending)
+	o.pending = o.pending[n:]
+	return n, nil
+}
+
+// fill reads more ciphertext, and decrypts as much of it as possible.
+// The last block is always held back until we reach the end of the
+// ciphertext, as it needs to be unpadded.
+func (o *Opener) fill() {
+	n, err := o.src.Read(o.in[o.inlen:])
+	if n > 0 {
+		o.mac.Write(o.in[o.inlen : o.inlen+n])
+		o.inlen += n
+	}
+
+	if err != nil {
+		if err == io.EOF {
+			err = o.finalize()
+		}
+		o.err = err
+		return
+	}
+
+	bs := o.mode.BlockSize()
+	if o.inlen <= bs {
+		return
+	}
+
+	l := ((o.inlen - 1) / bs) * bs
+	o.mode.CryptBlocks(o.out[:l], o.in[:l])
+	o.pending = o.out[:l]
+	o.inlen = copy(o.in, o.in[l:o.inlen])
+}
+
+func (o *Opener) finalize() error {
+	bs := o.mode.BlockSize()
+	if o.inlen < bs || o.inlen%bs != 0 {
+		return fmt.Errorf(`aescbc: invalid ciphertext (invalid length)`)
+	}
+
+	tag, err := o.tagFunc()
+	if err != nil {
+		return fmt.Errorf(`aescbc: failed to retrieve tag: %w`, err)
+	}
+
+	if subtle.ConstantTimeCompare(finalizeMAC(o.mac, o.aad, o.tagsize), tag) != 1 {
+		return fmt.Errorf(`aescbc: invalid ciphertext (tag mismatch)`)
+	}
+
+	o.mode.CryptBlocks(o.out[:o.inlen], o.in[:o.inlen])
+	last, err := unpad(o.out[o.inlen-bs:o.inlen], bs)
+	if err != nil {
+		return fmt.Errorf(`aescbc: failed to generate plaintext from decrypted blocks: %w`, err)
+	}
+	o.pending = o.out[:o.inlen-bs+len(last)]
+	o.inlen = 0
+	return io.EOF
+}
@@ -0,0 +1,209 @@
+// Package aesgcm implements AES-GCM encryption and decryption over
+// streams of data. The output is compatible with that of crypto/cipher's
+// GCM implementation, but the content does not need to be kept in memory
+// all at once.
+//
+// Only 96-bit nonces and 128-bit tags, which are the parameters used by
+// JWE, are supported.
+package aesgcm
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	NonceSize = 12
+	TagSize   = 16
+
+	blockSize = 16
+
+	// GCM limits the plaintext to 2^39-256 bits, which is the point
+	// where the 32-bit block counter would wrap around
+	maxContentSize = ((1 << 32) - 2) * blockSize
+
+	chunkSize = 32 * 1024
+)
+
+type stream struct {
+	ghash   ghash
+	ctr     cipher.Stream
+	tagMask [blockSize]byte
+	aadLen  uint64
+	ctLen   uint64
+}
+
+func (s *stream) init(block cipher.Block, nonce, aad []byte) error {
+	if block.BlockSize() != blockSize {
+		return fmt.Errorf(`aesgcm: block cipher must have a block size of %d (got %d)`, blockSize, block.BlockSize())
+	}
+
+	if len(nonce) != NonceSize {
+		return fmt.Errorf(`aesgcm: invalid nonce size (expected %d, got %d)`, NonceSize, len(nonce))
+	}
+
+	var key [blockSize]byte
+	block.Encrypt(key[:], key[:])
+	s.ghash.init(&key)
+
+	// With a 96-bit nonce, the first counter block is nonce || 0^31 || 1.
+	// This is used to mask the tag, and the content is encrypted
+	// starting from the next counter value
+	var counter [blockSize]byte
+	copy(counter[:], nonce)
+	counter[blockSize-1] = 1
+	block.Encrypt(s.tagMask[:], counter[:])
+
+	counter[blockSize-1] = 2
+	s.ctr = cipher.NewCTR(block, counter[:])
+
+	s.ghash.write(aad)
+	s.ghash.flush()
+	s.aadLen = uint64(len(aad))
+	return nil
+}
+
+func (s *stream) add(n int) error {
+	if uint64(n) > maxContentSize-s.ctLen {
+		return fmt.Errorf(`aesgcm: content too large`)
+	}
+	s.ctLen += uint64(n)
+	return nil
+}
+
+func (s *stream) tag() []byte {
+	s.ghash.flush()
+
+	var lengths [blockSize]byte
+	binary.BigEndian.PutUint64(lengths[:], s.aadLen*8)
+	binary.BigEndian.PutUint64(lengths[8:], s.ctLen*8)
+	s.ghash.updateBlocks(lengths[:])
+
+	tag := make([]byte, TagSize)
+	s.ghash.sum(tag)
+	for i := range tag {
+		tag[i] ^= s.tagMask[i]
+	}
+	return tag
+}
+
+// Sealer encrypts the data written to it, and writes the resulting
+// ciphertext to the underlying io.Writer.
+type Sealer struct {
+	stream
+	dst io.Writer
+	buf []byte
+}
+
+// NewSealer creates a new Sealer. Data written to the Sealer will be
+// encrypted using `block` in GCM mode, and written to `dst`.
+// Finalize must be called after all of the data has been written
+// in order to obtain the authentication tag.
+func NewSealer(dst io.Writer, block cipher.Block, nonce, aad []byte) (*Sealer, error) {
+	s := &Sealer{
+		dst: dst,
+		buf: make([]byte, chunkSize),
+	}
+	if err := s.init(block, nonce, aad); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Sealer) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := len(p)
+		if n > len(s.buf) {
+			n = len(s.buf)
+		}
+
+		if err := s.add(n); err != nil {
+			return written, err
+		}
+
+		out := s.buf[:n]
+		s.ctr.XORKeyStream(out, p[:n])
+		s.ghash.write(out)
+		if _, err := s.dst.Write(out); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Finalize returns the authentication tag for the data that has been
+// written so far. The Sealer should not be used after calling this method.
+func (s *Sealer) Finalize() ([]byte, error) {
+	return s.tag(), nil
+}
+
+// Opener decrypts the ciphertext read from the underlying io.Reader.
+type Opener struct {
+	stream
+	src     io.Reader
+	tagFunc func() ([]byte, error)
+	err     error
+}
+
+// NewOpener creates a new Opener. Reading from the Opener yields the
+// plaintext corresponding to the ciphertext read from `src`.
+//
+// Once `src` has been exhausted, `tagFunc` is called to retrieve the
+// authentication tag. If the tag does not match the content, Read returns
+// an error instead of io.EOF.
+//
+// Note that the plaintext is returned from Read BEFORE the authentication
+// tag is verified. Users must not act on the plaintext until Read has
+// returned io.EOF.
+func NewOpener(src io.Reader, block cipher.Block, nonce, aad []byte, tagFunc func() ([]byte, error)) (*Opener, error) {
+	o := &Opener{
+		src:     src,
+		tagFunc: tagFunc,
+	}
+	if err := o.init(block, nonce, aad); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Opener) Read(p []byte) (int, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+
+	n, err := o.src.Read(p)
+	if n > 0 {
+		if adderr := o.add(n); adderr != nil {
+			o.err = adderr
+			return 0, o.err
+		}
+		o.ghash.write(p[:n])
+		o.ctr.XORKeyStream(p[:n], p[:n])
+	}
+
+	if err != nil {
+		if err == io.EOF {
+			err = o.verify()
+		}
+		o.err = err
+	}
+	return n, err
+}
+
+func (o *Opener) verify() error {
+	tag, err := o.tagFunc()
+	if err != nil {
+		return fmt.Errorf(`aesgcm: failed to retrieve tag: %w`, err)
+	}
+
+	if subtle.ConstantTimeCompare(o.tag(), tag) != 1 {
+		return fmt.Errorf(`aesgcm: invalid ciphertext (tag mismatch)`)
+	}
+	return io.EOF
+}
@@ -0,0 +1,83 @@
+package aesgcm
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStream(t *testing.T) {
+	sizes := []int{0, 1, 15, 16, 17, 100, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5}
+	for _, keysize := range []int{16, 24, 32} {
+		key := make([]byte, keysize)
+		_, err := rand.Read(key)
+		require.NoError(t, err, `rand.Read should succeed`)
+		nonce := make([]byte, NonceSize)
+		_, err = rand.Read(nonce)
+		require.NoError(t, err, `rand.Read should succeed`)
+
+		block, err := aes.NewCipher(key)
+		require.NoError(t, err, `aes.NewCipher should succeed`)
+		aead, err := cipher.NewGCM(block)
+		require.NoError(t, err, `cipher.NewGCM should succeed`)
+
+		for _, aadsize := range []int{0, 13, 16, 51} {
+			aad := make([]byte, aadsize)
+			_, err := rand.Read(aad)
+			require.NoError(t, err, `rand.Read should succeed`)
+
+			for _, size := range sizes {
+				keysize := keysize
+				size := size
+				t.Run(fmt.Sprintf("keysize=%d,aadsize=%d,size=%d", keysize, aadsize, size), func(t *testing.T) {
+					plaintext := make([]byte, size)
+					_, err := rand.Read(plaintext)
+					require.NoError(t, err, `rand.Read should succeed`)
+
+					expected := aead.Seal(nil, nonce, plaintext, aad)
+					tagOffset := len(expected) - TagSize
+
+					var ciphertext bytes.Buffer
+					sealer, err := NewSealer(&ciphertext, block, nonce, aad)
+					require.NoError(t, err, `NewSealer should succeed`)
+					// write in odd-sized pieces
+					for in := plaintext; len(in) > 0; {
+						n := 7
+						if n > len(in) {
+							n = len(in)
+						}
+						_, err := sealer.Write(in[:n])
+						require.NoError(t, err, `sealer.Write should succeed`)
+						in = in[n:]
+					}
+					tag, err := sealer.Finalize()
+					require.NoError(t, err, `sealer.Finalize should succeed`)
+					require.True(t, bytes.Equal(expected[:tagOffset], ciphertext.Bytes()), `ciphertext should match`)
+					require.Equal(t, expected[tagOffset:], tag, `tag should match`)
+
+					tagFunc := func() ([]byte, error) { return tag, nil }
+					opener, err := NewOpener(iotest.HalfReader(bytes.NewReader(ciphertext.Bytes())), block, nonce, aad, tagFunc)
+					require.NoError(t, err, `NewOpener should succeed`)
+					decrypted, err := io.ReadAll(opener)
+					require.NoError(t, err, `io.ReadAll should succeed`)
+					require.True(t, bytes.Equal(plaintext, decrypted), `plaintext should match`)
+
+					badTag := make([]byte, len(tag))
+					copy(badTag, tag)
+					badTag[0] ^= 0x1
+					opener, err = NewOpener(bytes.NewReader(ciphertext.Bytes()), block, nonce, aad, func() ([]byte, error) { return badTag, nil })
+					require.NoError(t, err, `NewOpener should succeed`)
+					_, err = io.ReadAll(opener)
+					require.Error(t, err, `io.ReadAll should fail`)
+				})
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package aesgcm
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// ghash computes GHASH over data that is written to it incrementally.
+//
+// The standard library does not expose GHASH by itself, and its AEAD
+// interface requires the entire message to be present in memory. The
+// multiplication in GF(2^128) below does not use table lookups or
+// branches that depend on secret data: carry-less products are computed
+// using integer multiplication with "holes" in the operands, so that
+// carries never spill into the bits that are kept. This is the technique
+// described by Thomas Pornin for BearSSL's constant-time GHASH.
+type ghash struct {
+	// the key H, in the same representation as y
+	h0, h1 uint64
+	// the current value, with y1 holding the first (big endian) 64 bits
+	y0, y1 uint64
+	buf    [blockSize]byte
+	buflen int
+}
+
+func (g *ghash) init(key *[blockSize]byte) {
+	g.h1 = binary.BigEndian.Uint64(key[:8])
+	g.h0 = binary.BigEndian.Uint64(key[8:])
+}
+
+// bmul64 returns the lower 64 bits of the carry-less product of x and y.
+// Each operand is split into four parts that have three zero bits
+// between every pair of data bits, which leaves enough room for the
+// carries of the integer multiplications to be discarded.
+func bmul64(x, y uint64) uint64 {
+	const (
+		m0 = 0x1111111111111111
+		m1 = 0x2222222222222222
+		m2 = 0x4444444444444444
+		m3 = 0x8888888888888888
+	)
+	x0, x1, x2, x3 := x&m0, x&m1, x&m2, x&m3
+	y0, y1, y2, y3 := y&m0, y&m1, y&m2, y&m3
+	z0 := (x0 * y0) ^ (x1 * y3) ^ (x2 * y2) ^ (x3 * y1)
+	z1 := (x0 * y1) ^ (x1 * y0) ^ (x2 * y3) ^ (x3 * y2)
+	z2 := (x0 * y2) ^ (x1 * y1) ^ (x2 * y0) ^ (x3 * y3)
+	z3 := (x0 * y3) ^ (x1 * y2) ^ (x2 * y1) ^ (x3 * y0)
+	return (z0 & m0) | (z1 & m1) | (z2 & m2) | (z3 & m3)
+}
+
+// mul sets y to y*H
+func (g *ghash) mul() {
+	// GCM uses a bit-reflected representation, so the lower half of each
+	// 128-bit product is computed from the operands as they are, and the
+	// upper half from the bit-reversed operands (Karatsuba)
+	y0, y1 := g.y0, g.y1
+	h0, h1 := g.h0, g.h1
+	y0r, y1r := bits.Reverse64(y0), bits.Reverse64(y1)
+	h0r, h1r := bits.Reverse64(h0), bits.Reverse64(h1)
+
+	z0 := bmul64(y0, h0)
+	z1 := bmul64(y1, h1)
+	z2 := bmul64(y0^y1, h0^h1)
+	z0h := bmul64(y0r, h0r)
+	z1h := bmul64(y1r, h1r)
+	z2h := bmul64(y0r^y1r, h0r^h1r)
+	z2 ^= z0 ^ z1
+	z2h ^= z0h ^ z1h
+	z0h = bits.Reverse64(z0h) >> 1
+	z1h = bits.Reverse64(z1h) >> 1
+	z2h = bits.Reverse64(z2h) >> 1
+
+	// the 256-bit product, which is shifted by one bit because of the
+	// bit reflection, and then reduced modulo x^128 + x^7 + x^2 + x + 1
+	v0 := z0
+	v1 := z0h ^ z2
+	v2 := z1 ^ z2h
+	v3 := z1h
+
+	v3 = (v3 << 1) | (v2 >> 63)
+	v2 = (v2 << 1) | (v1 >> 63)
+	v1 = (v1 << 1) | (v0 >> 63)
+	v0 <<= 1
+
+	v2 ^= v0 ^ (v0 >> 1) ^ (v0 >> 2) ^ (v0 >> 7)
+	v1 ^= (v0 << 63) ^ (v0 << 62) ^ (v0 << 57)
+	v3 ^= v1 ^ (v1 >> 1) ^ (v1 >> 2) ^ (v1 >> 7)
+	v2 ^= (v1 << 63) ^ (v1 << 62) ^ (v1 << 57)
+
+	g.y0, g.y1 = v2, v3
+}
+
+// updateBlocks extends y with more polynomial terms from blocks, based on
+// Horner's rule. There must be a multiple of blockSize bytes in blocks.
+func (g *ghash) updateBlocks(blocks []byte) {
+	for len(blocks) > 0 {
+		g.y1 ^= binary.BigEndian.Uint64(blocks)
+		g.y0 ^= binary.BigEndian.Uint64(blocks[8:])
+		g.mul()
+		blocks = blocks[blockSize:]
+	}
+}
+
+// write extends y with more polynomial terms from data. Incomplete
+// blocks are buffered until more data is written, or flush is called.
+func (g *ghash) write(data []byte) {
+	if g.buflen > 0 {
+		n := copy(g.buf[g.buflen:], data)
+		g.buflen += n
+		data = data[n:]
+		if g.buflen < blockSize {
+			return
+		}
+		g.updateBlocks(g.buf[:])
+		g.buflen = 0
+	}
+
+	fullBlocks := (len(data) / blockSize) * blockSize
+	g.updateBlocks(data[:fullBlocks])
+	g.buflen = copy(g.buf[:], data[fullBlocks:])
+}
+
+// flush zero pads any buffered data, and adds it to y.
+func (g *ghash) flush() {
+	if g.buflen == 0 {
+		return
+	}
+
+	for i := g.buflen; i < blockSize; i++ {
+		g.buf[i] = 0
+	}
+	g.updateBlocks(g.buf[:])
+	g.buflen = 0
+}
+
+// sum writes the current value of y to out
+func (g *ghash) sum(out []byte) {
+	binary.BigEndian.PutUint64(out, g.y1)
+	binary.BigEndian.PutUint64(out[8:], g.y0)
+}
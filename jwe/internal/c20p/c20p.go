@@ -0,0 +1,214 @@
+// Package c20p implements ChaCha20-Poly1305 and XChaCha20-Poly1305
+// encryption and decryption over streams of data. The output is
+// compatible with that of golang.org/x/crypto/chacha20poly1305, but the
+// content does not need to be kept in memory all at once.
+package c20p
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/poly1305" //nolint:staticcheck
+)
+
+const (
+	KeySize    = chacha20poly1305.KeySize
+	NonceSize  = chacha20poly1305.NonceSize
+	NonceSizeX = chacha20poly1305.NonceSizeX
+	TagSize    = chacha20poly1305.Overhead
+
+	// The first block of the key stream is used to generate the
+	// Poly1305 key, and the 32-bit block counter must not wrap around
+	maxContentSize = (1 << 38) - 64
+
+	chunkSize = 32 * 1024
+)
+
+type stream struct {
+	mac    *poly1305.MAC
+	cipher *chacha20.Cipher
+	aadLen uint64
+	ctLen  uint64
+}
+
+func (s *stream) init(key, nonce, aad []byte) error {
+	if len(key) != KeySize {
+		return fmt.Errorf(`c20p: invalid key size (expected %d, got %d)`, KeySize, len(key))
+	}
+
+	// A 24 byte nonce selects XChaCha20
+	if len(nonce) != NonceSize && len(nonce) != NonceSizeX {
+		return fmt.Errorf(`c20p: invalid nonce size (expected %d or %d, got %d)`, NonceSize, NonceSizeX, len(nonce))
+	}
+
+	c, err := chacha20.NewUnauthenticatedCipher(key, nonce)
+	if err != nil {
+		return fmt.Errorf(`c20p: failed to create cipher: %w`, err)
+	}
+
+	var polyKey [32]byte
+	c.XORKeyStream(polyKey[:], polyKey[:])
+	c.SetCounter(1)
+
+	s.cipher = c
+	s.mac = poly1305.New(&polyKey)
+	s.write(aad)
+	s.pad(uint64(len(aad)))
+	s.aadLen = uint64(len(aad))
+	return nil
+}
+
+func (s *stream) write(p []byte) {
+	_, _ = s.mac.Write(p)
+}
+
+// pad writes zeros to the MAC so that data of length n
+// is aligned to 16 bytes
+func (s *stream) pad(n uint64) {
+	var zeros [16]byte
+	if rem := n % 16; rem != 0 {
+		s.write(zeros[:16-rem])
+	}
+}
+
+func (s *stream) add(n int) error {
+	if uint64(n) > maxContentSize-s.ctLen {
+		return fmt.Errorf(`c20p: content too large`)
+	}
+	s.ctLen += uint64(n)
+	return nil
+}
+
+func (s *stream) tag() []byte {
+	s.pad(s.ctLen)
+
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:], s.aadLen)
+	binary.LittleEndian.PutUint64(lengths[8:], s.ctLen)
+	s.write(lengths[:])
+
+	return s.mac.Sum(nil)
+}
+
+// Sealer encrypts the data written to it, and writes the resulting
+// ciphertext to the underlying io.Writer.
+type Sealer struct {
+	stream
+	dst io.Writer
+	buf []byte
+}
+
+// NewSealer creates a new Sealer. Data written to the Sealer will be
+// encrypted using ChaCha20-Poly1305, or XChaCha20-Poly1305 if `nonce`
+// is 24 bytes long, and written to `dst`. Finalize must be called after
+// all of the data has been written in order to obtain the
+// authentication tag.
+func NewSealer(dst io.Writer, key, nonce, aad []byte) (*Sealer, error) {
+	s := &Sealer{
+		dst: dst,
+		buf: make([]byte, chunkSize),
+	}
+	if err := s.init(key, nonce, aad); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Sealer) Write(p []byte) (int, error) {
+	var written int
+	for len(p) > 0 {
+		n := len(p)
+		if n > len(s.buf) {
+			n = len(s.buf)
+		}
+
+		if err := s.add(n); err != nil {
+			return written, err
+		}
+
+		out := s.buf[:n]
+		s.cipher.XORKeyStream(out, p[:n])
+		s.write(out)
+		if _, err := s.dst.Write(out); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Finalize returns the authentication tag for the data that has been
+// written so far. The Sealer should not be used after calling this method.
+func (s *Sealer) Finalize() ([]byte, error) {
+	return s.tag(), nil
+}
+
+// Opener decrypts the ciphertext read from the underlying io.Reader.
+type Opener struct {
+	stream
+	src     io.Reader
+	tagFunc func() ([]byte, error)
+	err     error
+}
+
+// NewOpener creates a new Opener. Reading from the Opener yields the
+// plaintext corresponding to the ciphertext read from `src`.
+//
+// Once `src` has been exhausted, `tagFunc` is called to retrieve the
+// authentication tag. If the tag does not match the content, Read returns
+// an error instead of io.EOF.
+//
+// Note that the plaintext is returned from Read BEFORE the authentication
+// tag is verified. Users must not act on the plaintext until Read has
+// returned io.EOF.
+func NewOpener(src io.Reader, key, nonce, aad []byte, tagFunc func() ([]byte, error)) (*Opener, error) {
+	o := &Opener{
+		src:     src,
+		tagFunc: tagFunc,
+	}
+	if err := o.init(key, nonce, aad); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+func (o *Opener) Read(p []byte) (int, error) {
+	if o.err != nil {
+		return 0, o.err
+	}
+
+	n, err := o.src.Read(p)
+	if n > 0 {
+		if adderr := o.add(n); adderr != nil {
+			o.err = adderr
+			return 0, o.err
+		}
+		o.write(p[:n])
+		o.cipher.XORKeyStream(p[:n], p[:n])
+	}
+
+	if err != nil {
+		if err == io.EOF {
+			err = o.verify()
+		}
+		o.err = err
+	}
+	return n, err
+}
+
+func (o *Opener) verify() error {
+	tag, err := o.tagFunc()
+	if err != nil {
+		return fmt.Errorf(`c20p: failed to retrieve tag: %w`, err)
+	}
+
+	if subtle.ConstantTimeCompare(o.tag(), tag) != 1 {
+		return fmt.Errorf(`c20p: invalid ciphertext (tag mismatch)`)
+	}
+	return io.EOF
+}
@@ -0,0 +1,91 @@
+package c20p
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+func TestStream(t *testing.T) {
+	sizes := []int{0, 1, 15, 16, 17, 63, 64, 65, 100, chunkSize - 1, chunkSize, chunkSize + 1, 3*chunkSize + 5}
+
+	key := make([]byte, KeySize)
+	_, err := rand.Read(key)
+	require.NoError(t, err, `rand.Read should succeed`)
+
+	testcases := []struct {
+		Name      string
+		NonceSize int
+		New       func([]byte) (cipher.AEAD, error)
+	}{
+		{Name: "C20P", NonceSize: NonceSize, New: chacha20poly1305.New},
+		{Name: "XC20P", NonceSize: NonceSizeX, New: chacha20poly1305.NewX},
+	}
+
+	for _, tc := range testcases {
+		nonce := make([]byte, tc.NonceSize)
+		_, err = rand.Read(nonce)
+		require.NoError(t, err, `rand.Read should succeed`)
+
+		aead, err := tc.New(key)
+		require.NoError(t, err, `creating AEAD should succeed`)
+
+		for _, aadsize := range []int{0, 13, 16, 51} {
+			aad := make([]byte, aadsize)
+			_, err := rand.Read(aad)
+			require.NoError(t, err, `rand.Read should succeed`)
+
+			for _, size := range sizes {
+				size := size
+				t.Run(fmt.Sprintf("%s,aadsize=%d,size=%d", tc.Name, aadsize, size), func(t *testing.T) {
+					plaintext := make([]byte, size)
+					_, err := rand.Read(plaintext)
+					require.NoError(t, err, `rand.Read should succeed`)
+
+					expected := aead.Seal(nil, nonce, plaintext, aad)
+					tagOffset := len(expected) - TagSize
+
+					var ciphertext bytes.Buffer
+					sealer, err := NewSealer(&ciphertext, key, nonce, aad)
+					require.NoError(t, err, `NewSealer should succeed`)
+					// write in odd-sized pieces
+					for in := plaintext; len(in) > 0; {
+						n := 7
+						if n > len(in) {
+							n = len(in)
+						}
+						_, err := sealer.Write(in[:n])
+						require.NoError(t, err, `sealer.Write should succeed`)
+						in = in[n:]
+					}
+					tag, err := sealer.Finalize()
+					require.NoError(t, err, `sealer.Finalize should succeed`)
+					require.True(t, bytes.Equal(expected[:tagOffset], ciphertext.Bytes()), `ciphertext should match`)
+					require.Equal(t, expected[tagOffset:], tag, `tag should match`)
+
+					tagFunc := func() ([]byte, error) { return tag, nil }
+					opener, err := NewOpener(iotest.HalfReader(bytes.NewReader(ciphertext.Bytes())), key, nonce, aad, tagFunc)
+					require.NoError(t, err, `NewOpener should succeed`)
+					decrypted, err := io.ReadAll(opener)
+					require.NoError(t, err, `io.ReadAll should succeed`)
+					require.True(t, bytes.Equal(plaintext, decrypted), `plaintext should match`)
+
+					badTag := make([]byte, len(tag))
+					copy(badTag, tag)
+					badTag[0] ^= 0x1
+					opener, err = NewOpener(bytes.NewReader(ciphertext.Bytes()), key, nonce, aad, func() ([]byte, error) { return badTag, nil })
+					require.NoError(t, err, `NewOpener should succeed`)
+					_, err = io.ReadAll(opener)
+					require.Error(t, err, `io.ReadAll should fail`)
+				})
+			}
+		}
+	}
+}
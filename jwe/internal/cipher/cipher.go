@@ -4,14 +4,21 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"fmt"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/aescbc"
+	"github.com/lestrrat-go/jwx/v2/jwe/internal/aesgcm"
+	chacha "github.com/lestrrat-go/jwx/v2/jwe/internal/c20p"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
 )
 
 var gcm = &gcmFetcher{}
 var cbc = &cbcFetcher{}
+var c20p = &chachaFetcher{nonceSize: chacha.NonceSize}
+var xc20p = &chachaFetcher{nonceSize: chacha.NonceSizeX}
 
 func (f gcmFetcher) Fetch(key []byte) (cipher.AEAD, error) {
 	aescipher, err := aes.NewCipher(key)
@@ -26,6 +33,36 @@ func (f gcmFetcher) Fetch(key []byte) (cipher.AEAD, error) {
 	return aead, nil
 }
 
+func (f gcmFetcher) FetchSealer(key, nonce, aad []byte, dst io.Writer) (StreamSealer, error) {
+	aescipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf(`cipher: failed to create AES cipher for GCM: %w`, err)
+	}
+
+	sealer, err := aesgcm.NewSealer(dst, aescipher, nonce, aad)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create GCM sealer: %w`, err)
+	}
+	return sealer, nil
+}
+
+func (f gcmFetcher) FetchOpener(key, nonce, aad []byte, src io.Reader, tagFunc TagFunc) (io.Reader, error) {
+	aescipher, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf(`cipher: failed to create AES cipher for GCM: %w`, err)
+	}
+
+	opener, err := aesgcm.NewOpener(src, aescipher, nonce, aad, tagFunc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create GCM opener: %w`, err)
+	}
+	return opener, nil
+}
+
+func (f gcmFetcher) NonceSize() int {
+	return aesgcm.NonceSize
+}
+
 func (f cbcFetcher) Fetch(key []byte) (cipher.AEAD, error) {
 	aead, err := aescbc.New(key, aes.NewCipher)
 	if err != nil {
@@ -34,11 +71,75 @@ func (f cbcFetcher) Fetch(key []byte) (cipher.AEAD, error) {
 	return aead, nil
 }
 
-func (c AesContentCipher) KeySize() int {
+func (f cbcFetcher) FetchSealer(key, nonce, aad []byte, dst io.Writer) (StreamSealer, error) {
+	c, err := aescbc.New(key, aes.NewCipher)
+	if err != nil {
+		return nil, fmt.Errorf(`cipher: failed to create AES cipher for CBC: %w`, err)
+	}
+
+	sealer, err := c.NewSealer(dst, nonce, aad)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create CBC sealer: %w`, err)
+	}
+	return sealer, nil
+}
+
+func (f cbcFetcher) FetchOpener(key, nonce, aad []byte, src io.Reader, tagFunc TagFunc) (io.Reader, error) {
+	c, err := aescbc.New(key, aes.NewCipher)
+	if err != nil {
+		return nil, fmt.Errorf(`cipher: failed to create AES cipher for CBC: %w`, err)
+	}
+
+	opener, err := c.NewOpener(src, nonce, aad, tagFunc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create CBC opener: %w`, err)
+	}
+	return opener, nil
+}
+
+func (f cbcFetcher) NonceSize() int {
+	return aescbc.NonceSize
+}
+
+func (f chachaFetcher) Fetch(key []byte) (cipher.AEAD, error) {
+	var aead cipher.AEAD
+	var err error
+	if f.nonceSize == chacha.NonceSizeX {
+		aead, err = chacha20poly1305.NewX(key)
+	} else {
+		aead, err = chacha20poly1305.New(key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`cipher: failed to create ChaCha20-Poly1305 cipher: %w`, err)
+	}
+	return aead, nil
+}
+
+func (f chachaFetcher) FetchSealer(key, nonce, aad []byte, dst io.Writer) (StreamSealer, error) {
+	sealer, err := chacha.NewSealer(dst, key, nonce, aad)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create ChaCha20-Poly1305 sealer: %w`, err)
+	}
+	return sealer, nil
+}
+
+func (f chachaFetcher) FetchOpener(key, nonce, aad []byte, src io.Reader, tagFunc TagFunc) (io.Reader, error) {
+	opener, err := chacha.NewOpener(src, key, nonce, aad, tagFunc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create ChaCha20-Poly1305 opener: %w`, err)
+	}
+	return opener, nil
+}
+
+func (f chachaFetcher) NonceSize() int {
+	return f.nonceSize
+}
+
+func (c aeadContentCipher) KeySize() int {
 	return c.keysize
 }
 
-func (c AesContentCipher) TagSize() int {
+func (c aeadContentCipher) TagSize() int {
 	return c.tagsize
 }
 
@@ -76,13 +177,47 @@ func NewAES(alg jwa.ContentEncryptionAlgorithm) (*AesContentCipher, error) {
 	}
 
 	return &AesContentCipher{
-		keysize: keysize,
-		tagsize: tagsize,
-		fetch:   fetcher,
+		aeadContentCipher: aeadContentCipher{
+			keysize: keysize,
+			tagsize: tagsize,
+			fetch:   fetcher,
+		},
+	}, nil
+}
+
+// NewChaCha creates a content cipher for C20P (ChaCha20-Poly1305)
+// or XC20P (XChaCha20-Poly1305)
+func NewChaCha(alg jwa.ContentEncryptionAlgorithm) (*ChaChaContentCipher, error) {
+	var fetcher Fetcher
+	switch alg {
+	case jwa.C20P:
+		fetcher = c20p
+	case jwa.XC20P:
+		fetcher = xc20p
+	default:
+		return nil, fmt.Errorf("failed to create ChaCha content cipher: invalid algorithm (%s)", alg)
+	}
+
+	return &ChaChaContentCipher{
+		aeadContentCipher: aeadContentCipher{
+			keysize: chacha.KeySize,
+			tagsize: chacha.TagSize,
+			fetch:   fetcher,
+		},
 	}, nil
 }
 
-func (c AesContentCipher) Encrypt(cek, plaintext, aad []byte) (iv, ciphertxt, tag []byte, err error) {
+// New creates a content cipher for the given content encryption algorithm
+func New(alg jwa.ContentEncryptionAlgorithm) (ContentCipher, error) {
+	switch alg {
+	case jwa.C20P, jwa.XC20P:
+		return NewChaCha(alg)
+	default:
+		return NewAES(alg)
+	}
+}
+
+func (c aeadContentCipher) Encrypt(cek, plaintext, aad []byte) (iv, ciphertxt, tag []byte, err error) {
 	var aead cipher.AEAD
 	aead, err = c.fetch.Fetch(cek)
 	if err != nil {
@@ -127,7 +262,7 @@ func (c AesContentCipher) Encrypt(cek, plaintext, aad []byte) (iv, ciphertxt, ta
 	return
 }
 
-func (c AesContentCipher) Decrypt(cek, iv, ciphertxt, tag, aad []byte) (plaintext []byte, err error) {
+func (c aeadContentCipher) Decrypt(cek, iv, ciphertxt, tag, aad []byte) (plaintext []byte, err error) {
 	aead, err := c.fetch.Fetch(cek)
 	if err != nil {
 		return nil, fmt.Errorf(`failed to fetch AEAD data: %w`, err)
@@ -159,3 +294,39 @@ func (c AesContentCipher) Decrypt(cek, iv, ciphertxt, tag, aad []byte) (plaintex
 	plaintext = buf
 	return
 }
+
+// NewSealer creates a StreamSealer that encrypts the content written
+// to it using `cek`, and writes the ciphertext to `dst`. The
+// initialization vector that was generated for the content is
+// returned along with the sealer.
+func (c aeadContentCipher) NewSealer(dst io.Writer, cek, aad []byte) ([]byte, StreamSealer, error) {
+	var bs keygen.ByteSource
+	var err error
+	if c.NonceGenerator == nil {
+		bs, err = keygen.NewRandom(c.fetch.NonceSize()).Generate()
+	} else {
+		bs, err = c.NonceGenerator.Generate()
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to generate nonce: %w`, err)
+	}
+	iv := bs.Bytes()
+
+	sealer, err := c.fetch.FetchSealer(cek, iv, aad, dst)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to fetch sealer: %w`, err)
+	}
+	return iv, sealer, nil
+}
+
+// NewOpener creates an io.Reader that decrypts the ciphertext read
+// from `src`. Once `src` is exhausted, the authentication tag obtained
+// from `tagFunc` is verified, and an error is returned in place of
+// io.EOF if it does not match.
+func (c aeadContentCipher) NewOpener(src io.Reader, cek, iv, aad []byte, tagFunc TagFunc) (io.Reader, error) {
+	opener, err := c.fetch.FetchOpener(cek, iv, aad, src, tagFunc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to fetch opener: %w`, err)
+	}
+	return opener, nil
+}
@@ -25,3 +25,22 @@ func TestAES(t *testing.T) {
 		t.Logf("keysize = %d", c.KeySize())
 	}
 }
+
+func TestChaCha(t *testing.T) {
+	algs := []jwa.ContentEncryptionAlgorithm{
+		jwa.C20P,
+		jwa.XC20P,
+	}
+	for _, alg := range algs {
+		c, err := cipher.New(alg)
+		if !assert.NoError(t, err, "BuildCipher for %s succeeds", alg) {
+			return
+		}
+		if !assert.Equal(t, 32, c.KeySize(), "key size for %s should be 32", alg) {
+			return
+		}
+	}
+
+	_, err := cipher.NewChaCha(jwa.A128GCM)
+	assert.Error(t, err, "NewChaCha should fail for non-ChaCha algorithms")
+}
@@ -2,6 +2,7 @@ package cipher
 
 import (
 	"crypto/cipher"
+	"io"
 
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
 )
@@ -16,19 +17,52 @@ type ContentCipher interface {
 	KeySize() int
 	Encrypt(cek, aad, plaintext []byte) ([]byte, []byte, []byte, error)
 	Decrypt(cek, iv, aad, ciphertext, tag []byte) ([]byte, error)
+	NewSealer(dst io.Writer, cek, aad []byte) ([]byte, StreamSealer, error)
+	NewOpener(src io.Reader, cek, iv, aad []byte, tagFunc TagFunc) (io.Reader, error)
 }
 
+// StreamSealer encrypts the content written to it, and writes the
+// resulting ciphertext to an underlying io.Writer
+type StreamSealer interface {
+	io.Writer
+	// Finalize flushes any buffered content, and returns the
+	// authentication tag
+	Finalize() ([]byte, error)
+}
+
+// TagFunc is used by stream openers to retrieve the authentication
+// tag once the entire ciphertext has been consumed
+type TagFunc func() ([]byte, error)
+
 type Fetcher interface {
 	Fetch([]byte) (cipher.AEAD, error)
+	FetchSealer(key, nonce, aad []byte, dst io.Writer) (StreamSealer, error)
+	FetchOpener(key, nonce, aad []byte, src io.Reader, tagFunc TagFunc) (io.Reader, error)
+	NonceSize() int
 }
 
 type gcmFetcher struct{}
 type cbcFetcher struct{}
+type chachaFetcher struct {
+	nonceSize int
+}
 
-// AesContentCipher represents a cipher based on AES
-type AesContentCipher struct {
+// aeadContentCipher implements ContentCipher using the AEAD
+// obtained from a Fetcher
+type aeadContentCipher struct {
 	NonceGenerator keygen.Generator
 	fetch          Fetcher
 	keysize        int
 	tagsize        int
 }
+
+// AesContentCipher represents a cipher based on AES
+type AesContentCipher struct {
+	aeadContentCipher
+}
+
+// ChaChaContentCipher represents a cipher based on ChaCha20-Poly1305
+// or XChaCha20-Poly1305
+type ChaChaContentCipher struct {
+	aeadContentCipher
+}
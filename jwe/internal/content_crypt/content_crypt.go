@@ -2,6 +2,7 @@ package content_crypt //nolint:golint
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/cipher"
@@ -24,10 +25,21 @@ func (c Generic) Decrypt(cek, iv, ciphertext, tag, aad []byte) ([]byte, error) {
 	return c.cipher.Decrypt(cek, iv, ciphertext, tag, aad)
 }
 
+// NewSealer creates a sealer that encrypts the content written to it,
+// and writes the ciphertext to `dst`. The generated initialization
+// vector is returned along with the sealer.
+func (c Generic) NewSealer(dst io.Writer, cek, aad []byte) ([]byte, cipher.StreamSealer, error) {
+	iv, sealer, err := c.cipher.NewSealer(dst, cek, aad)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to create content sealer: %w`, err)
+	}
+	return iv, sealer, nil
+}
+
 func NewGeneric(alg jwa.ContentEncryptionAlgorithm) (*Generic, error) {
-	c, err := cipher.NewAES(alg)
+	c, err := cipher.New(alg)
 	if err != nil {
-		return nil, fmt.Errorf(`aes crypt: failed to create content cipher: %w`, err)
+		return nil, fmt.Errorf(`failed to create content cipher: %w`, err)
 	}
 
 	return &Generic{
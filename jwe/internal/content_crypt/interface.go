@@ -1,6 +1,8 @@
 package content_crypt //nolint:golint
 
 import (
+	"io"
+
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/cipher"
 )
@@ -16,5 +18,6 @@ type Generic struct {
 
 type Cipher interface {
 	Decrypt([]byte, []byte, []byte, []byte, []byte) ([]byte, error)
+	NewOpener(io.Reader, []byte, []byte, []byte, cipher.TagFunc) (io.Reader, error)
 	KeySize() int
 }
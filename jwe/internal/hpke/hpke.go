@@ -0,0 +1,209 @@
+// Package hpke implements the HPKE (RFC 9180) operations used by the
+// HPKE based key management algorithms for JWE.
+package hpke
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/cloudflare/circl/hpke"
+	"github.com/cloudflare/circl/kem"
+	"github.com/lestrrat-go/jwx/v2/internal/keyconv"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
+)
+
+// IsHPKE returns true if the given algorithm is one of the HPKE algorithms
+func IsHPKE(alg jwa.KeyEncryptionAlgorithm) bool {
+	_, err := suiteFor(alg)
+	return err == nil
+}
+
+func suiteFor(alg jwa.KeyEncryptionAlgorithm) (hpke.Suite, error) {
+	switch alg {
+	case jwa.HPKE_0:
+		return hpke.NewSuite(hpke.KEM_P256_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES128GCM), nil
+	case jwa.HPKE_1:
+		return hpke.NewSuite(hpke.KEM_P384_HKDF_SHA384, hpke.KDF_HKDF_SHA384, hpke.AEAD_AES256GCM), nil
+	case jwa.HPKE_2:
+		return hpke.NewSuite(hpke.KEM_P521_HKDF_SHA512, hpke.KDF_HKDF_SHA512, hpke.AEAD_AES256GCM), nil
+	case jwa.HPKE_3:
+		return hpke.NewSuite(hpke.KEM_X25519_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES128GCM), nil
+	case jwa.HPKE_4:
+		return hpke.NewSuite(hpke.KEM_X25519_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_ChaCha20Poly1305), nil
+	case jwa.HPKE_5:
+		return hpke.NewSuite(hpke.KEM_X448_HKDF_SHA512, hpke.KDF_HKDF_SHA512, hpke.AEAD_AES256GCM), nil
+	case jwa.HPKE_6:
+		return hpke.NewSuite(hpke.KEM_X448_HKDF_SHA512, hpke.KDF_HKDF_SHA512, hpke.AEAD_ChaCha20Poly1305), nil
+	case jwa.HPKE_7:
+		return hpke.NewSuite(hpke.KEM_P256_HKDF_SHA256, hpke.KDF_HKDF_SHA256, hpke.AEAD_AES256GCM), nil
+	default:
+		return hpke.Suite{}, fmt.Errorf(`unsupported HPKE algorithm (%s)`, alg)
+	}
+}
+
+func curveFor(kemID hpke.KEM) elliptic.Curve {
+	switch kemID {
+	case hpke.KEM_P256_HKDF_SHA256:
+		return elliptic.P256()
+	case hpke.KEM_P384_HKDF_SHA384:
+		return elliptic.P384()
+	case hpke.KEM_P521_HKDF_SHA512:
+		return elliptic.P521()
+	default:
+		return nil
+	}
+}
+
+func publicKey(alg jwa.KeyEncryptionAlgorithm, kemID hpke.KEM, key interface{}) (kem.PublicKey, error) {
+	scheme := kemID.Scheme()
+
+	var buf []byte
+	if crv := curveFor(kemID); crv != nil {
+		var pubkey ecdsa.PublicKey
+		if err := keyconv.ECDSAPublicKey(&pubkey, key); err != nil {
+			return nil, fmt.Errorf(`%s requires an ECDSA public key: %w`, alg, err)
+		}
+		if pubkey.Curve != crv {
+			return nil, fmt.Errorf(`%s requires a key on curve %s (got %s)`, alg, crv.Params().Name, pubkey.Curve.Params().Name)
+		}
+		buf = elliptic.Marshal(pubkey.Curve, pubkey.X, pubkey.Y)
+	} else {
+		switch key := key.(type) {
+		case x25519.PublicKey:
+			if kemID != hpke.KEM_X25519_HKDF_SHA256 {
+				return nil, fmt.Errorf(`%s can not be used with X25519 keys`, alg)
+			}
+			buf = key
+		case *x25519.PublicKey:
+			return publicKey(alg, kemID, *key)
+		case x448.PublicKey:
+			if kemID != hpke.KEM_X448_HKDF_SHA512 {
+				return nil, fmt.Errorf(`%s can not be used with X448 keys`, alg)
+			}
+			buf = key
+		case *x448.PublicKey:
+			return publicKey(alg, kemID, *key)
+		default:
+			return nil, fmt.Errorf(`invalid public key type for %s: %T`, alg, key)
+		}
+		if len(buf) != scheme.PublicKeySize() {
+			return nil, fmt.Errorf(`invalid public key size for %s: %d`, alg, len(buf))
+		}
+	}
+
+	pubkey, err := scheme.UnmarshalBinaryPublicKey(buf)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create HPKE public key: %w`, err)
+	}
+	return pubkey, nil
+}
+
+func privateKey(alg jwa.KeyEncryptionAlgorithm, kemID hpke.KEM, key interface{}) (kem.PrivateKey, error) {
+	scheme := kemID.Scheme()
+
+	var buf []byte
+	if crv := curveFor(kemID); crv != nil {
+		var privkey ecdsa.PrivateKey
+		if err := keyconv.ECDSAPrivateKey(&privkey, key); err != nil {
+			return nil, fmt.Errorf(`%s requires an ECDSA private key: %w`, alg, err)
+		}
+		if privkey.Curve != crv {
+			return nil, fmt.Errorf(`%s requires a key on curve %s (got %s)`, alg, crv.Params().Name, privkey.Curve.Params().Name)
+		}
+		buf = privkey.D.FillBytes(make([]byte, scheme.PrivateKeySize()))
+	} else {
+		switch key := key.(type) {
+		case x25519.PrivateKey:
+			if kemID != hpke.KEM_X25519_HKDF_SHA256 {
+				return nil, fmt.Errorf(`%s can not be used with X25519 keys`, alg)
+			}
+			buf = key.Seed()
+		case *x25519.PrivateKey:
+			return privateKey(alg, kemID, *key)
+		case x448.PrivateKey:
+			if kemID != hpke.KEM_X448_HKDF_SHA512 {
+				return nil, fmt.Errorf(`%s can not be used with X448 keys`, alg)
+			}
+			buf = key.Seed()
+		case *x448.PrivateKey:
+			return privateKey(alg, kemID, *key)
+		default:
+			return nil, fmt.Errorf(`invalid private key type for %s: %T`, alg, key)
+		}
+		if len(buf) != scheme.PrivateKeySize() {
+			return nil, fmt.Errorf(`invalid private key size for %s: %d`, alg, len(buf))
+		}
+	}
+
+	privkey, err := scheme.UnmarshalBinaryPrivateKey(buf)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create HPKE private key: %w`, err)
+	}
+	return privkey, nil
+}
+
+// Seal encrypts `plaintext` for the recipient's public key using HPKE
+// in base mode, and returns the encapsulated key along with the ciphertext.
+func Seal(alg jwa.KeyEncryptionAlgorithm, pubkey interface{}, info, aad, plaintext []byte) ([]byte, []byte, error) {
+	suite, err := suiteFor(alg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kemID, _, _ := suite.Params()
+	pk, err := publicKey(alg, kemID, pubkey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sender, err := suite.NewSender(pk, info)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to create HPKE sender: %w`, err)
+	}
+
+	enc, sealer, err := sender.Setup(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to setup HPKE sender: %w`, err)
+	}
+
+	ciphertext, err := sealer.Seal(plaintext, aad)
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to seal: %w`, err)
+	}
+	return enc, ciphertext, nil
+}
+
+// Open decrypts `ciphertext` using the recipient's private key and the
+// encapsulated key `enc`, using HPKE in base mode.
+func Open(alg jwa.KeyEncryptionAlgorithm, privkey interface{}, enc, info, aad, ciphertext []byte) ([]byte, error) {
+	suite, err := suiteFor(alg)
+	if err != nil {
+		return nil, err
+	}
+
+	kemID, _, _ := suite.Params()
+	sk, err := privateKey(alg, kemID, privkey)
+	if err != nil {
+		return nil, err
+	}
+
+	receiver, err := suite.NewReceiver(sk, info)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create HPKE receiver: %w`, err)
+	}
+
+	opener, err := receiver.Setup(enc)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to setup HPKE receiver: %w`, err)
+	}
+
+	plaintext, err := opener.Open(ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to open: %w`, err)
+	}
+	return plaintext, nil
+}
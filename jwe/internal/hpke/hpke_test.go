@@ -0,0 +1,71 @@
+package hpke
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	type keypair struct {
+		private interface{}
+		public  interface{}
+	}
+	ecdsaKey := func(t *testing.T, crv elliptic.Curve) keypair {
+		t.Helper()
+		key, err := ecdsa.GenerateKey(crv, rand.Reader)
+		require.NoError(t, err, `ecdsa.GenerateKey should succeed`)
+		return keypair{private: key, public: &key.PublicKey}
+	}
+
+	x25519pub, x25519priv, err := x25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x25519.GenerateKey should succeed`)
+	x448pub, x448priv, err := x448.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x448.GenerateKey should succeed`)
+
+	testcases := []struct {
+		alg jwa.KeyEncryptionAlgorithm
+		key keypair
+	}{
+		{alg: jwa.HPKE_0, key: ecdsaKey(t, elliptic.P256())},
+		{alg: jwa.HPKE_1, key: ecdsaKey(t, elliptic.P384())},
+		{alg: jwa.HPKE_2, key: ecdsaKey(t, elliptic.P521())},
+		{alg: jwa.HPKE_3, key: keypair{private: x25519priv, public: x25519pub}},
+		{alg: jwa.HPKE_4, key: keypair{private: &x25519priv, public: &x25519pub}},
+		{alg: jwa.HPKE_5, key: keypair{private: x448priv, public: x448pub}},
+		{alg: jwa.HPKE_6, key: keypair{private: &x448priv, public: &x448pub}},
+		{alg: jwa.HPKE_7, key: ecdsaKey(t, elliptic.P256())},
+	}
+
+	plaintext := []byte("Lorem Ipsum")
+	aad := []byte("aad")
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.alg.String(), func(t *testing.T) {
+			require.True(t, IsHPKE(tc.alg), `IsHPKE should be true`)
+
+			enc, ciphertext, err := Seal(tc.alg, tc.key.public, nil, aad, plaintext)
+			require.NoError(t, err, `Seal should succeed`)
+
+			decrypted, err := Open(tc.alg, tc.key.private, enc, nil, aad, ciphertext)
+			require.NoError(t, err, `Open should succeed`)
+			require.Equal(t, plaintext, decrypted, `plaintext should match`)
+
+			_, err = Open(tc.alg, tc.key.private, enc, nil, []byte("other"), ciphertext)
+			require.Error(t, err, `Open should fail with different aad`)
+
+			_, err = Open(tc.alg, tc.key.private, enc, []byte("info"), aad, ciphertext)
+			require.Error(t, err, `Open should fail with different info`)
+		})
+	}
+
+	require.False(t, IsHPKE(jwa.ECDH_ES), `IsHPKE should be false`)
+	_, _, err = Seal(jwa.HPKE_3, x448pub, nil, nil, plaintext)
+	require.Error(t, err, `Seal should fail for mismatched key types`)
+}
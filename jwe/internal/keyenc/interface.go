@@ -1,6 +1,7 @@
 package keyenc
 
 import (
+	"crypto"
 	"crypto/rsa"
 	"hash"
 
@@ -64,6 +65,53 @@ type ECDHESDecrypt struct {
 	pubkey     interface{}
 }
 
+// ECDH1PUEncrypt encrypts content encryption keys using ECDH-1PU.
+type ECDH1PUEncrypt struct {
+	algorithm  jwa.KeyEncryptionAlgorithm
+	contentalg jwa.ContentEncryptionAlgorithm
+	keyID      string
+	apu        []byte
+	apv        []byte
+	ephemeral  interface{}
+	privkey    interface{}
+	pubkey     interface{}
+}
+
+// ECDH1PUDecrypt decrypts keys using ECDH-1PU.
+type ECDH1PUDecrypt struct {
+	keyalg     jwa.KeyEncryptionAlgorithm
+	contentalg jwa.ContentEncryptionAlgorithm
+	apu        []byte
+	apv        []byte
+	tag        []byte
+	privkey    interface{}
+	pubkey     interface{}
+	senderkey  interface{}
+}
+
+// ECDH1PUWrapKey is the result of ECDH1PUEncrypt in key wrapping mode.
+// The key encryption key is derived using the authentication tag of
+// the content, and therefore the content encryption key can only be
+// wrapped after the content has been encrypted.
+type ECDH1PUWrapKey struct {
+	keygen.ByteWithECPublicKey
+	wrap func([]byte) ([]byte, error)
+}
+
+// HPKEEncrypt encrypts content encryption keys using HPKE
+type HPKEEncrypt struct {
+	alg    jwa.KeyEncryptionAlgorithm
+	pubkey interface{}
+	keyID  string
+}
+
+// HPKEDecrypt decrypts content encryption keys using HPKE
+type HPKEDecrypt struct {
+	alg     jwa.KeyEncryptionAlgorithm
+	enc     []byte
+	privkey interface{}
+}
+
 // RSAOAEPEncrypt encrypts keys using RSA OAEP algorithm
 type RSAOAEPEncrypt struct {
 	alg    jwa.KeyEncryptionAlgorithm
@@ -74,13 +122,13 @@ type RSAOAEPEncrypt struct {
 // RSAOAEPDecrypt decrypts keys using RSA OAEP algorithm
 type RSAOAEPDecrypt struct {
 	alg     jwa.KeyEncryptionAlgorithm
-	privkey *rsa.PrivateKey
+	privkey crypto.Decrypter
 }
 
 // RSAPKCS15Decrypt decrypts keys using RSA PKCS1v15 algorithm
 type RSAPKCS15Decrypt struct {
 	alg       jwa.KeyEncryptionAlgorithm
-	privkey   *rsa.PrivateKey
+	privkey   crypto.Decrypter
 	generator keygen.Generator
 }
 
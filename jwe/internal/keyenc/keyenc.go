@@ -23,8 +23,10 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	contentcipher "github.com/lestrrat-go/jwx/v2/jwe/internal/cipher"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/concatkdf"
+	"github.com/lestrrat-go/jwx/v2/jwe/internal/hpke"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 func NewNoop(alg jwa.KeyEncryptionAlgorithm, sharedkey []byte) (*Noop, error) {
@@ -217,7 +219,9 @@ func NewECDHESEncrypt(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionA
 	case *ecdsa.PublicKey:
 		generator, err = keygen.NewEcdhes(alg, enc, keysize, key, apu, apv)
 	case x25519.PublicKey:
-		generator, err = keygen.NewX25519(alg, enc, keysize, key)
+		generator, err = keygen.NewX25519(alg, enc, keysize, key, apu, apv)
+	case x448.PublicKey:
+		generator, err = keygen.NewX448(alg, enc, keysize, key, apu, apv)
 	default:
 		return nil, fmt.Errorf("unexpected key type %T", keyif)
 	}
@@ -292,8 +296,45 @@ func (kw ECDHESDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
 	return kw.keyalg
 }
 
+// ecdhPrivateKey describes private keys that perform the key agreement
+// by themselves, such as keys backed by a hardware security module.
+// It is exposed to the users as jwe.ECDHPrivateKey
+type ecdhPrivateKey interface {
+	Public() crypto.PublicKey
+	ECDH(crypto.PublicKey) ([]byte, error)
+}
+
 func DeriveZ(privkeyif interface{}, pubkeyif interface{}) ([]byte, error) {
 	switch privkeyif.(type) {
+	case ecdhPrivateKey:
+		//nolint:forcetypeassert
+		privkey := privkeyif.(ecdhPrivateKey)
+		switch mypub := privkey.Public().(type) {
+		case *ecdsa.PublicKey:
+			pubkey, ok := pubkeyif.(*ecdsa.PublicKey)
+			if !ok {
+				return nil, fmt.Errorf(`public key must be of the same type as the private key (%T), was: %T`, mypub, pubkeyif)
+			}
+			if mypub.Curve != pubkey.Curve || !mypub.Curve.IsOnCurve(pubkey.X, pubkey.Y) {
+				return nil, fmt.Errorf(`public key must be on the same curve as private key`)
+			}
+		case x25519.PublicKey:
+			if _, ok := pubkeyif.(x25519.PublicKey); !ok {
+				return nil, fmt.Errorf(`public key must be of the same type as the private key (%T), was: %T`, mypub, pubkeyif)
+			}
+		case x448.PublicKey:
+			if _, ok := pubkeyif.(x448.PublicKey); !ok {
+				return nil, fmt.Errorf(`public key must be of the same type as the private key (%T), was: %T`, mypub, pubkeyif)
+			}
+		default:
+			return nil, fmt.Errorf(`unsupported public key type for key agreement: %T`, mypub)
+		}
+
+		z, err := privkey.ECDH(pubkeyif)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to perform key agreement: %w`, err)
+		}
+		return z, nil
 	case x25519.PrivateKey:
 		privkey, ok := privkeyif.(x25519.PrivateKey)
 		if !ok {
@@ -304,6 +345,16 @@ func DeriveZ(privkeyif interface{}, pubkeyif interface{}) ([]byte, error) {
 			return nil, fmt.Errorf(`public key must be x25519.PublicKey, was: %T`, pubkeyif)
 		}
 		return curve25519.X25519(privkey.Seed(), pubkey)
+	case x448.PrivateKey:
+		privkey, ok := privkeyif.(x448.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf(`private key must be x448.PrivateKey, was: %T`, privkeyif)
+		}
+		pubkey, ok := pubkeyif.(x448.PublicKey)
+		if !ok {
+			return nil, fmt.Errorf(`public key must be x448.PublicKey, was: %T`, pubkeyif)
+		}
+		return x448.X448(privkey.Seed(), pubkey)
 	default:
 		privkey, ok := privkeyif.(*ecdsa.PrivateKey)
 		if !ok {
@@ -353,7 +404,7 @@ func (kw ECDHESDecrypt) Decrypt(enckey []byte) ([]byte, error) {
 	switch kw.keyalg {
 	case jwa.ECDH_ES:
 		// Create a content cipher from the content encryption algorithm
-		c, err := contentcipher.NewAES(kw.contentalg)
+		c, err := contentcipher.New(kw.contentalg)
 		if err != nil {
 			return nil, fmt.Errorf(`failed to create content cipher for %s: %w`, kw.contentalg, err)
 		}
@@ -387,10 +438,299 @@ func (kw ECDHESDecrypt) Decrypt(enckey []byte) ([]byte, error) {
 	return Unwrap(block, enckey)
 }
 
+// DeriveECDH1PU derives the key from the shared secret Z = Ze || Zs, as
+// described in draft-madden-jose-ecdh-1pu-04. In key wrapping mode, the
+// authentication tag of the content must be passed as `tag`.
+func DeriveECDH1PU(alg, apu, apv, ze, zs, tag []byte, keysize uint32) ([]byte, error) {
+	pubinfo := make([]byte, 4, 8+len(tag))
+	binary.BigEndian.PutUint32(pubinfo, keysize*8)
+	if tag != nil {
+		taglen := make([]byte, 4)
+		binary.BigEndian.PutUint32(taglen, uint32(len(tag)))
+		pubinfo = append(append(pubinfo, taglen...), tag...)
+	}
+
+	z := make([]byte, 0, len(ze)+len(zs))
+	z = append(append(z, ze...), zs...)
+	kdf := concatkdf.New(crypto.SHA256, alg, z, apu, apv, pubinfo, []byte{})
+	key := make([]byte, keysize)
+	if _, err := kdf.Read(key); err != nil {
+		return nil, fmt.Errorf(`failed to read kdf: %w`, err)
+	}
+	return key, nil
+}
+
+// ecdh1PUParams returns the KDF algorithm ID and the key size for the
+// given ECDH-1PU algorithm. Key wrapping modes require content encryption
+// algorithms that produce an authentication tag which commits to the key
+// (i.e. AES-CBC-HMAC-SHA2).
+func ecdh1PUParams(keyalg jwa.KeyEncryptionAlgorithm, contentalg jwa.ContentEncryptionAlgorithm) ([]byte, uint32, error) {
+	switch keyalg {
+	case jwa.ECDH_1PU:
+		c, err := contentcipher.New(contentalg)
+		if err != nil {
+			return nil, 0, fmt.Errorf(`failed to create content cipher for %s: %w`, contentalg, err)
+		}
+		return []byte(contentalg.String()), uint32(c.KeySize()), nil
+	case jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		switch contentalg {
+		case jwa.A128CBC_HS256, jwa.A192CBC_HS384, jwa.A256CBC_HS512:
+		default:
+			return nil, 0, fmt.Errorf(`%s requires an AES-CBC-HMAC-SHA2 content encryption algorithm (got %s)`, keyalg, contentalg)
+		}
+
+		var keysize uint32
+		switch keyalg {
+		case jwa.ECDH_1PU_A128KW:
+			keysize = 16
+		case jwa.ECDH_1PU_A192KW:
+			keysize = 24
+		case jwa.ECDH_1PU_A256KW:
+			keysize = 32
+		}
+		return []byte(keyalg.String()), keysize, nil
+	default:
+		return nil, 0, fmt.Errorf(`invalid ECDH-1PU key wrap algorithm (%s)`, keyalg)
+	}
+}
+
+// NewECDH1PUEncrypt creates a new key encrypter based on ECDH-1PU.
+// `pubkey` is the public key of the recipient, and `privkey` is the
+// static private key of the sender. `ephemeral` is the ephemeral
+// private key, which must be the same for all recipients of a message.
+func NewECDH1PUEncrypt(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, pubkey, privkey, ephemeral interface{}, apu, apv []byte) (*ECDH1PUEncrypt, error) {
+	if _, _, err := ecdh1PUParams(alg, enc); err != nil {
+		return nil, err
+	}
+
+	if _, ok := ephemeral.(interface{ Public() crypto.PublicKey }); !ok {
+		return nil, fmt.Errorf(`unexpected ephemeral key type %T`, ephemeral)
+	}
+
+	return &ECDH1PUEncrypt{
+		algorithm:  alg,
+		contentalg: enc,
+		apu:        apu,
+		apv:        apv,
+		ephemeral:  ephemeral,
+		privkey:    privkey,
+		pubkey:     pubkey,
+	}, nil
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (kw ECDH1PUEncrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return kw.algorithm
+}
+
+func (kw *ECDH1PUEncrypt) SetKeyID(v string) {
+	kw.keyID = v
+}
+
+// KeyID returns the key ID associated with this encrypter
+func (kw ECDH1PUEncrypt) KeyID() string {
+	return kw.keyID
+}
+
+// Encrypt encrypts the content encryption key using ECDH-1PU. In key
+// wrapping mode, the result is an ECDH1PUWrapKey, which must be
+// used to wrap the key once the content has been encrypted.
+func (kw ECDH1PUEncrypt) Encrypt(cek []byte) (keygen.ByteSource, error) {
+	algBytes, keysize, err := ecdh1PUParams(kw.algorithm, kw.contentalg)
+	if err != nil {
+		return nil, err
+	}
+
+	ze, err := DeriveZ(kw.ephemeral, kw.pubkey)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to determine Ze: %w`, err)
+	}
+	zs, err := DeriveZ(kw.privkey, kw.pubkey)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to determine Zs: %w`, err)
+	}
+
+	//nolint:forcetypeassert
+	epk := kw.ephemeral.(interface{ Public() crypto.PublicKey }).Public()
+
+	if kw.algorithm == jwa.ECDH_1PU {
+		key, err := DeriveECDH1PU(algBytes, kw.apu, kw.apv, ze, zs, nil, keysize)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to derive ECDH-1PU key: %w`, err)
+		}
+		return keygen.ByteWithECPublicKey{
+			PublicKey: epk,
+			ByteKey:   keygen.ByteKey(key),
+		}, nil
+	}
+
+	return ECDH1PUWrapKey{
+		ByteWithECPublicKey: keygen.ByteWithECPublicKey{
+			PublicKey: epk,
+		},
+		wrap: func(tag []byte) ([]byte, error) {
+			kek, err := DeriveECDH1PU(algBytes, kw.apu, kw.apv, ze, zs, tag, keysize)
+			if err != nil {
+				return nil, fmt.Errorf(`failed to derive ECDH-1PU key: %w`, err)
+			}
+
+			block, err := aes.NewCipher(kek)
+			if err != nil {
+				return nil, fmt.Errorf(`failed to generate cipher from generated key: %w`, err)
+			}
+
+			jek, err := Wrap(block, cek)
+			if err != nil {
+				return nil, fmt.Errorf(`failed to wrap data: %w`, err)
+			}
+			return jek, nil
+		},
+	}, nil
+}
+
+// Wrap wraps the content encryption key using the authentication
+// tag of the encrypted content
+func (k ECDH1PUWrapKey) Wrap(tag []byte) ([]byte, error) {
+	return k.wrap(tag)
+}
+
+// NewECDH1PUDecrypt creates a new key decrypter using ECDH-1PU. `pubkey`
+// is the ephemeral public key, and `senderkey` is the static public
+// key of the sender. In key wrapping mode, the authentication tag of the
+// content must be passed as `tag`.
+func NewECDH1PUDecrypt(keyalg jwa.KeyEncryptionAlgorithm, contentalg jwa.ContentEncryptionAlgorithm, pubkey, senderkey interface{}, apu, apv, tag []byte, privkey interface{}) *ECDH1PUDecrypt {
+	return &ECDH1PUDecrypt{
+		keyalg:     keyalg,
+		contentalg: contentalg,
+		apu:        apu,
+		apv:        apv,
+		tag:        tag,
+		privkey:    privkey,
+		pubkey:     pubkey,
+		senderkey:  senderkey,
+	}
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (kw ECDH1PUDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return kw.keyalg
+}
+
+// Decrypt decrypts the encrypted key using ECDH-1PU
+func (kw ECDH1PUDecrypt) Decrypt(enckey []byte) ([]byte, error) {
+	algBytes, keysize, err := ecdh1PUParams(kw.keyalg, kw.contentalg)
+	if err != nil {
+		return nil, err
+	}
+
+	var tag []byte
+	if kw.keyalg != jwa.ECDH_1PU {
+		if len(kw.tag) == 0 {
+			return nil, fmt.Errorf(`%s requires the authentication tag of the content`, kw.keyalg)
+		}
+		tag = kw.tag
+	}
+
+	ze, err := DeriveZ(kw.privkey, kw.pubkey)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to determine Ze: %w`, err)
+	}
+	zs, err := DeriveZ(kw.privkey, kw.senderkey)
+	if err != nil {
+		return nil, fmt.Errorf(`unable to determine Zs: %w`, err)
+	}
+
+	key, err := DeriveECDH1PU(algBytes, kw.apu, kw.apv, ze, zs, tag, keysize)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to derive ECDH-1PU encryption key: %w`, err)
+	}
+
+	// ECDH-1PU does not wrap keys
+	if kw.keyalg == jwa.ECDH_1PU {
+		return key, nil
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create cipher for ECDH-1PU key wrap: %w`, err)
+	}
+
+	return Unwrap(block, enckey)
+}
+
+// NewHPKEEncrypt creates a new key encrypter using HPKE. The content
+// encryption key is encrypted to `pubkey` using HPKE in base mode, and
+// the HPKE encapsulated key is stored in the "ek" header.
+func NewHPKEEncrypt(alg jwa.KeyEncryptionAlgorithm, pubkey interface{}) (*HPKEEncrypt, error) {
+	if !hpke.IsHPKE(alg) {
+		return nil, fmt.Errorf(`invalid HPKE encrypt algorithm (%s)`, alg)
+	}
+	return &HPKEEncrypt{
+		alg:    alg,
+		pubkey: pubkey,
+	}, nil
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (e HPKEEncrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return e.alg
+}
+
+func (e *HPKEEncrypt) SetKeyID(v string) {
+	e.keyID = v
+}
+
+// KeyID returns the key ID associated with this encrypter
+func (e HPKEEncrypt) KeyID() string {
+	return e.keyID
+}
+
+// Encrypt encrypts the content encryption key using HPKE
+func (e HPKEEncrypt) Encrypt(cek []byte) (keygen.ByteSource, error) {
+	enc, encrypted, err := hpke.Seal(e.alg, e.pubkey, nil, nil, cek)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to encrypt key using HPKE: %w`, err)
+	}
+	return keygen.ByteWithEncapsulatedKey{
+		ByteKey:         encrypted,
+		EncapsulatedKey: enc,
+	}, nil
+}
+
+// NewHPKEDecrypt creates a new key decrypter using HPKE. `enc` is
+// the HPKE encapsulated key found in the "ek" header
+func NewHPKEDecrypt(alg jwa.KeyEncryptionAlgorithm, enc []byte, privkey interface{}) (*HPKEDecrypt, error) {
+	if !hpke.IsHPKE(alg) {
+		return nil, fmt.Errorf(`invalid HPKE decrypt algorithm (%s)`, alg)
+	}
+	return &HPKEDecrypt{
+		alg:     alg,
+		enc:     enc,
+		privkey: privkey,
+	}, nil
+}
+
+// Algorithm returns the key encryption algorithm being used
+func (d HPKEDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
+	return d.alg
+}
+
+// Decrypt decrypts the encrypted key using HPKE
+func (d HPKEDecrypt) Decrypt(enckey []byte) ([]byte, error) {
+	if len(d.enc) == 0 {
+		return nil, fmt.Errorf(`%s requires the HPKE encapsulated key ("ek")`, d.alg)
+	}
+	cek, err := hpke.Open(d.alg, d.privkey, d.enc, nil, nil, enckey)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to decrypt key using HPKE: %w`, err)
+	}
+	return cek, nil
+}
+
 // NewRSAOAEPEncrypt creates a new key encrypter using RSA OAEP
 func NewRSAOAEPEncrypt(alg jwa.KeyEncryptionAlgorithm, pubkey *rsa.PublicKey) (*RSAOAEPEncrypt, error) {
 	switch alg {
-	case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+	case jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
 	default:
 		return nil, fmt.Errorf("invalid RSA OAEP encrypt algorithm (%s)", alg)
 	}
@@ -462,8 +802,12 @@ func (e RSAOAEPEncrypt) Encrypt(cek []byte) (keygen.ByteSource, error) {
 		hash = sha1.New()
 	case jwa.RSA_OAEP_256:
 		hash = sha256.New()
+	case jwa.RSA_OAEP_384:
+		hash = sha512.New384()
+	case jwa.RSA_OAEP_512:
+		hash = sha512.New()
 	default:
-		return nil, fmt.Errorf(`failed to generate key encrypter for RSA-OAEP: RSA_OAEP/RSA_OAEP_256 required`)
+		return nil, fmt.Errorf(`failed to generate key encrypter for RSA-OAEP: RSA_OAEP/RSA_OAEP_256/RSA_OAEP_384/RSA_OAEP_512 required`)
 	}
 	encrypted, err := rsa.EncryptOAEP(hash, rand.Reader, e.pubkey, cek, []byte{})
 	if err != nil {
@@ -473,7 +817,10 @@ func (e RSAOAEPEncrypt) Encrypt(cek []byte) (keygen.ByteSource, error) {
 }
 
 // NewRSAPKCS15Decrypt creates a new decrypter using RSA PKCS1v15
-func NewRSAPKCS15Decrypt(alg jwa.KeyEncryptionAlgorithm, privkey *rsa.PrivateKey, keysize int) *RSAPKCS15Decrypt {
+//
+// `privkey` is usually a *rsa.PrivateKey, but any crypto.Decrypter whose
+// public key is a *rsa.PublicKey can be used
+func NewRSAPKCS15Decrypt(alg jwa.KeyEncryptionAlgorithm, privkey crypto.Decrypter, keysize int) *RSAPKCS15Decrypt {
 	generator := keygen.NewRandom(keysize * 2)
 	return &RSAPKCS15Decrypt{
 		alg:       alg,
@@ -500,8 +847,13 @@ func (d RSAPKCS15Decrypt) Decrypt(enckey []byte) ([]byte, error) {
 		_ = recover()
 	}()
 
+	pubkey, ok := d.privkey.Public().(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf(`expected *rsa.PublicKey, got %T`, d.privkey.Public())
+	}
+
 	// Perform some input validation.
-	expectedlen := d.privkey.PublicKey.N.BitLen() / 8
+	expectedlen := pubkey.N.BitLen() / 8
 	if expectedlen != len(enckey) {
 		// Input size is incorrect, the encrypted payload should always match
 		// the size of the public modulus (e.g. using a 2048 bit key will
@@ -525,18 +877,36 @@ func (d RSAPKCS15Decrypt) Decrypt(enckey []byte) ([]byte, error) {
 	// prevent chosen-ciphertext attacks as described in RFC 3218, "Preventing
 	// the Million Message Attack on Cryptographic Message Syntax". We are
 	// therefore deliberately ignoring errors here.
-	err = rsa.DecryptPKCS1v15SessionKey(rand.Reader, d.privkey, enckey, cek)
-	if err != nil {
-		return nil, fmt.Errorf(`failed to decrypt via PKCS1v15: %w`, err)
+	if privkey, ok := d.privkey.(*rsa.PrivateKey); ok {
+		err = rsa.DecryptPKCS1v15SessionKey(rand.Reader, privkey, enckey, cek)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to decrypt via PKCS1v15: %w`, err)
+		}
+		return cek, nil
 	}
 
-	return cek, nil
+	// Other implementations are expected to honor SessionKeyLen in the
+	// same way as *rsa.PrivateKey does: invalid padding should result
+	// in a random key being returned instead of an error. As we can not
+	// rely on this, any failure results in the random key being used,
+	// so that the caller can not tell invalid padding apart from a
+	// wrong key
+	decrypted, err := d.privkey.Decrypt(rand.Reader, enckey, &rsa.PKCS1v15DecryptOptions{
+		SessionKeyLen: len(cek),
+	})
+	if err != nil || len(decrypted) != len(cek) {
+		return cek, nil
+	}
+	return decrypted, nil
 }
 
 // NewRSAOAEPDecrypt creates a new key decrypter using RSA OAEP
-func NewRSAOAEPDecrypt(alg jwa.KeyEncryptionAlgorithm, privkey *rsa.PrivateKey) (*RSAOAEPDecrypt, error) {
+//
+// `privkey` is usually a *rsa.PrivateKey, but any crypto.Decrypter whose
+// public key is a *rsa.PublicKey can be used
+func NewRSAOAEPDecrypt(alg jwa.KeyEncryptionAlgorithm, privkey crypto.Decrypter) (*RSAOAEPDecrypt, error) {
 	switch alg {
-	case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+	case jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
 	default:
 		return nil, fmt.Errorf("invalid RSA OAEP decrypt algorithm (%s)", alg)
 	}
@@ -554,16 +924,20 @@ func (d RSAOAEPDecrypt) Algorithm() jwa.KeyEncryptionAlgorithm {
 
 // Decrypt decrypts the encrypted key using RSA OAEP
 func (d RSAOAEPDecrypt) Decrypt(enckey []byte) ([]byte, error) {
-	var hash hash.Hash
+	var hash crypto.Hash
 	switch d.alg {
 	case jwa.RSA_OAEP:
-		hash = sha1.New()
+		hash = crypto.SHA1
 	case jwa.RSA_OAEP_256:
-		hash = sha256.New()
+		hash = crypto.SHA256
+	case jwa.RSA_OAEP_384:
+		hash = crypto.SHA384
+	case jwa.RSA_OAEP_512:
+		hash = crypto.SHA512
 	default:
-		return nil, fmt.Errorf(`failed to generate key encrypter for RSA-OAEP: RSA_OAEP/RSA_OAEP_256 required`)
+		return nil, fmt.Errorf(`failed to generate key decrypter for RSA-OAEP: RSA_OAEP/RSA_OAEP_256/RSA_OAEP_384/RSA_OAEP_512 required`)
 	}
-	return rsa.DecryptOAEP(hash, rand.Reader, d.privkey, enckey, []byte{})
+	return d.privkey.Decrypt(rand.Reader, enckey, &rsa.OAEPOptions{Hash: hash})
 }
 
 // Decrypt for DirectDecrypt does not do anything other than
@@ -2,14 +2,30 @@ package keyenc_test
 
 import (
 	"bytes"
+	"crypto"
 	"crypto/aes"
 	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
 	"testing"
 
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keyenc"
+	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func mustHexDecode(s string) []byte {
@@ -182,3 +198,270 @@ func TestKeyWrap(t *testing.T) {
 		t.Error("key unwrap did not return original input, got", unwrap2, "wanted", cek2)
 	}
 }
+
+func TestDeriveECDH1PU(t *testing.T) {
+	// Example from draft-madden-jose-ecdh-1pu-04, Appendix A
+	const aliceKeySrc = `{"kty":"EC",
+      "crv":"P-256",
+      "x":"WKn-ZIGevcwGIyyrzFoZNBdaq9_TsqzGl96oc0CWuis",
+      "y":"y77t-RvAHRKTsSGdIYUfweuOvwrvDD-Q3Hv5J0fSKbE",
+      "d":"Hndv7ZZjs_ke8o9zXYo3iq-Yr8SewI5vrqd0pAvEPqg"
+     }`
+	const bobKeySrc = `{"kty":"EC",
+      "crv":"P-256",
+      "x":"weNJy2HscCSM6AEDTDg04biOvhFhyyWvOHQfeF_PxMQ",
+      "y":"e8lnCO-AlStT-NJVX-crhB7QRYhiix03illJOVAOyck",
+      "d":"VEmDZpDXXK8p8N0Cndsxs924q6nS1RXFASRl6BfUqdw"
+     }`
+	const ephemeralKeySrc = `{"kty":"EC",
+      "crv":"P-256",
+      "x":"gI0GAILBdu7T53akrFmMyGcsF3n5dO7MmwNBHKW5SV0",
+      "y":"SLW_xSffzlPWrHEVI30DHM_4egVwt3NQqeUD7nMFpps",
+      "d":"0_NxaRPUMQoAJt50Gz8YiTr8gRTwyEaCumd-MToTmIo"
+     }`
+
+	var aliceKey, bobKey, ephemeralKey ecdsa.PrivateKey
+	for _, pair := range []struct {
+		src string
+		dst *ecdsa.PrivateKey
+	}{
+		{aliceKeySrc, &aliceKey},
+		{bobKeySrc, &bobKey},
+		{ephemeralKeySrc, &ephemeralKey},
+	} {
+		key, err := jwk.ParseKey([]byte(pair.src))
+		if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+			return
+		}
+		if !assert.NoError(t, key.Raw(pair.dst), `key.Raw should succeed`) {
+			return
+		}
+	}
+
+	expectedZe := mustHexDecode("9e56d91d817135d372834283bf84269cfb316ea3da806a48f6daa7798cfe90c4")
+	expectedZs := mustHexDecode("e3ca3474384c9f62b30bfd4c688b3e7d4110a1b4badc3cc54ef7b81241efd50d")
+	expected := mustHexDecode("6caf13723d14850ad4b42cd6dde935bffd2fff00a9ba70de05c203a5e1722ca7")
+
+	// sender's side
+	ze, err := keyenc.DeriveZ(&ephemeralKey, &bobKey.PublicKey)
+	if !assert.NoError(t, err, `keyenc.DeriveZ should succeed`) {
+		return
+	}
+	if !assert.Equal(t, expectedZe, ze, `Ze should match`) {
+		return
+	}
+	zs, err := keyenc.DeriveZ(&aliceKey, &bobKey.PublicKey)
+	if !assert.NoError(t, err, `keyenc.DeriveZ should succeed`) {
+		return
+	}
+	if !assert.Equal(t, expectedZs, zs, `Zs should match`) {
+		return
+	}
+
+	output, err := keyenc.DeriveECDH1PU([]byte("A256GCM"), []byte("Alice"), []byte("Bob"), ze, zs, nil, 32)
+	if !assert.NoError(t, err, `keyenc.DeriveECDH1PU should succeed`) {
+		return
+	}
+	if !assert.Equal(t, expected, output, `result should match`) {
+		return
+	}
+
+	// recipient's side
+	dec := keyenc.NewECDH1PUDecrypt(jwa.ECDH_1PU, jwa.A256GCM, &ephemeralKey.PublicKey, &aliceKey.PublicKey, []byte("Alice"), []byte("Bob"), nil, &bobKey)
+	output, err = dec.Decrypt(nil)
+	if !assert.NoError(t, err, `Decrypt should succeed`) {
+		return
+	}
+	if !assert.Equal(t, expected, output, `result should match`) {
+		return
+	}
+}
+
+func TestRSAOAEP(t *testing.T) {
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, `rsa.GenerateKey should succeed`)
+
+	testcases := []struct {
+		alg  jwa.KeyEncryptionAlgorithm
+		hash func() hash.Hash
+	}{
+		{alg: jwa.RSA_OAEP, hash: sha1.New},
+		{alg: jwa.RSA_OAEP_256, hash: sha256.New},
+		{alg: jwa.RSA_OAEP_384, hash: sha512.New384},
+		{alg: jwa.RSA_OAEP_512, hash: sha512.New},
+	}
+
+	cek := []byte("0123456789abcdef0123456789abcdef")
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.alg.String(), func(t *testing.T) {
+			enc, err := keyenc.NewRSAOAEPEncrypt(tc.alg, &privkey.PublicKey)
+			require.NoError(t, err, `keyenc.NewRSAOAEPEncrypt should succeed`)
+			encrypted, err := enc.Encrypt(cek)
+			require.NoError(t, err, `Encrypt should succeed`)
+
+			// Interoperate with crypto/rsa using the expected hash
+			decrypted, err := rsa.DecryptOAEP(tc.hash(), rand.Reader, privkey, encrypted.Bytes(), nil)
+			require.NoError(t, err, `rsa.DecryptOAEP should succeed`)
+			require.Equal(t, cek, decrypted, `decrypted key should match`)
+
+			encrypted2, err := rsa.EncryptOAEP(tc.hash(), rand.Reader, &privkey.PublicKey, cek, nil)
+			require.NoError(t, err, `rsa.EncryptOAEP should succeed`)
+			dec, err := keyenc.NewRSAOAEPDecrypt(tc.alg, privkey)
+			require.NoError(t, err, `keyenc.NewRSAOAEPDecrypt should succeed`)
+			decrypted, err = dec.Decrypt(encrypted2)
+			require.NoError(t, err, `Decrypt should succeed`)
+			require.Equal(t, cek, decrypted, `decrypted key should match`)
+		})
+	}
+
+	_, err = keyenc.NewRSAOAEPEncrypt(jwa.RSA1_5, &privkey.PublicKey)
+	require.Error(t, err, `keyenc.NewRSAOAEPEncrypt should fail for RSA1_5`)
+}
+
+// strictDecrypter is a crypto.Decrypter that does not honor
+// SessionKeyLen, and reports invalid padding as an error
+type strictDecrypter struct {
+	*rsa.PrivateKey
+}
+
+func (d strictDecrypter) Decrypt(_ io.Reader, ciphertext []byte, _ crypto.DecrypterOpts) ([]byte, error) {
+	return rsa.DecryptPKCS1v15(rand.Reader, d.PrivateKey, ciphertext)
+}
+
+func TestRSAPKCS15Decrypt(t *testing.T) {
+	privkey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, `rsa.GenerateKey should succeed`)
+
+	cek := []byte("0123456789abcdef0123456789abcdef")
+	enc, err := keyenc.NewRSAPKCSEncrypt(jwa.RSA1_5, &privkey.PublicKey)
+	require.NoError(t, err, `keyenc.NewRSAPKCSEncrypt should succeed`)
+	encrypted, err := enc.Encrypt(cek)
+	require.NoError(t, err, `Encrypt should succeed`)
+
+	// Encrypt a random message without any padding. The ciphertext must
+	// be smaller than the modulus, or decryption fails regardless of padding
+	m, err := rand.Int(rand.Reader, privkey.N)
+	require.NoError(t, err, `rand.Int should succeed`)
+	c := new(big.Int).Exp(m, big.NewInt(int64(privkey.E)), privkey.N)
+	invalid := c.FillBytes(make([]byte, len(encrypted.Bytes())))
+
+	for _, key := range []crypto.Decrypter{privkey, strictDecrypter{privkey}} {
+		key := key
+		t.Run(fmt.Sprintf(`%T`, key), func(t *testing.T) {
+			dec := keyenc.NewRSAPKCS15Decrypt(jwa.RSA1_5, key, len(cek)/2)
+			decrypted, err := dec.Decrypt(encrypted.Bytes())
+			require.NoError(t, err, `Decrypt should succeed`)
+			require.Equal(t, cek, decrypted, `decrypted key should match`)
+
+			// Invalid padding must not be distinguishable from a wrong key
+			decrypted, err = dec.Decrypt(invalid)
+			require.NoError(t, err, `Decrypt should succeed for invalid padding`)
+			require.Len(t, decrypted, len(cek), `a random key should be returned`)
+		})
+	}
+}
+
+// opaqueECDHKey implements the ECDH private key interface accepted by
+// keyenc.DeriveZ. Like some external key stores, it does not validate
+// the type of the public key it is given
+type opaqueECDHKey struct {
+	key x25519.PrivateKey
+}
+
+func (k opaqueECDHKey) Public() crypto.PublicKey {
+	return k.key.Public()
+}
+
+func (k opaqueECDHKey) ECDH(pubkey crypto.PublicKey) ([]byte, error) {
+	if pubkey, ok := pubkey.(x25519.PublicKey); ok {
+		return keyenc.DeriveZ(k.key, pubkey)
+	}
+	return make([]byte, x25519.PublicKeySize), nil
+}
+
+func TestDeriveZ(t *testing.T) {
+	_, privkey, err := x25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x25519.GenerateKey should succeed`)
+	peerpub, _, err := x25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x25519.GenerateKey should succeed`)
+	x448pub, _, err := x448.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x448.GenerateKey should succeed`)
+	ecpriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, `ecdsa.GenerateKey should succeed`)
+
+	key := opaqueECDHKey{key: privkey}
+	z, err := keyenc.DeriveZ(key, peerpub)
+	require.NoError(t, err, `keyenc.DeriveZ should succeed`)
+	expected, err := keyenc.DeriveZ(privkey, peerpub)
+	require.NoError(t, err, `keyenc.DeriveZ should succeed`)
+	require.Equal(t, expected, z, `shared secrets should match`)
+
+	_, err = keyenc.DeriveZ(key, x448pub)
+	require.Error(t, err, `keyenc.DeriveZ should fail for an X448 public key`)
+	_, err = keyenc.DeriveZ(key, &ecpriv.PublicKey)
+	require.Error(t, err, `keyenc.DeriveZ should fail for an ECDSA public key`)
+}
+
+func TestECDHESKeyGenerators(t *testing.T) {
+	// All curves must feed apu/apv into the Concat KDF in the same way,
+	// so that the recipient derives the same key from the headers
+	ecpriv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, `ecdsa.GenerateKey should succeed`)
+	x25519pub, x25519priv, err := x25519.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x25519.GenerateKey should succeed`)
+	x448pub, x448priv, err := x448.GenerateKey(rand.Reader)
+	require.NoError(t, err, `x448.GenerateKey should succeed`)
+
+	apu := []byte("Alice")
+	apv := []byte("Bob")
+	const keysize = 16
+
+	testcases := []struct {
+		name    string
+		newGen  func() (keygen.Generator, error)
+		privkey interface{}
+	}{
+		{
+			name: `P-256`,
+			newGen: func() (keygen.Generator, error) {
+				return keygen.NewEcdhes(jwa.ECDH_ES, jwa.A128GCM, keysize, &ecpriv.PublicKey, apu, apv)
+			},
+			privkey: ecpriv,
+		},
+		{
+			name: `X25519`,
+			newGen: func() (keygen.Generator, error) {
+				return keygen.NewX25519(jwa.ECDH_ES, jwa.A128GCM, keysize, x25519pub, apu, apv)
+			},
+			privkey: x25519priv,
+		},
+		{
+			name: `X448`,
+			newGen: func() (keygen.Generator, error) {
+				return keygen.NewX448(jwa.ECDH_ES, jwa.A128GCM, keysize, x448pub, apu, apv)
+			},
+			privkey: x448priv,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			gen, err := tc.newGen()
+			require.NoError(t, err, `creating the generator should succeed`)
+			generated, err := gen.Generate()
+			require.NoError(t, err, `Generate should succeed`)
+			epk, ok := generated.(keygen.ByteWithECPublicKey)
+			require.True(t, ok, `generated key should contain the ephemeral public key`)
+
+			derived, err := keyenc.DeriveECDHES([]byte(jwa.A128GCM.String()), apu, apv, tc.privkey, epk.PublicKey, keysize)
+			require.NoError(t, err, `keyenc.DeriveECDHES should succeed`)
+			require.Equal(t, derived, epk.Bytes(), `derived keys should match`)
+
+			derived, err = keyenc.DeriveECDHES([]byte(jwa.A128GCM.String()), nil, nil, tc.privkey, epk.PublicKey, keysize)
+			require.NoError(t, err, `keyenc.DeriveECDHES should succeed`)
+			require.NotEqual(t, derived, epk.Bytes(), `derived keys should depend on apu/apv`)
+		})
+	}
+}
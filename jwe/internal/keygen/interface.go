@@ -5,6 +5,7 @@ import (
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 type Generator interface {
@@ -36,6 +37,18 @@ type X25519 struct {
 	enc       jwa.ContentEncryptionAlgorithm
 	keysize   int
 	pubkey    x25519.PublicKey
+	apu       []byte
+	apv       []byte
+}
+
+// X448 generates keys using ECDH-ES algorithm / X448 curve
+type X448 struct {
+	algorithm jwa.KeyEncryptionAlgorithm
+	enc       jwa.ContentEncryptionAlgorithm
+	keysize   int
+	pubkey    x448.PublicKey
+	apu       []byte
+	apv       []byte
 }
 
 // ByteKey is a generated key that only has the key's byte buffer
@@ -63,6 +76,13 @@ type ByteWithSaltAndCount struct {
 	Count int
 }
 
+// ByteWithEncapsulatedKey holds the HPKE encapsulated key along
+// with the encrypted content encryption key
+type ByteWithEncapsulatedKey struct {
+	ByteKey
+	EncapsulatedKey []byte
+}
+
 // ByteSource is an interface for things that return a byte sequence.
 // This is used for KeyGenerator so that the result of computations can
 // carry more than just the generate byte sequence.
@@ -15,6 +15,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/concatkdf"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 // Bytes returns the byte from this ByteKey
@@ -104,12 +105,14 @@ func (g Ecdhes) Generate() (ByteSource, error) {
 }
 
 // NewX25519 creates a new key generator using ECDH-ES
-func NewX25519(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, keysize int, pubkey x25519.PublicKey) (*X25519, error) {
+func NewX25519(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, keysize int, pubkey x25519.PublicKey, apu, apv []byte) (*X25519, error) {
 	return &X25519{
 		algorithm: alg,
 		enc:       enc,
 		keysize:   keysize,
 		pubkey:    pubkey,
+		apu:       apu,
+		apv:       apv,
 	}, nil
 }
 
@@ -139,7 +142,57 @@ func (g X25519) Generate() (ByteSource, error) {
 	if err != nil {
 		return nil, fmt.Errorf(`failed to compute Z: %w`, err)
 	}
-	kdf := concatkdf.New(crypto.SHA256, []byte(algorithm), zBytes, []byte{}, []byte{}, pubinfo, []byte{})
+	kdf := concatkdf.New(crypto.SHA256, []byte(algorithm), zBytes, g.apu, g.apv, pubinfo, []byte{})
+	kek := make([]byte, g.keysize)
+	if _, err := kdf.Read(kek); err != nil {
+		return nil, fmt.Errorf(`failed to read kdf: %w`, err)
+	}
+
+	return ByteWithECPublicKey{
+		PublicKey: pub,
+		ByteKey:   ByteKey(kek),
+	}, nil
+}
+
+// NewX448 creates a new key generator using ECDH-ES
+func NewX448(alg jwa.KeyEncryptionAlgorithm, enc jwa.ContentEncryptionAlgorithm, keysize int, pubkey x448.PublicKey, apu, apv []byte) (*X448, error) {
+	return &X448{
+		algorithm: alg,
+		enc:       enc,
+		keysize:   keysize,
+		pubkey:    pubkey,
+		apu:       apu,
+		apv:       apv,
+	}, nil
+}
+
+// Size returns the key size associated with this generator
+func (g X448) Size() int {
+	return g.keysize
+}
+
+// Generate generates new keys using ECDH-ES
+func (g X448) Generate() (ByteSource, error) {
+	pub, priv, err := x448.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generate key for X448: %w`, err)
+	}
+
+	var algorithm string
+	if g.algorithm == jwa.ECDH_ES {
+		algorithm = g.enc.String()
+	} else {
+		algorithm = g.algorithm.String()
+	}
+
+	pubinfo := make([]byte, 4)
+	binary.BigEndian.PutUint32(pubinfo, uint32(g.keysize)*8)
+
+	zBytes, err := x448.X448(priv.Seed(), g.pubkey)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to compute Z: %w`, err)
+	}
+	kdf := concatkdf.New(crypto.SHA256, []byte(algorithm), zBytes, g.apu, g.apv, pubinfo, []byte{})
 	kek := make([]byte, g.keysize)
 	if _, err := kdf.Read(kek); err != nil {
 		return nil, fmt.Errorf(`failed to read kdf: %w`, err)
@@ -192,3 +245,40 @@ func (k ByteWithSaltAndCount) Populate(h Setter) error {
 
 	return nil
 }
+
+// HeaderPopulate populates the header with the HPKE encapsulated
+// key ('ek')
+func (k ByteWithEncapsulatedKey) Populate(h Setter) error {
+	if err := h.Set("ek", k.EncapsulatedKey); err != nil {
+		return fmt.Errorf(`failed to write header: %w`, err)
+	}
+	return nil
+}
+
+// NewEphemeralKey generates an ephemeral private key that can be used
+// for key agreement with `pubkey`. The returned key is one of
+// *ecdsa.PrivateKey, x25519.PrivateKey, or x448.PrivateKey
+func NewEphemeralKey(pubkey interface{}) (interface{}, error) {
+	switch pubkey := pubkey.(type) {
+	case *ecdsa.PublicKey:
+		priv, err := ecdsa.GenerateKey(pubkey.Curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate ephemeral key for ECDSA: %w`, err)
+		}
+		return priv, nil
+	case x25519.PublicKey:
+		_, priv, err := x25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate ephemeral key for X25519: %w`, err)
+		}
+		return priv, nil
+	case x448.PublicKey:
+		_, priv, err := x448.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate ephemeral key for X448: %w`, err)
+		}
+		return priv, nil
+	default:
+		return nil, fmt.Errorf(`unexpected key type %T`, pubkey)
+	}
+}
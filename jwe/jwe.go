@@ -10,18 +10,22 @@ import (
 	"crypto/rsa"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/lestrrat-go/blackmagic"
 	"github.com/lestrrat-go/jwx/v2/internal/base64"
 	"github.com/lestrrat-go/jwx/v2/internal/json"
 	"github.com/lestrrat-go/jwx/v2/internal/keyconv"
+	"github.com/lestrrat-go/jwx/v2/internal/keyusage"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/content_crypt"
+	"github.com/lestrrat-go/jwx/v2/jwe/internal/hpke"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keyenc"
 	"github.com/lestrrat-go/jwx/v2/jwe/internal/keygen"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 const (
@@ -37,10 +41,76 @@ var _ = fmtMax
 
 var registry = json.NewRegistry()
 
+var strictKeyUsage keyusage.Setting
+
+// Settings controls global settings that are specific to JWE.
+func Settings(options ...GlobalOption) {
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identStrictKeyUsage{}:
+			strictKeyUsage.Set(option.Value().(bool))
+		}
+	}
+}
+
+// keyOperationFor returns the key operation that a key used with `alg`
+// performs, either to encrypt or to decrypt
+func keyOperationFor(alg jwa.KeyEncryptionAlgorithm, encrypt bool) jwk.KeyOperation {
+	switch name := alg.String(); {
+	case alg == jwa.DIRECT:
+		if encrypt {
+			return jwk.KeyOpEncrypt
+		}
+		return jwk.KeyOpDecrypt
+	case strings.HasPrefix(name, `ECDH-`), strings.HasPrefix(name, `HPKE-`):
+		return jwk.KeyOpDeriveKey
+	default:
+		if encrypt {
+			return jwk.KeyOpWrapKey
+		}
+		return jwk.KeyOpUnwrapKey
+	}
+}
+
 type recipientBuilder struct {
-	alg     jwa.KeyEncryptionAlgorithm
-	key     interface{}
-	headers Headers
+	alg       jwa.KeyEncryptionAlgorithm
+	key       interface{}
+	sender    interface{}
+	headers   Headers
+	ephemeral *ephemeralKey
+	wrapper   tagKeyWrapper
+}
+
+// ephemeralKey holds the ephemeral private key used for ECDH-1PU, which
+// is shared among all recipients of a message
+type ephemeralKey struct {
+	key interface{}
+}
+
+func (e *ephemeralKey) Get(pubkey interface{}) (interface{}, error) {
+	if e.key == nil {
+		key, err := keygen.NewEphemeralKey(pubkey)
+		if err != nil {
+			return nil, err
+		}
+		e.key = key
+	}
+	return e.key, nil
+}
+
+// tagKeyWrapper is implemented by the results of key encrypters whose
+// encrypted key depends on the authentication tag of the content.
+// The encrypted key is computed after the content has been encrypted.
+type tagKeyWrapper interface {
+	Wrap(tag []byte) ([]byte, error)
+}
+
+// pendingKeyWrap associates a recipient with the tagKeyWrapper that
+// will produce its encrypted key
+type pendingKeyWrap struct {
+	recipient Recipient
+	wrapper   tagKeyWrapper
 }
 
 func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm, cc *content_crypt.Generic) (Recipient, []byte, error) {
@@ -74,7 +144,7 @@ func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm
 			return nil, nil, fmt.Errorf(`failed to create RSA PKCS encrypter: %w`, err)
 		}
 		enc = v
-	case jwa.RSA_OAEP, jwa.RSA_OAEP_256:
+	case jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
 		var pubkey rsa.PublicKey
 		if err := keyconv.RSAPublicKey(&pubkey, rawKey); err != nil {
 			return nil, nil, fmt.Errorf(`failed to generate public key from key (%T): %w`, rawKey, err)
@@ -126,7 +196,7 @@ func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm
 		}
 
 		switch key := rawKey.(type) {
-		case x25519.PublicKey:
+		case x25519.PublicKey, x448.PublicKey:
 			var apu, apv []byte
 			if hdrs := b.headers; hdrs != nil {
 				apu = hdrs.AgreementPartyUInfo()
@@ -156,6 +226,60 @@ func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm
 			}
 			enc = v
 		}
+	case jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+		sender := b.sender
+		if sender == nil {
+			return nil, nil, fmt.Errorf(`%s requires the private key of the sender (see jwe.WithSenderKey())`, b.alg)
+		}
+		if jwkKey, ok := sender.(jwk.Key); ok {
+			var raw interface{}
+			if err := jwkKey.Raw(&raw); err != nil {
+				return nil, nil, fmt.Errorf(`failed to retrieve raw key out of %T: %w`, sender, err)
+			}
+			sender = raw
+		}
+
+		pubkey := rawKey
+		switch rawKey.(type) {
+		case x25519.PublicKey, x448.PublicKey:
+		default:
+			var ecpubkey ecdsa.PublicKey
+			if err := keyconv.ECDSAPublicKey(&ecpubkey, rawKey); err != nil {
+				return nil, nil, fmt.Errorf(`failed to generate public key from key (%T): %w`, rawKey, err)
+			}
+			pubkey = &ecpubkey
+
+			if _, ok := sender.(ECDHPrivateKey); !ok {
+				var ecprivkey ecdsa.PrivateKey
+				if err := keyconv.ECDSAPrivateKey(&ecprivkey, sender); err != nil {
+					return nil, nil, fmt.Errorf(`failed to generate private key from sender key (%T): %w`, sender, err)
+				}
+				sender = &ecprivkey
+			}
+		}
+
+		ephemeral, err := b.ephemeral.Get(pubkey)
+		if err != nil {
+			return nil, nil, fmt.Errorf(`failed to generate ephemeral key: %w`, err)
+		}
+
+		var apu, apv []byte
+		if hdrs := b.headers; hdrs != nil {
+			apu = hdrs.AgreementPartyUInfo()
+			apv = hdrs.AgreementPartyVInfo()
+		}
+
+		v, err := keyenc.NewECDH1PUEncrypt(b.alg, calg, pubkey, sender, ephemeral, apu, apv)
+		if err != nil {
+			return nil, nil, fmt.Errorf(`failed to create ECDH-1PU key wrap encrypter: %w`, err)
+		}
+		enc = v
+	case jwa.HPKE_0, jwa.HPKE_1, jwa.HPKE_2, jwa.HPKE_3, jwa.HPKE_4, jwa.HPKE_5, jwa.HPKE_6, jwa.HPKE_7:
+		v, err := keyenc.NewHPKEEncrypt(b.alg, rawKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf(`failed to create HPKE key encrypter: %w`, err)
+		}
+		enc = v
 	case jwa.DIRECT:
 		sharedkey, ok := rawKey.([]byte)
 		if !ok {
@@ -189,9 +313,15 @@ func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm
 	if err != nil {
 		return nil, nil, fmt.Errorf(`failed to encrypt key: %w`, err)
 	}
-	if enc.Algorithm() == jwa.ECDH_ES || enc.Algorithm() == jwa.DIRECT {
+	switch enc.Algorithm() {
+	case jwa.ECDH_ES, jwa.ECDH_1PU, jwa.DIRECT:
 		rawCEK = enckey.Bytes()
-	} else {
+	default:
+		if w, ok := enckey.(tagKeyWrapper); ok {
+			// The encrypted key is set after the content is encrypted
+			b.wrapper = w
+			break
+		}
 		if err := r.SetEncryptedKey(enckey.Bytes()); err != nil {
 			return nil, nil, fmt.Errorf(`failed to set encrypted key: %w`, err)
 		}
@@ -227,6 +357,92 @@ func (b *recipientBuilder) Build(cek []byte, calg jwa.ContentEncryptionAlgorithm
 // Look for options that return `jwe.EncryptOption` or `jws.EncryptDecryptOption`
 // for a complete list of options that can be passed to this function.
 func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
+	ectx, err := newEncryptCtx(options)
+	if err != nil {
+		return nil, fmt.Errorf(`jwe.Encrypt: %w`, err)
+	}
+
+	if ectx.compression != jwa.NoCompress {
+		payload, err = compress(payload)
+		if err != nil {
+			return nil, fmt.Errorf(`jwe.Encrypt: failed to compress payload before encryption: %w`, err)
+		}
+	}
+
+	var iv, ciphertext, tag []byte
+	if ectx.integrated != nil {
+		ciphertext, err = ectx.integrated.Seal(ectx.recipients[0], ectx.aad, payload)
+	} else {
+		iv, ciphertext, tag, err = ectx.contentcrypt.Encrypt(ectx.cek, payload, ectx.aad)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`failed to encrypt payload: %w`, err)
+	}
+
+	if err := ectx.wrapKeys(tag); err != nil {
+		return nil, fmt.Errorf(`jwe.Encrypt: %w`, err)
+	}
+
+	msg, err := ectx.newMessage(iv)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := msg.Set(CipherTextKey, ciphertext); err != nil {
+		return nil, fmt.Errorf(`failed to set %s: %w`, CipherTextKey, err)
+	}
+	if err := msg.Set(TagKey, tag); err != nil {
+		return nil, fmt.Errorf(`failed to set %s: %w`, TagKey, err)
+	}
+
+	switch ectx.format {
+	case fmtCompact:
+		return Compact(msg)
+	case fmtJSON:
+		return json.Marshal(msg)
+	case fmtJSONPretty:
+		return json.MarshalIndent(msg, "", "  ")
+	default:
+		return nil, fmt.Errorf(`jwe.Encrypt: invalid serialization`)
+	}
+}
+
+// encryptCtx holds the information that is required to encrypt
+// the content, which is computed from the options passed to
+// `jwe.Encrypt()` or `jwe.EncryptStream()`
+type encryptCtx struct {
+	format       int
+	compression  jwa.CompressionAlgorithm
+	contentcrypt *content_crypt.Generic
+	cek          []byte
+	protected    Headers
+	recipients   []Recipient
+	keyWraps     []pendingKeyWrap
+	integrated   *integratedSealer
+	aad          []byte
+}
+
+// integratedSealer encrypts the payload directly using HPKE
+// (HPKE Integrated Encryption)
+type integratedSealer struct {
+	alg jwa.KeyEncryptionAlgorithm
+	key interface{}
+}
+
+// Seal encrypts the payload, and stores the HPKE encapsulated key as
+// the encrypted key of the recipient
+func (s *integratedSealer) Seal(r Recipient, aad, payload []byte) ([]byte, error) {
+	ek, ciphertext, err := hpke.Seal(s.alg, s.key, nil, aad, payload)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to encrypt payload using HPKE: %w`, err)
+	}
+	if err := r.SetEncryptedKey(ek); err != nil {
+		return nil, fmt.Errorf(`failed to set encrypted key: %w`, err)
+	}
+	return ciphertext, nil
+}
+
+func newEncryptCtx(options []EncryptOption) (*encryptCtx, error) {
 	// default content encryption algorithm
 	calg := jwa.A256GCM
 
@@ -241,6 +457,9 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 	var protected Headers
 	var mergeProtected bool
 	var useRawCEK bool
+	var integrated bool
+	var ephemeral ephemeralKey
+	strict := strictKeyUsage.Get()
 	for _, option := range options {
 		//nolint:forcetypeassert
 		switch option.Ident() {
@@ -248,18 +467,20 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 			data := option.Value().(*withKey)
 			v, ok := data.alg.(jwa.KeyEncryptionAlgorithm)
 			if !ok {
-				return nil, fmt.Errorf(`jwe.Encrypt: expected alg to be jwa.KeyEncryptionAlgorithm, but got %T`, data.alg)
+				return nil, fmt.Errorf(`expected alg to be jwa.KeyEncryptionAlgorithm, but got %T`, data.alg)
 			}
 
 			switch v {
-			case jwa.DIRECT, jwa.ECDH_ES:
+			case jwa.DIRECT, jwa.ECDH_ES, jwa.ECDH_1PU:
 				useRawCEK = true
 			}
 
 			builders = append(builders, &recipientBuilder{
-				alg:     v,
-				key:     data.key,
-				headers: data.headers,
+				alg:       v,
+				key:       data.key,
+				sender:    data.sender,
+				headers:   data.headers,
+				ephemeral: &ephemeral,
 			})
 		case identContentEncryptionAlgorithm{}:
 			calg = option.Value().(jwa.ContentEncryptionAlgorithm)
@@ -275,53 +496,77 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 				ctx := context.TODO()
 				merged, err := protected.Merge(ctx, v)
 				if err != nil {
-					return nil, fmt.Errorf(`jwe.Encrypt: failed to merge headers: %w`, err)
+					return nil, fmt.Errorf(`failed to merge headers: %w`, err)
 				}
 				protected = merged
 			}
 		case identSerialization{}:
 			format = option.Value().(int)
+		case identIntegratedEncryption{}:
+			integrated = option.Value().(bool)
+		case identStrictKeyUsage{}:
+			strict = option.Value().(bool)
 		}
 	}
 
 	// We need to have at least one builder
 	switch l := len(builders); {
 	case l == 0:
-		return nil, fmt.Errorf(`jwe.Encrypt: missing key encryption builders: use jwe.WithKey() to specify one`)
+		return nil, fmt.Errorf(`missing key encryption builders: use jwe.WithKey() to specify one`)
 	case l > 1:
 		if format == fmtCompact {
-			return nil, fmt.Errorf(`jwe.Encrypt: cannot use compact serialization when multiple recipients exist (check the number of WithKey() argument, or use WithJSON())`)
+			return nil, fmt.Errorf(`cannot use compact serialization when multiple recipients exist (check the number of WithKey() argument, or use WithJSON())`)
 		}
 	}
 
 	if useRawCEK {
 		if len(builders) != 1 {
-			return nil, fmt.Errorf(`jwe.Encrypt: multiple recipients for ECDH-ES/DIRECT mode supported`)
+			return nil, fmt.Errorf(`multiple recipients for ECDH-ES/ECDH-1PU/DIRECT mode supported`)
+		}
+	}
+
+	if strict {
+		for i, b := range builders {
+			if err := jwk.CheckKeyUsage(b.key, keyOperationFor(b.alg, true)); err != nil {
+				return nil, fmt.Errorf(`invalid key for recipient #%d: %w`, i, err)
+			}
+		}
+	}
+
+	if integrated {
+		if len(builders) != 1 {
+			return nil, fmt.Errorf(`multiple recipients for HPKE integrated encryption not supported`)
 		}
+		return newIntegratedEncryptCtx(builders[0], protected, compression, format)
 	}
 
 	// There is exactly one content encrypter.
 	contentcrypt, err := content_crypt.NewGeneric(calg)
 	if err != nil {
-		return nil, fmt.Errorf(`jwe.Encrypt: failed to create AES encrypter: %w`, err)
+		return nil, fmt.Errorf(`failed to create AES encrypter: %w`, err)
 	}
 
 	generator := keygen.NewRandom(contentcrypt.KeySize())
 	bk, err := generator.Generate()
 	if err != nil {
-		return nil, fmt.Errorf(`jwe.Encrypt: failed to generate key: %w`, err)
+		return nil, fmt.Errorf(`failed to generate key: %w`, err)
 	}
 	cek := bk.Bytes()
 
 	recipients := make([]Recipient, len(builders))
+	var keyWraps []pendingKeyWrap
 	for i, builder := range builders {
 		// some builders require hint from the contentcrypt object
 		r, rawCEK, err := builder.Build(cek, calg, contentcrypt)
 		if err != nil {
-			return nil, fmt.Errorf(`jwe.Encrypt: failed to create recipient #%d: %w`, i, err)
+			return nil, fmt.Errorf(`failed to create recipient #%d: %w`, i, err)
 		}
 		recipients[i] = r
 
+		if builder.wrapper != nil {
+			keyWraps = append(keyWraps, pendingKeyWrap{recipient: r, wrapper: builder.wrapper})
+		}
+
 		// Kinda feels weird, but if useRawCEK == true, we asserted earlier
 		// that len(builders) == 1, so this is OK
 		if useRawCEK {
@@ -334,16 +579,32 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 	}
 
 	if err := protected.Set(ContentEncryptionKey, calg); err != nil {
-		return nil, fmt.Errorf(`jwe.Encrypt: failed to set "enc" in protected header: %w`, err)
+		return nil, fmt.Errorf(`failed to set "enc" in protected header: %w`, err)
 	}
 
 	if compression != jwa.NoCompress {
-		payload, err = compress(payload)
-		if err != nil {
-			return nil, fmt.Errorf(`jwe.Encrypt: failed to compress payload before encryption: %w`, err)
-		}
 		if err := protected.Set(CompressionKey, compression); err != nil {
-			return nil, fmt.Errorf(`jwe.Encrypt: failed to set "zip" in protected header: %w`, err)
+			return nil, fmt.Errorf(`failed to set "zip" in protected header: %w`, err)
+		}
+	}
+
+	// ECDH-1PU uses the same ephemeral key for all recipients, and
+	// it is placed in the protected header
+	if ephemeral.key != nil && len(recipients) > 1 {
+		for _, r := range recipients {
+			switch r.Headers().Algorithm() {
+			case jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+				epk, ok := r.Headers().Get(EphemeralPublicKeyKey)
+				if !ok {
+					continue
+				}
+				if err := protected.Set(EphemeralPublicKeyKey, epk); err != nil {
+					return nil, fmt.Errorf(`failed to set "epk" in protected header: %w`, err)
+				}
+				if err := r.Headers().Remove(EphemeralPublicKeyKey); err != nil {
+					return nil, fmt.Errorf(`failed to remove "epk" from recipient header: %w`, err)
+				}
+			}
 		}
 	}
 
@@ -352,7 +613,7 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 	if len(recipients) == 1 {
 		h, err := protected.Merge(context.TODO(), recipients[0].Headers())
 		if err != nil {
-			return nil, fmt.Errorf(`jwe.Encrypt: failed to merge protected headers: %w`, err)
+			return nil, fmt.Errorf(`failed to merge protected headers: %w`, err)
 		}
 		protected = h
 	}
@@ -362,39 +623,114 @@ func Encrypt(payload []byte, options ...EncryptOption) ([]byte, error) {
 		return nil, fmt.Errorf(`failed to base64 encode protected headers: %w`, err)
 	}
 
-	iv, ciphertext, tag, err := contentcrypt.Encrypt(cek, payload, aad)
+	return &encryptCtx{
+		format:       format,
+		compression:  compression,
+		contentcrypt: contentcrypt,
+		cek:          cek,
+		protected:    protected,
+		recipients:   recipients,
+		keyWraps:     keyWraps,
+		aad:          aad,
+	}, nil
+}
+
+// newIntegratedEncryptCtx creates an encryptCtx for HPKE Integrated
+// Encryption, where there is no content encryption key
+func newIntegratedEncryptCtx(b *recipientBuilder, protected Headers, compression jwa.CompressionAlgorithm, format int) (*encryptCtx, error) {
+	if !hpke.IsHPKE(b.alg) {
+		return nil, fmt.Errorf(`integrated encryption requires an HPKE key encryption algorithm (got %s)`, b.alg)
+	}
+
+	key := b.key
+	r := NewRecipient()
+	if hdrs := b.headers; hdrs != nil {
+		_ = r.SetHeaders(hdrs)
+	}
+
+	if err := r.Headers().Set(AlgorithmKey, b.alg); err != nil {
+		return nil, fmt.Errorf(`failed to set header: %w`, err)
+	}
+	if jwkKey, ok := key.(jwk.Key); ok {
+		if kid := jwkKey.KeyID(); kid != "" {
+			if err := r.Headers().Set(KeyIDKey, kid); err != nil {
+				return nil, fmt.Errorf(`failed to set header: %w`, err)
+			}
+		}
+
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, fmt.Errorf(`failed to retrieve raw key out of %T: %w`, key, err)
+		}
+		key = raw
+	}
+
+	if protected == nil {
+		protected = NewHeaders()
+	}
+
+	// The payload is encrypted using the AEAD of the HPKE suite,
+	// so "enc" must not be present
+	if err := protected.Remove(ContentEncryptionKey); err != nil {
+		return nil, fmt.Errorf(`failed to remove "enc" from protected header: %w`, err)
+	}
+
+	if compression != jwa.NoCompress {
+		if err := protected.Set(CompressionKey, compression); err != nil {
+			return nil, fmt.Errorf(`failed to set "zip" in protected header: %w`, err)
+		}
+	}
+
+	protected, err := protected.Merge(context.TODO(), r.Headers())
 	if err != nil {
-		return nil, fmt.Errorf(`failed to encrypt payload: %w`, err)
+		return nil, fmt.Errorf(`failed to merge protected headers: %w`, err)
 	}
 
-	msg := NewMessage()
+	aad, err := protected.Encode()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to base64 encode protected headers: %w`, err)
+	}
 
-	if err := msg.Set(CipherTextKey, ciphertext); err != nil {
-		return nil, fmt.Errorf(`failed to set %s: %w`, CipherTextKey, err)
+	return &encryptCtx{
+		format:      format,
+		compression: compression,
+		protected:   protected,
+		recipients:  []Recipient{r},
+		integrated:  &integratedSealer{alg: b.alg, key: key},
+		aad:         aad,
+	}, nil
+}
+
+// wrapKeys computes the encrypted keys that depend on the authentication
+// tag of the content (i.e. ECDH-1PU key wrapping modes)
+func (ectx *encryptCtx) wrapKeys(tag []byte) error {
+	for _, kw := range ectx.keyWraps {
+		enckey, err := kw.wrapper.Wrap(tag)
+		if err != nil {
+			return fmt.Errorf(`failed to encrypt key: %w`, err)
+		}
+		if err := kw.recipient.SetEncryptedKey(enckey); err != nil {
+			return fmt.Errorf(`failed to set encrypted key: %w`, err)
+		}
 	}
+	return nil
+}
+
+// newMessage creates a message containing everything but the
+// ciphertext and the tag
+func (ectx *encryptCtx) newMessage(iv []byte) (*Message, error) {
+	msg := NewMessage()
+
 	if err := msg.Set(InitializationVectorKey, iv); err != nil {
 		return nil, fmt.Errorf(`failed to set %s: %w`, InitializationVectorKey, err)
 	}
-	if err := msg.Set(ProtectedHeadersKey, protected); err != nil {
+	if err := msg.Set(ProtectedHeadersKey, ectx.protected); err != nil {
 		return nil, fmt.Errorf(`failed to set %s: %w`, ProtectedHeadersKey, err)
 	}
-	if err := msg.Set(RecipientsKey, recipients); err != nil {
+	if err := msg.Set(RecipientsKey, ectx.recipients); err != nil {
 		return nil, fmt.Errorf(`failed to set %s: %w`, RecipientsKey, err)
 	}
-	if err := msg.Set(TagKey, tag); err != nil {
-		return nil, fmt.Errorf(`failed to set %s: %w`, TagKey, err)
-	}
-
-	switch format {
-	case fmtCompact:
-		return Compact(msg)
-	case fmtJSON:
-		return json.Marshal(msg)
-	case fmtJSONPretty:
-		return json.MarshalIndent(msg, "", "  ")
-	default:
-		return nil, fmt.Errorf(`jwe.Encrypt: invalid serialization`)
-	}
+	return msg, nil
 }
 
 type decryptCtx struct {
@@ -403,6 +739,11 @@ type decryptCtx struct {
 	computedAad      []byte
 	keyProviders     []KeyProvider
 	protectedHeaders Headers
+	keyUsed          interface{}
+	dst              *Message
+	criticalHeaders  map[string]struct{}
+	unverified       bool
+	strictKeyUsage   bool
 }
 
 // Decrypt takes the key encryption algorithm and the corresponding
@@ -415,42 +756,86 @@ type decryptCtx struct {
 //
 // `key` must be a private key. It can be either in its raw format (e.g. *rsa.PrivateKey) or a jwk.Key
 func Decrypt(buf []byte, options ...DecryptOption) ([]byte, error) {
-	var keyProviders []KeyProvider
-	var keyUsed interface{}
+	dctx, err := newDecryptCtx(options)
+	if err != nil {
+		return nil, fmt.Errorf(`jwe.Decrypt: %w`, err)
+	}
+
+	msg, err := parseJSONOrCompact(buf, true)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse buffer for Decrypt: %w`, err)
+	}
+
+	recipients, err := dctx.setMessage(msg)
+	if err != nil {
+		return nil, fmt.Errorf(`jwe.Decrypt: %w`, err)
+	}
+
+	ctx := context.TODO()
+	var lastError error
+	for _, recipient := range recipients {
+		decrypted, err := dctx.try(ctx, recipient, dctx.keyUsed)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		dctx.storeMessage()
+		return decrypted, nil
+	}
+	return nil, fmt.Errorf(`jwe.Decrypt: failed to decrypt any of the recipients (last error = %w)`, lastError)
+}
 
-	var dst *Message
+func newDecryptCtx(options []DecryptOption) (*decryptCtx, error) {
+	dctx := decryptCtx{
+		strictKeyUsage: strictKeyUsage.Get(),
+	}
 	//nolint:forcetypeassert
 	for _, option := range options {
 		switch option.Ident() {
 		case identMessage{}:
-			dst = option.Value().(*Message)
+			dctx.dst = option.Value().(*Message)
 		case identKeyProvider{}:
-			keyProviders = append(keyProviders, option.Value().(KeyProvider))
+			dctx.keyProviders = append(dctx.keyProviders, option.Value().(KeyProvider))
 		case identKeyUsed{}:
-			keyUsed = option.Value()
+			dctx.keyUsed = option.Value()
 		case identKey{}:
 			pair := option.Value().(*withKey)
 			alg, ok := pair.alg.(jwa.KeyEncryptionAlgorithm)
 			if !ok {
 				return nil, fmt.Errorf(`WithKey() option must be specified using jwa.KeyEncryptionAlgorithm (got %T)`, pair.alg)
 			}
-			keyProviders = append(keyProviders, &staticKeyProvider{
-				alg: alg,
-				key: pair.key,
+			dctx.keyProviders = append(dctx.keyProviders, &staticKeyProvider{
+				alg:    alg,
+				key:    pair.key,
+				sender: pair.sender,
 			})
+		case identCriticalHeaders{}:
+			if dctx.criticalHeaders == nil {
+				dctx.criticalHeaders = make(map[string]struct{})
+			}
+			for _, name := range option.Value().([]string) {
+				dctx.criticalHeaders[name] = struct{}{}
+			}
+		case identUnverifiedStreaming{}:
+			dctx.unverified = option.Value().(bool)
+		case identStrictKeyUsage{}:
+			dctx.strictKeyUsage = option.Value().(bool)
 		}
 	}
 
-	if len(keyProviders) < 1 {
-		return nil, fmt.Errorf(`jwe.Decrypt: no key providers have been provided (see jwe.WithKey(), jwe.WithKeySet(), and jwe.WithKeyProvider()`)
+	if len(dctx.keyProviders) < 1 {
+		return nil, fmt.Errorf(`no key providers have been provided (see jwe.WithKey(), jwe.WithKeySet(), and jwe.WithKeyProvider()`)
 	}
+	return &dctx, nil
+}
 
-	msg, err := parseJSONOrCompact(buf, true)
-	if err != nil {
-		return nil, fmt.Errorf(`failed to parse buffer for Decrypt: %w`, err)
+// setMessage processes things that are common to the message, and
+// returns the list of recipients that should be tried
+func (dctx *decryptCtx) setMessage(msg *Message) ([]Recipient, error) {
+	if err := verifyCriticalHeaders(msg, dctx.criticalHeaders); err != nil {
+		return nil, fmt.Errorf(`invalid "crit" header: %w`, err)
 	}
 
-	// Process things that are common to the message
 	ctx := context.TODO()
 	h, err := msg.protectedHeaders.Clone(ctx)
 	if err != nil {
@@ -489,29 +874,97 @@ func Decrypt(buf []byte, options ...DecryptOption) ([]byte, error) {
 		recipients = append(recipients, r)
 	}
 
-	var dctx decryptCtx
-
 	dctx.aad = aad
 	dctx.computedAad = computedAad
 	dctx.msg = msg
-	dctx.keyProviders = keyProviders
 	dctx.protectedHeaders = h
+	return recipients, nil
+}
 
-	var lastError error
-	for _, recipient := range recipients {
-		decrypted, err := dctx.try(ctx, recipient, keyUsed)
-		if err != nil {
-			lastError = err
-			continue
+// storeMessage copies the message to the destination specified
+// by `jwe.WithMessage()`, if any
+func (dctx *decryptCtx) storeMessage() {
+	if dst := dctx.dst; dst != nil {
+		*dst = *dctx.msg
+		dst.rawProtectedHeaders = nil
+		dst.storeProtectedHeaders = false
+	}
+}
+
+// registeredHeaders lists the header parameter names defined in RFC 7516
+// and RFC 7518. These may not be listed in the "crit" header.
+var registeredHeaders = map[string]struct{}{
+	AgreementPartyUInfoKey:    {},
+	AgreementPartyVInfoKey:    {},
+	AlgorithmKey:              {},
+	CompressionKey:            {},
+	ContentEncryptionKey:      {},
+	ContentTypeKey:            {},
+	CountKey:                  {},
+	CriticalKey:               {},
+	EncapsulatedKeyKey:        {},
+	EphemeralPublicKeyKey:     {},
+	InitializationVectorKey:   {},
+	JWKKey:                    {},
+	JWKSetURLKey:              {},
+	KeyIDKey:                  {},
+	SaltKey:                   {},
+	TagKey:                    {},
+	TypeKey:                   {},
+	X509CertChainKey:          {},
+	X509CertThumbprintKey:     {},
+	X509CertThumbprintS256Key: {},
+	X509URLKey:                {},
+}
+
+// verifyCriticalHeaders checks the "crit" header of the given message
+// as described in RFC 7516 Section 4.1.13. Extensions must be explicitly
+// declared by the user in `understood`.
+func verifyCriticalHeaders(msg *Message, understood map[string]struct{}) error {
+	var protectedCrit bool
+	if hdrs := msg.protectedHeaders; hdrs != nil {
+		_, protectedCrit = hdrs.Get(CriticalKey)
+	}
+
+	if hdrs := msg.unprotectedHeaders; hdrs != nil {
+		if _, ok := hdrs.Get(CriticalKey); ok {
+			return fmt.Errorf(`"crit" must be placed in the protected header`)
 		}
-		if dst != nil {
-			*dst = *msg
-			dst.rawProtectedHeaders = nil
-			dst.storeProtectedHeaders = false
+	}
+
+	// In compact serialization the recipient headers are a copy of the
+	// protected headers, so only complain when "crit" is not protected
+	if !protectedCrit {
+		for _, recipient := range msg.recipients {
+			if hdrs := recipient.Headers(); hdrs != nil {
+				if _, ok := hdrs.Get(CriticalKey); ok {
+					return fmt.Errorf(`"crit" must be placed in the protected header`)
+				}
+			}
 		}
-		return decrypted, nil
+		return nil
 	}
-	return nil, fmt.Errorf(`jwe.Decrypt: failed to decrypt any of the recipients (last error = %w)`, lastError)
+
+	hdrs := msg.protectedHeaders
+	crit := hdrs.Critical()
+	if len(crit) == 0 {
+		return fmt.Errorf(`"crit" must not be empty`)
+	}
+
+	for _, name := range crit {
+		if _, ok := registeredHeaders[name]; ok {
+			return fmt.Errorf(`"crit" must not contain registered header parameter %q`, name)
+		}
+
+		if _, ok := understood[name]; !ok {
+			return fmt.Errorf(`unsupported critical extension %q (see jwe.WithCriticalHeaders())`, name)
+		}
+
+		if _, ok := hdrs.Get(name); !ok {
+			return fmt.Errorf(`critical extension %q is not present in the protected header`, name)
+		}
+	}
+	return nil
 }
 
 func (dctx *decryptCtx) try(ctx context.Context, recipient Recipient, keyUsed interface{}) ([]byte, error) {
@@ -531,8 +984,14 @@ func (dctx *decryptCtx) try(ctx context.Context, recipient Recipient, keyUsed in
 			//nolint:forcetypeassert
 			alg := pair.alg.(jwa.KeyEncryptionAlgorithm)
 			key := pair.key
+			if dctx.strictKeyUsage {
+				if err := jwk.CheckKeyUsage(key, keyOperationFor(alg, false)); err != nil {
+					lastError = err
+					continue
+				}
+			}
 
-			decrypted, err := dctx.decryptKey(ctx, alg, key, recipient)
+			decrypted, err := dctx.decryptKey(ctx, alg, key, pair.sender, recipient)
 			if err != nil {
 				lastError = err
 				continue
@@ -546,10 +1005,57 @@ func (dctx *decryptCtx) try(ctx context.Context, recipient Recipient, keyUsed in
 			return decrypted, nil
 		}
 	}
-	return nil, fmt.Errorf(`jwe.Decrypt: tried %d keys, but failed to match any of the keys with recipient (last error = %s)`, tried, lastError)
+	return nil, fmt.Errorf(`jwe.Decrypt: tried %d keys, but failed to match any of the keys with recipient (last error = %w)`, tried, lastError)
 }
 
-func (dctx *decryptCtx) decryptKey(ctx context.Context, alg jwa.KeyEncryptionAlgorithm, key interface{}, recipient Recipient) ([]byte, error) {
+func (dctx *decryptCtx) decryptKey(ctx context.Context, alg jwa.KeyEncryptionAlgorithm, key, sender interface{}, recipient Recipient) ([]byte, error) {
+	if dctx.isIntegrated() {
+		return dctx.openIntegrated(alg, key, recipient)
+	}
+
+	dec, h2, err := dctx.newDecrypter(ctx, alg, key, sender, recipient)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := dec.Decrypt(recipient.EncryptedKey(), dctx.msg.cipherText)
+	if err != nil {
+		return nil, fmt.Errorf(`jwe.Decrypt: decryption failed: %w`, err)
+	}
+
+	if h2.Compression() == jwa.Deflate {
+		buf, err := uncompress(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf(`jwe.Derypt: failed to uncompress payload: %w`, err)
+		}
+		plaintext = buf
+	}
+
+	if plaintext == nil {
+		return nil, fmt.Errorf(`failed to find matching recipient`)
+	}
+
+	return plaintext, nil
+}
+
+// isIntegrated returns true if the message was encrypted using HPKE
+// Integrated Encryption, in which case the protected header does not
+// contain "enc"
+func (dctx *decryptCtx) isIntegrated() bool {
+	if _, ok := dctx.msg.protectedHeaders.Get(ContentEncryptionKey); ok {
+		return false
+	}
+	return hpke.IsHPKE(dctx.msg.protectedHeaders.Algorithm())
+}
+
+// openIntegrated decrypts a message that was encrypted using HPKE
+// Integrated Encryption. The encrypted key of the recipient is the
+// HPKE encapsulated key
+func (dctx *decryptCtx) openIntegrated(alg jwa.KeyEncryptionAlgorithm, key interface{}, recipient Recipient) ([]byte, error) {
+	if recipient.Headers().Algorithm() != alg {
+		return nil, fmt.Errorf(`jwe.Decrypt: key and recipient algorithms do not match`)
+	}
+
 	if jwkKey, ok := key.(jwk.Key); ok {
 		var raw interface{}
 		if err := jwkKey.Raw(&raw); err != nil {
@@ -558,6 +1064,38 @@ func (dctx *decryptCtx) decryptKey(ctx context.Context, alg jwa.KeyEncryptionAlg
 		key = raw
 	}
 
+	aad := dctx.computedAad
+	if dctx.aad != nil {
+		aad = append(append(aad[:len(aad):len(aad)], '.'), dctx.aad...)
+	}
+
+	plaintext, err := hpke.Open(alg, key, recipient.EncryptedKey(), nil, aad, dctx.msg.cipherText)
+	if err != nil {
+		return nil, fmt.Errorf(`jwe.Decrypt: decryption failed: %w`, err)
+	}
+
+	if dctx.protectedHeaders.Compression() == jwa.Deflate {
+		buf, err := uncompress(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf(`jwe.Decrypt: failed to uncompress payload: %w`, err)
+		}
+		plaintext = buf
+	}
+	return plaintext, nil
+}
+
+// newDecrypter creates a decrypter for the given key and recipient. The
+// headers that apply to the recipient are returned along with it.
+// `sender` is the sender's public key, which is only used for ECDH-1PU.
+func (dctx *decryptCtx) newDecrypter(ctx context.Context, alg jwa.KeyEncryptionAlgorithm, key, sender interface{}, recipient Recipient) (*decrypter, Headers, error) {
+	if jwkKey, ok := key.(jwk.Key); ok {
+		var raw interface{}
+		if err := jwkKey.Raw(&raw); err != nil {
+			return nil, nil, fmt.Errorf(`failed to retrieve raw key from %T: %w`, key, err)
+		}
+		key = raw
+	}
+
 	dec := newDecrypter(alg, dctx.msg.protectedHeaders.ContentEncryption(), key).
 		AuthenticatedData(dctx.aad).
 		ComputedAuthenticatedData(dctx.computedAad).
@@ -566,40 +1104,41 @@ func (dctx *decryptCtx) decryptKey(ctx context.Context, alg jwa.KeyEncryptionAlg
 
 	if recipient.Headers().Algorithm() != alg {
 		// algorithms don't match
-		return nil, fmt.Errorf(`jwe.Decrypt: key and recipient algorithms do not match`)
+		return nil, nil, fmt.Errorf(`jwe.Decrypt: key and recipient algorithms do not match`)
 	}
 
 	h2, err := dctx.protectedHeaders.Clone(ctx)
 	if err != nil {
-		return nil, fmt.Errorf(`jwe.Decrypt: failed to copy headers (1): %w`, err)
+		return nil, nil, fmt.Errorf(`jwe.Decrypt: failed to copy headers (1): %w`, err)
 	}
 
 	h2, err = h2.Merge(ctx, recipient.Headers())
 	if err != nil {
-		return nil, fmt.Errorf(`failed to copy headers (2): %w`, err)
+		return nil, nil, fmt.Errorf(`failed to copy headers (2): %w`, err)
 	}
 
 	switch alg {
-	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW:
+	case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW,
+		jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
 		epkif, ok := h2.Get(EphemeralPublicKeyKey)
 		if !ok {
-			return nil, fmt.Errorf(`failed to get 'epk' field`)
+			return nil, nil, fmt.Errorf(`failed to get 'epk' field`)
 		}
 		switch epk := epkif.(type) {
 		case jwk.ECDSAPublicKey:
 			var pubkey ecdsa.PublicKey
 			if err := epk.Raw(&pubkey); err != nil {
-				return nil, fmt.Errorf(`failed to get public key: %w`, err)
+				return nil, nil, fmt.Errorf(`failed to get public key: %w`, err)
 			}
 			dec.PublicKey(&pubkey)
 		case jwk.OKPPublicKey:
 			var pubkey interface{}
 			if err := epk.Raw(&pubkey); err != nil {
-				return nil, fmt.Errorf(`failed to get public key: %w`, err)
+				return nil, nil, fmt.Errorf(`failed to get public key: %w`, err)
 			}
 			dec.PublicKey(pubkey)
 		default:
-			return nil, fmt.Errorf("unexpected 'epk' type %T for alg %s", epkif, alg)
+			return nil, nil, fmt.Errorf("unexpected 'epk' type %T for alg %s", epkif, alg)
 		}
 
 		if apu := h2.AgreementPartyUInfo(); len(apu) > 0 {
@@ -608,77 +1147,83 @@ func (dctx *decryptCtx) decryptKey(ctx context.Context, alg jwa.KeyEncryptionAlg
 		if apv := h2.AgreementPartyVInfo(); len(apv) > 0 {
 			dec.AgreementPartyVInfo(apv)
 		}
+
+		switch alg {
+		case jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+			if len(dctx.msg.tag) == 0 {
+				return nil, nil, fmt.Errorf(`%s requires the authentication tag before the key can be decrypted (it can not be used with jwe.WithUnverifiedStreaming())`, alg)
+			}
+			fallthrough
+		case jwa.ECDH_1PU:
+			if jwkKey, ok := sender.(jwk.Key); ok {
+				var raw interface{}
+				if err := jwkKey.Raw(&raw); err != nil {
+					return nil, nil, fmt.Errorf(`failed to retrieve raw key from %T: %w`, sender, err)
+				}
+				sender = raw
+			}
+			dec.SenderPublicKey(sender)
+		}
+	case jwa.HPKE_0, jwa.HPKE_1, jwa.HPKE_2, jwa.HPKE_3, jwa.HPKE_4, jwa.HPKE_5, jwa.HPKE_6, jwa.HPKE_7:
+		ek := h2.EncapsulatedKey()
+		if len(ek) == 0 {
+			return nil, nil, fmt.Errorf(`failed to get 'ek' field`)
+		}
+		dec.EncapsulatedKey(ek)
 	case jwa.A128GCMKW, jwa.A192GCMKW, jwa.A256GCMKW:
 		ivB64, ok := h2.Get(InitializationVectorKey)
 		if !ok {
-			return nil, fmt.Errorf(`failed to get 'iv' field`)
+			return nil, nil, fmt.Errorf(`failed to get 'iv' field`)
 		}
 		ivB64Str, ok := ivB64.(string)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type for 'iv': %T", ivB64)
+			return nil, nil, fmt.Errorf("unexpected type for 'iv': %T", ivB64)
 		}
 		tagB64, ok := h2.Get(TagKey)
 		if !ok {
-			return nil, fmt.Errorf(`failed to get 'tag' field`)
+			return nil, nil, fmt.Errorf(`failed to get 'tag' field`)
 		}
 		tagB64Str, ok := tagB64.(string)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type for 'tag': %T", tagB64)
+			return nil, nil, fmt.Errorf("unexpected type for 'tag': %T", tagB64)
 		}
 		iv, err := base64.DecodeString(ivB64Str)
 		if err != nil {
-			return nil, fmt.Errorf(`failed to b64-decode 'iv': %w`, err)
+			return nil, nil, fmt.Errorf(`failed to b64-decode 'iv': %w`, err)
 		}
 		tag, err := base64.DecodeString(tagB64Str)
 		if err != nil {
-			return nil, fmt.Errorf(`failed to b64-decode 'tag': %w`, err)
+			return nil, nil, fmt.Errorf(`failed to b64-decode 'tag': %w`, err)
 		}
 		dec.KeyInitializationVector(iv)
 		dec.KeyTag(tag)
 	case jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
 		saltB64, ok := h2.Get(SaltKey)
 		if !ok {
-			return nil, fmt.Errorf(`failed to get 'p2s' field`)
+			return nil, nil, fmt.Errorf(`failed to get 'p2s' field`)
 		}
 		saltB64Str, ok := saltB64.(string)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type for 'p2s': %T", saltB64)
+			return nil, nil, fmt.Errorf("unexpected type for 'p2s': %T", saltB64)
 		}
 
 		count, ok := h2.Get(CountKey)
 		if !ok {
-			return nil, fmt.Errorf(`failed to get 'p2c' field`)
+			return nil, nil, fmt.Errorf(`failed to get 'p2c' field`)
 		}
 		countFlt, ok := count.(float64)
 		if !ok {
-			return nil, fmt.Errorf("unexpected type for 'p2c': %T", count)
+			return nil, nil, fmt.Errorf("unexpected type for 'p2c': %T", count)
 		}
 		salt, err := base64.DecodeString(saltB64Str)
 		if err != nil {
-			return nil, fmt.Errorf(`failed to b64-decode 'salt': %w`, err)
+			return nil, nil, fmt.Errorf(`failed to b64-decode 'salt': %w`, err)
 		}
 		dec.KeySalt(salt)
 		dec.KeyCount(int(countFlt))
 	}
 
-	plaintext, err := dec.Decrypt(recipient.EncryptedKey(), dctx.msg.cipherText)
-	if err != nil {
-		return nil, fmt.Errorf(`jwe.Decrypt: decryption failed: %w`, err)
-	}
-
-	if h2.Compression() == jwa.Deflate {
-		buf, err := uncompress(plaintext)
-		if err != nil {
-			return nil, fmt.Errorf(`jwe.Derypt: failed to uncompress payload: %w`, err)
-		}
-		plaintext = buf
-	}
-
-	if plaintext == nil {
-		return nil, fmt.Errorf(`failed to find matching recipient`)
-	}
-
-	return plaintext, nil
+	return dec, h2, nil
 }
 
 // Parse parses the JWE message into a Message object. The JWE message
@@ -1,6 +1,7 @@
 package jwe_test
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"crypto/ecdsa"
@@ -8,7 +9,9 @@ import (
 	"crypto/rand"
 	"crypto/rsa"
 	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"testing"
@@ -21,8 +24,10 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwe"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/curve25519"
 )
 
 const (
@@ -337,6 +342,31 @@ func TestRoundtrip_RSAES_OAEP_AES_GCM(t *testing.T) {
 			return
 		}
 	}
+
+	for _, alg := range []jwa.KeyEncryptionAlgorithm{jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512} {
+		alg := alg
+		t.Run(alg.String(), func(t *testing.T) {
+			encrypted, err := jwe.Encrypt(plaintext, jwe.WithKey(alg, &rsaPrivKey.PublicKey))
+			require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+			msg, err := jwe.Parse(encrypted)
+			require.NoError(t, err, `jwe.Parse should succeed`)
+			require.Equal(t, alg, msg.ProtectedHeaders().Algorithm(), `"alg" should match`)
+
+			decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(alg, rsaPrivKey))
+			require.NoError(t, err, `jwe.Decrypt should succeed`)
+			require.Equal(t, plaintext, decrypted, `decrypted content should match`)
+
+			// The hash function is part of the algorithm
+			for _, other := range []jwa.KeyEncryptionAlgorithm{jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512} {
+				if other == alg {
+					continue
+				}
+				_, err := jwe.Decrypt(encrypted, jwe.WithKey(other, rsaPrivKey))
+				require.Error(t, err, `jwe.Decrypt should fail for %s`, other)
+			}
+		})
+	}
 }
 
 func TestRoundtrip_RSA1_5_A128CBC_HS256(t *testing.T) {
@@ -461,6 +491,44 @@ func TestEncode_X25519(t *testing.T) {
 	testEncodeECDHWithKey(t, privkey, pubkey)
 }
 
+func TestEncode_X448(t *testing.T) {
+	pubkey, privkey, err := x448.GenerateKey(rand.Reader)
+	if !assert.NoError(t, err, `x448.GenerateKey should succeed`) {
+		return
+	}
+
+	testEncodeECDHWithKey(t, privkey, pubkey)
+
+	t.Run("jwk.Key with apu/apv", func(t *testing.T) {
+		jwkPrivkey, err := jwk.FromRaw(privkey)
+		if !assert.NoError(t, err, `jwk.FromRaw should succeed`) {
+			return
+		}
+		jwkPubkey, err := jwk.PublicKeyOf(jwkPrivkey)
+		if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+			return
+		}
+
+		hdrs := jwe.NewHeaders()
+		hdrs.Set(jwe.AgreementPartyUInfoKey, []byte(`Alice`))
+		hdrs.Set(jwe.AgreementPartyVInfoKey, []byte(`Bob`))
+
+		plaintext := []byte("Lorem ipsum")
+		encrypted, err := jwe.Encrypt(plaintext, jwe.WithKey(jwa.ECDH_ES_A256KW, jwkPubkey, jwe.WithPerRecipientHeaders(hdrs)))
+		if !assert.NoError(t, err, `jwe.Encrypt should succeed`) {
+			return
+		}
+
+		decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.ECDH_ES_A256KW, jwkPrivkey))
+		if !assert.NoError(t, err, `jwe.Decrypt should succeed`) {
+			return
+		}
+		if !assert.Equal(t, plaintext, decrypted, `plaintext should match`) {
+			return
+		}
+	})
+}
+
 func Test_GHIssue207(t *testing.T) {
 	const plaintext = "hi\n"
 	var testcases = []struct {
@@ -819,3 +887,728 @@ func TestGH803(t *testing.T) {
 	require.Equal(t, apu, msg.ProtectedHeaders().AgreementPartyUInfo())
 	require.Equal(t, apv, msg.ProtectedHeaders().AgreementPartyVInfo())
 }
+
+func TestCriticalHeaders(t *testing.T) {
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	require.NoError(t, err, `rand.Read should succeed`)
+
+	payload := []byte("Lorem Ipsum")
+	encrypt := func(t *testing.T, hdrs map[string]interface{}) []byte {
+		t.Helper()
+		protected := jwe.NewHeaders()
+		for k, v := range hdrs {
+			require.NoError(t, protected.Set(k, v), `protected.Set should succeed`)
+		}
+		encrypted, err := jwe.Encrypt(payload, jwe.WithKey(jwa.A128KW, key), jwe.WithProtectedHeaders(protected))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+		return encrypted
+	}
+
+	t.Run("Unknown extension", func(t *testing.T) {
+		encrypted := encrypt(t, map[string]interface{}{
+			jwe.CriticalKey: []string{"x-foo"},
+			"x-foo":         "bar",
+		})
+
+		_, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key))
+		require.Error(t, err, `jwe.Decrypt should fail`)
+
+		decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key), jwe.WithCriticalHeaders("x-foo"))
+		require.NoError(t, err, `jwe.Decrypt should succeed`)
+		require.Equal(t, payload, decrypted, `payloads should match`)
+	})
+	t.Run("Registered header in crit", func(t *testing.T) {
+		encrypted := encrypt(t, map[string]interface{}{
+			jwe.CriticalKey: []string{jwe.KeyIDKey},
+			jwe.KeyIDKey:    "foo",
+		})
+
+		_, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key), jwe.WithCriticalHeaders(jwe.KeyIDKey))
+		require.Error(t, err, `jwe.Decrypt should fail`)
+
+		for _, name := range []string{jwe.InitializationVectorKey, jwe.TagKey, jwe.SaltKey, jwe.CountKey} {
+			name := name
+			t.Run(name, func(t *testing.T) {
+				encrypted := encrypt(t, map[string]interface{}{
+					jwe.CriticalKey: []string{name},
+					name:            "Zm9v",
+				})
+
+				_, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key), jwe.WithCriticalHeaders(name))
+				require.Error(t, err, `jwe.Decrypt should fail`)
+			})
+		}
+	})
+	t.Run("Extension missing from header", func(t *testing.T) {
+		encrypted := encrypt(t, map[string]interface{}{
+			jwe.CriticalKey: []string{"x-foo"},
+		})
+
+		_, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key), jwe.WithCriticalHeaders("x-foo"))
+		require.Error(t, err, `jwe.Decrypt should fail`)
+	})
+	t.Run("crit in per-recipient header", func(t *testing.T) {
+		hdrs := jwe.NewHeaders()
+		require.NoError(t, hdrs.Set(jwe.CriticalKey, []string{"x-foo"}), `hdrs.Set should succeed`)
+		require.NoError(t, hdrs.Set("x-foo", "bar"), `hdrs.Set should succeed`)
+
+		encrypted, err := jwe.Encrypt(payload, jwe.WithJSON(), jwe.WithKey(jwa.A128KW, key, jwe.WithPerRecipientHeaders(hdrs)), jwe.WithKey(jwa.A128KW, key))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		_, err = jwe.Decrypt(encrypted, jwe.WithKey(jwa.A128KW, key), jwe.WithCriticalHeaders("x-foo"))
+		require.Error(t, err, `jwe.Decrypt should fail`)
+	})
+}
+
+func TestStream(t *testing.T) {
+	key := make([]byte, 16)
+	_, err := rand.Read(key)
+	require.NoError(t, err, `rand.Read should succeed`)
+
+	payload := make([]byte, 100*1024)
+	_, err = rand.Read(payload)
+	require.NoError(t, err, `rand.Read should succeed`)
+
+	// hide the io.Seeker implementation
+	type nonSeekable struct {
+		io.Reader
+	}
+
+	formats := []struct {
+		Name    string
+		Options []jwe.EncryptOption
+	}{
+		{Name: "Compact", Options: []jwe.EncryptOption{jwe.WithCompact()}},
+		{Name: "JSON", Options: []jwe.EncryptOption{jwe.WithJSON()}},
+		{Name: "Pretty JSON", Options: []jwe.EncryptOption{jwe.WithJSON(jwe.WithPretty(true))}},
+	}
+
+	for _, calg := range []jwa.ContentEncryptionAlgorithm{jwa.A128GCM, jwa.A256GCM, jwa.A128CBC_HS256, jwa.A256CBC_HS512, jwa.C20P, jwa.XC20P} {
+		calg := calg
+		for _, format := range formats {
+			format := format
+			for _, compress := range []jwa.CompressionAlgorithm{jwa.NoCompress, jwa.Deflate} {
+				compress := compress
+				for _, size := range []int{0, 1, 1000, len(payload)} {
+					size := size
+					t.Run(fmt.Sprintf("%s/%s/%s/%d", calg, format.Name, compress, size), func(t *testing.T) {
+						options := append([]jwe.EncryptOption{
+							jwe.WithKey(jwa.A128KW, key),
+							jwe.WithContentEncryption(calg),
+							jwe.WithCompress(compress),
+						}, format.Options...)
+
+						var encrypted bytes.Buffer
+						require.NoError(t, jwe.EncryptStream(&encrypted, bytes.NewReader(payload[:size]), options...), `jwe.EncryptStream should succeed`)
+
+						var decrypted bytes.Buffer
+						require.NoError(t, jwe.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes()), jwe.WithKey(jwa.A128KW, key)), `jwe.DecryptStream should succeed`)
+						require.True(t, bytes.Equal(payload[:size], decrypted.Bytes()), `payloads should match`)
+
+						decrypted.Reset()
+						require.NoError(t, jwe.DecryptStream(&decrypted, nonSeekable{bytes.NewReader(encrypted.Bytes())}, jwe.WithKey(jwa.A128KW, key), jwe.WithUnverifiedStreaming(true)), `jwe.DecryptStream should succeed`)
+						require.True(t, bytes.Equal(payload[:size], decrypted.Bytes()), `payloads should match`)
+
+						if size > 0 {
+							plaintext, err := jwe.Decrypt(encrypted.Bytes(), jwe.WithKey(jwa.A128KW, key))
+							require.NoError(t, err, `jwe.Decrypt should succeed`)
+							require.Equal(t, payload[:size], plaintext, `payloads should match`)
+
+							encrypted, err := jwe.Encrypt(payload[:size], options...)
+							require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+							decrypted.Reset()
+							require.NoError(t, jwe.DecryptStream(&decrypted, bytes.NewReader(encrypted), jwe.WithKey(jwa.A128KW, key)), `jwe.DecryptStream should succeed`)
+							require.Equal(t, payload[:size], decrypted.Bytes(), `payloads should match`)
+						}
+					})
+				}
+			}
+		}
+	}
+
+	t.Run("Tampered ciphertext", func(t *testing.T) {
+		var encrypted bytes.Buffer
+		require.NoError(t, jwe.EncryptStream(&encrypted, bytes.NewReader(payload), jwe.WithKey(jwa.A128KW, key)), `jwe.EncryptStream should succeed`)
+
+		parts := strings.Split(encrypted.String(), ".")
+		require.Len(t, parts, 5, `message should have five parts`)
+		ct := []byte(parts[3])
+		if ct[100] == 'A' {
+			ct[100] = 'B'
+		} else {
+			ct[100] = 'A'
+		}
+		parts[3] = string(ct)
+		tampered := strings.Join(parts, ".")
+
+		var decrypted bytes.Buffer
+		require.Error(t, jwe.DecryptStream(&decrypted, strings.NewReader(tampered), jwe.WithKey(jwa.A128KW, key)), `jwe.DecryptStream should fail`)
+		require.Equal(t, 0, decrypted.Len(), `nothing should be written`)
+
+		require.Error(t, jwe.DecryptStream(&decrypted, strings.NewReader(tampered), jwe.WithKey(jwa.A128KW, key), jwe.WithUnverifiedStreaming(true)), `jwe.DecryptStream should fail`)
+	})
+	t.Run("Ciphertext modified after verification", func(t *testing.T) {
+		var encrypted bytes.Buffer
+		require.NoError(t, jwe.EncryptStream(&encrypted, bytes.NewReader(payload), jwe.WithKey(jwa.A128KW, key)), `jwe.EncryptStream should succeed`)
+
+		// the source changes after the ciphertext has been verified, so
+		// the modification must be caught before the content is written
+		parts := strings.Split(encrypted.String(), ".")
+		ct, err := base64.RawURLEncoding.DecodeString(parts[3])
+		require.NoError(t, err, `base64.DecodeString should succeed`)
+		ct[0] ^= 1
+		parts[3] = base64.RawURLEncoding.EncodeToString(ct)
+
+		src := &swappingReader{
+			Reader: bytes.NewReader(encrypted.Bytes()),
+			after:  bytes.NewReader([]byte(strings.Join(parts, "."))),
+			seeks:  2,
+		}
+		var decrypted bytes.Buffer
+		require.Error(t, jwe.DecryptStream(&decrypted, src, jwe.WithKey(jwa.A128KW, key)), `jwe.DecryptStream should fail`)
+		require.Equal(t, 0, decrypted.Len(), `nothing should be written`)
+	})
+	t.Run("Multiple keys", func(t *testing.T) {
+		rsakey, err := jwxtest.GenerateRsaKey()
+		require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+
+		var encrypted bytes.Buffer
+		require.NoError(t, jwe.EncryptStream(&encrypted, bytes.NewReader(payload),
+			jwe.WithJSON(),
+			jwe.WithKey(jwa.A128KW, key),
+			jwe.WithKey(jwa.RSA_OAEP, &rsakey.PublicKey),
+		), `jwe.EncryptStream should succeed`)
+
+		wrongkey := make([]byte, 16)
+		var keyUsed interface{}
+		var decrypted bytes.Buffer
+		require.NoError(t, jwe.DecryptStream(&decrypted, bytes.NewReader(encrypted.Bytes()),
+			jwe.WithKey(jwa.A128KW, wrongkey),
+			jwe.WithKey(jwa.RSA_OAEP, rsakey),
+			jwe.WithKeyUsed(&keyUsed),
+		), `jwe.DecryptStream should succeed`)
+		require.Equal(t, payload, decrypted.Bytes(), `payloads should match`)
+		require.Equal(t, rsakey, keyUsed, `the RSA key should have been used`)
+	})
+	t.Run("Non-seekable source", func(t *testing.T) {
+		var encrypted bytes.Buffer
+		require.NoError(t, jwe.EncryptStream(&encrypted, bytes.NewReader(payload), jwe.WithKey(jwa.A128KW, key)), `jwe.EncryptStream should succeed`)
+
+		var decrypted bytes.Buffer
+		require.Error(t, jwe.DecryptStream(&decrypted, nonSeekable{&encrypted}, jwe.WithKey(jwa.A128KW, key)), `jwe.DecryptStream should fail`)
+	})
+	t.Run("Unverified JSON with ciphertext first", func(t *testing.T) {
+		// jwe.Encrypt() places the ciphertext before the other members
+		encrypted, err := jwe.Encrypt(payload, jwe.WithKey(jwa.A128KW, key), jwe.WithJSON())
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		var decrypted bytes.Buffer
+		require.Error(t, jwe.DecryptStream(&decrypted, bytes.NewReader(encrypted), jwe.WithKey(jwa.A128KW, key), jwe.WithUnverifiedStreaming(true)), `jwe.DecryptStream should fail`)
+	})
+}
+
+// swappingReader switches to reading from `after` once Seek has been
+// called more than `seeks` times, simulating a source that is modified
+// while it is being decrypted
+type swappingReader struct {
+	*bytes.Reader
+	after *bytes.Reader
+	seeks int
+}
+
+func (r *swappingReader) Seek(offset int64, whence int) (int64, error) {
+	if r.seeks == 0 {
+		r.Reader = r.after
+	}
+	r.seeks--
+	return r.Reader.Seek(offset, whence)
+}
+
+// opaqueRSAKey hides the *rsa.PrivateKey behind crypto.Decrypter,
+// much like a key stored in a hardware security module would
+type opaqueRSAKey struct {
+	key *rsa.PrivateKey
+}
+
+func (k opaqueRSAKey) Public() crypto.PublicKey {
+	return &k.key.PublicKey
+}
+
+func (k opaqueRSAKey) Decrypt(rand io.Reader, msg []byte, opts crypto.DecrypterOpts) ([]byte, error) {
+	return k.key.Decrypt(rand, msg, opts)
+}
+
+// opaqueECDHKey implements jwe.ECDHPrivateKey
+type opaqueECDHKey struct {
+	key interface{}
+}
+
+func (k opaqueECDHKey) Public() crypto.PublicKey {
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	case x25519.PrivateKey:
+		return key.Public()
+	}
+	return nil
+}
+
+func (k opaqueECDHKey) ECDH(pub crypto.PublicKey) ([]byte, error) {
+	switch key := k.key.(type) {
+	case *ecdsa.PrivateKey:
+		//nolint:forcetypeassert
+		pubkey := pub.(*ecdsa.PublicKey)
+		x, _ := key.Curve.ScalarMult(pubkey.X, pubkey.Y, key.D.Bytes())
+		z := make([]byte, (key.Curve.Params().BitSize+7)/8)
+		return x.FillBytes(z), nil
+	case x25519.PrivateKey:
+		//nolint:forcetypeassert
+		return curve25519.X25519(key.Seed(), pub.(x25519.PublicKey))
+	}
+	return nil, fmt.Errorf(`unsupported key type %T`, k.key)
+}
+
+func TestOpaqueKeys(t *testing.T) {
+	payload := []byte("Lorem Ipsum")
+
+	rsakey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+	eckey, err := jwxtest.GenerateEcdsaKey(jwa.P256)
+	require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+	xkey, err := jwxtest.GenerateX25519Key()
+	require.NoError(t, err, `jwxtest.GenerateX25519Key should succeed`)
+
+	testcases := []struct {
+		Alg     jwa.KeyEncryptionAlgorithm
+		Public  interface{}
+		Private interface{}
+	}{
+		{Alg: jwa.RSA1_5, Public: &rsakey.PublicKey, Private: opaqueRSAKey{rsakey}},
+		{Alg: jwa.RSA_OAEP, Public: &rsakey.PublicKey, Private: opaqueRSAKey{rsakey}},
+		{Alg: jwa.RSA_OAEP_256, Public: &rsakey.PublicKey, Private: opaqueRSAKey{rsakey}},
+		{Alg: jwa.RSA_OAEP_384, Public: &rsakey.PublicKey, Private: opaqueRSAKey{rsakey}},
+		{Alg: jwa.RSA_OAEP_512, Public: &rsakey.PublicKey, Private: opaqueRSAKey{rsakey}},
+		{Alg: jwa.ECDH_ES, Public: &eckey.PublicKey, Private: opaqueECDHKey{eckey}},
+		{Alg: jwa.ECDH_ES_A128KW, Public: &eckey.PublicKey, Private: opaqueECDHKey{eckey}},
+		{Alg: jwa.ECDH_ES_A256KW, Public: xkey.Public(), Private: opaqueECDHKey{xkey}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(fmt.Sprintf("%s/%T", tc.Alg, tc.Public), func(t *testing.T) {
+			encrypted, err := jwe.Encrypt(payload, jwe.WithKey(tc.Alg, tc.Public))
+			require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+			decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(tc.Alg, tc.Private))
+			require.NoError(t, err, `jwe.Decrypt should succeed`)
+			require.Equal(t, payload, decrypted, `payloads should match`)
+		})
+	}
+
+	t.Run("Key on a different curve", func(t *testing.T) {
+		other, err := jwxtest.GenerateEcdsaKey(jwa.P384)
+		require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+
+		encrypted, err := jwe.Encrypt(payload, jwe.WithKey(jwa.ECDH_ES, &eckey.PublicKey))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		_, err = jwe.Decrypt(encrypted, jwe.WithKey(jwa.ECDH_ES, opaqueECDHKey{other}))
+		require.Error(t, err, `jwe.Decrypt should fail`)
+	})
+}
+
+func TestECDH1PU(t *testing.T) {
+	payload := []byte("Lorem Ipsum")
+
+	type keypair struct {
+		private interface{}
+		public  interface{}
+	}
+	generate := func(t *testing.T, curve interface{}) keypair {
+		t.Helper()
+		switch curve {
+		case jwa.X25519:
+			key, err := jwxtest.GenerateX25519Key()
+			require.NoError(t, err, `jwxtest.GenerateX25519Key should succeed`)
+			return keypair{private: key, public: key.Public()}
+		default:
+			//nolint:forcetypeassert
+			key, err := jwxtest.GenerateEcdsaKey(curve.(jwa.EllipticCurveAlgorithm))
+			require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+			return keypair{private: key, public: &key.PublicKey}
+		}
+	}
+
+	curves := []jwa.EllipticCurveAlgorithm{jwa.P256, jwa.P384, jwa.P521, jwa.X25519}
+	algs := []jwa.KeyEncryptionAlgorithm{jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW}
+	for _, curve := range curves {
+		curve := curve
+		t.Run(curve.String(), func(t *testing.T) {
+			sender := generate(t, curve)
+			recipient := generate(t, curve)
+			for _, alg := range algs {
+				alg := alg
+				t.Run(alg.String(), func(t *testing.T) {
+					calg := jwa.A256CBC_HS512
+					if alg == jwa.ECDH_1PU {
+						calg = jwa.A256GCM
+					}
+
+					encrypted, err := jwe.Encrypt(payload, jwe.WithKey(alg, recipient.public, jwe.WithSenderKey(sender.private)), jwe.WithContentEncryption(calg))
+					require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+					decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(alg, recipient.private, jwe.WithSenderKey(sender.public)))
+					require.NoError(t, err, `jwe.Decrypt should succeed`)
+					require.Equal(t, payload, decrypted, `payloads should match`)
+
+					// The sender is authenticated
+					other := generate(t, curve)
+					_, err = jwe.Decrypt(encrypted, jwe.WithKey(alg, recipient.private, jwe.WithSenderKey(other.public)))
+					require.Error(t, err, `jwe.Decrypt should fail with the wrong sender key`)
+
+					_, err = jwe.Decrypt(encrypted, jwe.WithKey(alg, recipient.private))
+					require.Error(t, err, `jwe.Decrypt should fail without the sender key`)
+				})
+			}
+		})
+	}
+
+	t.Run("Multiple recipients", func(t *testing.T) {
+		sender := generate(t, jwa.X25519)
+		recipients := []keypair{generate(t, jwa.X25519), generate(t, jwa.X25519)}
+
+		senderJWK, err := jwk.FromRaw(sender.private)
+		require.NoError(t, err, `jwk.FromRaw should succeed`)
+		senderPubJWK, err := senderJWK.PublicKey()
+		require.NoError(t, err, `PublicKey should succeed`)
+
+		encrypted, err := jwe.Encrypt(payload,
+			jwe.WithJSON(),
+			jwe.WithContentEncryption(jwa.A256CBC_HS512),
+			jwe.WithKey(jwa.ECDH_1PU_A256KW, recipients[0].public, jwe.WithSenderKey(senderJWK)),
+			jwe.WithKey(jwa.ECDH_1PU_A256KW, recipients[1].public, jwe.WithSenderKey(senderJWK)),
+		)
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		msg, err := jwe.Parse(encrypted)
+		require.NoError(t, err, `jwe.Parse should succeed`)
+		_, ok := msg.ProtectedHeaders().Get(jwe.EphemeralPublicKeyKey)
+		require.True(t, ok, `"epk" should be in the protected header`)
+		for _, r := range msg.Recipients() {
+			_, ok := r.Headers().Get(jwe.EphemeralPublicKeyKey)
+			require.False(t, ok, `"epk" should not be in the recipient header`)
+		}
+
+		for i, recipient := range recipients {
+			decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.private, jwe.WithSenderKey(senderPubJWK)))
+			require.NoError(t, err, `jwe.Decrypt should succeed for recipient #%d`, i)
+			require.Equal(t, payload, decrypted, `payloads should match`)
+		}
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		sender := generate(t, jwa.P256)
+		recipient := generate(t, jwa.P256)
+
+		var buf bytes.Buffer
+		err := jwe.EncryptStream(&buf, bytes.NewReader(payload), jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.public, jwe.WithSenderKey(sender.private)), jwe.WithContentEncryption(jwa.A256CBC_HS512))
+		require.Error(t, err, `jwe.EncryptStream should fail for key wrapping modes`)
+
+		encrypted, err := jwe.Encrypt(payload, jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.public, jwe.WithSenderKey(sender.private)), jwe.WithContentEncryption(jwa.A256CBC_HS512))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		buf.Reset()
+		err = jwe.DecryptStream(&buf, bytes.NewReader(encrypted), jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.private, jwe.WithSenderKey(sender.public)))
+		require.NoError(t, err, `jwe.DecryptStream should succeed`)
+		require.Equal(t, payload, buf.Bytes(), `payloads should match`)
+
+		buf.Reset()
+		err = jwe.DecryptStream(&buf, bytes.NewReader(encrypted), jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.private, jwe.WithSenderKey(sender.public)), jwe.WithUnverifiedStreaming(true))
+		require.Error(t, err, `jwe.DecryptStream should fail in unverified mode`)
+	})
+
+	t.Run("Invalid parameters", func(t *testing.T) {
+		sender := generate(t, jwa.P256)
+		recipient := generate(t, jwa.P256)
+
+		_, err := jwe.Encrypt(payload, jwe.WithKey(jwa.ECDH_1PU_A256KW, recipient.public, jwe.WithSenderKey(sender.private)), jwe.WithContentEncryption(jwa.A256GCM))
+		require.Error(t, err, `key wrapping modes should require AES-CBC-HMAC-SHA2`)
+
+		_, err = jwe.Encrypt(payload, jwe.WithKey(jwa.ECDH_1PU, recipient.public))
+		require.Error(t, err, `jwe.Encrypt should fail without the sender key`)
+
+		other := generate(t, jwa.P384)
+		_, err = jwe.Encrypt(payload, jwe.WithKey(jwa.ECDH_1PU, recipient.public, jwe.WithSenderKey(other.private)))
+		require.Error(t, err, `jwe.Encrypt should fail when the curves do not match`)
+	})
+}
+
+func TestChaCha20Poly1305(t *testing.T) {
+	payload := []byte("Lorem Ipsum")
+
+	rsakey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+	eckey, err := jwxtest.GenerateEcdsaKey(jwa.P256)
+	require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+	x25519key, err := jwxtest.GenerateX25519Key()
+	require.NoError(t, err, `jwxtest.GenerateX25519Key should succeed`)
+	sharedkey := make([]byte, 32)
+	_, err = rand.Read(sharedkey)
+	require.NoError(t, err, `rand.Read should succeed`)
+
+	testcases := []struct {
+		alg     jwa.KeyEncryptionAlgorithm
+		public  interface{}
+		private interface{}
+	}{
+		{alg: jwa.RSA_OAEP_256, public: &rsakey.PublicKey, private: rsakey},
+		{alg: jwa.A256KW, public: sharedkey, private: sharedkey},
+		{alg: jwa.A256GCMKW, public: sharedkey, private: sharedkey},
+		{alg: jwa.DIRECT, public: sharedkey, private: sharedkey},
+		{alg: jwa.ECDH_ES, public: &eckey.PublicKey, private: eckey},
+		{alg: jwa.ECDH_ES_A256KW, public: &eckey.PublicKey, private: eckey},
+		{alg: jwa.ECDH_ES, public: x25519key.Public(), private: x25519key},
+	}
+
+	for _, calg := range []jwa.ContentEncryptionAlgorithm{jwa.C20P, jwa.XC20P} {
+		calg := calg
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(fmt.Sprintf("%s/%s/%T", calg, tc.alg, tc.private), func(t *testing.T) {
+				encrypted, err := jwe.Encrypt(payload, jwe.WithKey(tc.alg, tc.public), jwe.WithContentEncryption(calg))
+				require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+				msg, err := jwe.Parse(encrypted)
+				require.NoError(t, err, `jwe.Parse should succeed`)
+				require.Equal(t, calg, msg.ProtectedHeaders().ContentEncryption(), `"enc" should match`)
+
+				decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(tc.alg, tc.private))
+				require.NoError(t, err, `jwe.Decrypt should succeed`)
+				require.Equal(t, payload, decrypted, `payloads should match`)
+
+				// Tamper with the ciphertext
+				parts := bytes.Split(encrypted, []byte{'.'})
+				require.Len(t, parts, 5, `compact serialization should have 5 parts`)
+				ct, err := base64.RawURLEncoding.DecodeString(string(parts[3]))
+				require.NoError(t, err, `base64 decode should succeed`)
+				ct[0] ^= 0x01
+				parts[3] = []byte(base64.RawURLEncoding.EncodeToString(ct))
+				_, err = jwe.Decrypt(bytes.Join(parts, []byte{'.'}), jwe.WithKey(tc.alg, tc.private))
+				require.Error(t, err, `jwe.Decrypt should fail for tampered ciphertext`)
+			})
+		}
+	}
+
+	t.Run("Invalid key size", func(t *testing.T) {
+		_, err := jwe.Encrypt(payload, jwe.WithKey(jwa.DIRECT, sharedkey[:16]), jwe.WithContentEncryption(jwa.XC20P))
+		require.Error(t, err, `jwe.Encrypt should fail with a 128 bit key in direct mode`)
+	})
+}
+
+func TestHPKE(t *testing.T) {
+	payload := []byte("Lorem Ipsum")
+
+	type keypair struct {
+		private interface{}
+		public  interface{}
+	}
+	generate := func(t *testing.T, alg jwa.KeyEncryptionAlgorithm) keypair {
+		t.Helper()
+		switch alg {
+		case jwa.HPKE_3, jwa.HPKE_4:
+			key, err := jwxtest.GenerateX25519Key()
+			require.NoError(t, err, `jwxtest.GenerateX25519Key should succeed`)
+			return keypair{private: key, public: key.Public()}
+		case jwa.HPKE_5, jwa.HPKE_6:
+			key, err := jwxtest.GenerateX448Key()
+			require.NoError(t, err, `jwxtest.GenerateX448Key should succeed`)
+			return keypair{private: key, public: key.Public()}
+		}
+
+		crv := jwa.P256
+		switch alg {
+		case jwa.HPKE_1:
+			crv = jwa.P384
+		case jwa.HPKE_2:
+			crv = jwa.P521
+		}
+		key, err := jwxtest.GenerateEcdsaKey(crv)
+		require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+		return keypair{private: key, public: &key.PublicKey}
+	}
+
+	algs := []jwa.KeyEncryptionAlgorithm{jwa.HPKE_0, jwa.HPKE_1, jwa.HPKE_2, jwa.HPKE_3, jwa.HPKE_4, jwa.HPKE_5, jwa.HPKE_6, jwa.HPKE_7}
+	for _, alg := range algs {
+		alg := alg
+		t.Run(alg.String(), func(t *testing.T) {
+			kp := generate(t, alg)
+			t.Run("Key Encryption", func(t *testing.T) {
+				encrypted, err := jwe.Encrypt(payload, jwe.WithKey(alg, kp.public), jwe.WithContentEncryption(jwa.A128GCM))
+				require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+				msg, err := jwe.Parse(encrypted)
+				require.NoError(t, err, `jwe.Parse should succeed`)
+				require.Equal(t, jwa.A128GCM, msg.ProtectedHeaders().ContentEncryption(), `"enc" should be set`)
+				require.NotEmpty(t, msg.ProtectedHeaders().EncapsulatedKey(), `"ek" should be set`)
+
+				decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(alg, kp.private))
+				require.NoError(t, err, `jwe.Decrypt should succeed`)
+				require.Equal(t, payload, decrypted, `payloads should match`)
+
+				pubkey, err := jwk.FromRaw(kp.public)
+				require.NoError(t, err, `jwk.FromRaw should succeed`)
+				encrypted, err = jwe.Encrypt(payload, jwe.WithKey(alg, pubkey))
+				require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+				privkey, err := jwk.FromRaw(kp.private)
+				require.NoError(t, err, `jwk.FromRaw should succeed`)
+				decrypted, err = jwe.Decrypt(encrypted, jwe.WithKey(alg, privkey))
+				require.NoError(t, err, `jwe.Decrypt should succeed`)
+				require.Equal(t, payload, decrypted, `payloads should match`)
+
+				var encryptedStream bytes.Buffer
+				require.NoError(t, jwe.EncryptStream(&encryptedStream, bytes.NewReader(payload), jwe.WithKey(alg, pubkey)), `jwe.EncryptStream should succeed`)
+
+				var decryptedStream bytes.Buffer
+				require.NoError(t, jwe.DecryptStream(&decryptedStream, bytes.NewReader(encryptedStream.Bytes()), jwe.WithKey(alg, kp.private)), `jwe.DecryptStream should succeed`)
+				require.Equal(t, payload, decryptedStream.Bytes(), `payloads should match`)
+
+				other := generate(t, alg)
+				_, err = jwe.Decrypt(encrypted, jwe.WithKey(alg, other.private))
+				require.Error(t, err, `jwe.Decrypt should fail with the wrong key`)
+			})
+			t.Run("Integrated Encryption", func(t *testing.T) {
+				for _, compress := range []jwa.CompressionAlgorithm{jwa.NoCompress, jwa.Deflate} {
+					encrypted, err := jwe.Encrypt(payload, jwe.WithKey(alg, kp.public), jwe.WithIntegratedEncryption(true), jwe.WithCompress(compress))
+					require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+					msg, err := jwe.Parse(encrypted)
+					require.NoError(t, err, `jwe.Parse should succeed`)
+					_, ok := msg.ProtectedHeaders().Get(jwe.ContentEncryptionKey)
+					require.False(t, ok, `"enc" should not be set`)
+					require.Empty(t, msg.InitializationVector(), `iv should be empty`)
+					require.Empty(t, msg.Tag(), `tag should be empty`)
+					require.NotEmpty(t, msg.Recipients()[0].EncryptedKey(), `encrypted key should be the encapsulated key`)
+
+					decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(alg, kp.private))
+					require.NoError(t, err, `jwe.Decrypt should succeed`)
+					require.Equal(t, payload, decrypted, `payloads should match`)
+
+					// The protected header is authenticated
+					parts := bytes.Split(encrypted, []byte{'.'})
+					hdr, err := base64.RawURLEncoding.DecodeString(string(parts[0]))
+					require.NoError(t, err, `base64 decode should succeed`)
+					hdr = bytes.Replace(hdr, []byte(`"alg"`), []byte(`"foo":1,"alg"`), 1)
+					parts[0] = []byte(base64.RawURLEncoding.EncodeToString(hdr))
+					_, err = jwe.Decrypt(bytes.Join(parts, []byte{'.'}), jwe.WithKey(alg, kp.private))
+					require.Error(t, err, `jwe.Decrypt should fail for modified header`)
+
+					var decryptedStream bytes.Buffer
+					require.Error(t, jwe.DecryptStream(&decryptedStream, bytes.NewReader(encrypted), jwe.WithKey(alg, kp.private)), `jwe.DecryptStream should fail`)
+				}
+
+				encrypted, err := jwe.Encrypt(payload, jwe.WithKey(alg, kp.public), jwe.WithIntegratedEncryption(true), jwe.WithJSON())
+				require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+				decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(alg, kp.private))
+				require.NoError(t, err, `jwe.Decrypt should succeed`)
+				require.Equal(t, payload, decrypted, `payloads should match`)
+			})
+		})
+	}
+
+	t.Run("Multiple recipients", func(t *testing.T) {
+		kp1 := generate(t, jwa.HPKE_0)
+		kp2 := generate(t, jwa.HPKE_4)
+		encrypted, err := jwe.Encrypt(payload, jwe.WithJSON(), jwe.WithKey(jwa.HPKE_0, kp1.public), jwe.WithKey(jwa.HPKE_4, kp2.public))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		for _, tc := range []struct {
+			alg jwa.KeyEncryptionAlgorithm
+			key interface{}
+		}{{jwa.HPKE_0, kp1.private}, {jwa.HPKE_4, kp2.private}} {
+			decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(tc.alg, tc.key))
+			require.NoError(t, err, `jwe.Decrypt should succeed`)
+			require.Equal(t, payload, decrypted, `payloads should match`)
+		}
+
+		_, err = jwe.Encrypt(payload, jwe.WithJSON(), jwe.WithKey(jwa.HPKE_0, kp1.public), jwe.WithKey(jwa.HPKE_4, kp2.public), jwe.WithIntegratedEncryption(true))
+		require.Error(t, err, `jwe.Encrypt should fail for integrated encryption with multiple recipients`)
+	})
+	t.Run("Invalid parameters", func(t *testing.T) {
+		p256 := generate(t, jwa.HPKE_0)
+		_, err := jwe.Encrypt(payload, jwe.WithKey(jwa.HPKE_1, p256.public))
+		require.Error(t, err, `jwe.Encrypt should fail for mismatched curves`)
+
+		_, err = jwe.Encrypt(payload, jwe.WithKey(jwa.HPKE_3, p256.public))
+		require.Error(t, err, `jwe.Encrypt should fail for mismatched key types`)
+
+		_, err = jwe.Encrypt(payload, jwe.WithKey(jwa.RSA_OAEP, &rsaPrivKey.PublicKey), jwe.WithIntegratedEncryption(true))
+		require.Error(t, err, `jwe.Encrypt should fail for integrated encryption with non-HPKE algorithms`)
+
+		var buf bytes.Buffer
+		require.Error(t, jwe.EncryptStream(&buf, bytes.NewReader(payload), jwe.WithKey(jwa.HPKE_0, p256.public), jwe.WithIntegratedEncryption(true)), `jwe.EncryptStream should fail for integrated encryption`)
+	})
+}
+
+func TestStrictKeyUsage(t *testing.T) {
+	rawkey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+	key, err := jwk.FromRaw(rawkey)
+	require.NoError(t, err, `jwk.FromRaw should succeed`)
+	require.NoError(t, key.Set(jwk.KeyIDKey, `key-1`), `key.Set should succeed`)
+	pubkey, err := key.PublicKey()
+	require.NoError(t, err, `key.PublicKey should succeed`)
+
+	t.Run("Encrypt", func(t *testing.T) {
+		require.NoError(t, pubkey.Set(jwk.KeyUsageKey, jwk.ForSignature), `pubkey.Set should succeed`)
+		defer func() { _ = pubkey.Remove(jwk.KeyUsageKey) }()
+
+		_, err := jwe.Encrypt([]byte(examplePayload), jwe.WithKey(jwa.RSA_OAEP, pubkey))
+		require.NoError(t, err, `jwe.Encrypt should succeed without strict mode`)
+
+		_, err = jwe.Encrypt([]byte(examplePayload), jwe.WithKey(jwa.RSA_OAEP, pubkey), jwe.WithStrictKeyUsage(true))
+		require.Error(t, err, `jwe.Encrypt should fail in strict mode`)
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `error should be a *jwk.KeyUsageError`)
+		require.Equal(t, `key-1`, usageErr.KeyID, `KeyID should match`)
+		require.Equal(t, jwk.KeyOpWrapKey, usageErr.Operation, `Operation should match`)
+	})
+	t.Run("Decrypt", func(t *testing.T) {
+		encrypted, err := jwe.Encrypt([]byte(examplePayload), jwe.WithKey(jwa.RSA_OAEP, pubkey))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		require.NoError(t, key.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpDecrypt}), `key.Set should succeed`)
+		defer func() { _ = key.Remove(jwk.KeyOpsKey) }()
+
+		_, err = jwe.Decrypt(encrypted, jwe.WithKey(jwa.RSA_OAEP, key))
+		require.NoError(t, err, `jwe.Decrypt should succeed without strict mode`)
+
+		_, err = jwe.Decrypt(encrypted, jwe.WithKey(jwa.RSA_OAEP, key), jwe.WithStrictKeyUsage(true))
+		require.Error(t, err, `jwe.Decrypt should fail in strict mode`)
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `error should be a *jwk.KeyUsageError`)
+		require.Equal(t, jwk.KeyOpUnwrapKey, usageErr.Operation, `Operation should match`)
+
+		require.NoError(t, key.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpUnwrapKey}), `key.Set should succeed`)
+		decrypted, err := jwe.Decrypt(encrypted, jwe.WithKey(jwa.RSA_OAEP, key), jwe.WithStrictKeyUsage(true))
+		require.NoError(t, err, `jwe.Decrypt should succeed with a permitted key`)
+		require.Equal(t, []byte(examplePayload), decrypted, `payload should match`)
+	})
+	t.Run("Settings", func(t *testing.T) {
+		jwe.Settings(jwe.WithStrictKeyUsage(true))
+		defer jwe.Settings(jwe.WithStrictKeyUsage(false))
+
+		require.NoError(t, pubkey.Set(jwk.KeyUsageKey, jwk.ForSignature), `pubkey.Set should succeed`)
+		defer func() { _ = pubkey.Remove(jwk.KeyUsageKey) }()
+
+		_, err := jwe.Encrypt([]byte(examplePayload), jwe.WithKey(jwa.RSA_OAEP, pubkey))
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `jwe.Encrypt should fail when strict mode is enabled globally`)
+
+		_, err = jwe.Encrypt([]byte(examplePayload), jwe.WithKey(jwa.RSA_OAEP, pubkey), jwe.WithStrictKeyUsage(false))
+		require.NoError(t, err, `jwe.WithStrictKeyUsage(false) should override the global setting`)
+	})
+}
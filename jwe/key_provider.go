@@ -76,8 +76,9 @@ type KeySink interface {
 }
 
 type algKeyPair struct {
-	alg jwa.KeyAlgorithm
-	key interface{}
+	alg    jwa.KeyAlgorithm
+	key    interface{}
+	sender interface{}
 }
 
 type algKeySink struct {
@@ -86,17 +87,28 @@ type algKeySink struct {
 }
 
 func (s *algKeySink) Key(alg jwa.KeyEncryptionAlgorithm, key interface{}) {
+	s.keyWithSender(alg, key, nil)
+}
+
+// keyWithSender is used to pass the sender's public key along with
+// the recipient's key (i.e. for ECDH-1PU)
+func (s *algKeySink) keyWithSender(alg jwa.KeyEncryptionAlgorithm, key, sender interface{}) {
 	s.mu.Lock()
-	s.list = append(s.list, algKeyPair{alg, key})
+	s.list = append(s.list, algKeyPair{alg: alg, key: key, sender: sender})
 	s.mu.Unlock()
 }
 
 type staticKeyProvider struct {
-	alg jwa.KeyEncryptionAlgorithm
-	key interface{}
+	alg    jwa.KeyEncryptionAlgorithm
+	key    interface{}
+	sender interface{}
 }
 
 func (kp *staticKeyProvider) FetchKeys(_ context.Context, sink KeySink, _ Recipient, _ *Message) error {
+	if s, ok := sink.(*algKeySink); ok && kp.sender != nil {
+		s.keyWithSender(kp.alg, kp.key, kp.sender)
+		return nil
+	}
 	sink.Key(kp.alg, kp.key)
 	return nil
 }
@@ -106,8 +118,11 @@ type keySetProvider struct {
 	requireKid bool
 }
 
+// encryptionKeys matches the keys that may be used for encryption
+var encryptionKeys = jwk.NewKeyMatcher(jwk.WithMatchKeyUsage(jwk.ForEncryption))
+
 func (kp *keySetProvider) selectKey(sink KeySink, key jwk.Key, _ Recipient, _ *Message) error {
-	if usage := key.KeyUsage(); usage != "" && usage != jwk.ForEncryption.String() {
+	if !encryptionKeys.Match(key) {
 		return nil
 	}
 
@@ -142,8 +157,7 @@ func (kp *keySetProvider) FetchKeys(_ context.Context, sink KeySink, r Recipient
 		return kp.selectKey(sink, key, r, msg)
 	}
 
-	for i := 0; i < kp.set.Len(); i++ {
-		key, _ := kp.set.Key(i)
+	for _, key := range encryptionKeys.Select(kp.set) {
 		if err := kp.selectKey(sink, key, r, msg); err != nil {
 			continue
 		}
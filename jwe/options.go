@@ -20,6 +20,7 @@ func WithProtectedHeaders(h Headers) EncryptOption {
 type withKey struct {
 	alg     jwa.KeyAlgorithm
 	key     interface{}
+	sender  interface{}
 	headers Headers
 }
 
@@ -40,6 +41,18 @@ func WithPerRecipientHeaders(hdr Headers) WithKeySuboption {
 	return &withKeySuboption{option.New(identPerRecipientHeaders{}, hdr)}
 }
 
+// WithSenderKey is used to pass the static key of the sender for the
+// ECDH-1PU family of key encryption algorithms, which authenticate
+// the sender of the message.
+//
+// When used with `jwe.Encrypt()`, `key` must be the sender's private key.
+// When used with `jwe.Decrypt()`, `key` must be the sender's public key.
+// In both cases, it must be on the same curve as the recipient's key.
+// Either a raw key or `jwk.Key` may be passed.
+func WithSenderKey(key interface{}) WithKeySuboption {
+	return &withKeySuboption{option.New(identSenderKey{}, key)}
+}
+
 // WithKey is used to pass a static algorithm/key pair to either `jwe.Encrypt()` or `jwe.Decrypt()`.
 // either a raw key or `jwk.Key` may be passed as `key`.
 //
@@ -51,19 +64,26 @@ func WithPerRecipientHeaders(hdr Headers) WithKeySuboption {
 //
 // Unlike `jwe.WithKeySet()`, the `kid` field does not need to match for the key
 // to be tried.
+//
+// The ECDH-1PU family of algorithms additionally requires the sender's key,
+// which can be passed using the `jwe.WithSenderKey()` suboption.
 func WithKey(alg jwa.KeyAlgorithm, key interface{}, options ...WithKeySuboption) EncryptDecryptOption {
 	var hdr Headers
+	var sender interface{}
 	for _, option := range options {
 		//nolint:forcetypeassert
 		switch option.Ident() {
 		case identPerRecipientHeaders{}:
 			hdr = option.Value().(Headers)
+		case identSenderKey{}:
+			sender = option.Value()
 		}
 	}
 
 	return &encryptDecryptOption{option.New(identKey{}, &withKey{
 		alg:     alg,
 		key:     key,
+		sender:  sender,
 		headers: hdr,
 	})}
 }
@@ -105,3 +125,15 @@ func WithJSON(options ...WithJSONSuboption) EncryptOption {
 	}
 	return &encryptOption{option.New(identSerialization{}, format)}
 }
+
+// WithCriticalHeaders specifies the names of the JWE extension header
+// parameters that the caller understands and processes. When a JWE
+// message lists header parameter names in its "crit" header,
+// `jwe.Decrypt()` will fail unless every one of those names has been
+// declared using this option.
+//
+// This option may be specified multiple times, in which case the names
+// are accumulated.
+func WithCriticalHeaders(names ...string) DecryptOption {
+	return &decryptOption{option.New(identCriticalHeaders{}, names)}
+}
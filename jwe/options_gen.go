@@ -62,6 +62,37 @@ type encryptOption struct {
 
 func (*encryptOption) encryptOption() {}
 
+// GlobalEncryptDecryptOption describes options that can be passed to `jwe.Encrypt`, `jwe.Decrypt`,
+// as well as `jwe.Settings()`
+type GlobalEncryptDecryptOption interface {
+	Option
+	globalOption()
+	encryptOption()
+	decryptOption()
+}
+
+type globalEncryptDecryptOption struct {
+	Option
+}
+
+func (*globalEncryptDecryptOption) globalOption() {}
+
+func (*globalEncryptDecryptOption) encryptOption() {}
+
+func (*globalEncryptDecryptOption) decryptOption() {}
+
+// GlobalOption describes options that can be passed to `jwe.Settings()`
+type GlobalOption interface {
+	Option
+	globalOption()
+}
+
+type globalOption struct {
+	Option
+}
+
+func (*globalOption) globalOption() {}
+
 // ReadFileOption is a type of `Option` that can be passed to `jwe.Parse`
 type ParseOption interface {
 	Option
@@ -112,7 +143,9 @@ func (*withKeySetSuboption) withKeySetSuboption() {}
 
 type identCompress struct{}
 type identContentEncryptionAlgorithm struct{}
+type identCriticalHeaders struct{}
 type identFS struct{}
+type identIntegratedEncryption struct{}
 type identKey struct{}
 type identKeyProvider struct{}
 type identKeyUsed struct{}
@@ -122,7 +155,10 @@ type identPerRecipientHeaders struct{}
 type identPretty struct{}
 type identProtectedHeaders struct{}
 type identRequireKid struct{}
+type identSenderKey struct{}
 type identSerialization struct{}
+type identStrictKeyUsage struct{}
+type identUnverifiedStreaming struct{}
 
 func (identCompress) String() string {
 	return "WithCompress"
@@ -132,10 +168,18 @@ func (identContentEncryptionAlgorithm) String() string {
 	return "WithContentEncryption"
 }
 
+func (identCriticalHeaders) String() string {
+	return "WithCriticalHeaders"
+}
+
 func (identFS) String() string {
 	return "WithFS"
 }
 
+func (identIntegratedEncryption) String() string {
+	return "WithIntegratedEncryption"
+}
+
 func (identKey) String() string {
 	return "WithKey"
 }
@@ -172,10 +216,22 @@ func (identRequireKid) String() string {
 	return "WithRequireKid"
 }
 
+func (identSenderKey) String() string {
+	return "WithSenderKey"
+}
+
 func (identSerialization) String() string {
 	return "WithSerialization"
 }
 
+func (identStrictKeyUsage) String() string {
+	return "WithStrictKeyUsage"
+}
+
+func (identUnverifiedStreaming) String() string {
+	return "WithUnverifiedStreaming"
+}
+
 // WithCompress specifies the compression algorithm to use when encrypting
 // a payload using `jwe.Encrypt` (Yes, we know it can only be "" or "DEF",
 // but the way the specification is written it could allow for more options,
@@ -195,6 +251,19 @@ func WithFS(v fs.FS) ReadFileOption {
 	return &readFileOption{option.New(identFS{}, v)}
 }
 
+// WithIntegratedEncryption specifies that the payload should be encrypted
+// directly using HPKE ("HPKE Integrated Encryption"), instead of encrypting
+// it with a content encryption key that is in turn encrypted for each
+// recipient. This requires exactly one recipient specified using
+// `jwe.WithKey()` with one of the HPKE algorithms (`jwa.HPKE_0`, etc).
+//
+// In this mode the "enc" header is not present, the HPKE encapsulated
+// key is stored as the JWE Encrypted Key, and the JWE Initialization
+// Vector and Authentication Tag are empty.
+func WithIntegratedEncryption(v bool) EncryptOption {
+	return &encryptOption{option.New(identIntegratedEncryption{}, v)}
+}
+
 func WithKeyProvider(v KeyProvider) DecryptOption {
 	return &decryptOption{option.New(identKeyProvider{}, v)}
 }
@@ -245,6 +314,40 @@ func WithRequireKid(v bool) WithKeySetSuboption {
 	return &withKeySetSuboption{option.New(identRequireKid{}, v)}
 }
 
+// WithStrictKeyUsage specifies whether the "use" and "key_ops" fields of
+// `jwk.Key` objects should be enforced. When enabled, `jwe.Encrypt()` fails
+// if a key does not permit the operation it is used for, and `jwe.Decrypt()`
+// skips keys that do not permit it. In both cases the error returned can
+// be inspected using `errors.As()` with a `*jwk.KeyUsageError`.
+// Fields that are not specified in the key do not restrict its usage.
+//
+// The operation is "encrypt" and "decrypt" for the `dir` algorithm,
+// "deriveKey" for the ECDH-ES, ECDH-1PU and HPKE families of algorithms,
+// and "wrapKey" and "unwrapKey" for all other algorithms. Keys that are
+// not `jwk.Key` objects (e.g. `*rsa.PrivateKey`) are not affected.
+//
+// When passed to `jwe.Settings()`, it changes the default value, which
+// is false.
+func WithStrictKeyUsage(v bool) GlobalEncryptDecryptOption {
+	return &globalEncryptDecryptOption{option.New(identStrictKeyUsage{}, v)}
+}
+
+// WithUnverifiedStreaming specifies that `jwe.DecryptStream()` should
+// write the decrypted content to its destination as it is being
+// decrypted, before the authentication tag has been verified.
+//
+// By default `jwe.DecryptStream()` refuses to release any plaintext until
+// the authentication tag has been verified, which requires reading the
+// source twice. When this option is enabled, the source is only read once,
+// and it does not need to be seekable. However, if `jwe.DecryptStream()`
+// returns an error, the content that has already been written to the
+// destination MUST be discarded, as it could have been tampered with.
+//
+// This option has no effect on `jwe.Decrypt()`.
+func WithUnverifiedStreaming(v bool) DecryptOption {
+	return &decryptOption{option.New(identUnverifiedStreaming{}, v)}
+}
+
 // WithCompact specifies that the result of `jwe.Encrypt()` is serialized in
 // compact format.
 //
@@ -11,7 +11,9 @@ import (
 func TestOptionIdent(t *testing.T) {
 	require.Equal(t, "WithCompress", identCompress{}.String())
 	require.Equal(t, "WithContentEncryption", identContentEncryptionAlgorithm{}.String())
+	require.Equal(t, "WithCriticalHeaders", identCriticalHeaders{}.String())
 	require.Equal(t, "WithFS", identFS{}.String())
+	require.Equal(t, "WithIntegratedEncryption", identIntegratedEncryption{}.String())
 	require.Equal(t, "WithKey", identKey{}.String())
 	require.Equal(t, "WithKeyProvider", identKeyProvider{}.String())
 	require.Equal(t, "WithKeyUsed", identKeyUsed{}.String())
@@ -21,5 +23,8 @@ func TestOptionIdent(t *testing.T) {
 	require.Equal(t, "WithPretty", identPretty{}.String())
 	require.Equal(t, "WithProtectedHeaders", identProtectedHeaders{}.String())
 	require.Equal(t, "WithRequireKid", identRequireKid{}.String())
+	require.Equal(t, "WithSenderKey", identSenderKey{}.String())
 	require.Equal(t, "WithSerialization", identSerialization{}.String())
+	require.Equal(t, "WithStrictKeyUsage", identStrictKeyUsage{}.String())
+	require.Equal(t, "WithUnverifiedStreaming", identUnverifiedStreaming{}.String())
 }
@@ -0,0 +1,768 @@
+package jwe
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+
+	"github.com/lestrrat-go/blackmagic"
+	"github.com/lestrrat-go/jwx/v2/internal/base64"
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwe/internal/cipher"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// maxStreamFieldSize is the maximum size of any single element of a JWE
+// message other than the ciphertext that `jwe.DecryptStream()` is willing
+// to buffer in memory.
+const maxStreamFieldSize = 1024 * 1024
+
+// EncryptStream is the streaming version of `jwe.Encrypt()`. The content
+// to be encrypted is read from `src` until io.EOF, and the resulting JWE
+// message is written to `dst` as the content is being encrypted, so
+// that the entire payload never needs to be held in memory.
+//
+// `EncryptStream` accepts the same options as `jwe.Encrypt()`. When
+// JSON serialization is used, the "ciphertext" member is always emitted
+// after every other member except for "tag".
+//
+// If an error is returned, the data that has been written to `dst` so far
+// does not constitute a valid JWE message, and should be discarded.
+func EncryptStream(dst io.Writer, src io.Reader, options ...EncryptOption) error {
+	ectx, err := newEncryptCtx(options)
+	if err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: %w`, err)
+	}
+
+	if len(ectx.keyWraps) > 0 {
+		return fmt.Errorf(`jwe.EncryptStream: ECDH-1PU key wrapping algorithms can not be used, as the encrypted key depends on the authentication tag`)
+	}
+
+	if ectx.integrated != nil {
+		return fmt.Errorf(`jwe.EncryptStream: HPKE integrated encryption can not be used (see jwe.WithIntegratedEncryption())`)
+	}
+
+	// The ciphertext is base64 encoded on its way to dst
+	b64 := base64.NewEncoder(dst)
+	iv, sealer, err := ectx.contentcrypt.NewSealer(b64, ectx.cek, ectx.aad)
+	if err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to create content encrypter: %w`, err)
+	}
+
+	var head []byte
+	var tail func([]byte) []byte
+	switch ectx.format {
+	case fmtCompact:
+		var buf bytes.Buffer
+		buf.Write(ectx.aad)
+		buf.WriteByte('.')
+		buf.Write(base64.Encode(ectx.recipients[0].EncryptedKey()))
+		buf.WriteByte('.')
+		buf.Write(base64.Encode(iv))
+		buf.WriteByte('.')
+		head = buf.Bytes()
+		tail = func(tag []byte) []byte {
+			return append([]byte{'.'}, base64.Encode(tag)...)
+		}
+	case fmtJSON, fmtJSONPretty:
+		msg, err := ectx.newMessage(iv)
+		if err != nil {
+			return fmt.Errorf(`jwe.EncryptStream: %w`, err)
+		}
+
+		// The message does not have a ciphertext or a tag yet, so we
+		// serialize it, and splice the remaining members in by hand
+		if ectx.format == fmtJSON {
+			buf, err := json.Marshal(msg)
+			if err != nil {
+				return fmt.Errorf(`jwe.EncryptStream: failed to marshal message: %w`, err)
+			}
+			head = append(bytes.TrimSuffix(buf, []byte{'}'}), `,"ciphertext":"`...)
+			tail = func(tag []byte) []byte {
+				return []byte(`","tag":"` + base64.EncodeToString(tag) + `"}`)
+			}
+		} else {
+			buf, err := json.MarshalIndent(msg, "", "  ")
+			if err != nil {
+				return fmt.Errorf(`jwe.EncryptStream: failed to marshal message: %w`, err)
+			}
+			head = append(bytes.TrimSuffix(buf, []byte("\n}")), ",\n  \"ciphertext\": \""...)
+			tail = func(tag []byte) []byte {
+				return []byte("\",\n  \"tag\": \"" + base64.EncodeToString(tag) + "\"\n}")
+			}
+		}
+	default:
+		return fmt.Errorf(`jwe.EncryptStream: invalid serialization`)
+	}
+
+	if _, err := dst.Write(head); err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to write message: %w`, err)
+	}
+
+	var w io.Writer = sealer
+	var zw *flate.Writer
+	if ectx.compression != jwa.NoCompress {
+		// same compression level as compress()
+		zw, err = flate.NewWriter(sealer, 1)
+		if err != nil {
+			return fmt.Errorf(`jwe.EncryptStream: failed to create compressor: %w`, err)
+		}
+		w = zw
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to encrypt content: %w`, err)
+	}
+
+	if zw != nil {
+		if err := zw.Close(); err != nil {
+			return fmt.Errorf(`jwe.EncryptStream: failed to compress content: %w`, err)
+		}
+	}
+
+	tag, err := sealer.Finalize()
+	if err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to encrypt content: %w`, err)
+	}
+
+	if err := b64.Close(); err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to write message: %w`, err)
+	}
+
+	if _, err := dst.Write(tail(tag)); err != nil {
+		return fmt.Errorf(`jwe.EncryptStream: failed to write message: %w`, err)
+	}
+	return nil
+}
+
+// DecryptStream is the streaming version of `jwe.Decrypt()`. The JWE
+// message, in either compact or JSON format, is read from `src`, and the
+// decrypted content is written to `dst`.
+//
+// `DecryptStream` accepts the same options as `jwe.Decrypt()`. The
+// message returned via `jwe.WithMessage()` does not contain the ciphertext.
+//
+// By default no content is written to `dst` until the authentication tag
+// has been verified. In order to do this without keeping the content in
+// memory, `src` is read twice, and therefore it must implement io.Seeker.
+// While the tag is verified, a digest of each chunk of the ciphertext is
+// recorded, and each chunk is compared against its digest when it is read
+// again, so that content modified in between is never written to `dst`.
+// If `src` is not seekable, or you need the content to be written
+// as soon as it is decrypted, you may use `jwe.WithUnverifiedStreaming()`.
+// Read the documentation for that option carefully before using it.
+//
+// In unverified mode, JSON serialized messages must have all members
+// other than "tag" appear before "ciphertext", as is the case for messages
+// produced by `jwe.EncryptStream()`.
+func DecryptStream(dst io.Writer, src io.Reader, options ...DecryptOption) error {
+	dctx, err := newDecryptCtx(options)
+	if err != nil {
+		return fmt.Errorf(`jwe.DecryptStream: %w`, err)
+	}
+
+	if dctx.unverified {
+		err = dctx.decryptStream(dst, src)
+	} else {
+		rs, ok := src.(io.ReadSeeker)
+		if !ok {
+			return fmt.Errorf(`jwe.DecryptStream: source must implement io.Seeker unless jwe.WithUnverifiedStreaming() is specified`)
+		}
+		err = dctx.decryptSeekable(dst, rs)
+	}
+	if err != nil {
+		return fmt.Errorf(`jwe.DecryptStream: %w`, err)
+	}
+	return nil
+}
+
+// decryptSeekable reads the message once to locate the ciphertext and to
+// obtain the tag, verifies the content while recording digests of the
+// ciphertext, and then decrypts the ciphertext that matches the digests to dst
+func (dctx *decryptCtx) decryptSeekable(dst io.Writer, src io.ReadSeeker) error {
+	base, err := src.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf(`failed to determine position of source (use jwe.WithUnverifiedStreaming() if the source is not seekable): %w`, err)
+	}
+
+	r := newStreamReader(src)
+	msg, members, err := r.readHead()
+	if err != nil {
+		return err
+	}
+
+	start := r.n
+	if _, err := io.Copy(io.Discard, r.section()); err != nil {
+		return fmt.Errorf(`failed to read ciphertext: %w`, err)
+	}
+	// the closing delimiter is not part of the ciphertext
+	end := r.n - 1
+
+	if members != nil {
+		msg, err = r.readJSONTail(members, false)
+	} else {
+		err = r.readCompactTail(msg)
+	}
+	if err != nil {
+		return err
+	}
+
+	recipients, err := dctx.setMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	open := func() (io.Reader, error) {
+		if _, err := src.Seek(base+start, io.SeekStart); err != nil {
+			return nil, fmt.Errorf(`failed to seek to ciphertext: %w`, err)
+		}
+		return io.LimitReader(src, end-start), nil
+	}
+	tagFunc := func() ([]byte, error) {
+		return msg.tag, nil
+	}
+
+	var digests [][]byte
+	ctx := context.TODO()
+	ck, err := dctx.findContentKey(ctx, recipients, func(ck *contentKey) error {
+		rdr, err := open()
+		if err != nil {
+			return err
+		}
+		recorder := &digestRecorder{src: rdr}
+		content, err := ck.dec.ContentReader(ck.cek, base64.NewDecoder(recorder), tagFunc)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, content); err != nil {
+			return fmt.Errorf(`failed to verify content: %w`, err)
+		}
+		if err := recorder.finish(); err != nil {
+			return err
+		}
+		digests = recorder.digests
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rdr, err := open()
+	if err != nil {
+		return err
+	}
+	return dctx.copyContent(dst, ck, base64.NewDecoder(&digestVerifier{src: rdr, digests: digests}), tagFunc)
+}
+
+// decryptStream decrypts the message in a single pass, writing the
+// content to dst before the tag has been verified
+func (dctx *decryptCtx) decryptStream(dst io.Writer, src io.Reader) error {
+	r := newStreamReader(src)
+	msg, members, err := r.readHead()
+	if err != nil {
+		return err
+	}
+
+	if members != nil {
+		msg, err = parseMembers(members)
+		if err != nil {
+			return fmt.Errorf(`failed to parse members preceding "ciphertext" (all members other than "tag" must appear before "ciphertext" in unverified mode): %w`, err)
+		}
+	}
+
+	recipients, err := dctx.setMessage(msg)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.TODO()
+	ck, err := dctx.findContentKey(ctx, recipients, nil)
+	if err != nil {
+		return err
+	}
+
+	tagFunc := func() ([]byte, error) {
+		if members != nil {
+			tail, err := r.readJSONTail(members, true)
+			if err != nil {
+				return nil, err
+			}
+			return tail.tag, nil
+		}
+		if err := r.readCompactTail(msg); err != nil {
+			return nil, err
+		}
+		return msg.tag, nil
+	}
+	return dctx.copyContent(dst, ck, base64.NewDecoder(r.section()), tagFunc)
+}
+
+// contentKey holds a content encryption key that was successfully
+// derived from one of the recipients
+type contentKey struct {
+	dec     *decrypter
+	cek     []byte
+	headers Headers
+	key     interface{}
+}
+
+// findContentKey looks for a key that can decrypt the content encryption
+// key for one of the recipients. If `verify` is non-nil, it is called for
+// each candidate, and the candidate is only accepted if it returns nil.
+func (dctx *decryptCtx) findContentKey(ctx context.Context, recipients []Recipient, verify func(*contentKey) error) (*contentKey, error) {
+	if dctx.isIntegrated() {
+		return nil, fmt.Errorf(`messages encrypted using HPKE integrated encryption can not be streamed (use jwe.Decrypt())`)
+	}
+
+	var tried int
+	var lastError error
+	for _, recipient := range recipients {
+	providers:
+		for i, kp := range dctx.keyProviders {
+			var sink algKeySink
+			if err := kp.FetchKeys(ctx, &sink, recipient, dctx.msg); err != nil {
+				lastError = fmt.Errorf(`key provider %d failed: %w`, i, err)
+				break providers
+			}
+
+			for _, pair := range sink.list {
+				tried++
+				//nolint:forcetypeassert
+				alg := pair.alg.(jwa.KeyEncryptionAlgorithm)
+				if dctx.strictKeyUsage {
+					if err := jwk.CheckKeyUsage(pair.key, keyOperationFor(alg, false)); err != nil {
+						lastError = err
+						continue
+					}
+				}
+
+				dec, h2, err := dctx.newDecrypter(ctx, alg, pair.key, pair.sender, recipient)
+				if err != nil {
+					lastError = err
+					continue
+				}
+
+				cek, err := dec.DecryptKey(recipient.EncryptedKey())
+				if err != nil {
+					lastError = fmt.Errorf(`failed to decrypt key: %w`, err)
+					continue
+				}
+
+				ck := &contentKey{dec: dec, cek: cek, headers: h2, key: pair.key}
+				if verify != nil {
+					if err := verify(ck); err != nil {
+						lastError = err
+						continue
+					}
+				}
+				return ck, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf(`tried %d keys, but failed to decrypt the content (last error = %w)`, tried, lastError)
+}
+
+// copyContent decrypts (and uncompresses, if applicable) the ciphertext
+// read from src, and writes the result to dst
+func (dctx *decryptCtx) copyContent(dst io.Writer, ck *contentKey, src io.Reader, tagFunc cipher.TagFunc) error {
+	content, err := ck.dec.ContentReader(ck.cek, src, tagFunc)
+	if err != nil {
+		return err
+	}
+
+	rdr := content
+	if ck.headers.Compression() == jwa.Deflate {
+		rdr = flate.NewReader(content)
+	}
+
+	if _, err := io.Copy(dst, rdr); err != nil {
+		return fmt.Errorf(`failed to decrypt content: %w`, err)
+	}
+
+	// The decompressor may stop reading before the end of the
+	// ciphertext, in which case the tag has not been verified yet
+	if _, err := io.Copy(io.Discard, content); err != nil {
+		return fmt.Errorf(`failed to decrypt content: %w`, err)
+	}
+
+	if dctx.keyUsed != nil {
+		if err := blackmagic.AssignIfCompatible(dctx.keyUsed, ck.key); err != nil {
+			return fmt.Errorf(`failed to assign used key (%T) to %T: %w`, ck.key, dctx.keyUsed, err)
+		}
+	}
+	dctx.storeMessage()
+	return nil
+}
+
+// digestChunkSize is the size of the chunks of ciphertext whose digests
+// are compared between the two passes over a seekable source
+const digestChunkSize = 64 * 1024
+
+// digestRecorder records the SHA-256 digest of each chunk read from src
+type digestRecorder struct {
+	src     io.Reader
+	buf     []byte
+	digests [][]byte
+}
+
+func (r *digestRecorder) Read(p []byte) (int, error) {
+	n, err := r.src.Read(p)
+	for data := p[:n]; len(data) > 0; {
+		l := digestChunkSize - len(r.buf)
+		if l > len(data) {
+			l = len(data)
+		}
+		r.buf = append(r.buf, data[:l]...)
+		data = data[l:]
+		if len(r.buf) == digestChunkSize {
+			r.flush()
+		}
+	}
+	return n, err
+}
+
+func (r *digestRecorder) flush() {
+	sum := sha256.Sum256(r.buf)
+	r.digests = append(r.digests, sum[:])
+	r.buf = r.buf[:0]
+}
+
+// finish reads whatever the consumer left unread, and records the
+// digest of the last chunk
+func (r *digestRecorder) finish() error {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return fmt.Errorf(`failed to read ciphertext: %w`, err)
+	}
+	if len(r.buf) > 0 {
+		r.flush()
+	}
+	return nil
+}
+
+// digestVerifier reads src one chunk at a time, and only returns a chunk
+// after its digest has been compared to the one recorded by digestRecorder
+type digestVerifier struct {
+	src     io.Reader
+	digests [][]byte
+	chunk   []byte
+	rest    []byte
+}
+
+func (r *digestVerifier) Read(p []byte) (int, error) {
+	if len(r.rest) == 0 {
+		if r.chunk == nil {
+			r.chunk = make([]byte, digestChunkSize)
+		}
+		n, err := io.ReadFull(r.src, r.chunk)
+		switch {
+		case err == io.EOF:
+			if len(r.digests) > 0 {
+				return 0, fmt.Errorf(`ciphertext was modified after verification`)
+			}
+			return 0, io.EOF
+		case err != nil && err != io.ErrUnexpectedEOF:
+			return 0, err
+		}
+
+		sum := sha256.Sum256(r.chunk[:n])
+		if len(r.digests) == 0 || !bytes.Equal(sum[:], r.digests[0]) {
+			return 0, fmt.Errorf(`ciphertext was modified after verification`)
+		}
+		r.digests = r.digests[1:]
+		r.rest = r.chunk[:n]
+	}
+
+	n := copy(p, r.rest)
+	r.rest = r.rest[n:]
+	return n, nil
+}
+
+// streamReader reads a serialized JWE message, keeping track of the
+// number of bytes consumed. Apart from the ciphertext, no element of the
+// message may exceed maxStreamFieldSize. JSON members are parsed using
+// the JSON decoder, and only the ciphertext is handled separately.
+type streamReader struct {
+	rdr *bufio.Reader
+	n   int64
+}
+
+func newStreamReader(src io.Reader) *streamReader {
+	return &streamReader{rdr: bufio.NewReader(src)}
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	n, err := r.rdr.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// next returns the next non-whitespace byte
+func (r *streamReader) next() (byte, error) {
+	for {
+		c, err := r.rdr.ReadByte()
+		if err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return 0, err
+		}
+		r.n++
+		switch c {
+		case ' ', '\t', '\r', '\n':
+		default:
+			return c, nil
+		}
+	}
+}
+
+func (r *streamReader) expect(want byte) error {
+	c, err := r.next()
+	if err != nil {
+		return err
+	}
+	if c != want {
+		return fmt.Errorf(`expected %q, got %q at offset %d`, want, c, r.n-1)
+	}
+	return nil
+}
+
+// readUntil reads up to and including `delim`
+func (r *streamReader) readUntil(delim byte) ([]byte, error) {
+	var buf []byte
+	for {
+		chunk, err := r.rdr.ReadSlice(delim)
+		r.n += int64(len(chunk))
+		buf = append(buf, chunk...)
+		if len(buf) > maxStreamFieldSize {
+			return nil, fmt.Errorf(`field exceeds maximum size of %d bytes`, maxStreamFieldSize)
+		}
+		switch err {
+		case nil:
+			return buf, nil
+		case bufio.ErrBufferFull:
+		case io.EOF:
+			return nil, io.ErrUnexpectedEOF
+		default:
+			return nil, err
+		}
+	}
+}
+
+// readHead reads the message up to the beginning of the ciphertext. For
+// compact messages, a message containing everything but the ciphertext
+// and the tag is returned. For JSON messages, the members that appear
+// before "ciphertext" are returned instead, as they may not be enough to
+// construct a message.
+func (r *streamReader) readHead() (*Message, map[string]json.RawMessage, error) {
+	c, err := r.next()
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to read message: %w`, err)
+	}
+	if err := r.rdr.UnreadByte(); err != nil {
+		return nil, nil, err
+	}
+	r.n--
+
+	if c != '{' {
+		var head []byte
+		for i := 0; i < 3; i++ {
+			part, err := r.readUntil('.')
+			if err != nil {
+				return nil, nil, fmt.Errorf(`failed to read compact JWE message: %w`, err)
+			}
+			head = append(head, part...)
+		}
+		// ciphertext and tag are filled in later
+		msg, err := parseCompact(append(head, '.'), true)
+		if err != nil {
+			return nil, nil, err
+		}
+		return msg, nil, nil
+	}
+
+	// The members preceding "ciphertext" are read using the JSON decoder.
+	// As it reads ahead, the remaining data is pieced together from
+	// what it has buffered and the rest of the source
+	dec := json.NewDecoder(io.LimitReader(r.rdr, maxStreamFieldSize))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, fmt.Errorf(`failed to read JSON JWE message: %w`, err)
+	}
+
+	members := make(map[string]json.RawMessage)
+	for {
+		if !dec.More() {
+			return nil, nil, fmt.Errorf(`"ciphertext" not found`)
+		}
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf(`failed to read JSON JWE message: %w`, err)
+		}
+		name, ok := tok.(string)
+		if !ok {
+			return nil, nil, fmt.Errorf(`invalid JSON JWE message: expected member name, got %v`, tok)
+		}
+		if name == CipherTextKey {
+			break
+		}
+		if _, ok := members[name]; ok {
+			return nil, nil, fmt.Errorf(`duplicate member %q`, name)
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, nil, fmt.Errorf(`failed to read %q: %w`, name, err)
+		}
+		members[name] = raw
+	}
+
+	buffered, err := io.ReadAll(dec.Buffered())
+	if err != nil {
+		return nil, nil, err
+	}
+	r.n += int64(dec.InputOffset())
+	rest := bufio.NewReader(io.MultiReader(bytes.NewReader(buffered), r.rdr))
+	r.rdr = rest
+
+	if err := r.expect(':'); err != nil {
+		return nil, nil, fmt.Errorf(`failed to read "ciphertext": %w`, err)
+	}
+	if err := r.expect('"'); err != nil {
+		return nil, nil, fmt.Errorf(`failed to read "ciphertext": %w`, err)
+	}
+	return nil, members, nil
+}
+
+// section returns an io.Reader that reads the ciphertext, up to the
+// closing '"' of a JSON string, or the '.' preceding the tag of a
+// compact message. The delimiter is consumed, but not returned.
+// Note that the base64 decoder rejects either delimiter, as well as
+// JSON escape sequences, so the ciphertext can not contain them.
+func (r *streamReader) section() io.Reader {
+	return &sectionReader{r: r}
+}
+
+type sectionReader struct {
+	r    *streamReader
+	done bool
+}
+
+func (s *sectionReader) Read(p []byte) (int, error) {
+	if s.done {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	// Read whatever is currently buffered, up to the delimiter
+	rdr := s.r.rdr
+	if _, err := rdr.Peek(1); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+	buf, _ := rdr.Peek(rdr.Buffered())
+	if len(buf) > len(p) {
+		buf = buf[:len(p)]
+	}
+
+	n := len(buf)
+	if i := bytes.IndexAny(buf, `".`); i >= 0 {
+		n = i
+		s.done = true
+	}
+	copy(p, buf[:n])
+	consumed := n
+	if s.done {
+		consumed++
+	}
+	if _, err := rdr.Discard(consumed); err != nil {
+		return 0, err
+	}
+	s.r.n += int64(consumed)
+	return n, nil
+}
+
+// readRest reads everything up to io.EOF
+func (r *streamReader) readRest() ([]byte, error) {
+	buf, err := io.ReadAll(io.LimitReader(r, maxStreamFieldSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) > maxStreamFieldSize {
+		return nil, fmt.Errorf(`message exceeds maximum size of %d bytes after the ciphertext`, maxStreamFieldSize)
+	}
+	return buf, nil
+}
+
+// readCompactTail reads the tag of a compact message, which should be
+// everything after the ciphertext, and stores it in `msg`
+func (r *streamReader) readCompactTail(msg *Message) error {
+	buf, err := r.readRest()
+	if err != nil {
+		return fmt.Errorf(`failed to read tag: %w`, err)
+	}
+
+	tag, err := base64.Decode(bytes.TrimSpace(buf))
+	if err != nil {
+		return fmt.Errorf(`failed to base64 decode tag: %w`, err)
+	}
+	if err := msg.Set(TagKey, tag); err != nil {
+		return fmt.Errorf(`failed to set %s: %w`, TagKey, err)
+	}
+	return nil
+}
+
+// readJSONTail reads the members of a JSON message following the
+// ciphertext, and parses the message from these and the members
+// preceding the ciphertext. If `tagOnly` is true, "tag" is the only
+// member allowed after the ciphertext.
+func (r *streamReader) readJSONTail(members map[string]json.RawMessage, tagOnly bool) (*Message, error) {
+	buf, err := r.readRest()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read JSON JWE message: %w`, err)
+	}
+
+	// What follows the ciphertext is either the end of the object, or
+	// a comma and more members. Either way, it can be turned into a
+	// complete object by replacing the comma with an opening brace
+	buf = bytes.TrimSpace(buf)
+	switch {
+	case len(buf) > 0 && buf[0] == '}':
+		buf = append([]byte{'{'}, buf...)
+	case len(buf) > 0 && buf[0] == ',':
+		buf[0] = '{'
+	default:
+		return nil, fmt.Errorf(`invalid data following "ciphertext"`)
+	}
+
+	var tail map[string]json.RawMessage
+	if err := json.Unmarshal(buf, &tail); err != nil {
+		return nil, fmt.Errorf(`failed to parse members following "ciphertext": %w`, err)
+	}
+	for name, raw := range tail {
+		if tagOnly && name != TagKey {
+			return nil, fmt.Errorf(`unexpected member %q after "ciphertext" (use a seekable source without jwe.WithUnverifiedStreaming())`, name)
+		}
+		if _, ok := members[name]; ok || name == CipherTextKey {
+			return nil, fmt.Errorf(`duplicate member %q`, name)
+		}
+		members[name] = raw
+	}
+	return parseMembers(members)
+}
+
+// parseMembers parses a JSON message from its members. The ciphertext
+// is not included.
+func parseMembers(members map[string]json.RawMessage) (*Message, error) {
+	buf, err := json.Marshal(members)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to encode JSON JWE message: %w`, err)
+	}
+	return parseJSON(buf, true)
+}
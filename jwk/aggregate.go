@@ -0,0 +1,232 @@
+package jwk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lestrrat-go/iter/arrayiter"
+)
+
+// AggregateSet is a read-only `jwk.Set` that combines multiple `jwk.Set`
+// objects, such as `jwk.CachedSet` objects for several JWKS endpoints and
+// statically configured keys, into a single view. This is useful when tokens
+// must be verified against keys from multiple sources, for example while
+// migrating from one identity provider to another.
+//
+// The sets are consulted in the order they were given to `jwk.NewAggregateSet()`,
+// and sets that come first take precedence: `LookupKeyID()` returns the key
+// from the first set that contains the key ID, and non-Key fields are
+// retrieved from the first set that contains them. By default keys whose
+// key ID is also used by a key in a preceding set are hidden from `Key()`,
+// `Keys()`, `Len()` and `Index()` as well (see `jwk.WithDuplicateKeyIDs`).
+// Keys without a key ID are always exposed.
+//
+// Sets are evaluated every time an operation is performed, so changes to
+// the underlying sets (e.g. refreshes of a `jwk.Cache`) are reflected
+// immediately. `jwk.CachedSet` objects whose `jwk.Cache` could not provide
+// a `jwk.Set` are skipped.
+//
+// When `LookupKeyID()` can not find a key ID in any of the sets,
+// `jwk.CachedSet` objects that were created with `jwk.WithRefreshOnUnknownKeyID(true)`
+// are given a chance to refresh themselves. This only happens after all
+// sets have been searched, so that a key that is available in a later set
+// does not trigger unnecessary refreshes.
+//
+// As with `jwk.CachedSet`, all operations that mutate the object are
+// no-ops and return an error.
+type AggregateSet struct {
+	sets       []Set
+	duplicates bool
+}
+
+var _ Set = &AggregateSet{}
+
+// NewAggregateSet creates a `jwk.Set` that combines the keys in `sets`.
+// See the documentation for `jwk.AggregateSet` for details.
+func NewAggregateSet(sets []Set, options ...AggregateSetOption) Set {
+	as := &AggregateSet{
+		sets: make([]Set, len(sets)),
+	}
+	copy(as.sets, sets)
+
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identDuplicateKeyIDs{}:
+			as.duplicates = option.Value().(bool)
+		}
+	}
+	return as
+}
+
+// snapshot returns the current contents of `s`, without triggering
+// any refreshes. It returns nil if the contents are not available
+func snapshot(s Set) Set {
+	switch s := s.(type) {
+	case *CachedSet:
+		set, err := s.cached()
+		if err != nil {
+			return nil
+		}
+		return set
+	case *AggregateSet:
+		return &set{keys: s.keys()}
+	default:
+		return s
+	}
+}
+
+func (as *AggregateSet) keys() []Key {
+	var keys []Key
+	seen := make(map[string]struct{})
+	for _, s := range as.sets {
+		src := snapshot(s)
+		if src == nil {
+			continue
+		}
+
+		// Keys that share a key ID within the same set are not hidden
+		var kids []string
+		for i := 0; i < src.Len(); i++ {
+			key, ok := src.Key(i)
+			if !ok {
+				continue
+			}
+
+			if kid := key.KeyID(); kid != "" && !as.duplicates {
+				if _, ok := seen[kid]; ok {
+					continue
+				}
+				kids = append(kids, kid)
+			}
+			keys = append(keys, key)
+		}
+		for _, kid := range kids {
+			seen[kid] = struct{}{}
+		}
+	}
+	return keys
+}
+
+// AddKey is a no-op for `jwk.AggregateSet`, as the `jwk.Set` should be treated read-only
+func (*AggregateSet) AddKey(_ Key) error {
+	return fmt.Errorf(`(jwk.AggregateSet).AddKey: jwk.AggregateSet is immutable`)
+}
+
+// Clear is a no-op for `jwk.AggregateSet`, as the `jwk.Set` should be treated read-only
+func (*AggregateSet) Clear() error {
+	return fmt.Errorf(`(jwk.AggregateSet).Clear: jwk.AggregateSet is immutable`)
+}
+
+// Set is a no-op for `jwk.AggregateSet`, as the `jwk.Set` should be treated read-only
+func (*AggregateSet) Set(_ string, _ interface{}) error {
+	return fmt.Errorf(`(jwk.AggregateSet).Set: jwk.AggregateSet is immutable`)
+}
+
+// Remove is a no-op for `jwk.AggregateSet`, as the `jwk.Set` should be treated read-only
+func (*AggregateSet) Remove(_ string) error {
+	return fmt.Errorf(`(jwk.AggregateSet).Remove: jwk.AggregateSet is immutable`)
+}
+
+// RemoveKey is a no-op for `jwk.AggregateSet`, as the `jwk.Set` should be treated read-only
+func (*AggregateSet) RemoveKey(_ Key) error {
+	return fmt.Errorf(`(jwk.AggregateSet).RemoveKey: jwk.AggregateSet is immutable`)
+}
+
+// Clone returns a regular `jwk.Set` containing the keys that are
+// currently exposed by the `jwk.AggregateSet`
+func (as *AggregateSet) Clone() (Set, error) {
+	return &set{keys: as.keys()}, nil
+}
+
+// Get returns the value of non-Key field stored in the first set that contains it
+func (as *AggregateSet) Get(name string) (interface{}, bool) {
+	for _, s := range as.sets {
+		src := snapshot(s)
+		if src == nil {
+			continue
+		}
+		if v, ok := src.Get(name); ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// Key returns the Key at the specified index
+func (as *AggregateSet) Key(idx int) (Key, bool) {
+	keys := as.keys()
+	if idx >= 0 && idx < len(keys) {
+		return keys[idx], true
+	}
+	return nil, false
+}
+
+func (as *AggregateSet) Index(key Key) int {
+	for i, k := range as.keys() {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (as *AggregateSet) Keys(ctx context.Context) KeyIterator {
+	keys := as.keys()
+	ch := make(chan *KeyPair, len(keys))
+	go iterate(ctx, keys, ch)
+	return arrayiter.New(ch)
+}
+
+// Iterate iterates over the non-Key fields of all sets. If a field
+// exists in multiple sets, the value from the first set is used
+func (as *AggregateSet) Iterate(ctx context.Context) HeaderIterator {
+	params := make(map[string]interface{})
+	for i := len(as.sets) - 1; i >= 0; i-- {
+		src := snapshot(as.sets[i])
+		if src == nil {
+			continue
+		}
+		for iter := src.Iterate(ctx); iter.Next(ctx); {
+			pair := iter.Pair()
+			//nolint:forcetypeassert
+			params[pair.Key.(string)] = pair.Value
+		}
+	}
+	return (&set{privateParams: params}).Iterate(ctx)
+}
+
+func (as *AggregateSet) Len() int {
+	return len(as.keys())
+}
+
+// LookupKeyID returns the key with the given key ID from the first set
+// that contains it
+func (as *AggregateSet) LookupKeyID(kid string) (Key, bool) {
+	for _, s := range as.sets {
+		src := snapshot(s)
+		if src == nil {
+			continue
+		}
+		if key, ok := src.LookupKeyID(kid); ok {
+			return key, true
+		}
+	}
+
+	// Give sets that can refresh themselves a chance to do so
+	for _, s := range as.sets {
+		switch s := s.(type) {
+		case *CachedSet:
+			if !s.refreshOnUnknown {
+				continue
+			}
+		case *AggregateSet:
+		default:
+			continue
+		}
+		if key, ok := s.LookupKeyID(kid); ok {
+			return key, true
+		}
+	}
+	return nil, false
+}
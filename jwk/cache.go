@@ -5,6 +5,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/lestrrat-go/httprc"
@@ -82,6 +85,41 @@ type Whitelist = httprc.Whitelist
 // as keep the objects mostly fresh.
 type Cache struct {
 	cache *httprc.Cache
+
+	mu      sync.RWMutex
+	issuers map[string]string // issuer -> discovery URL
+
+	cooldown   time.Duration
+	throttleMu sync.Mutex
+	throttles  map[string]*refreshThrottle
+
+	storage CacheStorage
+
+	listeners []CacheListener
+	resources map[string]*observedResource // URL -> resource, guarded by mu
+}
+
+// refreshThrottle keeps track of throttled refreshes for a single URL
+type refreshThrottle struct {
+	lastRefresh time.Time
+	inflight    *refreshCall
+}
+
+// refreshCall represents a refresh in progress, shared by all callers
+type refreshCall struct {
+	done chan struct{}
+	set  Set
+	err  error
+}
+
+// wait waits for the refresh to complete, or for `ctx` to be canceled
+func (call *refreshCall) wait(ctx context.Context) (Set, error) {
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.done:
+	}
+	return call.set, call.err
 }
 
 // PostFetcher is an interface for objects that want to perform
@@ -113,14 +151,21 @@ type jwksTransform struct {
 var defaultTransform = &jwksTransform{}
 
 func (t *jwksTransform) Transform(u string, res *http.Response) (interface{}, error) {
-	buf, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf(`failed to read response body status: %w`, err)
-	}
+	var set Set
+	if body, ok := res.Body.(*loadedBody); ok {
+		// Sets loaded via jwk.Loader do not need to be parsed
+		set = body.set
+	} else {
+		buf, err := io.ReadAll(res.Body)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to read response body status: %w`, err)
+		}
 
-	set, err := Parse(buf, t.parseOptions...)
-	if err != nil {
-		return nil, fmt.Errorf(`failed to parse JWK set at %q: %w`, u, err)
+		v, err := Parse(buf, t.parseOptions...)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse JWK set at %q: %w`, u, err)
+		}
+		set = v
 	}
 
 	if pf := t.postFetch; pf != nil {
@@ -140,6 +185,9 @@ func (t *jwksTransform) Transform(u string, res *http.Response) (interface{}, er
 // details.
 func NewCache(ctx context.Context, options ...CacheOption) *Cache {
 	var hrcopts []httprc.CacheOption
+	cooldown := time.Minute
+	var storage CacheStorage
+	var listeners []CacheListener
 	for _, option := range options {
 		//nolint:forcetypeassert
 		switch option.Ident() {
@@ -147,37 +195,45 @@ func NewCache(ctx context.Context, options ...CacheOption) *Cache {
 			hrcopts = append(hrcopts, httprc.WithRefreshWindow(option.Value().(time.Duration)))
 		case identErrSink{}:
 			hrcopts = append(hrcopts, httprc.WithErrSink(option.Value().(ErrSink)))
+		case identRefreshCooldown{}:
+			// The cooldown can not be disabled, as it protects the
+			// remote server from lookups for unknown key IDs
+			if v := option.Value().(time.Duration); v > 0 {
+				cooldown = v
+			}
+		case identStorage{}:
+			storage = option.Value().(CacheStorage)
+		case identCacheListener{}:
+			listeners = append(listeners, option.Value().(CacheListener))
 		}
 	}
 
 	return &Cache{
-		cache: httprc.NewCache(ctx, hrcopts...),
+		cache:     httprc.NewCache(ctx, hrcopts...),
+		issuers:   make(map[string]string),
+		cooldown:  cooldown,
+		throttles: make(map[string]*refreshThrottle),
+		storage:   storage,
+		listeners: listeners,
+		resources: make(map[string]*observedResource),
 	}
 }
 
-// Register registers a URL to be managed by the cache. URLs must
-// be registered before issuing `Get`
-//
-// This method is almost identical to `(httprc.Cache).Register`, except
-// it accepts some extra options.
-//
-// Use `jwk.WithParser` to configure how the JWKS should be parsed,
-// such as passing it extra options.
-//
-// Please refer to the documentation for `(httprc.Cache).Register` for more
-// details.
-//
-// Register does not check for the validity of the url being registered.
-// If you need to make sure that a url is valid before entering your main
-// loop, call `Refresh` once to make sure the JWKS is available.
-//
-//	_ = cache.Register(url)
-//	if _, err := cache.Refresh(ctx, url); err != nil {
-//	  // url is not a valid JWKS
-//	  panic(err)
-//	}
-func (c *Cache) Register(u string, options ...RegisterOption) error {
-	var hrropts []httprc.RegisterOption
+type registerParams struct {
+	hrropts            []httprc.RegisterOption
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+	transform          *jwksTransform
+	client             HTTPClient
+	whitelist          Whitelist
+	discoveryURL       string
+	loader             Loader
+}
+
+func parseRegisterOptions(options ...RegisterOption) *registerParams {
+	params := registerParams{
+		minRefreshInterval: 15 * time.Minute, // same as httprc
+	}
 	var pf PostFetcher
 	var parseOptions []ParseOption
 
@@ -191,32 +247,146 @@ func (c *Cache) Register(u string, options ...RegisterOption) error {
 		//nolint:forcetypeassert
 		switch option.Ident() {
 		case identHTTPClient{}:
-			hrropts = append(hrropts, httprc.WithHTTPClient(option.Value().(HTTPClient)))
+			params.client = option.Value().(HTTPClient)
+			params.hrropts = append(params.hrropts, httprc.WithHTTPClient(params.client))
 		case identRefreshInterval{}:
-			hrropts = append(hrropts, httprc.WithRefreshInterval(option.Value().(time.Duration)))
+			params.refreshInterval = option.Value().(time.Duration)
+			params.hrropts = append(params.hrropts, httprc.WithRefreshInterval(params.refreshInterval))
 		case identMinRefreshInterval{}:
-			hrropts = append(hrropts, httprc.WithMinRefreshInterval(option.Value().(time.Duration)))
+			params.minRefreshInterval = option.Value().(time.Duration)
+			params.hrropts = append(params.hrropts, httprc.WithMinRefreshInterval(params.minRefreshInterval))
 		case identFetchWhitelist{}:
-			hrropts = append(hrropts, httprc.WithWhitelist(option.Value().(httprc.Whitelist)))
+			params.whitelist = option.Value().(httprc.Whitelist)
+			params.hrropts = append(params.hrropts, httprc.WithWhitelist(params.whitelist))
 		case identPostFetcher{}:
 			pf = option.Value().(PostFetcher)
+		case identDiscoveryURL{}:
+			params.discoveryURL = option.Value().(string)
+		case identLoader{}:
+			params.loader = option.Value().(Loader)
 		}
 	}
 
-	var t *jwksTransform
 	if pf == nil && len(parseOptions) == 0 {
-		t = defaultTransform
+		params.transform = defaultTransform
 	} else {
 		// User-supplied PostFetcher is attached to the transformer
-		t = &jwksTransform{
+		params.transform = &jwksTransform{
 			postFetch:    pf,
 			parseOptions: parseOptions,
 		}
 	}
+	return &params
+}
 
-	// Set the transfomer at the end so that nobody can override it
-	hrropts = append(hrropts, httprc.WithTransformer(t))
-	return c.cache.Register(u, hrropts...)
+// resolve returns the URL that is used as the key in the underlying
+// cache: for issuers registered via RegisterIssuer, this is the URL
+// of the discovery document
+func (c *Cache) resolve(u string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if v, ok := c.issuers[u]; ok {
+		return v
+	}
+	return u
+}
+
+// Register registers a URL to be managed by the cache. URLs must
+// be registered before issuing `Get`
+//
+// This method is almost identical to `(httprc.Cache).Register`, except
+// it accepts some extra options.
+//
+// Use `jwk.WithParser` to configure how the JWKS should be parsed,
+// such as passing it extra options.
+//
+// Please refer to the documentation for `(httprc.Cache).Register` for more
+// details.
+//
+// Register does not check for the validity of the url being registered.
+// If you need to make sure that a url is valid before entering your main
+// loop, call `Refresh` once to make sure the JWKS is available.
+//
+//	_ = cache.Register(url)
+//	if _, err := cache.Refresh(ctx, url); err != nil {
+//	  // url is not a valid JWKS
+//	  panic(err)
+//	}
+//
+// If the cache was created with `jwk.WithStorage`, the entry stored for
+// the url is loaded first, and is used until (and whenever) the remote
+// resource can not be fetched.
+//
+// Besides HTTP(S) URLs, jwk.Set objects can be loaded from local files
+// using URLs with the "file" scheme (e.g. "file:///etc/jwks.json"). The
+// file may contain a JWK, a JWKS, or PEM encoded keys and certificates.
+// If the URL points to a directory, keys from all of the files in it
+// (excluding hidden files and subdirectories) are merged into a single
+// jwk.Set. This allows loading keys from, for example, mounted Kubernetes
+// secrets. Other sources can be used by specifying a `jwk.Loader` via
+// `jwk.WithLoader`.
+func (c *Cache) Register(u string, options ...RegisterOption) error {
+	params := parseRegisterOptions(options...)
+
+	if params.loader == nil && strings.HasPrefix(u, `file:`) {
+		if _, err := filePath(u); err != nil {
+			return err
+		}
+		params.loader = &fileLoader{parseOptions: params.transform.parseOptions}
+	}
+	if params.loader != nil {
+		params.client = &loaderClient{loader: params.loader}
+		params.hrropts = append(params.hrropts, httprc.WithHTTPClient(params.client))
+	}
+
+	if c.storage == nil {
+		return c.register(u, params, params.client, params.transform)
+	}
+
+	entry, err := c.storage.Load(u)
+	if err != nil {
+		return fmt.Errorf(`failed to load stored entry for %q: %w`, u, err)
+	}
+
+	client := params.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res := &storedResource{
+		storage:   c.storage,
+		client:    client,
+		transform: params.transform,
+		entry:     entry,
+	}
+	return c.register(u, params, res, res)
+}
+
+// register registers `u` in the underlying cache, wrapping the client
+// and the transformer so that the health of the resource is tracked
+// and events are sent to the listeners
+func (c *Cache) register(u string, params *registerParams, client HTTPClient, transform Transformer) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res := &observedResource{
+		listeners:          c.listeners,
+		client:             client,
+		transform:          transform,
+		refreshInterval:    params.refreshInterval,
+		minRefreshInterval: params.minRefreshInterval,
+		health:             CacheHealth{URL: u},
+	}
+
+	// Set the client and the transfomer at the end so that nobody can override them
+	hrropts := append(params.hrropts, httprc.WithHTTPClient(res), httprc.WithTransformer(res))
+	if err := c.cache.Register(u, hrropts...); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.resources[u] = res
+	c.mu.Unlock()
+	return nil
 }
 
 // Get returns the stored JWK set (`Set`) from the cache.
@@ -224,7 +394,7 @@ func (c *Cache) Register(u string, options ...RegisterOption) error {
 // Please refer to the documentation for `(httprc.Cache).Get` for more
 // details.
 func (c *Cache) Get(ctx context.Context, u string) (Set, error) {
-	v, err := c.cache.Get(ctx, u)
+	v, err := c.cache.Get(ctx, c.resolve(u))
 	if err != nil {
 		return nil, err
 	}
@@ -242,7 +412,7 @@ func (c *Cache) Get(ctx context.Context, u string) (Set, error) {
 // Please refer to the documentation for `(httprc.Cache).Refresh` for
 // more details
 func (c *Cache) Refresh(ctx context.Context, u string) (Set, error) {
-	v, err := c.cache.Refresh(ctx, u)
+	v, err := c.cache.Refresh(ctx, c.resolve(u))
 	if err != nil {
 		return nil, err
 	}
@@ -254,13 +424,62 @@ func (c *Cache) Refresh(ctx context.Context, u string) (Set, error) {
 	return set, nil
 }
 
+// RefreshThrottled is similar to Refresh(), but it is intended to be called
+// when a key that is expected to be in the Set could not be found, such as
+// after the remote server has rotated its keys.
+//
+// To protect the remote server from excessive requests, the resource is
+// fetched at most once per cooldown period (see `jwk.WithRefreshCooldown`)
+// for each URL: calls made during the cooldown period return the currently
+// cached Set without fetching it. Concurrent calls for the same URL share a
+// single request, and receive the same result.
+func (c *Cache) RefreshThrottled(ctx context.Context, u string) (Set, error) {
+	u = c.resolve(u)
+
+	c.throttleMu.Lock()
+	t, ok := c.throttles[u]
+	if !ok {
+		t = &refreshThrottle{}
+		c.throttles[u] = t
+	}
+
+	if call := t.inflight; call != nil {
+		c.throttleMu.Unlock()
+		return call.wait(ctx)
+	}
+
+	if !t.lastRefresh.IsZero() && time.Since(t.lastRefresh) < c.cooldown {
+		c.throttleMu.Unlock()
+		return c.Get(ctx, u)
+	}
+
+	call := &refreshCall{done: make(chan struct{})}
+	t.inflight = call
+	t.lastRefresh = time.Now()
+	c.throttleMu.Unlock()
+
+	// The refresh is shared by all callers, and the cooldown period has
+	// already started, so it must not be cancelled along with the context
+	// of the caller that happened to start it
+	go func() {
+		call.set, call.err = c.Refresh(context.Background(), u)
+
+		c.throttleMu.Lock()
+		t.inflight = nil
+		c.throttleMu.Unlock()
+		close(call.done)
+	}()
+
+	return call.wait(ctx)
+}
+
 // IsRegistered returns true if the given URL `u` has already been registered
 // in the cache.
 //
 // Please refer to the documentation for `(httprc.Cache).IsRegistered` for more
 // details.
 func (c *Cache) IsRegistered(u string) bool {
-	return c.cache.IsRegistered(u)
+	return c.cache.IsRegistered(c.resolve(u))
 }
 
 // Unregister removes the given URL `u` from the cache.
@@ -268,13 +487,42 @@ func (c *Cache) IsRegistered(u string) bool {
 // Please refer to the documentation for `(httprc.Cache).Unregister` for more
 // details.
 func (c *Cache) Unregister(u string) error {
-	return c.cache.Unregister(u)
+	c.mu.Lock()
+	target, ok := c.issuers[u]
+	if ok {
+		delete(c.issuers, u)
+	} else {
+		target = u
+	}
+	delete(c.resources, target)
+	c.mu.Unlock()
+
+	c.throttleMu.Lock()
+	delete(c.throttles, target)
+	c.throttleMu.Unlock()
+	return c.cache.Unregister(target)
 }
 
 func (c *Cache) Snapshot() *httprc.Snapshot {
 	return c.cache.Snapshot()
 }
 
+// Health returns the health of each registered URL at the given moment
+// (see `jwk.CacheHealth`), ordered by URL.
+func (c *Cache) Health() []CacheHealth {
+	c.mu.RLock()
+	health := make([]CacheHealth, 0, len(c.resources))
+	for _, res := range c.resources {
+		health = append(health, res.snapshot())
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(health, func(i, j int) bool {
+		return health[i].URL < health[j].URL
+	})
+	return health
+}
+
 // CachedSet is a thin shim over jwk.Cache that allows the user to cloack
 // jwk.Cache as if it's a `jwk.Set`. Behind the scenes, the `jwk.Set` is
 // retrieved from the `jwk.Cache` for every operation.
@@ -289,17 +537,32 @@ func (c *Cache) Snapshot() *httprc.Snapshot {
 //
 // Make sure that you read the documentation for `jwk.Cache` as well.
 type CachedSet struct {
-	cache *Cache
-	url   string
+	cache            *Cache
+	url              string
+	refreshOnUnknown bool
 }
 
 var _ Set = &CachedSet{}
 
-func NewCachedSet(cache *Cache, url string) Set {
-	return &CachedSet{
+// NewCachedSet creates a `jwk.Set` backed by the given `jwk.Cache`.
+// `url` must already be registered in the cache.
+//
+// By default looking up an unknown key ID simply fails. Specify
+// `jwk.WithRefreshOnUnknownKeyID(true)` to refresh the cache upon
+// such lookups.
+func NewCachedSet(cache *Cache, url string, options ...CachedSetOption) Set {
+	cs := &CachedSet{
 		cache: cache,
 		url:   url,
 	}
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identRefreshOnUnknownKeyID{}:
+			cs.refreshOnUnknown = option.Value().(bool)
+		}
+	}
+	return cs
 }
 
 func (cs *CachedSet) cached() (Set, error) {
@@ -397,11 +660,23 @@ func (cs *CachedSet) Len() int {
 	return set.Len()
 }
 
+// LookupKeyID returns the key with the given key ID. If
+// `jwk.WithRefreshOnUnknownKeyID(true)` was specified and the key could
+// not be found, the cache is refreshed using `(*jwk.Cache).RefreshThrottled()`
+// before trying again.
 func (cs *CachedSet) LookupKeyID(kid string) (Key, bool) {
 	set, err := cs.cached()
 	if err != nil {
 		return nil, false
 	}
 
+	if key, ok := set.LookupKeyID(kid); ok || !cs.refreshOnUnknown {
+		return key, ok
+	}
+
+	set, err = cs.cache.RefreshThrottled(context.Background(), cs.url)
+	if err != nil {
+		return nil, false
+	}
 	return set.LookupKeyID(kid)
 }
@@ -0,0 +1,114 @@
+package jwk
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+)
+
+// authorizationServerMetadata contains the fields that we care about
+// in OpenID Connect Discovery / RFC 8414 authorization server metadata
+type authorizationServerMetadata struct {
+	Issuer  string `json:"issuer"`
+	JWKSURI string `json:"jwks_uri"`
+}
+
+// httprc.Transformer that transforms the response into a JWKS, by
+// following the `jwks_uri` in the authorization server metadata
+type issuerTransform struct {
+	issuer    string
+	client    HTTPClient
+	whitelist Whitelist
+	jwks      *jwksTransform
+}
+
+func (t *issuerTransform) Transform(u string, res *http.Response) (interface{}, error) {
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(`failed to fetch authorization server metadata at %q: unexpected status code %d`, u, res.StatusCode)
+	}
+
+	buf, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read response body status: %w`, err)
+	}
+
+	var md authorizationServerMetadata
+	if err := json.Unmarshal(buf, &md); err != nil {
+		return nil, fmt.Errorf(`failed to parse authorization server metadata at %q: %w`, u, err)
+	}
+
+	if md.Issuer != t.issuer {
+		return nil, fmt.Errorf(`issuer in authorization server metadata at %q does not match (expected %q, got %q)`, u, t.issuer, md.Issuer)
+	}
+
+	if md.JWKSURI == "" {
+		return nil, fmt.Errorf(`authorization server metadata at %q does not contain "jwks_uri"`, u)
+	}
+
+	if wl := t.whitelist; wl != nil && !wl.IsAllowed(md.JWKSURI) {
+		return nil, fmt.Errorf(`fetching url %q rejected by whitelist`, md.JWKSURI)
+	}
+
+	jwksres, err := t.client.Get(md.JWKSURI)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to fetch %q: %w`, md.JWKSURI, err)
+	}
+	defer jwksres.Body.Close()
+
+	if jwksres.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf(`failed to fetch %q: unexpected status code %d`, md.JWKSURI, jwksres.StatusCode)
+	}
+	return t.jwks.Transform(md.JWKSURI, jwksres)
+}
+
+// RegisterIssuer registers an issuer to be managed by the cache, using
+// OpenID Connect Discovery or RFC 8414 authorization server metadata to
+// locate its JWKS.
+//
+// Instead of the JWKS itself, the cache fetches the metadata document
+// (by default `{issuer}/.well-known/openid-configuration`, see
+// `jwk.WithDiscoveryURL`), verifies that its "issuer" matches `issuer`,
+// and then fetches the JWKS from its "jwks_uri". This is done every time
+// the cache is refreshed, so a change in "jwks_uri" is picked up
+// automatically. The refresh interval is computed from the response
+// for the metadata document.
+//
+// Once registered, the issuer can be passed to `Get`, `Refresh`,
+// `IsRegistered`, and `Unregister` in place of the JWKS URL.
+//
+// The same options as `Register` are accepted. The whitelist specified
+// via `jwk.WithFetchWhitelist` is applied to both the metadata document
+// and "jwks_uri", and `jwk.WithPostFetcher` receives the "jwks_uri" along
+// with the JWKS.
+func (c *Cache) RegisterIssuer(issuer string, options ...RegisterOption) error {
+	params := parseRegisterOptions(options...)
+
+	u := params.discoveryURL
+	if u == "" {
+		u = strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	}
+
+	client := params.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	t := &issuerTransform{
+		issuer:    issuer,
+		client:    client,
+		whitelist: params.whitelist,
+		jwks:      params.transform,
+	}
+
+	if err := c.register(u, params, client, t); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.issuers[issuer] = u
+	c.mu.Unlock()
+	return nil
+}
@@ -11,4 +11,5 @@ import (
 
 func init() {
 	ecutil.RegisterCurve(secp256k1.S256(), jwa.Secp256k1)
+	ecdsaCurves[jwa.ES256K] = jwa.Secp256k1
 }
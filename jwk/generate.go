@@ -0,0 +1,363 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
+)
+
+const (
+	defaultRSAKeySize       = 2048
+	defaultSymmetricKeySize = 256
+)
+
+// keySpec describes the keys that are suitable for an algorithm
+type keySpec struct {
+	types []jwa.KeyType
+	// curves lists the curves that may be used for EC and OKP keys.
+	// The first curve of each key type is used by default
+	curves []jwa.EllipticCurveAlgorithm
+	// size is the minimum size (or the exact size, if exact is true)
+	// in bits for RSA and symmetric keys
+	size  int
+	exact bool
+	usage KeyUsageType
+}
+
+// ecdsaCurves maps the ECDSA signature algorithms to their curves
+var ecdsaCurves = map[jwa.SignatureAlgorithm]jwa.EllipticCurveAlgorithm{
+	jwa.ES256: jwa.P256,
+	jwa.ES384: jwa.P384,
+	jwa.ES512: jwa.P521,
+}
+
+var ecdhCurves = []jwa.EllipticCurveAlgorithm{jwa.P256, jwa.P384, jwa.P521, jwa.X25519, jwa.X448}
+
+func keySpecFor(alg jwa.KeyAlgorithm) (*keySpec, error) {
+	switch alg := alg.(type) {
+	case jwa.SignatureAlgorithm:
+		spec := keySpec{usage: ForSignature}
+		switch alg {
+		case jwa.HS256, jwa.HS384, jwa.HS512:
+			spec.types = []jwa.KeyType{jwa.OctetSeq}
+			spec.size = map[jwa.SignatureAlgorithm]int{jwa.HS256: 256, jwa.HS384: 384, jwa.HS512: 512}[alg]
+		case jwa.RS256, jwa.RS384, jwa.RS512, jwa.PS256, jwa.PS384, jwa.PS512:
+			spec.types = []jwa.KeyType{jwa.RSA}
+			spec.size = defaultRSAKeySize
+		case jwa.EdDSA:
+			spec.types = []jwa.KeyType{jwa.OKP}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.Ed25519, jwa.Ed448}
+		default:
+			crv, ok := ecdsaCurves[alg]
+			if !ok {
+				return nil, fmt.Errorf(`can not generate keys for signature algorithm %q`, alg)
+			}
+			spec.types = []jwa.KeyType{jwa.EC}
+			spec.curves = []jwa.EllipticCurveAlgorithm{crv}
+		}
+		return &spec, nil
+	case jwa.KeyEncryptionAlgorithm:
+		spec := keySpec{usage: ForEncryption}
+		switch alg {
+		case jwa.RSA1_5, jwa.RSA_OAEP, jwa.RSA_OAEP_256, jwa.RSA_OAEP_384, jwa.RSA_OAEP_512:
+			spec.types = []jwa.KeyType{jwa.RSA}
+			spec.size = defaultRSAKeySize
+		case jwa.A128KW, jwa.A128GCMKW:
+			spec.types = []jwa.KeyType{jwa.OctetSeq}
+			spec.size = 128
+			spec.exact = true
+		case jwa.A192KW, jwa.A192GCMKW:
+			spec.types = []jwa.KeyType{jwa.OctetSeq}
+			spec.size = 192
+			spec.exact = true
+		case jwa.A256KW, jwa.A256GCMKW:
+			spec.types = []jwa.KeyType{jwa.OctetSeq}
+			spec.size = 256
+			spec.exact = true
+		case jwa.DIRECT, jwa.PBES2_HS256_A128KW, jwa.PBES2_HS384_A192KW, jwa.PBES2_HS512_A256KW:
+			spec.types = []jwa.KeyType{jwa.OctetSeq}
+		case jwa.ECDH_ES, jwa.ECDH_ES_A128KW, jwa.ECDH_ES_A192KW, jwa.ECDH_ES_A256KW,
+			jwa.ECDH_1PU, jwa.ECDH_1PU_A128KW, jwa.ECDH_1PU_A192KW, jwa.ECDH_1PU_A256KW:
+			spec.types = []jwa.KeyType{jwa.EC, jwa.OKP}
+			spec.curves = ecdhCurves
+		case jwa.HPKE_0, jwa.HPKE_7:
+			spec.types = []jwa.KeyType{jwa.EC}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.P256}
+		case jwa.HPKE_1:
+			spec.types = []jwa.KeyType{jwa.EC}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.P384}
+		case jwa.HPKE_2:
+			spec.types = []jwa.KeyType{jwa.EC}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.P521}
+		case jwa.HPKE_3, jwa.HPKE_4:
+			spec.types = []jwa.KeyType{jwa.OKP}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.X25519}
+		case jwa.HPKE_5, jwa.HPKE_6:
+			spec.types = []jwa.KeyType{jwa.OKP}
+			spec.curves = []jwa.EllipticCurveAlgorithm{jwa.X448}
+		default:
+			return nil, fmt.Errorf(`can not generate keys for key encryption algorithm %q`, alg)
+		}
+		return &spec, nil
+	default:
+		return nil, fmt.Errorf(`invalid algorithm %q`, alg)
+	}
+}
+
+func isOKPCurve(crv jwa.EllipticCurveAlgorithm) bool {
+	switch crv {
+	case jwa.Ed25519, jwa.Ed448, jwa.X25519, jwa.X448:
+		return true
+	default:
+		return false
+	}
+}
+
+// Generate generates a new private key of type `kty`, and returns it
+// as a `jwk.Key`. Use `(jwk.Key).PublicKey()` to obtain its public key.
+//
+// The size of RSA and symmetric ("oct") keys can be specified using
+// `jwk.WithKeySize()`, and the curve of EC and OKP keys can be specified
+// using `jwk.WithCurve()`.
+//
+// The "alg", "use" and "key_ops" fields can be specified using
+// `jwk.WithKeyAlgorithm()`, `jwk.WithKeyUsage()` and `jwk.WithKeyOperations()`.
+// When an algorithm is specified, the key type, size, curve, usage and
+// operations are checked against it, and unspecified sizes and curves
+// default to values suitable for the algorithm. For example, keys for
+// HS384 are 384 bits long, and keys for ES512 use the P-521 curve.
+//
+// Specify `jwk.WithAssignKeyID(true)` to assign the "kid" field using
+// `jwk.AssignKeyID()`.
+func Generate(kty jwa.KeyType, options ...GenerateOption) (Key, error) {
+	var size int
+	var crv jwa.EllipticCurveAlgorithm
+	var alg jwa.KeyAlgorithm
+	var usage KeyUsageType
+	var ops KeyOperationList
+	var assignKeyID bool
+	var assignKeyIDOptions []AssignKeyIDOption
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identKeySize{}:
+			size = option.Value().(int)
+		case identCurve{}:
+			crv = option.Value().(jwa.EllipticCurveAlgorithm)
+		case identKeyAlgorithm{}:
+			alg = option.Value().(jwa.KeyAlgorithm)
+		case identKeyUsage{}:
+			usage = option.Value().(KeyUsageType)
+		case identKeyOperations{}:
+			ops = option.Value().(KeyOperationList)
+		case identAssignKeyID{}:
+			assignKeyID = option.Value().(bool)
+		case identThumbprintHash{}:
+			assignKeyIDOptions = append(assignKeyIDOptions, option.(AssignKeyIDOption))
+		}
+	}
+
+	var spec *keySpec
+	if alg != nil {
+		v, err := keySpecFor(alg)
+		if err != nil {
+			return nil, fmt.Errorf(`jwk.Generate: %w`, err)
+		}
+		spec = v
+	}
+
+	if err := checkGenerateParams(kty, spec, &size, &crv, usage, ops); err != nil {
+		if alg != nil {
+			return nil, fmt.Errorf(`jwk.Generate: invalid parameters for algorithm %q: %w`, alg, err)
+		}
+		return nil, fmt.Errorf(`jwk.Generate: %w`, err)
+	}
+
+	raw, err := generateRawKey(kty, size, crv)
+	if err != nil {
+		return nil, fmt.Errorf(`jwk.Generate: %w`, err)
+	}
+
+	key, err := FromRaw(raw)
+	if err != nil {
+		return nil, fmt.Errorf(`jwk.Generate: failed to create jwk.Key: %w`, err)
+	}
+
+	if alg != nil {
+		if err := key.Set(AlgorithmKey, alg); err != nil {
+			return nil, fmt.Errorf(`jwk.Generate: failed to set "alg": %w`, err)
+		}
+	}
+	if usage != "" {
+		if err := key.Set(KeyUsageKey, usage); err != nil {
+			return nil, fmt.Errorf(`jwk.Generate: failed to set "use": %w`, err)
+		}
+	}
+	if len(ops) > 0 {
+		if err := key.Set(KeyOpsKey, ops); err != nil {
+			return nil, fmt.Errorf(`jwk.Generate: failed to set "key_ops": %w`, err)
+		}
+	}
+	if assignKeyID {
+		if err := AssignKeyID(key, assignKeyIDOptions...); err != nil {
+			return nil, fmt.Errorf(`jwk.Generate: failed to assign "kid": %w`, err)
+		}
+	}
+	return key, nil
+}
+
+// checkGenerateParams checks that the parameters are compatible with
+// each other and with `spec` (if any), and fills in the default size
+// and curve
+func checkGenerateParams(kty jwa.KeyType, spec *keySpec, size *int, crv *jwa.EllipticCurveAlgorithm, usage KeyUsageType, ops KeyOperationList) error {
+	if spec != nil {
+		var ok bool
+		for _, v := range spec.types {
+			if v == kty {
+				ok = true
+				break
+			}
+		}
+		if !ok {
+			return fmt.Errorf(`key type must be one of %q (got %q)`, spec.types, kty)
+		}
+
+		if usage != "" && usage != spec.usage {
+			return fmt.Errorf(`"use" must be %q (got %q)`, spec.usage, usage)
+		}
+	}
+
+	for _, op := range ops {
+		want := usage
+		if spec != nil {
+			want = spec.usage
+		}
+		if want != "" && usageFor(op) != want {
+			return fmt.Errorf(`"key_ops" can not contain %q when "use" is %q`, op, want)
+		}
+	}
+
+	switch kty {
+	case jwa.RSA:
+		if *size == 0 {
+			*size = defaultRSAKeySize
+		}
+		if spec != nil && *size < spec.size {
+			return fmt.Errorf(`RSA keys must be at least %d bits long (got %d)`, spec.size, *size)
+		}
+	case jwa.OctetSeq:
+		if *size == 0 {
+			*size = defaultSymmetricKeySize
+			if spec != nil && spec.size > 0 {
+				*size = spec.size
+			}
+		}
+		if *size <= 0 || *size%8 != 0 {
+			return fmt.Errorf(`symmetric key size must be a positive multiple of 8 (got %d)`, *size)
+		}
+		if spec != nil {
+			if spec.exact && *size != spec.size {
+				return fmt.Errorf(`symmetric keys must be %d bits long (got %d)`, spec.size, *size)
+			}
+			if *size < spec.size {
+				return fmt.Errorf(`symmetric keys must be at least %d bits long (got %d)`, spec.size, *size)
+			}
+		}
+	case jwa.EC, jwa.OKP:
+		var curves []jwa.EllipticCurveAlgorithm
+		if spec != nil {
+			for _, v := range spec.curves {
+				if isOKPCurve(v) == (kty == jwa.OKP) {
+					curves = append(curves, v)
+				}
+			}
+		}
+
+		if *crv == "" {
+			switch {
+			case len(curves) > 0:
+				*crv = curves[0]
+			case kty == jwa.EC:
+				*crv = jwa.P256
+			default:
+				*crv = jwa.Ed25519
+			}
+		}
+
+		if isOKPCurve(*crv) != (kty == jwa.OKP) {
+			return fmt.Errorf(`curve %q can not be used with key type %q`, *crv, kty)
+		}
+
+		if len(curves) > 0 {
+			var ok bool
+			for _, v := range curves {
+				if v == *crv {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				return fmt.Errorf(`curve must be one of %q (got %q)`, curves, *crv)
+			}
+		}
+	default:
+		return fmt.Errorf(`invalid key type %q`, kty)
+	}
+	return nil
+}
+
+func generateRawKey(kty jwa.KeyType, size int, crv jwa.EllipticCurveAlgorithm) (interface{}, error) {
+	switch kty {
+	case jwa.RSA:
+		key, err := rsa.GenerateKey(rand.Reader, size)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate RSA private key: %w`, err)
+		}
+		return key, nil
+	case jwa.EC:
+		curve, ok := CurveForAlgorithm(crv)
+		if !ok {
+			return nil, fmt.Errorf(`unsupported curve %q for EC keys`, crv)
+		}
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate ECDSA private key: %w`, err)
+		}
+		return key, nil
+	case jwa.OKP:
+		var key interface{}
+		var err error
+		switch crv {
+		case jwa.Ed25519:
+			_, key, err = ed25519.GenerateKey(rand.Reader)
+		case jwa.Ed448:
+			_, key, err = ed448.GenerateKey(rand.Reader)
+		case jwa.X25519:
+			_, key, err = x25519.GenerateKey(rand.Reader)
+		case jwa.X448:
+			_, key, err = x448.GenerateKey(rand.Reader)
+		default:
+			return nil, fmt.Errorf(`unsupported curve %q for OKP keys`, crv)
+		}
+		if err != nil {
+			return nil, fmt.Errorf(`failed to generate %s private key: %w`, crv, err)
+		}
+		return key, nil
+	case jwa.OctetSeq:
+		octets := make([]byte, size/8)
+		if _, err := io.ReadFull(rand.Reader, octets); err != nil {
+			return nil, fmt.Errorf(`failed to generate symmetric key: %w`, err)
+		}
+		return octets, nil
+	default:
+		return nil, fmt.Errorf(`invalid key type %q`, kty)
+	}
+}
@@ -0,0 +1,162 @@
+package jwk
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/internal/base64"
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+const defaultHandlerMaxAge = 15 * time.Minute
+
+// SetProvider provides the `jwk.Set` served by `jwk.Handler`.
+type SetProvider interface {
+	ProvideSet() (Set, error)
+}
+
+// SetProviderFunc is a SetProvider based on a function. For example,
+// `jwk.SetProviderFunc(keyring.PublicSet)` serves the keys in a `jwk.KeyRing`.
+type SetProviderFunc func() (Set, error)
+
+func (f SetProviderFunc) ProvideSet() (Set, error) {
+	return f()
+}
+
+// StaticSetProvider creates a `jwk.SetProvider` that always provides
+// `set`. Note that `set` may still change over time if it is, for example,
+// a `jwk.CachedSet`.
+func StaticSetProvider(set Set) SetProvider {
+	return SetProviderFunc(func() (Set, error) {
+		return set, nil
+	})
+}
+
+// Handler is an `http.Handler` that serves a `jwk.Set` as a JWKS, for
+// example at "/.well-known/jwks.json".
+//
+// The set is obtained from the `jwk.SetProvider` on every request. Only
+// the public keys are served: private keys are converted to public keys,
+// and symmetric keys are omitted. The response carries a strong ETag
+// computed from its contents, and conditional requests using
+// If-None-Match are answered with 304 Not Modified. The Cache-Control
+// header is set using the max-age specified by `jwk.WithMaxAge()`.
+type Handler struct {
+	provider SetProvider
+	maxAge   time.Duration
+}
+
+// NewHandler creates a new `jwk.Handler` that serves the set provided by `provider`.
+func NewHandler(provider SetProvider, options ...HandlerOption) *Handler {
+	h := &Handler{
+		provider: provider,
+		maxAge:   defaultHandlerMaxAge,
+	}
+
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identMaxAge{}:
+			h.maxAge = option.Value().(time.Duration)
+		}
+	}
+	return h
+}
+
+// publicSetOf returns the result of `jwk.PublicSetOf()` for `set`, after
+// omitting symmetric keys, as they can not be published. Unlike
+// `jwk.PublicSetOf()`, fields of the set other than "keys" are retained.
+func publicSetOf(set Set) (Set, error) {
+	filtered, err := set.Clone()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to clone set: %w`, err)
+	}
+	for i := 0; i < filtered.Len(); {
+		key, _ := filtered.Key(i)
+		if key.KeyType() != jwa.OctetSeq {
+			i++
+			continue
+		}
+		if err := filtered.RemoveKey(key); err != nil {
+			return nil, fmt.Errorf(`failed to remove symmetric key: %w`, err)
+		}
+	}
+
+	pubset, err := PublicSetOf(filtered)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to create public key set: %w`, err)
+	}
+
+	ctx := context.Background()
+	for iter := set.Iterate(ctx); iter.Next(ctx); {
+		pair := iter.Pair()
+		//nolint:forcetypeassert
+		if err := pubset.Set(pair.Key.(string), pair.Value); err != nil {
+			return nil, fmt.Errorf(`failed to set %q: %w`, pair.Key, err)
+		}
+	}
+	return pubset, nil
+}
+
+// etagMatches checks if the value of an If-None-Match header matches
+// `etag`, using the weak comparison function (RFC 7232 Section 3.2)
+func etagMatches(header, etag string) bool {
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == `*` || strings.TrimPrefix(v, `W/`) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set(`Allow`, `GET, HEAD`)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	set, err := h.provider.ProvideSet()
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	pubset, err := publicSetOf(set)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	buf, err := json.Marshal(pubset)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	sum := sha256.Sum256(buf)
+	etag := `"` + base64.EncodeToString(sum[:]) + `"`
+
+	hdr := w.Header()
+	hdr.Set(`ETag`, etag)
+	hdr.Set(`Cache-Control`, `public, max-age=`+strconv.FormatInt(int64(h.maxAge/time.Second), 10))
+
+	if v := r.Header.Get(`If-None-Match`); v != "" && etagMatches(v, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	hdr.Set(`Content-Type`, `application/jwk-set+json`)
+	hdr.Set(`Content-Length`, strconv.Itoa(len(buf)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodGet {
+		_, _ = w.Write(buf)
+	}
+}
@@ -16,11 +16,13 @@ import (
 	"io"
 	"math/big"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/jwx/v2/internal/base64"
 	"github.com/lestrrat-go/jwx/v2/internal/ecutil"
 	"github.com/lestrrat-go/jwx/v2/internal/json"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 var registry = json.NewRegistry()
@@ -40,6 +42,9 @@ func bigIntToBytes(n *big.Int) ([]byte, error) {
 //   - "crypto/rsa".PrivateKey and "crypto/rsa".PublicKey creates an RSA based key
 //   - "crypto/ecdsa".PrivateKey and "crypto/ecdsa".PublicKey creates an EC based key
 //   - "crypto/ed25519".PrivateKey and "crypto/ed25519".PublicKey creates an OKP based key
+//   - "github.com/cloudflare/circl/sign/ed448".PrivateKey and "github.com/cloudflare/circl/sign/ed448".PublicKey creates an OKP based key
+//   - "github.com/lestrrat-go/jwx/v2/x25519".PrivateKey and "github.com/lestrrat-go/jwx/v2/x25519".PublicKey creates an OKP based key
+//   - "github.com/lestrrat-go/jwx/v2/x448".PrivateKey and "github.com/lestrrat-go/jwx/v2/x448".PublicKey creates an OKP based key
 //   - []byte creates a symmetric key
 func FromRaw(key interface{}) (Key, error) {
 	if key == nil {
@@ -97,6 +102,18 @@ func FromRaw(key interface{}) (Key, error) {
 			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
 		}
 		return k, nil
+	case ed448.PrivateKey:
+		k := newOKPPrivateKey()
+		if err := k.FromRaw(rawKey); err != nil {
+			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
+		}
+		return k, nil
+	case ed448.PublicKey:
+		k := newOKPPublicKey()
+		if err := k.FromRaw(rawKey); err != nil {
+			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
+		}
+		return k, nil
 	case x25519.PrivateKey:
 		k := newOKPPrivateKey()
 		if err := k.FromRaw(rawKey); err != nil {
@@ -109,6 +126,18 @@ func FromRaw(key interface{}) (Key, error) {
 			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
 		}
 		return k, nil
+	case x448.PrivateKey:
+		k := newOKPPrivateKey()
+		if err := k.FromRaw(rawKey); err != nil {
+			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
+		}
+		return k, nil
+	case x448.PublicKey:
+		k := newOKPPublicKey()
+		if err := k.FromRaw(rawKey); err != nil {
+			return nil, fmt.Errorf(`failed to initialize %T from %T: %w`, k, rawKey, err)
+		}
+		return k, nil
 	case []byte:
 		k := newSymmetricKey()
 		if err := k.FromRaw(rawKey); err != nil {
@@ -222,10 +251,18 @@ func PublicRawKeyOf(v interface{}) (interface{}, error) {
 		return x.Public(), nil
 	case ed25519.PublicKey:
 		return x, nil
+	case ed448.PrivateKey:
+		return x.Public(), nil
+	case ed448.PublicKey:
+		return x, nil
 	case x25519.PrivateKey:
 		return x.Public(), nil
 	case x25519.PublicKey:
 		return x, nil
+	case x448.PrivateKey:
+		return x.Public(), nil
+	case x448.PublicKey:
+		return x, nil
 	case []byte:
 		return x, nil
 	default:
@@ -270,14 +307,14 @@ func EncodeX509(v interface{}) (string, []byte, error) {
 			return "", nil, err
 		}
 		return "ECDSA PRIVATE KEY", marshaled, nil
-	case ed25519.PrivateKey:
-		marshaled, err := x509.MarshalPKCS8PrivateKey(v)
+	case ed25519.PrivateKey, ed448.PrivateKey, x448.PrivateKey:
+		marshaled, err := marshalPKCS8PrivateKey(v)
 		if err != nil {
 			return "", nil, err
 		}
 		return pmPrivateKey, marshaled, nil
-	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey:
-		marshaled, err := x509.MarshalPKIXPublicKey(v)
+	case *rsa.PublicKey, *ecdsa.PublicKey, ed25519.PublicKey, ed448.PublicKey, x448.PublicKey:
+		marshaled, err := marshalPKIXPublicKey(v)
 		if err != nil {
 			return "", nil, err
 		}
@@ -336,13 +373,13 @@ func DecodePEM(src []byte) (interface{}, []byte, error) {
 		return key, rest, nil
 	case pmPublicKey:
 		// XXX *could* return dsa.PublicKey
-		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		key, err := parsePKIXPublicKey(block.Bytes)
 		if err != nil {
 			return nil, nil, fmt.Errorf(`failed to parse PKIX public key: %w`, err)
 		}
 		return key, rest, nil
 	case pmPrivateKey:
-		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		key, err := parsePKCS8PrivateKey(block.Bytes)
 		if err != nil {
 			return nil, nil, fmt.Errorf(`failed to parse PKCS8 private key: %w`, err)
 		}
@@ -352,6 +389,15 @@ func DecodePEM(src []byte) (interface{}, []byte, error) {
 		if err != nil {
 			return nil, nil, fmt.Errorf(`failed to parse certificate: %w`, err)
 		}
+		if cert.PublicKey == nil {
+			// The x509 package leaves keys that it does not understand
+			// (e.g. Ed448) unparsed, so try our own parser
+			key, err := parsePKIXPublicKey(cert.RawSubjectPublicKeyInfo)
+			if err != nil {
+				return nil, nil, fmt.Errorf(`failed to parse certificate public key: %w`, err)
+			}
+			return key, rest, nil
+		}
 		return cert.PublicKey, rest, nil
 	default:
 		return nil, nil, fmt.Errorf(`invalid PEM block type %s`, block.Type)
@@ -637,7 +683,7 @@ func cloneKey(src Key) (Key, error) {
 //
 // # Argument must be of type jwk.Key or jwk.Set
 //
-// Currently only EC (including Ed25519) and RSA keys (and jwk.Set
+// Currently only EC (including Ed25519 and Ed448) and RSA keys (and jwk.Set
 // comprised of these key types) are supported.
 func Pem(v interface{}) ([]byte, error) {
 	var set Set
@@ -676,7 +722,7 @@ func asnEncode(key Key) (string, []byte, error) {
 		if err := key.Raw(&rawkey); err != nil {
 			return "", nil, fmt.Errorf(`failed to get raw key from jwk.Key: %w`, err)
 		}
-		buf, err := x509.MarshalPKCS8PrivateKey(rawkey)
+		buf, err := marshalPKCS8PrivateKey(rawkey)
 		if err != nil {
 			return "", nil, fmt.Errorf(`failed to marshal PKCS8: %w`, err)
 		}
@@ -686,7 +732,7 @@ func asnEncode(key Key) (string, []byte, error) {
 		if err := key.Raw(&rawkey); err != nil {
 			return "", nil, fmt.Errorf(`failed to get raw key from jwk.Key: %w`, err)
 		}
-		buf, err := x509.MarshalPKIXPublicKey(rawkey)
+		buf, err := marshalPKIXPublicKey(rawkey)
 		if err != nil {
 			return "", nil, fmt.Errorf(`failed to marshal PKIX: %w`, err)
 		}
@@ -12,13 +12,16 @@ import (
 	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/jwx/v2/cert"
 	"github.com/lestrrat-go/jwx/v2/internal/ecutil"
 	"github.com/lestrrat-go/jwx/v2/internal/jose"
@@ -30,6 +33,7 @@ import (
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -150,8 +154,12 @@ func expectedRawKeyType(key jwk.Key) interface{} {
 		switch key.Crv() {
 		case jwa.Ed25519:
 			return ed25519.PrivateKey(nil)
+		case jwa.Ed448:
+			return ed448.PrivateKey(nil)
 		case jwa.X25519:
 			return x25519.PrivateKey(nil)
+		case jwa.X448:
+			return x448.PrivateKey(nil)
 		default:
 			panic("unknown curve type for OKPPrivateKey:" + key.Crv())
 		}
@@ -159,8 +167,12 @@ func expectedRawKeyType(key jwk.Key) interface{} {
 		switch key.Crv() {
 		case jwa.Ed25519:
 			return ed25519.PublicKey(nil)
+		case jwa.Ed448:
+			return ed448.PublicKey(nil)
 		case jwa.X25519:
 			return x25519.PublicKey(nil)
+		case jwa.X448:
+			return x448.PublicKey(nil)
 		default:
 			panic("unknown curve type for OKPPublicKey:" + key.Crv())
 		}
@@ -386,12 +398,24 @@ func TestParse(t *testing.T) {
 							return
 						}
 						crawkey = rawkey
+					case jwa.Ed448:
+						var rawkey ed448.PrivateKey
+						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&ed448.PrivateKey) should succeed`) {
+							return
+						}
+						crawkey = rawkey
 					case jwa.X25519:
 						var rawkey x25519.PrivateKey
 						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&x25519.PrivateKey) should succeed`) {
 							return
 						}
 						crawkey = rawkey
+					case jwa.X448:
+						var rawkey x448.PrivateKey
+						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&x448.PrivateKey) should succeed`) {
+							return
+						}
+						crawkey = rawkey
 					default:
 						t.Errorf(`invalid curve %s`, k.Crv())
 					}
@@ -406,12 +430,24 @@ func TestParse(t *testing.T) {
 							return
 						}
 						crawkey = rawkey
+					case jwa.Ed448:
+						var rawkey ed448.PublicKey
+						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&ed448.PublicKey) should succeed`) {
+							return
+						}
+						crawkey = rawkey
 					case jwa.X25519:
 						var rawkey x25519.PublicKey
 						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&x25519.PublicKey) should succeed`) {
 							return
 						}
 						crawkey = rawkey
+					case jwa.X448:
+						var rawkey x448.PublicKey
+						if !assert.NoError(t, key.Raw(&rawkey), `key.Raw(&x448.PublicKey) should succeed`) {
+							return
+						}
+						crawkey = rawkey
 					default:
 						t.Errorf(`invalid curve %s`, k.Crv())
 					}
@@ -504,6 +540,27 @@ func TestParse(t *testing.T) {
 		}`
 		verify(t, src, reflect.TypeOf((*jwk.OKPPrivateKey)(nil)).Elem())
 	})
+	t.Run("Ed448 Public Key", func(t *testing.T) {
+		t.Parallel()
+		// Key taken from RFC 8032
+		const src = `{
+		  "kty" : "OKP",
+		  "crv" : "Ed448",
+		  "x"   : "X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA"
+		}`
+		verify(t, src, reflect.TypeOf((*jwk.OKPPublicKey)(nil)).Elem())
+	})
+	t.Run("Ed448 Private Key", func(t *testing.T) {
+		t.Parallel()
+		// Key taken from RFC 8032
+		const src = `{
+		  "kty" : "OKP",
+		  "crv" : "Ed448",
+		  "d"   : "bIKlYsuAjRDWMr6JyFE-v2ySnzTd-oyfY8mWDvbjSKNSjIo_zC8ETjmj_FuUSS-PAy51SaIAmPlb",
+		  "x"   : "X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA"
+		}`
+		verify(t, src, reflect.TypeOf((*jwk.OKPPrivateKey)(nil)).Elem())
+	})
 	t.Run("X25519 Public Key", func(t *testing.T) {
 		t.Parallel()
 		// Key taken from RFC 8037
@@ -525,6 +582,27 @@ func TestParse(t *testing.T) {
 		}`
 		verify(t, src, reflect.TypeOf((*jwk.OKPPrivateKey)(nil)).Elem())
 	})
+	t.Run("X448 Public Key", func(t *testing.T) {
+		t.Parallel()
+		// Key taken from RFC 7748
+		const src = `{
+		  "kty" : "OKP",
+		  "crv" : "X448",
+		  "x"   : "mwj3zDG34-Z9ItWuoSEHSic70rg94Jxj-qc9LCLF2bvINmRyQdlT1AxbEtqIEg1TF3-A5TLEH6A"
+		}`
+		verify(t, src, reflect.TypeOf((*jwk.OKPPublicKey)(nil)).Elem())
+	})
+	t.Run("X448 Private Key", func(t *testing.T) {
+		t.Parallel()
+		// Key taken from RFC 7748
+		const src = `{
+		  "kty" : "OKP",
+		  "crv" : "X448",
+		  "d"   : "mo9JJdFRn1d1z0awS1gA1O6e6LrovFVl1JjCjdnJuvV0qUGXRIlzkQBjgqbxJ6sdmsLYwKWYcms",
+		  "x"   : "mwj3zDG34-Z9ItWuoSEHSic70rg94Jxj-qc9LCLF2bvINmRyQdlT1AxbEtqIEg1TF3-A5TLEH6A"
+		}`
+		verify(t, src, reflect.TypeOf((*jwk.OKPPrivateKey)(nil)).Elem())
+	})
 }
 
 func TestRoundtrip(t *testing.T) {
@@ -573,6 +651,28 @@ func TestRoundtrip(t *testing.T) {
 		return k, nil
 	}
 
+	generateEd448 := func(use, keyID string) (jwk.Key, error) {
+		k, err := jwxtest.GenerateEd448Jwk()
+		if err != nil {
+			return nil, err
+		}
+
+		k.Set(jwk.KeyUsageKey, use)
+		k.Set(jwk.KeyIDKey, keyID)
+		return k, nil
+	}
+
+	generateX448 := func(use, keyID string) (jwk.Key, error) {
+		k, err := jwxtest.GenerateX448Jwk()
+		if err != nil {
+			return nil, err
+		}
+
+		k.Set(jwk.KeyUsageKey, use)
+		k.Set(jwk.KeyIDKey, keyID)
+		return k, nil
+	}
+
 	generateX25519 := func(use, keyID string) (jwk.Key, error) {
 		k, err := jwxtest.GenerateX25519Jwk()
 		if err != nil {
@@ -639,11 +739,21 @@ func TestRoundtrip(t *testing.T) {
 			keyID:    "sig6",
 			generate: generateEd25519,
 		},
+		{
+			use:      "sig",
+			keyID:    "sig7",
+			generate: generateEd448,
+		},
 		{
 			use:      "enc",
 			keyID:    "enc6",
 			generate: generateX25519,
 		},
+		{
+			use:      "enc",
+			keyID:    "enc7",
+			generate: generateX448,
+		},
 	}
 
 	ks1 := jwk.NewSet()
@@ -766,6 +876,7 @@ func TestAssignKeyID(t *testing.T) {
 		jwxtest.GenerateEcdsaPublicJwk,
 		jwxtest.GenerateSymmetricJwk,
 		jwxtest.GenerateEd25519Jwk,
+		jwxtest.GenerateEd448Jwk,
 	}
 
 	for _, generator := range generators {
@@ -807,11 +918,21 @@ func TestPublicKeyOf(t *testing.T) {
 		return
 	}
 
+	ed448key, err := jwxtest.GenerateEd448Key()
+	if !assert.NoError(t, err, `generating raw Ed448 key should succeed`) {
+		return
+	}
+
 	x25519key, err := jwxtest.GenerateX25519Key()
 	if !assert.NoError(t, err, `generating raw X25519 key should succeed`) {
 		return
 	}
 
+	x448key, err := jwxtest.GenerateX448Key()
+	if !assert.NoError(t, err, `generating raw X448 key should succeed`) {
+		return
+	}
+
 	keys := []struct {
 		Key           interface{}
 		PublicKeyType reflect.Type
@@ -860,6 +981,14 @@ func TestPublicKeyOf(t *testing.T) {
 			Key:           ed25519key.Public(),
 			PublicKeyType: reflect.TypeOf(ed25519key.Public()),
 		},
+		{
+			Key:           ed448key,
+			PublicKeyType: reflect.TypeOf(ed448key.Public()),
+		},
+		{
+			Key:           ed448key.Public(),
+			PublicKeyType: reflect.TypeOf(ed448key.Public()),
+		},
 		{
 			Key:           x25519key,
 			PublicKeyType: reflect.TypeOf(x25519key.Public()),
@@ -868,6 +997,14 @@ func TestPublicKeyOf(t *testing.T) {
 			Key:           x25519key.Public(),
 			PublicKeyType: reflect.TypeOf(x25519key.Public()),
 		},
+		{
+			Key:           x448key,
+			PublicKeyType: reflect.TypeOf(x448key.Public()),
+		},
+		{
+			Key:           x448key.Public(),
+			PublicKeyType: reflect.TypeOf(x448key.Public()),
+		},
 	}
 
 	for _, key := range keys {
@@ -1325,6 +1462,87 @@ func TestOKP(t *testing.T) {
 			})
 		})
 	})
+	t.Run("Ed448", func(t *testing.T) {
+		t.Parallel()
+		t.Run("PrivateKey", func(t *testing.T) {
+			t.Parallel()
+			VerifyKey(t, map[string]keyDef{
+				jwk.KeyTypeKey: {
+					Method: "KeyType",
+					Value:  jwa.OKP,
+				},
+				jwk.OKPDKey: expectBase64(keyDef{
+					Method: "D",
+					Value:  "bIKlYsuAjRDWMr6JyFE-v2ySnzTd-oyfY8mWDvbjSKNSjIo_zC8ETjmj_FuUSS-PAy51SaIAmPlb",
+				}),
+				jwk.OKPXKey: expectBase64(keyDef{
+					Method: "X",
+					Value:  "X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA",
+				}),
+				jwk.OKPCrvKey: {
+					Method: "Crv",
+					Value:  jwa.Ed448,
+				},
+			})
+		})
+		t.Run("PublicKey", func(t *testing.T) {
+			t.Parallel()
+			VerifyKey(t, map[string]keyDef{
+				jwk.KeyTypeKey: {
+					Method: "KeyType",
+					Value:  jwa.OKP,
+				},
+				jwk.OKPXKey: expectBase64(keyDef{
+					Method: "X",
+					Value:  "X9dEm1m0Yf0s54fsYWrUah2hNCSFpw4fig6nXYDpZ3jt8SR2m0bHBhvWeD3x5Q9s0foavq_oJWGA",
+				}),
+				jwk.OKPCrvKey: {
+					Method: "Crv",
+					Value:  jwa.Ed448,
+				},
+			})
+		})
+		t.Run("PEM", func(t *testing.T) {
+			t.Parallel()
+			key, err := jwxtest.GenerateEd448Jwk()
+			if !assert.NoError(t, err, `jwxtest.GenerateEd448Jwk should succeed`) {
+				return
+			}
+
+			pubkey, err := jwk.PublicKeyOf(key)
+			if !assert.NoError(t, err, `jwk.PublicKeyOf should succeed`) {
+				return
+			}
+
+			for _, k := range []jwk.Key{key, pubkey} {
+				buf, err := jwk.Pem(k)
+				if !assert.NoError(t, err, `jwk.Pem should succeed`) {
+					return
+				}
+
+				parsed, err := jwk.ParseKey(buf, jwk.WithPEM(true))
+				if !assert.NoError(t, err, `jwk.ParseKey should succeed`) {
+					return
+				}
+
+				if !assert.Equal(t, k.KeyType(), parsed.KeyType(), `key types should match`) {
+					return
+				}
+
+				expected, err := k.Thumbprint(crypto.SHA256)
+				if !assert.NoError(t, err, `k.Thumbprint should succeed`) {
+					return
+				}
+				actual, err := parsed.Thumbprint(crypto.SHA256)
+				if !assert.NoError(t, err, `parsed.Thumbprint should succeed`) {
+					return
+				}
+				if !assert.Equal(t, expected, actual, `thumbprints should match`) {
+					return
+				}
+			}
+		})
+	})
 	t.Run("X25519", func(t *testing.T) {
 		t.Parallel()
 		t.Run("PublicKey", func(t *testing.T) {
@@ -2070,3 +2288,256 @@ func TestGH730(t *testing.T) {
 	require.NoError(t, set.AddKey(key), `first AddKey should succeed`)
 	require.Error(t, set.AddKey(key), `second AddKey should fail`)
 }
+
+func TestGenerate(t *testing.T) {
+	t.Run("Key types", func(t *testing.T) {
+		type testcase struct {
+			Name    string
+			KeyType jwa.KeyType
+			Options []jwk.GenerateOption
+			Check   func(*testing.T, jwk.Key)
+		}
+		testcases := []testcase{
+			{
+				Name:    "RSA",
+				KeyType: jwa.RSA,
+				Options: []jwk.GenerateOption{jwk.WithKeySize(2048)},
+				Check: func(t *testing.T, key jwk.Key) {
+					var raw rsa.PrivateKey
+					require.NoError(t, key.Raw(&raw), `key.Raw should succeed`)
+					require.Equal(t, 2048, raw.N.BitLen(), `key size should match`)
+				},
+			},
+			{
+				Name:    "EC (default curve)",
+				KeyType: jwa.EC,
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Equal(t, jwa.P256, key.(jwk.ECDSAPrivateKey).Crv(), `curve should be P-256`)
+				},
+			},
+			{
+				Name:    "OKP (X25519)",
+				KeyType: jwa.OKP,
+				Options: []jwk.GenerateOption{jwk.WithCurve(jwa.X25519)},
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Equal(t, jwa.X25519, key.(jwk.OKPPrivateKey).Crv(), `curve should be X25519`)
+				},
+			},
+			{
+				Name:    "oct (default size)",
+				KeyType: jwa.OctetSeq,
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Len(t, key.(jwk.SymmetricKey).Octets(), 32, `key size should be 256 bits`)
+				},
+			},
+			{
+				Name:    "oct (size from algorithm)",
+				KeyType: jwa.OctetSeq,
+				Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.HS512)},
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Len(t, key.(jwk.SymmetricKey).Octets(), 64, `key size should be 512 bits`)
+				},
+			},
+			{
+				Name:    "EC (curve from algorithm)",
+				KeyType: jwa.EC,
+				Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.ES384)},
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Equal(t, jwa.P384, key.(jwk.ECDSAPrivateKey).Crv(), `curve should be P-384`)
+				},
+			},
+		}
+
+		for _, curve := range jwk.AvailableCurves() {
+			crv, ok := ecutil.AlgorithmForCurve(curve)
+			require.True(t, ok, `ecutil.AlgorithmForCurve should succeed`)
+			testcases = append(testcases, testcase{
+				Name:    fmt.Sprintf("EC (%s)", crv),
+				KeyType: jwa.EC,
+				Options: []jwk.GenerateOption{jwk.WithCurve(crv)},
+				Check: func(t *testing.T, key jwk.Key) {
+					require.Equal(t, crv, key.(jwk.ECDSAPrivateKey).Crv(), `curve should match`)
+				},
+			})
+		}
+
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				key, err := jwk.Generate(tc.KeyType, tc.Options...)
+				require.NoError(t, err, `jwk.Generate should succeed`)
+				require.Equal(t, tc.KeyType, key.KeyType(), `key type should match`)
+				tc.Check(t, key)
+			})
+		}
+	})
+	t.Run("Fields", func(t *testing.T) {
+		key, err := jwk.Generate(jwa.OKP,
+			jwk.WithKeyAlgorithm(jwa.EdDSA),
+			jwk.WithKeyUsage(jwk.ForSignature),
+			jwk.WithKeyOperations(jwk.KeyOpSign, jwk.KeyOpVerify),
+			jwk.WithAssignKeyID(true),
+			jwk.WithThumbprintHash(crypto.SHA512),
+		)
+		require.NoError(t, err, `jwk.Generate should succeed`)
+		require.Equal(t, jwa.Ed25519, key.(jwk.OKPPrivateKey).Crv(), `curve should be Ed25519`)
+		require.Equal(t, jwa.EdDSA.String(), key.Algorithm().String(), `"alg" should match`)
+		require.Equal(t, jwk.ForSignature.String(), key.KeyUsage(), `"use" should match`)
+		require.Equal(t, jwk.KeyOperationList{jwk.KeyOpSign, jwk.KeyOpVerify}, key.KeyOps(), `"key_ops" should match`)
+
+		tp, err := key.Thumbprint(crypto.SHA512)
+		require.NoError(t, err, `key.Thumbprint should succeed`)
+		require.Equal(t, base64.EncodeToString(tp), key.KeyID(), `"kid" should be the thumbprint`)
+
+		signed, err := jws.Sign([]byte(`Lorem ipsum`), jws.WithKey(jwa.EdDSA, key), jws.WithStrictKeyUsage(true))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		pubkey, err := key.PublicKey()
+		require.NoError(t, err, `key.PublicKey should succeed`)
+		_, err = jws.Verify(signed, jws.WithKey(jwa.EdDSA, pubkey), jws.WithStrictKeyUsage(true))
+		require.NoError(t, err, `jws.Verify should succeed`)
+	})
+	t.Run("Incompatible parameters", func(t *testing.T) {
+		testcases := []struct {
+			Name    string
+			KeyType jwa.KeyType
+			Options []jwk.GenerateOption
+		}{
+			{Name: "key type", KeyType: jwa.EC, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.RS256)}},
+			{Name: "RSA key size", KeyType: jwa.RSA, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.PS256), jwk.WithKeySize(1024)}},
+			{Name: "HMAC key size", KeyType: jwa.OctetSeq, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.HS384), jwk.WithKeySize(256)}},
+			{Name: "AES key size", KeyType: jwa.OctetSeq, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.A128KW), jwk.WithKeySize(256)}},
+			{Name: "symmetric key size", KeyType: jwa.OctetSeq, Options: []jwk.GenerateOption{jwk.WithKeySize(100)}},
+			{Name: "curve", KeyType: jwa.EC, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.ES256), jwk.WithCurve(jwa.P384)}},
+			{Name: "OKP curve for EC", KeyType: jwa.EC, Options: []jwk.GenerateOption{jwk.WithCurve(jwa.Ed25519)}},
+			{Name: "signature curve for ECDH", KeyType: jwa.OKP, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.ECDH_ES), jwk.WithCurve(jwa.Ed25519)}},
+			{Name: "use", KeyType: jwa.RSA, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.RSA_OAEP), jwk.WithKeyUsage(jwk.ForSignature)}},
+			{Name: "key_ops", KeyType: jwa.RSA, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.RS256), jwk.WithKeyOperations(jwk.KeyOpEncrypt)}},
+			{Name: "key_ops and use", KeyType: jwa.RSA, Options: []jwk.GenerateOption{jwk.WithKeyUsage(jwk.ForEncryption), jwk.WithKeyOperations(jwk.KeyOpSign)}},
+			{Name: "none", KeyType: jwa.OctetSeq, Options: []jwk.GenerateOption{jwk.WithKeyAlgorithm(jwa.NoSignature)}},
+		}
+		for _, tc := range testcases {
+			tc := tc
+			t.Run(tc.Name, func(t *testing.T) {
+				_, err := jwk.Generate(tc.KeyType, tc.Options...)
+				require.Error(t, err, `jwk.Generate should fail`)
+			})
+		}
+	})
+}
+
+func TestKeyRing(t *testing.T) {
+	const day = 24 * time.Hour
+	var mu sync.Mutex
+	now := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+	clock := jwk.ClockFunc(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	storage, err := jwk.NewFileKeyRingStorage(filepath.Join(t.TempDir(), `keyring`, `keys.json`))
+	require.NoError(t, err, `jwk.NewFileKeyRingStorage should succeed`)
+
+	var generated int
+	generator := jwk.KeyGeneratorFunc(func() (jwk.Key, error) {
+		generated++
+		return jwk.Generate(jwa.EC, jwk.WithKeyAlgorithm(jwa.ES256), jwk.WithKeyUsage(jwk.ForSignature))
+	})
+	options := []jwk.KeyRingOption{
+		jwk.WithRotationPeriod(7 * day),
+		jwk.WithPrePublishPeriod(2 * day),
+		jwk.WithRetentionPeriod(3 * day),
+		jwk.WithKeyRingStorage(storage),
+		jwk.WithClock(clock),
+	}
+
+	states := func(t *testing.T, kr *jwk.KeyRing) []jwk.KeyState {
+		t.Helper()
+		entries, err := kr.Entries()
+		require.NoError(t, err, `kr.Entries should succeed`)
+		var states []jwk.KeyState
+		for _, e := range entries {
+			states = append(states, e.State(clock.Now()))
+		}
+		return states
+	}
+
+	kr, err := jwk.NewKeyRing(generator, options...)
+	require.NoError(t, err, `jwk.NewKeyRing should succeed`)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateActive}, states(t, kr), `there should be a single active key`)
+
+	first, err := kr.ActiveKey()
+	require.NoError(t, err, `kr.ActiveKey should succeed`)
+	require.NotEmpty(t, first.KeyID(), `key ID should be assigned`)
+
+	// The next key is published two days before the rotation
+	advance(5 * day)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateActive, jwk.KeyStatePending}, states(t, kr), `the next key should be pending`)
+	pubset, err := kr.PublicSet()
+	require.NoError(t, err, `kr.PublicSet should succeed`)
+	require.Equal(t, 2, pubset.Len(), `both keys should be published`)
+	for i := 0; i < pubset.Len(); i++ {
+		key, _ := pubset.Key(i)
+		_, ok := key.(jwk.ECDSAPublicKey)
+		require.True(t, ok, `published keys should be public keys`)
+	}
+
+	advance(2 * day)
+	second, err := kr.ActiveKey()
+	require.NoError(t, err, `kr.ActiveKey should succeed`)
+	require.NotEqual(t, first.KeyID(), second.KeyID(), `active key should be rotated`)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateRetiring, jwk.KeyStateActive}, states(t, kr), `the first key should be retiring`)
+
+	// Reloading from the storage gives the same keys
+	reloaded, err := jwk.NewKeyRing(generator, options...)
+	require.NoError(t, err, `jwk.NewKeyRing should succeed`)
+	key, err := reloaded.ActiveKey()
+	require.NoError(t, err, `reloaded.ActiveKey should succeed`)
+	require.Equal(t, second.KeyID(), key.KeyID(), `reloaded key ring should have the same active key`)
+	require.Equal(t, 2, generated, `no keys should be generated when reloading`)
+
+	advance(3 * day)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateActive}, states(t, kr), `the first key should be removed`)
+
+	// Forced rotation activates a new key immediately
+	require.NoError(t, kr.Rotate(), `kr.Rotate should succeed`)
+	third, err := kr.ActiveKey()
+	require.NoError(t, err, `kr.ActiveKey should succeed`)
+	require.NotEqual(t, second.KeyID(), third.KeyID(), `active key should be rotated`)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateRetiring, jwk.KeyStateActive}, states(t, kr), `the second key should be retiring`)
+
+	// Keys are rotated even if the key ring has not been used for a while
+	advance(30 * day)
+	fourth, err := kr.ActiveKey()
+	require.NoError(t, err, `kr.ActiveKey should succeed`)
+	require.NotEqual(t, third.KeyID(), fourth.KeyID(), `active key should be rotated`)
+	require.Equal(t, []jwk.KeyState{jwk.KeyStateActive}, states(t, kr), `there should be a single active key`)
+
+	// The clock may pass the rotation time while the active key is being selected
+	t.Run(`Rotation during lookup`, func(t *testing.T) {
+		start := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+		var calls int
+		clock := jwk.ClockFunc(func() time.Time {
+			mu.Lock()
+			defer mu.Unlock()
+			// Every call observes a time one second later than the previous one
+			calls++
+			return start.Add(time.Duration(calls) * time.Second)
+		})
+		kr, err := jwk.NewKeyRing(generator,
+			jwk.WithRotationPeriod(3*time.Second),
+			jwk.WithPrePublishPeriod(0),
+			jwk.WithClock(clock),
+		)
+		require.NoError(t, err, `jwk.NewKeyRing should succeed`)
+		for i := 0; i < 10; i++ {
+			_, err := kr.ActiveKey()
+			require.NoError(t, err, `kr.ActiveKey should succeed`)
+		}
+	})
+}
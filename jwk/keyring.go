@@ -0,0 +1,435 @@
+package jwk
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+const (
+	defaultRotationPeriod   = 30 * 24 * time.Hour
+	defaultPrePublishPeriod = 24 * time.Hour
+	defaultRetentionPeriod  = 24 * time.Hour
+)
+
+// KeyState describes the state of a key in a `jwk.KeyRing`
+type KeyState int
+
+const (
+	// KeyStatePending is the state of keys that have been published,
+	// but are not used for signing yet
+	KeyStatePending KeyState = iota + 1
+	// KeyStateActive is the state of the key that is used for signing
+	KeyStateActive
+	// KeyStateRetiring is the state of keys that are no longer used
+	// for signing, but are still published so that tokens signed
+	// using them can be verified
+	KeyStateRetiring
+	// KeyStateExpired is the state of keys that are no longer published.
+	// These keys are removed from the key ring
+	KeyStateExpired
+)
+
+func (s KeyState) String() string {
+	switch s {
+	case KeyStatePending:
+		return `pending`
+	case KeyStateActive:
+		return `active`
+	case KeyStateRetiring:
+		return `retiring`
+	case KeyStateExpired:
+		return `expired`
+	default:
+		return `unknown`
+	}
+}
+
+// Clock is used to obtain the current time
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc is a Clock based on a function.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time {
+	return f()
+}
+
+// KeyGenerator generates the keys for a `jwk.KeyRing`.
+type KeyGenerator interface {
+	GenerateKey() (Key, error)
+}
+
+// KeyGeneratorFunc is a KeyGenerator based on a function.
+type KeyGeneratorFunc func() (Key, error)
+
+func (f KeyGeneratorFunc) GenerateKey() (Key, error) {
+	return f()
+}
+
+// NewKeyGenerator creates a `jwk.KeyGenerator` that generates keys
+// using `jwk.Generate()`.
+func NewKeyGenerator(kty jwa.KeyType, options ...GenerateOption) KeyGenerator {
+	return KeyGeneratorFunc(func() (Key, error) {
+		return Generate(kty, options...)
+	})
+}
+
+// KeyRingEntry is a key in a `jwk.KeyRing`, along with its schedule.
+// The state of the key is determined by the schedule: it is pending
+// until `ActivateAt`, active until `RetireAt`, retiring until `ExpireAt`,
+// and expired afterwards.
+type KeyRingEntry struct {
+	Key        Key       `json:"key"`
+	ActivateAt time.Time `json:"activate_at"`
+	RetireAt   time.Time `json:"retire_at"`
+	ExpireAt   time.Time `json:"expire_at"`
+}
+
+// State returns the state of the key at time `t`
+func (e *KeyRingEntry) State(t time.Time) KeyState {
+	switch {
+	case t.Before(e.ActivateAt):
+		return KeyStatePending
+	case t.Before(e.RetireAt):
+		return KeyStateActive
+	case t.Before(e.ExpireAt):
+		return KeyStateRetiring
+	default:
+		return KeyStateExpired
+	}
+}
+
+func (e *KeyRingEntry) UnmarshalJSON(data []byte) error {
+	var proxy struct {
+		Key        json.RawMessage `json:"key"`
+		ActivateAt time.Time       `json:"activate_at"`
+		RetireAt   time.Time       `json:"retire_at"`
+		ExpireAt   time.Time       `json:"expire_at"`
+	}
+	if err := json.Unmarshal(data, &proxy); err != nil {
+		return fmt.Errorf(`failed to unmarshal jwk.KeyRingEntry: %w`, err)
+	}
+
+	key, err := ParseKey(proxy.Key)
+	if err != nil {
+		return fmt.Errorf(`failed to parse key in jwk.KeyRingEntry: %w`, err)
+	}
+
+	e.Key = key
+	e.ActivateAt = proxy.ActivateAt
+	e.RetireAt = proxy.RetireAt
+	e.ExpireAt = proxy.ExpireAt
+	return nil
+}
+
+// KeyRingStorage persists the keys in a `jwk.KeyRing`.
+type KeyRingStorage interface {
+	// Load returns the stored entries. If nothing has been stored yet,
+	// it must return nil and no error
+	Load() ([]KeyRingEntry, error)
+	// Store replaces the stored entries with `entries`
+	Store(entries []KeyRingEntry) error
+}
+
+// KeyRing manages a set of keys that are rotated on a schedule. At any
+// given time, exactly one of the keys is active, and should be used for
+// signing (see `jws.WithKeyRing()` and `jwt.WithKeyRing()`).
+//
+// Each key is active for the rotation period (`jwk.WithRotationPeriod()`).
+// The key that succeeds it is generated in advance (`jwk.WithPrePublishPeriod()`),
+// so that consumers can learn about it before it is used. After a key is
+// retired, it is kept for the retention period (`jwk.WithRetentionPeriod()`),
+// so that tokens signed using it can still be verified. The public keys of
+// all pending, active and retiring keys are available from `PublicSet()`,
+// which is suitable for publishing as a JWKS.
+//
+// The schedule is evaluated whenever the methods of the key ring are
+// called, therefore no background goroutines are required. Keys that are
+// generated are assigned a key ID using `jwk.AssignKeyID()` unless the
+// generator assigns one.
+type KeyRing struct {
+	mu         sync.Mutex
+	generator  KeyGenerator
+	storage    KeyRingStorage
+	clock      Clock
+	rotation   time.Duration
+	prePublish time.Duration
+	retention  time.Duration
+	entries    []KeyRingEntry
+}
+
+// NewKeyRing creates a new `jwk.KeyRing` that generates keys using
+// `generator`. If a storage is specified, the stored keys are loaded.
+func NewKeyRing(generator KeyGenerator, options ...KeyRingOption) (*KeyRing, error) {
+	kr := &KeyRing{
+		generator:  generator,
+		clock:      ClockFunc(time.Now),
+		rotation:   defaultRotationPeriod,
+		prePublish: defaultPrePublishPeriod,
+		retention:  defaultRetentionPeriod,
+	}
+
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identRotationPeriod{}:
+			kr.rotation = option.Value().(time.Duration)
+		case identPrePublishPeriod{}:
+			kr.prePublish = option.Value().(time.Duration)
+		case identRetentionPeriod{}:
+			kr.retention = option.Value().(time.Duration)
+		case identKeyRingStorage{}:
+			kr.storage = option.Value().(KeyRingStorage)
+		case identClock{}:
+			kr.clock = option.Value().(Clock)
+		}
+	}
+
+	if kr.rotation <= 0 {
+		return nil, fmt.Errorf(`jwk.NewKeyRing: rotation period must be positive`)
+	}
+	if kr.prePublish < 0 || kr.retention < 0 {
+		return nil, fmt.Errorf(`jwk.NewKeyRing: pre-publish and retention periods must not be negative`)
+	}
+
+	if kr.storage != nil {
+		entries, err := kr.storage.Load()
+		if err != nil {
+			return nil, fmt.Errorf(`jwk.NewKeyRing: failed to load keys: %w`, err)
+		}
+		kr.entries = entries
+	}
+
+	if _, err := kr.maintain(false); err != nil {
+		return nil, fmt.Errorf(`jwk.NewKeyRing: %w`, err)
+	}
+	return kr, nil
+}
+
+func (kr *KeyRing) generate(activateAt time.Time) (KeyRingEntry, error) {
+	key, err := kr.generator.GenerateKey()
+	if err != nil {
+		return KeyRingEntry{}, fmt.Errorf(`failed to generate key: %w`, err)
+	}
+	if err := AssignKeyID(key); err != nil {
+		return KeyRingEntry{}, fmt.Errorf(`failed to assign key ID: %w`, err)
+	}
+	return kr.schedule(key, activateAt), nil
+}
+
+func (kr *KeyRing) schedule(key Key, activateAt time.Time) KeyRingEntry {
+	retireAt := activateAt.Add(kr.rotation)
+	return KeyRingEntry{
+		Key:        key,
+		ActivateAt: activateAt,
+		RetireAt:   retireAt,
+		ExpireAt:   retireAt.Add(kr.retention),
+	}
+}
+
+// maintain brings the entries up to date with the schedule, and stores
+// them if they were changed. If `rotate` is true, the active key is
+// retired immediately. The entry that is active at the time used to
+// bring the entries up to date is returned. kr.mu must be held by the caller
+func (kr *KeyRing) maintain(rotate bool) (KeyRingEntry, error) {
+	now := kr.clock.Now()
+	entries := make([]KeyRingEntry, 0, len(kr.entries)+2)
+	var changed bool
+	for _, e := range kr.entries {
+		if e.State(now) == KeyStateExpired {
+			changed = true
+			continue
+		}
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].ActivateAt.Before(entries[j].ActivateAt)
+	})
+
+	active := -1
+	for i, e := range entries {
+		if e.State(now) == KeyStateActive {
+			active = i
+		}
+	}
+
+	if rotate && active >= 0 {
+		entries[active].RetireAt = now
+		entries[active].ExpireAt = now.Add(kr.retention)
+		active = -1
+		changed = true
+	}
+
+	if active < 0 {
+		// Promote the earliest pending key if there is one, as it
+		// has already been published. Otherwise generate a new key
+		for i, e := range entries {
+			if e.State(now) == KeyStatePending {
+				entries[i] = kr.schedule(e.Key, now)
+				active = i
+				break
+			}
+		}
+		if active < 0 {
+			e, err := kr.generate(now)
+			if err != nil {
+				return KeyRingEntry{}, err
+			}
+			entries = append(entries, e)
+			active = len(entries) - 1
+		}
+		changed = true
+	}
+
+	var hasNext bool
+	for _, e := range entries {
+		if !e.ActivateAt.Before(entries[active].RetireAt) {
+			hasNext = true
+			break
+		}
+	}
+	if !hasNext && !now.Before(entries[active].RetireAt.Add(-kr.prePublish)) {
+		e, err := kr.generate(entries[active].RetireAt)
+		if err != nil {
+			return KeyRingEntry{}, err
+		}
+		entries = append(entries, e)
+		changed = true
+	}
+
+	if !changed {
+		return entries[active], nil
+	}
+
+	if kr.storage != nil {
+		if err := kr.storage.Store(entries); err != nil {
+			return KeyRingEntry{}, fmt.Errorf(`failed to store keys: %w`, err)
+		}
+	}
+	kr.entries = entries
+	return entries[active], nil
+}
+
+// ActiveKey returns the key that should currently be used for signing.
+// Keys are rotated as necessary before the key is returned.
+func (kr *KeyRing) ActiveKey() (Key, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	// The active key must be selected using the same time that was used
+	// to maintain the entries, otherwise there may be no active key if
+	// the clock passes the rotation time in between
+	active, err := kr.maintain(false)
+	if err != nil {
+		return nil, fmt.Errorf(`(jwk.KeyRing).ActiveKey: %w`, err)
+	}
+	return active.Key, nil
+}
+
+// Entries returns the keys in the key ring along with their schedules,
+// ordered by their activation time. Keys are rotated as necessary before
+// the entries are returned.
+func (kr *KeyRing) Entries() ([]KeyRingEntry, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if _, err := kr.maintain(false); err != nil {
+		return nil, fmt.Errorf(`(jwk.KeyRing).Entries: %w`, err)
+	}
+
+	entries := make([]KeyRingEntry, len(kr.entries))
+	copy(entries, kr.entries)
+	return entries, nil
+}
+
+// PublicSet returns a `jwk.Set` containing the public keys of all pending,
+// active and retiring keys in the key ring. Keys are rotated as necessary
+// before the set is created.
+func (kr *KeyRing) PublicSet() (Set, error) {
+	entries, err := kr.Entries()
+	if err != nil {
+		return nil, err
+	}
+
+	set := NewSet()
+	for _, e := range entries {
+		if err := set.AddKey(e.Key); err != nil {
+			return nil, fmt.Errorf(`(jwk.KeyRing).PublicSet: failed to add key: %w`, err)
+		}
+	}
+
+	pubset, err := PublicSetOf(set)
+	if err != nil {
+		return nil, fmt.Errorf(`(jwk.KeyRing).PublicSet: %w`, err)
+	}
+	return pubset, nil
+}
+
+// Rotate retires the active key immediately, and activates the next key.
+// This is useful when the active key may have been compromised. Note that
+// the retired key is still published for the retention period.
+func (kr *KeyRing) Rotate() error {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+
+	if _, err := kr.maintain(true); err != nil {
+		return fmt.Errorf(`(jwk.KeyRing).Rotate: %w`, err)
+	}
+	return nil
+}
+
+// FileKeyRingStorage is a `jwk.KeyRingStorage` that stores the keys in
+// a JSON file. As the file contains private keys, it is created so that
+// it is only readable by the owner.
+type FileKeyRingStorage struct {
+	path string
+}
+
+// NewFileKeyRingStorage creates a new `jwk.FileKeyRingStorage` that stores
+// the keys in the file `path`. The parent directory is created if it does
+// not exist.
+func NewFileKeyRingStorage(path string) (*FileKeyRingStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf(`failed to create directory for %q: %w`, path, err)
+	}
+	return &FileKeyRingStorage{path: path}, nil
+}
+
+// Load reads the entries from the file
+func (s *FileKeyRingStorage) Load() ([]KeyRingEntry, error) {
+	buf, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`failed to read %q: %w`, s.path, err)
+	}
+
+	var entries []KeyRingEntry
+	if err := json.Unmarshal(buf, &entries); err != nil {
+		return nil, fmt.Errorf(`failed to parse %q: %w`, s.path, err)
+	}
+	return entries, nil
+}
+
+// Store writes the entries to the file. The file is replaced atomically.
+func (s *FileKeyRingStorage) Store(entries []KeyRingEntry) error {
+	buf, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf(`failed to marshal key ring entries: %w`, err)
+	}
+
+	if err := writeFile(s.path, buf); err != nil {
+		return fmt.Errorf(`failed to store %q: %w`, s.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package jwk
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/lestrrat-go/jwx/v2/jwa"
+)
+
+// KeyMatcher selects keys based on their fields, such as "kid", "kty",
+// "alg", "use", "key_ops" and "crv", as well as their thumbprints.
+// A key matches if it satisfies all of the specified criteria.
+//
+// The "use", "alg" and "key_ops" fields are optional, and by default keys
+// that do not specify them match any query on those fields. Specify
+// `jwk.WithMatchStrict(true)` to change this behavior.
+//
+// KeyMatcher objects are also used by `jws.WithKeySet()` and `jwe.WithKeySet()`
+// to choose the candidate keys in a `jwk.Set`.
+type KeyMatcher struct {
+	kid        *string
+	kty        *jwa.KeyType
+	alg        jwa.KeyAlgorithm
+	usage      KeyUsageType
+	ops        KeyOperationList
+	crv        *jwa.EllipticCurveAlgorithm
+	thumbprint *thumbprintMatch
+	strict     bool
+}
+
+// NewKeyMatcher creates a new KeyMatcher from the given options.
+func NewKeyMatcher(options ...KeyMatchOption) *KeyMatcher {
+	var m KeyMatcher
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identMatchKeyID{}:
+			v := option.Value().(string)
+			m.kid = &v
+		case identMatchKeyType{}:
+			v := option.Value().(jwa.KeyType)
+			m.kty = &v
+		case identMatchAlgorithm{}:
+			m.alg = option.Value().(jwa.KeyAlgorithm)
+		case identMatchKeyUsage{}:
+			m.usage = option.Value().(KeyUsageType)
+		case identMatchKeyOperations{}:
+			m.ops = option.Value().(KeyOperationList)
+		case identMatchCurve{}:
+			v := option.Value().(jwa.EllipticCurveAlgorithm)
+			m.crv = &v
+		case identMatchThumbprint{}:
+			v := option.Value().(thumbprintMatch)
+			m.thumbprint = &v
+		case identMatchStrict{}:
+			m.strict = option.Value().(bool)
+		}
+	}
+	return &m
+}
+
+// Match returns true if the key satisfies all of the criteria
+func (m *KeyMatcher) Match(key Key) bool {
+	if m.kid != nil && key.KeyID() != *m.kid {
+		return false
+	}
+
+	if m.kty != nil && key.KeyType() != *m.kty {
+		return false
+	}
+
+	if m.alg != nil {
+		if v := key.Algorithm().String(); v != "" || m.strict {
+			if v != m.alg.String() {
+				return false
+			}
+		}
+	}
+
+	if m.usage != "" {
+		if v := key.KeyUsage(); v != "" || m.strict {
+			if v != m.usage.String() {
+				return false
+			}
+		}
+	}
+
+	if len(m.ops) > 0 {
+		if ops := key.KeyOps(); len(ops) > 0 || m.strict {
+			for _, want := range m.ops {
+				var found bool
+				for _, op := range ops {
+					if op == want {
+						found = true
+						break
+					}
+				}
+				if !found {
+					return false
+				}
+			}
+		}
+	}
+
+	if m.crv != nil {
+		ck, ok := key.(interface {
+			Crv() jwa.EllipticCurveAlgorithm
+		})
+		if !ok || ck.Crv() != *m.crv {
+			return false
+		}
+	}
+
+	if m.thumbprint != nil {
+		tp, err := key.Thumbprint(m.thumbprint.hash)
+		if err != nil || !bytes.Equal(tp, m.thumbprint.value) {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupKeys returns the keys in `set` that match the criteria specified
+// by the options, in the order they appear in the set.
+//
+// See the documentation for `jwk.KeyMatcher` for details.
+func LookupKeys(set Set, options ...KeyMatchOption) []Key {
+	return NewKeyMatcher(options...).Select(set)
+}
+
+// Select returns the keys in `set` that match, in the order they appear in the set
+func (m *KeyMatcher) Select(set Set) []Key {
+	ctx := context.Background()
+	var keys []Key
+	for iter := set.Keys(ctx); iter.Next(ctx); {
+		//nolint:forcetypeassert
+		key := iter.Pair().Value.(Key)
+		if m.Match(key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// Filter returns a new `jwk.Set` containing the keys in `set` that match
+// the criteria specified by the options. The keys are shared between the
+// two sets.
+//
+// See the documentation for `jwk.KeyMatcher` for details.
+func Filter(set Set, options ...KeyMatchOption) (Set, error) {
+	filtered := NewSet()
+	for _, key := range LookupKeys(set, options...) {
+		if err := filtered.AddKey(key); err != nil {
+			return nil, err
+		}
+	}
+	return filtered, nil
+}
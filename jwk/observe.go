@@ -0,0 +1,340 @@
+package jwk
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/httpcc"
+)
+
+// CacheEventType describes the type of a `jwk.CacheEvent`
+type CacheEventType int
+
+const (
+	// CacheEventFetchStarted is emitted when a resource is about to be fetched
+	CacheEventFetchStarted CacheEventType = iota + 1
+	// CacheEventFetchSucceeded is emitted when a resource has been fetched
+	// and transformed successfully, and is about to be stored in the cache
+	CacheEventFetchSucceeded
+	// CacheEventFetchFailed is emitted when a resource could not be fetched
+	// or transformed. The previously cached contents are kept
+	CacheEventFetchFailed
+	// CacheEventSetChanged is emitted when the key IDs in a fetched
+	// `jwk.Set` differ from those in the previously fetched one
+	CacheEventSetChanged
+	// CacheEventRefreshScheduled is emitted when the next refresh of
+	// a resource has been scheduled
+	CacheEventRefreshScheduled
+)
+
+func (t CacheEventType) String() string {
+	switch t {
+	case CacheEventFetchStarted:
+		return `fetch_started`
+	case CacheEventFetchSucceeded:
+		return `fetch_succeeded`
+	case CacheEventFetchFailed:
+		return `fetch_failed`
+	case CacheEventSetChanged:
+		return `set_changed`
+	case CacheEventRefreshScheduled:
+		return `refresh_scheduled`
+	default:
+		return `unknown`
+	}
+}
+
+// Possible values for `(jwk.CacheEvent).RefreshReason`
+const (
+	RefreshReasonRefreshInterval    = `refresh_interval`
+	RefreshReasonCacheControl       = `cache_control`
+	RefreshReasonExpires            = `expires`
+	RefreshReasonMinRefreshInterval = `min_refresh_interval`
+)
+
+// CacheEvent describes something that happened while refreshing a
+// resource registered in a `jwk.Cache`. Which fields are populated
+// depends on `Type`.
+type CacheEvent struct {
+	Type CacheEventType
+	URL  string
+	Time time.Time
+
+	// Duration is the time it took to fetch and transform the resource.
+	// Available for CacheEventFetchSucceeded and CacheEventFetchFailed
+	Duration time.Duration
+
+	// StatusCode is the status code of the HTTP response. Available for
+	// CacheEventFetchSucceeded, and for CacheEventFetchFailed if a
+	// response was received
+	StatusCode int
+
+	// Error is the reason of the failure for CacheEventFetchFailed
+	Error error
+
+	// KeyCount is the number of keys in the fetched `jwk.Set`. Available
+	// for CacheEventFetchSucceeded and CacheEventSetChanged
+	KeyCount int
+
+	// Added and Removed contain the key IDs that were added to and
+	// removed from the `jwk.Set`, for CacheEventSetChanged
+	Added   []string
+	Removed []string
+
+	// NextRefresh is the time at which the resource is scheduled to
+	// be refreshed, and RefreshInterval is the interval until then.
+	// RefreshReason tells how the interval was determined (one of the
+	// `jwk.RefreshReason*` constants). Available for CacheEventRefreshScheduled
+	NextRefresh     time.Time
+	RefreshInterval time.Duration
+	RefreshReason   string
+}
+
+// CacheListener receives the `jwk.CacheEvent`s emitted by a `jwk.Cache`.
+// Use `jwk.WithCacheListener` to register a listener.
+//
+// Listeners are called synchronously from the goroutines that refresh the
+// resources, therefore they should return quickly and must not call the
+// methods of `jwk.Cache` for the same URL.
+type CacheListener interface {
+	OnCacheEvent(CacheEvent)
+}
+
+// CacheListenerFunc is a CacheListener based on a function.
+type CacheListenerFunc func(CacheEvent)
+
+func (f CacheListenerFunc) OnCacheEvent(ev CacheEvent) {
+	f(ev)
+}
+
+// CacheHealth describes the state of a single resource registered in
+// a `jwk.Cache`.
+type CacheHealth struct {
+	URL                 string    `json:"url"`
+	LastAttempt         time.Time `json:"last_attempt"`
+	LastSuccess         time.Time `json:"last_success"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	KeyCount            int       `json:"key_count"`
+	NextRefresh         time.Time `json:"next_refresh"`
+}
+
+// Healthy returns true if the resource has been fetched at least once,
+// and the last attempt to fetch it was successful.
+func (h CacheHealth) Healthy() bool {
+	return !h.LastSuccess.IsZero() && h.ConsecutiveFailures == 0
+}
+
+// observedResource sits between httprc and the client/transformer of a
+// single URL registered in a `jwk.Cache`, and keeps track of its health
+// while emitting events to the listeners
+type observedResource struct {
+	listeners          []CacheListener
+	client             HTTPClient
+	transform          Transformer
+	refreshInterval    time.Duration
+	minRefreshInterval time.Duration
+
+	mu       sync.Mutex
+	started  time.Time
+	fallback bool
+	health   CacheHealth
+	kids     map[string]struct{}
+}
+
+// fallbackClient is implemented by clients that substitute stored data
+// when the remote resource is unavailable (see storedResource). The
+// failure to fetch the remote resource is reported via `cause`.
+type fallbackClient interface {
+	getOrFallback(u string) (res *http.Response, status int, cause error, err error)
+}
+
+func (r *observedResource) emit(ev CacheEvent) {
+	for _, l := range r.listeners {
+		l.OnCacheEvent(ev)
+	}
+}
+
+func (r *observedResource) snapshot() CacheHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.health
+}
+
+// schedule determines the interval until the next refresh in the same
+// manner as httprc does
+func (r *observedResource) schedule(res *http.Response) (time.Duration, string) {
+	if r.refreshInterval > 0 {
+		return r.refreshInterval, RefreshReasonRefreshInterval
+	}
+
+	if res != nil {
+		if v := res.Header.Get(`Cache-Control`); v != "" {
+			if dir, err := httpcc.ParseResponse(v); err == nil {
+				if maxAge, ok := dir.MaxAge(); ok {
+					if d := time.Duration(maxAge) * time.Second; d > r.minRefreshInterval {
+						return d, RefreshReasonCacheControl
+					}
+					return r.minRefreshInterval, RefreshReasonMinRefreshInterval
+				}
+			}
+		}
+
+		if v := res.Header.Get(`Expires`); v != "" {
+			if expires, err := http.ParseTime(v); err == nil {
+				if d := time.Until(expires); d > r.minRefreshInterval {
+					return d, RefreshReasonExpires
+				}
+				return r.minRefreshInterval, RefreshReasonMinRefreshInterval
+			}
+		}
+	}
+	return r.minRefreshInterval, RefreshReasonMinRefreshInterval
+}
+
+func (r *observedResource) Get(u string) (*http.Response, error) {
+	now := time.Now()
+	r.mu.Lock()
+	r.started = now
+	r.health.LastAttempt = now
+	r.mu.Unlock()
+	r.emit(CacheEvent{Type: CacheEventFetchStarted, URL: u, Time: now})
+
+	var res *http.Response
+	var err error
+	var status int
+	var cause error
+	if fc, ok := r.client.(fallbackClient); ok {
+		res, status, cause, err = fc.getOrFallback(u)
+	} else {
+		res, err = r.client.Get(u)
+	}
+
+	interval, reason := r.schedule(res)
+	now = time.Now()
+	next := now.Add(interval)
+	r.mu.Lock()
+	r.health.NextRefresh = next
+	r.mu.Unlock()
+	r.emit(CacheEvent{
+		Type:            CacheEventRefreshScheduled,
+		URL:             u,
+		Time:            now,
+		NextRefresh:     next,
+		RefreshInterval: interval,
+		RefreshReason:   reason,
+	})
+
+	if err != nil {
+		r.fail(u, 0, err)
+		return nil, err
+	}
+
+	// The stored data is still used, but the remote resource has failed
+	r.mu.Lock()
+	r.fallback = cause != nil
+	r.mu.Unlock()
+	if cause != nil {
+		r.fail(u, status, cause)
+	}
+	return res, nil
+}
+
+func (r *observedResource) fail(u string, status int, err error) {
+	now := time.Now()
+	r.mu.Lock()
+	r.health.LastError = err.Error()
+	r.health.ConsecutiveFailures++
+	started := r.started
+	r.mu.Unlock()
+
+	r.emit(CacheEvent{
+		Type:       CacheEventFetchFailed,
+		URL:        u,
+		Time:       now,
+		Duration:   now.Sub(started),
+		StatusCode: status,
+		Error:      err,
+	})
+}
+
+func (r *observedResource) Transform(u string, res *http.Response) (interface{}, error) {
+	r.mu.Lock()
+	fallback := r.fallback
+	r.fallback = false
+	r.mu.Unlock()
+
+	v, err := r.transform.Transform(u, res)
+	if fallback {
+		// the failure has already been reported
+		return v, err
+	}
+	if err != nil {
+		r.fail(u, res.StatusCode, err)
+		return nil, err
+	}
+
+	now := time.Now()
+	set, isSet := v.(Set)
+
+	var count int
+	var added, removed []string
+	var changed bool
+	if isSet {
+		count = set.Len()
+		kids := make(map[string]struct{})
+		for i := 0; i < count; i++ {
+			key, _ := set.Key(i)
+			if kid := key.KeyID(); kid != "" {
+				kids[kid] = struct{}{}
+			}
+		}
+
+		r.mu.Lock()
+		for kid := range kids {
+			if _, ok := r.kids[kid]; !ok {
+				added = append(added, kid)
+			}
+		}
+		for kid := range r.kids {
+			if _, ok := kids[kid]; !ok {
+				removed = append(removed, kid)
+			}
+		}
+		changed = r.kids == nil || len(added) > 0 || len(removed) > 0
+		r.kids = kids
+		r.mu.Unlock()
+		sort.Strings(added)
+		sort.Strings(removed)
+	}
+
+	r.mu.Lock()
+	r.health.LastSuccess = now
+	r.health.LastError = ""
+	r.health.ConsecutiveFailures = 0
+	r.health.KeyCount = count
+	started := r.started
+	r.mu.Unlock()
+
+	r.emit(CacheEvent{
+		Type:       CacheEventFetchSucceeded,
+		URL:        u,
+		Time:       now,
+		Duration:   now.Sub(started),
+		StatusCode: res.StatusCode,
+		KeyCount:   count,
+	})
+	if changed {
+		r.emit(CacheEvent{
+			Type:     CacheEventSetChanged,
+			URL:      u,
+			Time:     now,
+			KeyCount: count,
+			Added:    added,
+			Removed:  removed,
+		})
+	}
+	return v, nil
+}
@@ -6,10 +6,12 @@ import (
 	"crypto/ed25519"
 	"fmt"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/blackmagic"
 	"github.com/lestrrat-go/jwx/v2/internal/base64"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/x25519"
+	"github.com/lestrrat-go/jwx/v2/x448"
 )
 
 func (k *okpPublicKey) FromRaw(rawKeyIf interface{}) error {
@@ -22,10 +24,18 @@ func (k *okpPublicKey) FromRaw(rawKeyIf interface{}) error {
 		k.x = rawKey
 		crv = jwa.Ed25519
 		k.crv = &crv
+	case ed448.PublicKey:
+		k.x = rawKey
+		crv = jwa.Ed448
+		k.crv = &crv
 	case x25519.PublicKey:
 		k.x = rawKey
 		crv = jwa.X25519
 		k.crv = &crv
+	case x448.PublicKey:
+		k.x = rawKey
+		crv = jwa.X448
+		k.crv = &crv
 	default:
 		return fmt.Errorf(`unknown key type %T`, rawKeyIf)
 	}
@@ -44,11 +54,21 @@ func (k *okpPrivateKey) FromRaw(rawKeyIf interface{}) error {
 		k.x = rawKey.Public().(ed25519.PublicKey) //nolint:forcetypeassert
 		crv = jwa.Ed25519
 		k.crv = &crv
+	case ed448.PrivateKey:
+		k.d = rawKey.Seed()
+		k.x = rawKey.Public().(ed448.PublicKey) //nolint:forcetypeassert
+		crv = jwa.Ed448
+		k.crv = &crv
 	case x25519.PrivateKey:
 		k.d = rawKey.Seed()
 		k.x = rawKey.Public().(x25519.PublicKey) //nolint:forcetypeassert
 		crv = jwa.X25519
 		k.crv = &crv
+	case x448.PrivateKey:
+		k.d = rawKey.Seed()
+		k.x = rawKey.Public().(x448.PublicKey) //nolint:forcetypeassert
+		crv = jwa.X448
+		k.crv = &crv
 	default:
 		return fmt.Errorf(`unknown key type %T`, rawKeyIf)
 	}
@@ -60,8 +80,12 @@ func buildOKPPublicKey(alg jwa.EllipticCurveAlgorithm, xbuf []byte) (interface{}
 	switch alg {
 	case jwa.Ed25519:
 		return ed25519.PublicKey(xbuf), nil
+	case jwa.Ed448:
+		return ed448.PublicKey(xbuf), nil
 	case jwa.X25519:
 		return x25519.PublicKey(xbuf), nil
+	case jwa.X448:
+		return x448.PublicKey(xbuf), nil
 	default:
 		return nil, fmt.Errorf(`invalid curve algorithm %s`, alg)
 	}
@@ -89,6 +113,16 @@ func buildOKPPrivateKey(alg jwa.EllipticCurveAlgorithm, xbuf []byte, dbuf []byte
 			return nil, fmt.Errorf(`invalid x value given d value`)
 		}
 		return ret, nil
+	case jwa.Ed448:
+		if len(dbuf) != ed448.SeedSize {
+			return nil, fmt.Errorf(`unexpected seed size for ed448 private key: %d`, len(dbuf))
+		}
+		ret := ed448.NewKeyFromSeed(dbuf)
+		//nolint:forcetypeassert
+		if !bytes.Equal(xbuf, ret.Public().(ed448.PublicKey)) {
+			return nil, fmt.Errorf(`invalid x value given d value`)
+		}
+		return ret, nil
 	case jwa.X25519:
 		ret, err := x25519.NewKeyFromSeed(dbuf)
 		if err != nil {
@@ -99,6 +133,16 @@ func buildOKPPrivateKey(alg jwa.EllipticCurveAlgorithm, xbuf []byte, dbuf []byte
 			return nil, fmt.Errorf(`invalid x value given d value`)
 		}
 		return ret, nil
+	case jwa.X448:
+		ret, err := x448.NewKeyFromSeed(dbuf)
+		if err != nil {
+			return nil, fmt.Errorf(`unable to construct x448 private key from seed: %w`, err)
+		}
+		//nolint:forcetypeassert
+		if !bytes.Equal(xbuf, ret.Public().(x448.PublicKey)) {
+			return nil, fmt.Errorf(`invalid x value given d value`)
+		}
+		return ret, nil
 	default:
 		return nil, fmt.Errorf(`invalid curve algorithm %s`, alg)
 	}
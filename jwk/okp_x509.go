@@ -0,0 +1,147 @@
+package jwk
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"fmt"
+
+	"github.com/cloudflare/circl/sign/ed448"
+	"github.com/lestrrat-go/jwx/v2/x448"
+)
+
+// The x509 package in the standard library does not know about some of
+// the OKP curves that we support, so we need to do the ASN.1 encoding
+// and decoding (RFC 8410) ourselves for those.
+
+var (
+	oidPublicKeyX448  = asn1.ObjectIdentifier{1, 3, 101, 111}
+	oidPublicKeyEd448 = asn1.ObjectIdentifier{1, 3, 101, 113}
+)
+
+type okpPKCS8PrivateKey struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+type okpPKIXPublicKey struct {
+	Algo      pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+func marshalOKPPKCS8PrivateKey(oid asn1.ObjectIdentifier, seed []byte) ([]byte, error) {
+	// RFC 8410 wraps the seed in an extra OCTET STRING (CurvePrivateKey)
+	curvePrivateKey, err := asn1.Marshal(seed)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to marshal private key: %w`, err)
+	}
+
+	return asn1.Marshal(okpPKCS8PrivateKey{
+		Algo:       pkix.AlgorithmIdentifier{Algorithm: oid},
+		PrivateKey: curvePrivateKey,
+	})
+}
+
+func marshalOKPPKIXPublicKey(oid asn1.ObjectIdentifier, pubkey []byte) ([]byte, error) {
+	return asn1.Marshal(okpPKIXPublicKey{
+		Algo: pkix.AlgorithmIdentifier{Algorithm: oid},
+		PublicKey: asn1.BitString{
+			Bytes:     pubkey,
+			BitLength: 8 * len(pubkey),
+		},
+	})
+}
+
+// marshalPKCS8PrivateKey is a wrapper around x509.MarshalPKCS8PrivateKey that
+// also handles the OKP keys that the x509 package does not support
+func marshalPKCS8PrivateKey(key interface{}) ([]byte, error) {
+	switch key := key.(type) {
+	case ed448.PrivateKey:
+		return marshalOKPPKCS8PrivateKey(oidPublicKeyEd448, key.Seed())
+	case x448.PrivateKey:
+		return marshalOKPPKCS8PrivateKey(oidPublicKeyX448, key.Seed())
+	default:
+		return x509.MarshalPKCS8PrivateKey(key)
+	}
+}
+
+// marshalPKIXPublicKey is a wrapper around x509.MarshalPKIXPublicKey that
+// also handles the OKP keys that the x509 package does not support
+func marshalPKIXPublicKey(key interface{}) ([]byte, error) {
+	switch key := key.(type) {
+	case ed448.PublicKey:
+		return marshalOKPPKIXPublicKey(oidPublicKeyEd448, key)
+	case x448.PublicKey:
+		return marshalOKPPKIXPublicKey(oidPublicKeyX448, key)
+	default:
+		return x509.MarshalPKIXPublicKey(key)
+	}
+}
+
+// parsePKCS8PrivateKey is a wrapper around x509.ParsePKCS8PrivateKey that
+// also handles the OKP keys that the x509 package does not support
+func parsePKCS8PrivateKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return key, nil
+	}
+
+	var privkey okpPKCS8PrivateKey
+	if _, asnerr := asn1.Unmarshal(der, &privkey); asnerr != nil {
+		return nil, err
+	}
+
+	switch {
+	case privkey.Algo.Algorithm.Equal(oidPublicKeyEd448):
+		var seed []byte
+		if _, err := asn1.Unmarshal(privkey.PrivateKey, &seed); err != nil {
+			return nil, fmt.Errorf(`failed to unmarshal ed448 private key: %w`, err)
+		}
+		if len(seed) != ed448.SeedSize {
+			return nil, fmt.Errorf(`invalid ed448 private key length: %d`, len(seed))
+		}
+		return ed448.NewKeyFromSeed(seed), nil
+	case privkey.Algo.Algorithm.Equal(oidPublicKeyX448):
+		var seed []byte
+		if _, err := asn1.Unmarshal(privkey.PrivateKey, &seed); err != nil {
+			return nil, fmt.Errorf(`failed to unmarshal x448 private key: %w`, err)
+		}
+		key, err := x448.NewKeyFromSeed(seed)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to create x448 private key: %w`, err)
+		}
+		return key, nil
+	default:
+		return nil, err
+	}
+}
+
+// parsePKIXPublicKey is a wrapper around x509.ParsePKIXPublicKey that
+// also handles the OKP keys that the x509 package does not support
+func parsePKIXPublicKey(der []byte) (interface{}, error) {
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err == nil {
+		return key, nil
+	}
+
+	var pubkey okpPKIXPublicKey
+	if _, asnerr := asn1.Unmarshal(der, &pubkey); asnerr != nil {
+		return nil, err
+	}
+
+	switch {
+	case pubkey.Algo.Algorithm.Equal(oidPublicKeyEd448):
+		if len(pubkey.PublicKey.Bytes) != ed448.PublicKeySize {
+			return nil, fmt.Errorf(`invalid ed448 public key length: %d`, len(pubkey.PublicKey.Bytes))
+		}
+		return ed448.PublicKey(pubkey.PublicKey.Bytes), nil
+	case pubkey.Algo.Algorithm.Equal(oidPublicKeyX448):
+		if len(pubkey.PublicKey.Bytes) != x448.PublicKeySize {
+			return nil, fmt.Errorf(`invalid x448 public key length: %d`, len(pubkey.PublicKey.Bytes))
+		}
+		return x448.PublicKey(pubkey.PublicKey.Bytes), nil
+	default:
+		return nil, err
+	}
+}
@@ -1,6 +1,8 @@
 package jwk
 
 import (
+	"crypto"
+
 	"github.com/lestrrat-go/option"
 )
 
@@ -36,3 +38,32 @@ func WithTypedField(name string, object interface{}) ParseOption {
 		),
 	}
 }
+
+type identMatchKeyOperations struct{}
+type identMatchThumbprint struct{}
+
+type thumbprintMatch struct {
+	hash  crypto.Hash
+	value []byte
+}
+
+// WithMatchKeyOperations specifies that only keys whose "key_ops" field
+// contains all of the given operations match. Keys that do not specify
+// "key_ops" match as well, unless `jwk.WithMatchStrict(true)` is specified.
+func WithMatchKeyOperations(ops ...KeyOperation) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchKeyOperations{}, KeyOperationList(ops))}
+}
+
+// WithMatchThumbprint specifies that only keys whose JWK thumbprint
+// (RFC 7638), computed using `hash`, is equal to `value` match.
+func WithMatchThumbprint(hash crypto.Hash, value []byte) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchThumbprint{}, thumbprintMatch{hash: hash, value: value})}
+}
+
+type identKeyOperations struct{}
+
+// WithKeyOperations specifies the value of the "key_ops" field of the key
+// generated by `jwk.Generate()`.
+func WithKeyOperations(ops ...KeyOperation) GenerateOption {
+	return &generateOption{option.New(identKeyOperations{}, KeyOperationList(ops))}
+}
@@ -8,14 +8,31 @@ import (
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/option"
 )
 
 type Option = option.Interface
 
+// AggregateSetOption is a type of Option that can be passed to `jwk.NewAggregateSet()`
+type AggregateSetOption interface {
+	Option
+	aggregateSetOption()
+}
+
+type aggregateSetOption struct {
+	Option
+}
+
+func (*aggregateSetOption) aggregateSetOption() {}
+
+// AssignKeyIDOption is a type of Option that can be passed to `jwk.AssignKeyID()`
+// AssignKeyIDOption also implements the `GenerateOption`, and thus can
+// safely be passed to `jwk.Generate()`
 type AssignKeyIDOption interface {
 	Option
 	assignKeyIDOption()
+	generateOption()
 }
 
 type assignKeyIDOption struct {
@@ -24,6 +41,8 @@ type assignKeyIDOption struct {
 
 func (*assignKeyIDOption) assignKeyIDOption() {}
 
+func (*assignKeyIDOption) generateOption() {}
+
 // CacheOption is a type of Option that can be passed to the
 // `jwk.Cache` object.
 type CacheOption interface {
@@ -37,6 +56,67 @@ type cacheOption struct {
 
 func (*cacheOption) cacheOption() {}
 
+// CachedSetOption is a type of Option that can be passed to `jwk.NewCachedSet()`
+type CachedSetOption interface {
+	Option
+	cachedSetOption()
+}
+
+type cachedSetOption struct {
+	Option
+}
+
+func (*cachedSetOption) cachedSetOption() {}
+
+// GenerateOption is a type of Option that can be passed to `jwk.Generate()`
+type GenerateOption interface {
+	Option
+	generateOption()
+}
+
+type generateOption struct {
+	Option
+}
+
+func (*generateOption) generateOption() {}
+
+// HandlerOption is a type of Option that can be passed to `jwk.NewHandler()`
+type HandlerOption interface {
+	Option
+	handlerOption()
+}
+
+type handlerOption struct {
+	Option
+}
+
+func (*handlerOption) handlerOption() {}
+
+// KeyMatchOption is a type of Option that can be passed to `jwk.LookupKeys()`,
+// `jwk.Filter()` and `jwk.NewKeyMatcher()`
+type KeyMatchOption interface {
+	Option
+	keyMatchOption()
+}
+
+type keyMatchOption struct {
+	Option
+}
+
+func (*keyMatchOption) keyMatchOption() {}
+
+// KeyRingOption is a type of Option that can be passed to `jwk.NewKeyRing()`
+type KeyRingOption interface {
+	Option
+	keyRingOption()
+}
+
+type keyRingOption struct {
+	Option
+}
+
+func (*keyRingOption) keyRingOption() {}
+
 // FetchOption is a type of Option that can be passed to `jwk.Fetch()`
 // FetchOption also implements the `CacheOption`, and thus can
 // safely be passed to `(*jwk.Cache).Configure()`
@@ -101,19 +181,67 @@ type registerOption struct {
 
 func (*registerOption) registerOption() {}
 
+type identAssignKeyID struct{}
+type identCacheListener struct{}
+type identClock struct{}
+type identCurve struct{}
+type identDiscoveryURL struct{}
+type identDuplicateKeyIDs struct{}
 type identErrSink struct{}
 type identFS struct{}
 type identFetchWhitelist struct{}
 type identHTTPClient struct{}
 type identIgnoreParseError struct{}
+type identKeyAlgorithm struct{}
+type identKeyRingStorage struct{}
+type identKeySize struct{}
+type identKeyUsage struct{}
+type identLoader struct{}
 type identLocalRegistry struct{}
+type identMatchAlgorithm struct{}
+type identMatchCurve struct{}
+type identMatchKeyID struct{}
+type identMatchKeyType struct{}
+type identMatchKeyUsage struct{}
+type identMatchStrict struct{}
+type identMaxAge struct{}
 type identMinRefreshInterval struct{}
 type identPEM struct{}
 type identPostFetcher struct{}
+type identPrePublishPeriod struct{}
+type identRefreshCooldown struct{}
 type identRefreshInterval struct{}
+type identRefreshOnUnknownKeyID struct{}
 type identRefreshWindow struct{}
+type identRetentionPeriod struct{}
+type identRotationPeriod struct{}
+type identStorage struct{}
 type identThumbprintHash struct{}
 
+func (identAssignKeyID) String() string {
+	return "WithAssignKeyID"
+}
+
+func (identCacheListener) String() string {
+	return "WithCacheListener"
+}
+
+func (identClock) String() string {
+	return "WithClock"
+}
+
+func (identCurve) String() string {
+	return "WithCurve"
+}
+
+func (identDiscoveryURL) String() string {
+	return "WithDiscoveryURL"
+}
+
+func (identDuplicateKeyIDs) String() string {
+	return "WithDuplicateKeyIDs"
+}
+
 func (identErrSink) String() string {
 	return "WithErrSink"
 }
@@ -134,10 +262,58 @@ func (identIgnoreParseError) String() string {
 	return "WithIgnoreParseError"
 }
 
+func (identKeyAlgorithm) String() string {
+	return "WithKeyAlgorithm"
+}
+
+func (identKeyRingStorage) String() string {
+	return "WithKeyRingStorage"
+}
+
+func (identKeySize) String() string {
+	return "WithKeySize"
+}
+
+func (identKeyUsage) String() string {
+	return "WithKeyUsage"
+}
+
+func (identLoader) String() string {
+	return "WithLoader"
+}
+
 func (identLocalRegistry) String() string {
 	return "withLocalRegistry"
 }
 
+func (identMatchAlgorithm) String() string {
+	return "WithMatchAlgorithm"
+}
+
+func (identMatchCurve) String() string {
+	return "WithMatchCurve"
+}
+
+func (identMatchKeyID) String() string {
+	return "WithMatchKeyID"
+}
+
+func (identMatchKeyType) String() string {
+	return "WithMatchKeyType"
+}
+
+func (identMatchKeyUsage) String() string {
+	return "WithMatchKeyUsage"
+}
+
+func (identMatchStrict) String() string {
+	return "WithMatchStrict"
+}
+
+func (identMaxAge) String() string {
+	return "WithMaxAge"
+}
+
 func (identMinRefreshInterval) String() string {
 	return "WithMinRefreshInterval"
 }
@@ -150,18 +326,100 @@ func (identPostFetcher) String() string {
 	return "WithPostFetcher"
 }
 
+func (identPrePublishPeriod) String() string {
+	return "WithPrePublishPeriod"
+}
+
+func (identRefreshCooldown) String() string {
+	return "WithRefreshCooldown"
+}
+
 func (identRefreshInterval) String() string {
 	return "WithRefreshInterval"
 }
 
+func (identRefreshOnUnknownKeyID) String() string {
+	return "WithRefreshOnUnknownKeyID"
+}
+
 func (identRefreshWindow) String() string {
 	return "WithRefreshWindow"
 }
 
+func (identRetentionPeriod) String() string {
+	return "WithRetentionPeriod"
+}
+
+func (identRotationPeriod) String() string {
+	return "WithRotationPeriod"
+}
+
+func (identStorage) String() string {
+	return "WithStorage"
+}
+
 func (identThumbprintHash) String() string {
 	return "WithThumbprintHash"
 }
 
+// WithAssignKeyID specifies that the "kid" field of the key generated
+// by `jwk.Generate()` should be assigned using `jwk.AssignKeyID()`.
+// Use `jwk.WithThumbprintHash()` to specify the hash function.
+func WithAssignKeyID(v bool) GenerateOption {
+	return &generateOption{option.New(identAssignKeyID{}, v)}
+}
+
+// WithCacheListener specifies a `jwk.CacheListener` that receives events
+// about the refresh lifecycle of the resources registered in the `jwk.Cache`,
+// such as fetches, changes to the key IDs in the fetched `jwk.Set`, and
+// refresh scheduling decisions.
+//
+// This option may be specified multiple times, in which case the listeners
+// are called in the order they were specified.
+func WithCacheListener(v CacheListener) CacheOption {
+	return &cacheOption{option.New(identCacheListener{}, v)}
+}
+
+// WithClock specifies the `jwk.Clock` used by `jwk.KeyRing` to determine
+// the current time. By default `time.Now()` is used.
+func WithClock(v Clock) KeyRingOption {
+	return &keyRingOption{option.New(identClock{}, v)}
+}
+
+// WithCurve specifies the curve of the EC or OKP key generated by
+// `jwk.Generate()`. EC keys may use any of the curves returned by
+// `jwk.AvailableCurves()`, and OKP keys may use Ed25519, Ed448, X25519
+// or X448. If unspecified, the curve is determined by the algorithm
+// specified via `jwk.WithKeyAlgorithm()`, or defaults to P-256 and
+// Ed25519, respectively.
+func WithCurve(v jwa.EllipticCurveAlgorithm) GenerateOption {
+	return &generateOption{option.New(identCurve{}, v)}
+}
+
+// WithDiscoveryURL specifies the URL of the authorization server metadata
+// document to be used by `(*jwk.Cache).RegisterIssuer()`. By default the
+// OpenID Connect discovery document located at
+// `{issuer}/.well-known/openid-configuration` is used. Use this option to
+// specify other locations, such as the RFC 8414 metadata URL
+// (`https://{host}/.well-known/oauth-authorization-server{path}`).
+//
+// This option has no effect when used with `(*jwk.Cache).Register()`.
+func WithDiscoveryURL(v string) RegisterOption {
+	return &registerOption{option.New(identDiscoveryURL{}, v)}
+}
+
+// WithDuplicateKeyIDs specifies whether a `jwk.AggregateSet` should expose
+// keys whose key ID is also used by a key in a set that precedes it.
+//
+// By default such keys are hidden, so that each key ID is resolved to
+// the keys in the first set that contains it. When set to true, all keys
+// are exposed, which allows `jws.WithKeySet()` to try every key with a
+// matching key ID (see `jws.WithMultipleKeysPerKeyID()`). `LookupKeyID()`
+// always returns the key from the first set.
+func WithDuplicateKeyIDs(v bool) AggregateSetOption {
+	return &aggregateSetOption{option.New(identDuplicateKeyIDs{}, v)}
+}
+
 // WithErrSink specifies the `httprc.ErrSink` object that handles errors
 // that occurred during the cache's execution.
 //
@@ -216,6 +474,102 @@ func withLocalRegistry(v *json.Registry) ParseOption {
 	return &parseOption{option.New(identLocalRegistry{}, v)}
 }
 
+// WithKeyAlgorithm specifies the value of the "alg" field of the key
+// generated by `jwk.Generate()`. `jwk.Generate()` returns an error if
+// the key type, size or curve is not suitable for the algorithm.
+func WithKeyAlgorithm(v jwa.KeyAlgorithm) GenerateOption {
+	return &generateOption{option.New(identKeyAlgorithm{}, v)}
+}
+
+// WithKeyRingStorage specifies a `jwk.KeyRingStorage` that is used to persist
+// the keys in a `jwk.KeyRing`. The stored keys are loaded when the key ring
+// is created, and the keys are stored every time they change.
+func WithKeyRingStorage(v KeyRingStorage) KeyRingOption {
+	return &keyRingOption{option.New(identKeyRingStorage{}, v)}
+}
+
+// WithKeySize specifies the size of the key generated by `jwk.Generate()`
+// in bits. It applies to RSA keys (default 2048) and symmetric keys
+// (default 256, or the size required by the algorithm specified via
+// `jwk.WithKeyAlgorithm()`). The size of symmetric keys must be a
+// multiple of 8.
+func WithKeySize(v int) GenerateOption {
+	return &generateOption{option.New(identKeySize{}, v)}
+}
+
+// WithKeyUsage specifies the value of the "use" field of the key
+// generated by `jwk.Generate()`.
+func WithKeyUsage(v KeyUsageType) GenerateOption {
+	return &generateOption{option.New(identKeyUsage{}, v)}
+}
+
+// WithLoader specifies a `jwk.Loader` that is used to load the jwk.Set
+// registered in a `jwk.Cache`, instead of fetching it via HTTP. The URL
+// passed to `(*jwk.Cache).Register()` is only used as the key to identify
+// the jwk.Set, and is passed to the loader as is.
+//
+// Sets returned by the loader are scheduled for refresh, passed to the
+// `jwk.PostFetcher`, and reported to the error sink just like those
+// fetched via HTTP. However, they are not parsed, therefore options
+// such as `jwk.WithPEM()` do not apply.
+//
+// URLs with the "file" scheme do not require this option: see
+// `(*jwk.Cache).Register()` for details.
+func WithLoader(v Loader) RegisterOption {
+	return &registerOption{option.New(identLoader{}, v)}
+}
+
+// WithMatchAlgorithm specifies that only keys whose "alg" field is equal
+// to the given algorithm match. Keys that do not specify "alg" match
+// as well, unless `jwk.WithMatchStrict(true)` is specified.
+func WithMatchAlgorithm(v jwa.KeyAlgorithm) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchAlgorithm{}, v)}
+}
+
+// WithMatchCurve specifies that only keys on the given curve ("crv") match.
+// This applies to EC and OKP keys: other types of keys never match.
+func WithMatchCurve(v jwa.EllipticCurveAlgorithm) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchCurve{}, v)}
+}
+
+// WithMatchKeyID specifies that only keys with the given key ID ("kid") match.
+func WithMatchKeyID(v string) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchKeyID{}, v)}
+}
+
+// WithMatchKeyType specifies that only keys of the given key type ("kty") match.
+func WithMatchKeyType(v jwa.KeyType) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchKeyType{}, v)}
+}
+
+// WithMatchKeyUsage specifies that only keys whose "use" field is equal
+// to the given usage match. Keys that do not specify "use" match
+// as well, unless `jwk.WithMatchStrict(true)` is specified.
+func WithMatchKeyUsage(v KeyUsageType) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchKeyUsage{}, v)}
+}
+
+// WithMatchStrict specifies that keys that do not specify the "use",
+// "alg" or "key_ops" fields should not match queries on those fields.
+//
+// By default such keys match, as these fields are optional, and a key
+// that does not specify them may be used for any purpose.
+func WithMatchStrict(v bool) KeyMatchOption {
+	return &keyMatchOption{option.New(identMatchStrict{}, v)}
+}
+
+// WithMaxAge specifies the value of the max-age directive in the Cache-Control
+// header sent by `jwk.Handler`. The default is 15 minutes, which is the same
+// as the default minimum refresh interval of `jwk.Cache`: consumers using
+// `jwk.Cache` with the default settings refresh the jwk.Set at this interval.
+//
+// When serving the keys in a `jwk.KeyRing`, this value should be shorter
+// than the pre-publish period, so that consumers obtain new keys before
+// they are used.
+func WithMaxAge(v time.Duration) HandlerOption {
+	return &handlerOption{option.New(identMaxAge{}, v)}
+}
+
 // WithMinRefreshInterval specifies the minimum refresh interval to be used
 // when using `jwk.Cache`. This value is ONLY used if you did not specify
 // a user-supplied static refresh interval via `WithRefreshInterval`.
@@ -252,6 +606,26 @@ func WithPostFetcher(v PostFetcher) RegisterOption {
 	return &registerOption{option.New(identPostFetcher{}, v)}
 }
 
+// WithPrePublishPeriod specifies how long before its activation the next
+// key in a `jwk.KeyRing` is generated and published as a pending key.
+// This should be longer than the interval at which consumers refresh the
+// published `jwk.Set`. The default is 1 day.
+func WithPrePublishPeriod(v time.Duration) KeyRingOption {
+	return &keyRingOption{option.New(identPrePublishPeriod{}, v)}
+}
+
+// WithRefreshCooldown specifies the minimum interval between refreshes
+// of the same URL that are triggered by a lookup for an unknown key ID
+// (see `jwk.WithRefreshOnUnknownKeyID`). Lookups during this period
+// do not cause the resource to be fetched again, which prevents
+// unknown key IDs sent by an attacker from overwhelming the remote server.
+//
+// The default value is 1 minute. Values less than or equal to 0 are
+// ignored, as the cooldown period can not be disabled.
+func WithRefreshCooldown(v time.Duration) CacheOption {
+	return &cacheOption{option.New(identRefreshCooldown{}, v)}
+}
+
 // WithRefreshInterval specifies the static interval between refreshes
 // of jwk.Set objects controlled by jwk.Cache.
 //
@@ -262,6 +636,17 @@ func WithRefreshInterval(v time.Duration) RegisterOption {
 	return &registerOption{option.New(identRefreshInterval{}, v)}
 }
 
+// WithRefreshOnUnknownKeyID specifies that `LookupKeyID()` on a `jwk.CachedSet`
+// should refresh the underlying `jwk.Cache` when the requested key ID
+// is not found, in order to pick up keys that were recently rotated.
+//
+// The refresh is done using `(*jwk.Cache).RefreshThrottled()`, so it happens
+// at most once per cooldown period (see `jwk.WithRefreshCooldown`) for each URL,
+// and concurrent lookups share a single request.
+func WithRefreshOnUnknownKeyID(v bool) CachedSetOption {
+	return &cachedSetOption{option.New(identRefreshOnUnknownKeyID{}, v)}
+}
+
 // WithRefreshWindow specifies the interval between checks for refreshes.
 //
 // See the documentation in `httprc.WithRefreshWindow` for more details.
@@ -269,6 +654,31 @@ func WithRefreshWindow(v time.Duration) CacheOption {
 	return &cacheOption{option.New(identRefreshWindow{}, v)}
 }
 
+// WithRetentionPeriod specifies how long a key in a `jwk.KeyRing` is
+// published after it has been retired. This should be longer than the
+// lifetime of the tokens signed using the key. The default is 1 day.
+func WithRetentionPeriod(v time.Duration) KeyRingOption {
+	return &keyRingOption{option.New(identRetentionPeriod{}, v)}
+}
+
+// WithRotationPeriod specifies how long each key in a `jwk.KeyRing` is
+// used as the active key. The default is 30 days.
+func WithRotationPeriod(v time.Duration) KeyRingOption {
+	return &keyRingOption{option.New(identRotationPeriod{}, v)}
+}
+
+// WithStorage specifies a `jwk.CacheStorage` that is used to persist
+// the `jwk.Set` objects fetched by the `jwk.Cache`, so that they are
+// available immediately after the program is restarted.
+//
+// When a storage is specified, the entry stored for a URL is loaded when
+// it is registered using `Register()`. Refreshes send conditional requests
+// using the stored ETag and Last-Modified values, and if the remote
+// resource can not be fetched, the stored `jwk.Set` continues to be used.
+func WithStorage(v CacheStorage) CacheOption {
+	return &cacheOption{option.New(identStorage{}, v)}
+}
+
 func WithThumbprintHash(v crypto.Hash) AssignKeyIDOption {
 	return &assignKeyIDOption{option.New(identThumbprintHash{}, v)}
 }
@@ -9,16 +9,40 @@ import (
 )
 
 func TestOptionIdent(t *testing.T) {
+	require.Equal(t, "WithAssignKeyID", identAssignKeyID{}.String())
+	require.Equal(t, "WithCacheListener", identCacheListener{}.String())
+	require.Equal(t, "WithClock", identClock{}.String())
+	require.Equal(t, "WithCurve", identCurve{}.String())
+	require.Equal(t, "WithDiscoveryURL", identDiscoveryURL{}.String())
+	require.Equal(t, "WithDuplicateKeyIDs", identDuplicateKeyIDs{}.String())
 	require.Equal(t, "WithErrSink", identErrSink{}.String())
 	require.Equal(t, "WithFS", identFS{}.String())
 	require.Equal(t, "WithFetchWhitelist", identFetchWhitelist{}.String())
 	require.Equal(t, "WithHTTPClient", identHTTPClient{}.String())
 	require.Equal(t, "WithIgnoreParseError", identIgnoreParseError{}.String())
+	require.Equal(t, "WithKeyAlgorithm", identKeyAlgorithm{}.String())
+	require.Equal(t, "WithKeyRingStorage", identKeyRingStorage{}.String())
+	require.Equal(t, "WithKeySize", identKeySize{}.String())
+	require.Equal(t, "WithKeyUsage", identKeyUsage{}.String())
+	require.Equal(t, "WithLoader", identLoader{}.String())
 	require.Equal(t, "withLocalRegistry", identLocalRegistry{}.String())
+	require.Equal(t, "WithMatchAlgorithm", identMatchAlgorithm{}.String())
+	require.Equal(t, "WithMatchCurve", identMatchCurve{}.String())
+	require.Equal(t, "WithMatchKeyID", identMatchKeyID{}.String())
+	require.Equal(t, "WithMatchKeyType", identMatchKeyType{}.String())
+	require.Equal(t, "WithMatchKeyUsage", identMatchKeyUsage{}.String())
+	require.Equal(t, "WithMatchStrict", identMatchStrict{}.String())
+	require.Equal(t, "WithMaxAge", identMaxAge{}.String())
 	require.Equal(t, "WithMinRefreshInterval", identMinRefreshInterval{}.String())
 	require.Equal(t, "WithPEM", identPEM{}.String())
 	require.Equal(t, "WithPostFetcher", identPostFetcher{}.String())
+	require.Equal(t, "WithPrePublishPeriod", identPrePublishPeriod{}.String())
+	require.Equal(t, "WithRefreshCooldown", identRefreshCooldown{}.String())
 	require.Equal(t, "WithRefreshInterval", identRefreshInterval{}.String())
+	require.Equal(t, "WithRefreshOnUnknownKeyID", identRefreshOnUnknownKeyID{}.String())
 	require.Equal(t, "WithRefreshWindow", identRefreshWindow{}.String())
+	require.Equal(t, "WithRetentionPeriod", identRetentionPeriod{}.String())
+	require.Equal(t, "WithRotationPeriod", identRotationPeriod{}.String())
+	require.Equal(t, "WithStorage", identStorage{}.String())
 	require.Equal(t, "WithThumbprintHash", identThumbprintHash{}.String())
 }
@@ -6,14 +6,19 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/lestrrat-go/jwx/v2/internal/json"
 	"github.com/lestrrat-go/jwx/v2/internal/jwxtest"
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //nolint:revive,golint
@@ -47,6 +52,14 @@ func checkAccessCount(t *testing.T, ctx context.Context, src jwk.Set, expected .
 	return assert.Failf(t, `checking access count failed`, `key.Get("accessCount") should be one of %s (got %f)`, buf.String(), v)
 }
 
+// newKeyWithID generates an RSA key with the key ID `kid`
+func newKeyWithID(t *testing.T, kid string) jwk.Key {
+	t.Helper()
+	key, err := jwxtest.GenerateRsaJwkWithKeyID(kid)
+	require.NoError(t, err, `jwxtest.GenerateRsaJwkWithKeyID should succeed`)
+	return key
+}
+
 func TestCache(t *testing.T) {
 	t.Parallel()
 
@@ -549,3 +562,736 @@ func TestPostFetch(t *testing.T) {
 		})
 	}
 }
+
+func TestRegisterIssuer(t *testing.T) {
+	t.Parallel()
+
+	newSet := func(t *testing.T) jwk.Set {
+		t.Helper()
+		key, err := jwxtest.GenerateRsaJwk()
+		require.NoError(t, err, `jwxtest.GenerateRsaJwk should succeed`)
+		require.NoError(t, key.Set(jwk.KeyIDKey, fmt.Sprintf(`%p`, key)), `key.Set should succeed`)
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+		return set
+	}
+
+	type server struct {
+		*httptest.Server
+		mu      sync.Mutex
+		issuer  string
+		jwksURI string
+		sets    map[string]jwk.Set
+	}
+	newServer := func(t *testing.T) *server {
+		t.Helper()
+		s := &server{sets: make(map[string]jwk.Set)}
+		mux := http.NewServeMux()
+		metadata := func(w http.ResponseWriter, _ *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			w.Header().Set(`Content-Type`, `application/json`)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				`issuer`:   s.issuer,
+				`jwks_uri`: s.jwksURI,
+			})
+		}
+		mux.HandleFunc(`/.well-known/openid-configuration`, metadata)
+		mux.HandleFunc(`/.well-known/oauth-authorization-server`, metadata)
+		mux.HandleFunc(`/`, func(w http.ResponseWriter, r *http.Request) {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			set, ok := s.sets[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set(`Content-Type`, `application/json`)
+			_ = json.NewEncoder(w).Encode(set)
+		})
+		s.Server = httptest.NewServer(mux)
+		s.issuer = s.URL
+		s.jwksURI = s.URL + `/jwks1`
+		return s
+	}
+
+	t.Run(`OpenID Connect discovery`, func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		srv := newServer(t)
+		defer srv.Close()
+		set1, set2 := newSet(t), newSet(t)
+		srv.sets[`/jwks1`] = set1
+		srv.sets[`/jwks2`] = set2
+
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.RegisterIssuer(srv.URL), `c.RegisterIssuer should succeed`)
+		require.True(t, c.IsRegistered(srv.URL), `c.IsRegistered should be true`)
+
+		fetched, err := c.Get(ctx, srv.URL)
+		require.NoError(t, err, `c.Get should succeed`)
+		require.Equal(t, set1, fetched, `sets should match`)
+
+		// jwks_uri is re-resolved upon refresh
+		srv.mu.Lock()
+		srv.jwksURI = srv.URL + `/jwks2`
+		srv.mu.Unlock()
+
+		fetched, err = c.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c.Refresh should succeed`)
+		require.Equal(t, set2, fetched, `sets should match`)
+
+		require.NoError(t, c.Unregister(srv.URL), `c.Unregister should succeed`)
+		require.False(t, c.IsRegistered(srv.URL), `c.IsRegistered should be false`)
+	})
+	t.Run(`RFC 8414 metadata`, func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		srv := newServer(t)
+		defer srv.Close()
+		set1 := newSet(t)
+		srv.sets[`/jwks1`] = set1
+
+		var postFetched string
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.RegisterIssuer(srv.URL,
+			jwk.WithDiscoveryURL(srv.URL+`/.well-known/oauth-authorization-server`),
+			jwk.WithPostFetcher(jwk.PostFetchFunc(func(u string, set jwk.Set) (jwk.Set, error) {
+				postFetched = u
+				return set, nil
+			})),
+		), `c.RegisterIssuer should succeed`)
+
+		fetched, err := c.Get(ctx, srv.URL)
+		require.NoError(t, err, `c.Get should succeed`)
+		require.Equal(t, set1, fetched, `sets should match`)
+		require.Equal(t, srv.URL+`/jwks1`, postFetched, `PostFetcher should receive jwks_uri`)
+	})
+	t.Run(`Issuer mismatch`, func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		srv := newServer(t)
+		defer srv.Close()
+		srv.sets[`/jwks1`] = newSet(t)
+		srv.issuer = `https://attacker.example.com`
+
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.RegisterIssuer(srv.URL), `c.RegisterIssuer should succeed`)
+		_, err := c.Get(ctx, srv.URL)
+		require.Error(t, err, `c.Get should fail`)
+	})
+	t.Run(`Whitelist`, func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		srv := newServer(t)
+		defer srv.Close()
+		srv.sets[`/jwks1`] = newSet(t)
+
+		c := jwk.NewCache(ctx)
+		wl := jwk.WhitelistFunc(func(u string) bool {
+			return strings.HasSuffix(u, `/openid-configuration`)
+		})
+		require.NoError(t, c.RegisterIssuer(srv.URL, jwk.WithFetchWhitelist(wl)), `c.RegisterIssuer should succeed`)
+		_, err := c.Get(ctx, srv.URL)
+		require.Error(t, err, `c.Get should fail when jwks_uri is not whitelisted`)
+	})
+}
+
+func TestRefreshOnUnknownKeyID(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	var requests int
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(newKeyWithID(t, `key-1`)), `set.AddKey should succeed`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		w.Header().Set(`Content-Type`, `application/json`)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+	countRequests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	c := jwk.NewCache(ctx, jwk.WithRefreshCooldown(500*time.Millisecond))
+	require.NoError(t, c.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+	_, err := c.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c.Refresh should succeed`)
+	require.Equal(t, 1, countRequests(), `there should be 1 request`)
+
+	t.Run(`Disabled by default`, func(t *testing.T) {
+		cs := jwk.NewCachedSet(c, srv.URL)
+		_, ok := cs.LookupKeyID(`unknown`)
+		require.False(t, ok, `cs.LookupKeyID should fail`)
+		require.Equal(t, 1, countRequests(), `there should be no extra requests`)
+	})
+
+	// Rotate keys on the server
+	mu.Lock()
+	require.NoError(t, set.AddKey(newKeyWithID(t, `key-2`)), `set.AddKey should succeed`)
+	mu.Unlock()
+
+	cs := jwk.NewCachedSet(c, srv.URL, jwk.WithRefreshOnUnknownKeyID(true))
+
+	// Many concurrent lookups for unknown key IDs should only result in
+	// a single request
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cs.LookupKeyID(fmt.Sprintf(`random-%d`, i))
+		}(i)
+	}
+	wg.Wait()
+	require.Equal(t, 2, countRequests(), `there should be exactly 1 extra request`)
+
+	// The rotated key was picked up by the refresh above
+	_, ok := cs.LookupKeyID(`key-2`)
+	require.True(t, ok, `cs.LookupKeyID should succeed for the rotated key`)
+
+	// Within the cooldown period, no further requests are made
+	_, ok = cs.LookupKeyID(`key-3`)
+	require.False(t, ok, `cs.LookupKeyID should fail`)
+	require.Equal(t, 2, countRequests(), `there should be no extra requests during cooldown`)
+
+	mu.Lock()
+	require.NoError(t, set.AddKey(newKeyWithID(t, `key-3`)), `set.AddKey should succeed`)
+	mu.Unlock()
+
+	time.Sleep(time.Second)
+	_, ok = cs.LookupKeyID(`key-3`)
+	require.True(t, ok, `cs.LookupKeyID should succeed after the cooldown period`)
+	require.Equal(t, 3, countRequests(), `there should be 1 extra request after cooldown`)
+}
+
+func TestRefreshThrottled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := jwxtest.GenerateRsaPublicJwk()
+	require.NoError(t, err, `jwxtest.GenerateRsaPublicJwk should succeed`)
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+
+	var mu sync.Mutex
+	var requests int
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	var blocking bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		requests++
+		block := blocking
+		mu.Unlock()
+		if block {
+			started <- struct{}{}
+			<-release
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+	countRequests := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+
+	// Non-positive values do not disable the cooldown period
+	c := jwk.NewCache(ctx, jwk.WithRefreshCooldown(0))
+	require.NoError(t, c.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+	_, err = c.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c.Refresh should succeed`)
+
+	mu.Lock()
+	blocking = true
+	mu.Unlock()
+
+	// The first caller gives up while the refresh is in progress. This
+	// must not affect the other callers waiting for the same refresh
+	firstCtx, firstCancel := context.WithCancel(ctx)
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := c.RefreshThrottled(firstCtx, srv.URL)
+		firstErr <- err
+	}()
+	<-started
+
+	secondErr := make(chan error, 1)
+	go func() {
+		_, err := c.RefreshThrottled(ctx, srv.URL)
+		secondErr <- err
+	}()
+	// Give the second caller time to join the refresh in progress
+	time.Sleep(100 * time.Millisecond)
+
+	firstCancel()
+	require.ErrorIs(t, <-firstErr, context.Canceled, `the first caller should be canceled`)
+	close(release)
+	require.NoError(t, <-secondErr, `the second caller should receive the refreshed set`)
+	require.Equal(t, 2, countRequests(), `there should be 1 extra request`)
+
+	_, err = c.RefreshThrottled(ctx, srv.URL)
+	require.NoError(t, err, `c.RefreshThrottled should succeed`)
+	require.Equal(t, 2, countRequests(), `there should be no extra requests during cooldown`)
+}
+
+func TestCacheStorage(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	key, err := jwxtest.GenerateRsaPublicJwk()
+	require.NoError(t, err, `jwxtest.GenerateRsaPublicJwk should succeed`)
+	require.NoError(t, key.Set(jwk.KeyIDKey, `key-1`), `key.Set should succeed`)
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+
+	const etag = `"v1"`
+	var mu sync.Mutex
+	var full, notModified int
+	var failing bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		if r.Header.Get(`If-None-Match`) == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full++
+		w.Header().Set(`Content-Type`, `application/json`)
+		w.Header().Set(`ETag`, etag)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+	counts := func() (int, int) {
+		mu.Lock()
+		defer mu.Unlock()
+		return full, notModified
+	}
+
+	storage, err := jwk.NewFileStorage(t.TempDir())
+	require.NoError(t, err, `jwk.NewFileStorage should succeed`)
+
+	entry, err := storage.Load(srv.URL)
+	require.NoError(t, err, `storage.Load should succeed`)
+	require.Nil(t, entry, `storage should be empty`)
+
+	c1 := jwk.NewCache(ctx, jwk.WithStorage(storage))
+	require.NoError(t, c1.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c1.Register should succeed`)
+	_, err = c1.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c1.Refresh should succeed`)
+
+	entry, err = storage.Load(srv.URL)
+	require.NoError(t, err, `storage.Load should succeed`)
+	require.NotNil(t, entry, `entry should be stored`)
+	require.Equal(t, etag, entry.ETag, `ETag should be stored`)
+	require.False(t, entry.FetchedAt.IsZero(), `fetch time should be stored`)
+	_, ok := entry.Set.LookupKeyID(`key-1`)
+	require.True(t, ok, `stored set should contain the key`)
+
+	t.Run(`Conditional request after restart`, func(t *testing.T) {
+		c2 := jwk.NewCache(ctx, jwk.WithStorage(storage))
+		require.NoError(t, c2.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c2.Register should succeed`)
+		got, err := c2.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c2.Refresh should succeed`)
+		_, ok := got.LookupKeyID(`key-1`)
+		require.True(t, ok, `set should contain the key`)
+
+		nfull, nnotmodified := counts()
+		require.Equal(t, 1, nfull, `there should be no extra full responses`)
+		require.True(t, nnotmodified > 0, `server should have responded with 304`)
+	})
+	t.Run(`Stored set is used while the server is failing`, func(t *testing.T) {
+		mu.Lock()
+		failing = true
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			failing = false
+			mu.Unlock()
+		}()
+
+		var evmu sync.Mutex
+		var events []jwk.CacheEvent
+		listener := jwk.CacheListenerFunc(func(ev jwk.CacheEvent) {
+			evmu.Lock()
+			defer evmu.Unlock()
+			if ev.Type == jwk.CacheEventFetchSucceeded || ev.Type == jwk.CacheEventFetchFailed {
+				events = append(events, ev)
+			}
+		})
+
+		c3 := jwk.NewCache(ctx, jwk.WithStorage(storage), jwk.WithCacheListener(listener))
+		require.NoError(t, c3.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c3.Register should succeed`)
+		got, err := c3.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c3.Refresh should succeed`)
+		_, ok := got.LookupKeyID(`key-1`)
+		require.True(t, ok, `set should contain the key`)
+
+		// The failure of the server is reported, even though the stored set is used
+		evmu.Lock()
+		require.Len(t, events, 1, `there should be one event`)
+		require.Equal(t, jwk.CacheEventFetchFailed, events[0].Type, `fetch should fail`)
+		require.Equal(t, http.StatusServiceUnavailable, events[0].StatusCode, `status code should match`)
+		evmu.Unlock()
+
+		health := c3.Health()
+		require.Len(t, health, 1, `there should be one resource`)
+		require.False(t, health[0].Healthy(), `resource should not be healthy`)
+		require.Equal(t, 1, health[0].ConsecutiveFailures, `there should be one failure`)
+		require.NotEmpty(t, health[0].LastError, `error should be recorded`)
+
+		// Without storage, the same request fails
+		c4 := jwk.NewCache(ctx)
+		require.NoError(t, c4.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c4.Register should succeed`)
+		_, err = c4.Refresh(ctx, srv.URL)
+		require.Error(t, err, `c4.Refresh should fail`)
+	})
+}
+
+func TestCacheSources(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	writeJSON := func(t *testing.T, name string, v interface{}) {
+		t.Helper()
+		buf, err := json.Marshal(v)
+		require.NoError(t, err, `json.Marshal should succeed`)
+		require.NoError(t, os.WriteFile(name, buf, 0600), `os.WriteFile should succeed`)
+	}
+
+	t.Run(`File`, func(t *testing.T) {
+		name := filepath.Join(t.TempDir(), `jwks.json`)
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(newKeyWithID(t, `key-1`)), `set.AddKey should succeed`)
+		writeJSON(t, name, set)
+
+		u := `file://` + filepath.ToSlash(name)
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.Register(u, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+		cs := jwk.NewCachedSet(c, u)
+		_, ok := cs.LookupKeyID(`key-1`)
+		require.True(t, ok, `key-1 should be found`)
+
+		// Rotate the file on disk
+		require.NoError(t, set.AddKey(newKeyWithID(t, `key-2`)), `set.AddKey should succeed`)
+		writeJSON(t, name, set)
+		_, err := c.Refresh(ctx, u)
+		require.NoError(t, err, `c.Refresh should succeed`)
+		_, ok = cs.LookupKeyID(`key-2`)
+		require.True(t, ok, `key-2 should be found`)
+
+		// A broken file does not replace the last good set
+		require.NoError(t, os.WriteFile(name, []byte(`{`), 0600), `os.WriteFile should succeed`)
+		_, err = c.Refresh(ctx, u)
+		require.Error(t, err, `c.Refresh should fail`)
+		require.Equal(t, 2, cs.Len(), `cached set should be preserved`)
+
+		require.Error(t, c.Register(`file://example.com/jwks.json`), `c.Register with a remote host should fail`)
+	})
+	t.Run(`Directory`, func(t *testing.T) {
+		dir := t.TempDir()
+		writeJSON(t, filepath.Join(dir, `key.json`), newKeyWithID(t, `json-key`))
+
+		raw, err := jwxtest.GenerateEcdsaKey(jwa.P256)
+		require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+		pem, err := jwk.EncodePEM(&raw.PublicKey)
+		require.NoError(t, err, `jwk.EncodePEM should succeed`)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, `key.pem`), pem, 0600), `os.WriteFile should succeed`)
+
+		// Hidden files and subdirectories are ignored
+		require.NoError(t, os.WriteFile(filepath.Join(dir, `.hidden`), []byte(`garbage`), 0600), `os.WriteFile should succeed`)
+		require.NoError(t, os.Mkdir(filepath.Join(dir, `subdir`), 0700), `os.Mkdir should succeed`)
+
+		u := `file://` + filepath.ToSlash(dir)
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.Register(u, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+		set, err := c.Get(ctx, u)
+		require.NoError(t, err, `c.Get should succeed`)
+		require.Equal(t, 2, set.Len(), `set should contain keys from both files`)
+		_, ok := set.LookupKeyID(`json-key`)
+		require.True(t, ok, `json-key should be found`)
+		key, _ := set.Key(1)
+		require.Equal(t, jwa.EC, key.KeyType(), `second key should be the PEM encoded key`)
+	})
+	t.Run(`Loader`, func(t *testing.T) {
+		const u = `secrets://my-keys`
+		var mu sync.Mutex
+		var loads int
+		loader := jwk.LoaderFunc(func(v string) (jwk.Set, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if v != u {
+				return nil, fmt.Errorf(`unexpected url %q`, v)
+			}
+			loads++
+			set := jwk.NewSet()
+			if err := set.AddKey(newKeyWithID(t, fmt.Sprintf(`key-%d`, loads))); err != nil {
+				return nil, err
+			}
+			return set, nil
+		})
+		postFetch := jwk.PostFetchFunc(func(_ string, set jwk.Set) (jwk.Set, error) {
+			for i := 0; i < set.Len(); i++ {
+				key, _ := set.Key(i)
+				if err := key.Set(jwk.AlgorithmKey, jwa.RS256); err != nil {
+					return nil, err
+				}
+			}
+			return set, nil
+		})
+
+		c := jwk.NewCache(ctx)
+		require.NoError(t, c.Register(u, jwk.WithLoader(loader), jwk.WithPostFetcher(postFetch), jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+		set, err := c.Get(ctx, u)
+		require.NoError(t, err, `c.Get should succeed`)
+		key, ok := set.LookupKeyID(`key-1`)
+		require.True(t, ok, `key-1 should be found`)
+		require.Equal(t, jwa.RS256, key.Algorithm(), `post fetcher should have been applied`)
+
+		set, err = c.Refresh(ctx, u)
+		require.NoError(t, err, `c.Refresh should succeed`)
+		_, ok = set.LookupKeyID(`key-2`)
+		require.True(t, ok, `key-2 should be found`)
+	})
+}
+
+func TestCacheListener(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var mu sync.Mutex
+	var failing bool
+	set := jwk.NewSet()
+	require.NoError(t, set.AddKey(newKeyWithID(t, `key-1`)), `set.AddKey should succeed`)
+	require.NoError(t, set.AddKey(newKeyWithID(t, `key-2`)), `set.AddKey should succeed`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set(`Content-Type`, `application/json`)
+		w.Header().Set(`Cache-Control`, `max-age=3600`)
+		_ = json.NewEncoder(w).Encode(set)
+	}))
+	defer srv.Close()
+
+	var events []jwk.CacheEvent
+	listener := jwk.CacheListenerFunc(func(ev jwk.CacheEvent) {
+		mu.Lock()
+		events = append(events, ev)
+		mu.Unlock()
+	})
+	drain := func() []jwk.CacheEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		ret := events
+		events = nil
+		return ret
+	}
+	eventTypes := func(events []jwk.CacheEvent) []jwk.CacheEventType {
+		var types []jwk.CacheEventType
+		for _, ev := range events {
+			types = append(types, ev.Type)
+		}
+		return types
+	}
+
+	c := jwk.NewCache(ctx, jwk.WithCacheListener(listener))
+	require.NoError(t, c.Register(srv.URL, jwk.WithMinRefreshInterval(time.Second)), `c.Register should succeed`)
+	_, err := c.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c.Refresh should succeed`)
+
+	t.Run(`Initial fetch`, func(t *testing.T) {
+		got := drain()
+		require.Equal(t, []jwk.CacheEventType{
+			jwk.CacheEventFetchStarted,
+			jwk.CacheEventRefreshScheduled,
+			jwk.CacheEventFetchSucceeded,
+			jwk.CacheEventSetChanged,
+		}, eventTypes(got), `events should match`)
+
+		scheduled := got[1]
+		require.Equal(t, jwk.RefreshReasonCacheControl, scheduled.RefreshReason, `refresh should be scheduled according to Cache-Control`)
+		require.Equal(t, time.Hour, scheduled.RefreshInterval, `refresh interval should match max-age`)
+
+		succeeded := got[2]
+		require.Equal(t, http.StatusOK, succeeded.StatusCode, `status code should match`)
+		require.Equal(t, 2, succeeded.KeyCount, `key count should match`)
+
+		healths := c.Health()
+		require.Len(t, healths, 1, `there should be 1 health entry`)
+		health := healths[0]
+		require.Equal(t, srv.URL, health.URL, `health URL should match`)
+		require.True(t, health.Healthy(), `resource should be healthy`)
+		require.Equal(t, 2, health.KeyCount, `key count should match`)
+		require.Equal(t, scheduled.NextRefresh, health.NextRefresh, `next refresh should match`)
+		require.Len(t, c.Snapshot().Entries, 1, `httprc entries should be available`)
+	})
+	t.Run(`Key rotation`, func(t *testing.T) {
+		mu.Lock()
+		key, _ := set.LookupKeyID(`key-1`)
+		require.NoError(t, set.RemoveKey(key), `set.RemoveKey should succeed`)
+		require.NoError(t, set.AddKey(newKeyWithID(t, `key-3`)), `set.AddKey should succeed`)
+		mu.Unlock()
+
+		_, err := c.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c.Refresh should succeed`)
+
+		got := drain()
+		require.Equal(t, jwk.CacheEventSetChanged, got[len(got)-1].Type, `last event should be set_changed`)
+		require.Equal(t, []string{`key-3`}, got[len(got)-1].Added, `added key IDs should match`)
+		require.Equal(t, []string{`key-1`}, got[len(got)-1].Removed, `removed key IDs should match`)
+
+		// Fetching the same set again does not emit set_changed
+		_, err = c.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c.Refresh should succeed`)
+		require.Equal(t, jwk.CacheEventFetchSucceeded, drain()[2].Type, `last event should be fetch_succeeded`)
+	})
+	t.Run(`Failure`, func(t *testing.T) {
+		mu.Lock()
+		failing = true
+		mu.Unlock()
+
+		_, err := c.Refresh(ctx, srv.URL)
+		require.Error(t, err, `c.Refresh should fail`)
+
+		got := drain()
+		require.Equal(t, jwk.CacheEventFetchFailed, got[len(got)-1].Type, `last event should be fetch_failed`)
+		require.Equal(t, http.StatusInternalServerError, got[len(got)-1].StatusCode, `status code should match`)
+		require.Error(t, got[len(got)-1].Error, `event should contain the error`)
+
+		health := c.Health()[0]
+		require.False(t, health.Healthy(), `resource should not be healthy`)
+		require.Equal(t, 1, health.ConsecutiveFailures, `there should be 1 failure`)
+		require.NotEmpty(t, health.LastError, `last error should be recorded`)
+
+		require.NoError(t, c.Unregister(srv.URL), `c.Unregister should succeed`)
+		require.Len(t, c.Health(), 0, `health should be removed`)
+	})
+}
+
+func TestHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	set := jwk.NewSet()
+	for _, kty := range []jwa.KeyType{jwa.RSA, jwa.EC, jwa.OctetSeq} {
+		key, err := jwk.Generate(kty, jwk.WithAssignKeyID(true))
+		require.NoError(t, err, `jwk.Generate should succeed`)
+		require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+	}
+	require.NoError(t, set.Set(`x-issuer`, `https://example.com`), `set.Set should succeed`)
+
+	srv := httptest.NewServer(jwk.NewHandler(jwk.StaticSetProvider(set), jwk.WithMaxAge(time.Hour)))
+	defer srv.Close()
+
+	var etag string
+	t.Run("GET", func(t *testing.T) {
+		res, err := http.Get(srv.URL)
+		require.NoError(t, err, `http.Get should succeed`)
+		defer res.Body.Close()
+
+		require.Equal(t, http.StatusOK, res.StatusCode, `status code should be 200`)
+		require.Equal(t, `application/jwk-set+json`, res.Header.Get(`Content-Type`), `Content-Type should match`)
+		require.Equal(t, `public, max-age=3600`, res.Header.Get(`Cache-Control`), `Cache-Control should match`)
+		etag = res.Header.Get(`ETag`)
+		require.True(t, strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`), `ETag should be a strong validator`)
+
+		served, err := jwk.ParseReader(res.Body)
+		require.NoError(t, err, `jwk.ParseReader should succeed`)
+		require.Equal(t, 2, served.Len(), `symmetric keys should be omitted`)
+		for i := 0; i < served.Len(); i++ {
+			key, _ := served.Key(i)
+			switch key.(type) {
+			case jwk.RSAPublicKey, jwk.ECDSAPublicKey:
+			default:
+				require.Fail(t, `only public keys should be served`, `got %T`, key)
+			}
+		}
+
+		v, ok := served.Get(`x-issuer`)
+		require.True(t, ok, `fields of the set should be served`)
+		require.Equal(t, `https://example.com`, v, `field should match`)
+		require.Equal(t, 3, set.Len(), `the original set should not be modified`)
+	})
+	t.Run("Conditional GET", func(t *testing.T) {
+		for _, v := range []string{etag, `"foo", ` + etag, `W/` + etag, `*`} {
+			req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+			require.NoError(t, err, `http.NewRequest should succeed`)
+			req.Header.Set(`If-None-Match`, v)
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err, `http.Do should succeed`)
+			res.Body.Close()
+			require.Equal(t, http.StatusNotModified, res.StatusCode, `status code should be 304 for %q`, v)
+			require.Equal(t, etag, res.Header.Get(`ETag`), `ETag should match`)
+		}
+
+		req, err := http.NewRequest(http.MethodGet, srv.URL, nil)
+		require.NoError(t, err, `http.NewRequest should succeed`)
+		req.Header.Set(`If-None-Match`, `"foo"`)
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, `http.Do should succeed`)
+		res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode, `status code should be 200`)
+	})
+	t.Run("Method not allowed", func(t *testing.T) {
+		res, err := http.Post(srv.URL, `application/json`, strings.NewReader(`{}`))
+		require.NoError(t, err, `http.Post should succeed`)
+		res.Body.Close()
+		require.Equal(t, http.StatusMethodNotAllowed, res.StatusCode, `status code should be 405`)
+	})
+	t.Run("jwk.Cache", func(t *testing.T) {
+		var mu sync.Mutex
+		var interval time.Duration
+		listener := jwk.CacheListenerFunc(func(ev jwk.CacheEvent) {
+			if ev.Type == jwk.CacheEventRefreshScheduled {
+				mu.Lock()
+				interval = ev.RefreshInterval
+				mu.Unlock()
+			}
+		})
+
+		c := jwk.NewCache(ctx, jwk.WithCacheListener(listener))
+		require.NoError(t, c.Register(srv.URL), `c.Register should succeed`)
+		fetched, err := c.Refresh(ctx, srv.URL)
+		require.NoError(t, err, `c.Refresh should succeed`)
+		require.Equal(t, 2, fetched.Len(), `fetched set should contain the public keys`)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Equal(t, time.Hour, interval, `refresh interval should match max-age`)
+	})
+}
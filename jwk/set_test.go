@@ -1,11 +1,21 @@
 package jwk_test
 
 import (
+	"context"
+	"crypto"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/lestrrat-go/jwx/v2/internal/json"
 	"github.com/lestrrat-go/jwx/v2/internal/jwxtest"
+	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestSet(t *testing.T) {
@@ -70,3 +80,165 @@ func TestSet(t *testing.T) {
 		return
 	}
 }
+
+func TestAggregateSet(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	publicSet := func(t *testing.T, keys ...jwk.Key) jwk.Set {
+		t.Helper()
+		set := jwk.NewSet()
+		for _, key := range keys {
+			pubkey, err := key.PublicKey()
+			require.NoError(t, err, `key.PublicKey should succeed`)
+			require.NoError(t, set.AddKey(pubkey), `set.AddKey should succeed`)
+		}
+		return set
+	}
+
+	oldKey := newKeyWithID(t, `old`)
+	sharedOld := newKeyWithID(t, `shared`)
+	sharedNew := newKeyWithID(t, `shared`)
+	newIdPKey := newKeyWithID(t, `new`)
+	rotatedKey := newKeyWithID(t, `rotated`)
+
+	var mu sync.Mutex
+	remote := publicSet(t, sharedNew, newIdPKey)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set(`Content-Type`, `application/json`)
+		_ = json.NewEncoder(w).Encode(remote)
+	}))
+	defer srv.Close()
+
+	c := jwk.NewCache(ctx, jwk.WithRefreshCooldown(time.Nanosecond))
+	require.NoError(t, c.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+	_, err := c.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c.Refresh should succeed`)
+
+	static := publicSet(t, oldKey, sharedOld)
+	require.NoError(t, static.Set(`source`, `static`), `static.Set should succeed`)
+	cached := jwk.NewCachedSet(c, srv.URL, jwk.WithRefreshOnUnknownKeyID(true))
+
+	t.Run(`Precedence`, func(t *testing.T) {
+		as := jwk.NewAggregateSet([]jwk.Set{static, cached})
+		require.Equal(t, 3, as.Len(), `duplicate key ID should be hidden`)
+
+		key, ok := as.LookupKeyID(`shared`)
+		require.True(t, ok, `as.LookupKeyID should succeed`)
+		want, err := sharedOld.Thumbprint(crypto.SHA256)
+		require.NoError(t, err, `sharedOld.Thumbprint should succeed`)
+		got, err := key.Thumbprint(crypto.SHA256)
+		require.NoError(t, err, `key.Thumbprint should succeed`)
+		require.Equal(t, want, got, `key from the first set should be returned`)
+		_, ok = as.LookupKeyID(`new`)
+		require.True(t, ok, `as.LookupKeyID should succeed for keys in the second set`)
+
+		var kids []string
+		for iter := as.Keys(ctx); iter.Next(ctx); {
+			//nolint:forcetypeassert
+			kids = append(kids, iter.Pair().Value.(jwk.Key).KeyID())
+		}
+		require.Equal(t, []string{`old`, `shared`, `new`}, kids, `keys should be listed in order`)
+
+		v, ok := as.Get(`source`)
+		require.True(t, ok, `as.Get should succeed`)
+		require.Equal(t, `static`, v, `as.Get should return the value from the static set`)
+
+		require.Error(t, as.AddKey(rotatedKey), `as.AddKey should fail`)
+	})
+	t.Run(`Duplicate key IDs`, func(t *testing.T) {
+		as := jwk.NewAggregateSet([]jwk.Set{static, cached}, jwk.WithDuplicateKeyIDs(true))
+		require.Equal(t, 4, as.Len(), `duplicate key ID should be exposed`)
+
+		// Both keys with the same key ID can be used for verification
+		as = jwk.NewAggregateSet([]jwk.Set{cached, static}, jwk.WithDuplicateKeyIDs(true))
+		for _, key := range []jwk.Key{sharedOld, sharedNew} {
+			signed, err := jws.Sign([]byte(`payload`), jws.WithKey(jwa.RS256, key))
+			require.NoError(t, err, `jws.Sign should succeed`)
+			_, err = jws.Verify(signed, jws.WithKeySet(as, jws.WithMultipleKeysPerKeyID(true)))
+			require.NoError(t, err, `jws.Verify should succeed`)
+		}
+	})
+	t.Run(`Refresh on unknown key ID`, func(t *testing.T) {
+		as := jwk.NewAggregateSet([]jwk.Set{static, cached})
+
+		pubkey, err := rotatedKey.PublicKey()
+		require.NoError(t, err, `rotatedKey.PublicKey should succeed`)
+		mu.Lock()
+		require.NoError(t, remote.AddKey(pubkey), `remote.AddKey should succeed`)
+		mu.Unlock()
+
+		signed, err := jws.Sign([]byte(`payload`), jws.WithKey(jwa.RS256, rotatedKey))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		_, err = jws.Verify(signed, jws.WithKeySet(as))
+		require.NoError(t, err, `jws.Verify should succeed`)
+	})
+}
+
+func TestLookupKeys(t *testing.T) {
+	t.Parallel()
+
+	rsaSig, err := jwxtest.GenerateRsaPublicJwk()
+	require.NoError(t, err, `jwxtest.GenerateRsaPublicJwk should succeed`)
+	require.NoError(t, rsaSig.Set(jwk.KeyIDKey, `rsa-sig`), `key.Set should succeed`)
+	require.NoError(t, rsaSig.Set(jwk.KeyUsageKey, jwk.ForSignature), `key.Set should succeed`)
+	require.NoError(t, rsaSig.Set(jwk.AlgorithmKey, jwa.RS256), `key.Set should succeed`)
+	require.NoError(t, rsaSig.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpVerify}), `key.Set should succeed`)
+
+	rsaEnc, err := jwxtest.GenerateRsaPublicJwk()
+	require.NoError(t, err, `jwxtest.GenerateRsaPublicJwk should succeed`)
+	require.NoError(t, rsaEnc.Set(jwk.KeyIDKey, `rsa-enc`), `key.Set should succeed`)
+	require.NoError(t, rsaEnc.Set(jwk.KeyUsageKey, jwk.ForEncryption), `key.Set should succeed`)
+	require.NoError(t, rsaEnc.Set(jwk.AlgorithmKey, jwa.RSA_OAEP_256), `key.Set should succeed`)
+	require.NoError(t, rsaEnc.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpEncrypt, jwk.KeyOpWrapKey}), `key.Set should succeed`)
+
+	ecKey, err := jwxtest.GenerateEcdsaPublicJwk()
+	require.NoError(t, err, `jwxtest.GenerateEcdsaPublicJwk should succeed`)
+	require.NoError(t, ecKey.Set(jwk.KeyIDKey, `ec`), `key.Set should succeed`)
+
+	set := jwk.NewSet()
+	for _, key := range []jwk.Key{rsaSig, rsaEnc, ecKey} {
+		require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+	}
+
+	tp, err := ecKey.Thumbprint(crypto.SHA256)
+	require.NoError(t, err, `ecKey.Thumbprint should succeed`)
+
+	testcases := []struct {
+		Name     string
+		Options  []jwk.KeyMatchOption
+		Expected []string
+	}{
+		{Name: `No criteria`, Expected: []string{`rsa-sig`, `rsa-enc`, `ec`}},
+		{Name: `kid`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyID(`rsa-enc`)}, Expected: []string{`rsa-enc`}},
+		{Name: `kty`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyType(jwa.RSA)}, Expected: []string{`rsa-sig`, `rsa-enc`}},
+		{Name: `use`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyUsage(jwk.ForEncryption)}, Expected: []string{`rsa-enc`, `ec`}},
+		{Name: `use (strict)`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyUsage(jwk.ForEncryption), jwk.WithMatchStrict(true)}, Expected: []string{`rsa-enc`}},
+		{Name: `alg`, Options: []jwk.KeyMatchOption{jwk.WithMatchAlgorithm(jwa.RSA_OAEP_256), jwk.WithMatchKeyType(jwa.RSA)}, Expected: []string{`rsa-enc`}},
+		{Name: `key_ops`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyOperations(jwk.KeyOpVerify)}, Expected: []string{`rsa-sig`, `ec`}},
+		{Name: `key_ops (multiple)`, Options: []jwk.KeyMatchOption{jwk.WithMatchKeyOperations(jwk.KeyOpEncrypt, jwk.KeyOpWrapKey), jwk.WithMatchStrict(true)}, Expected: []string{`rsa-enc`}},
+		{Name: `crv`, Options: []jwk.KeyMatchOption{jwk.WithMatchCurve(jwa.P521)}, Expected: []string{`ec`}},
+		{Name: `crv mismatch`, Options: []jwk.KeyMatchOption{jwk.WithMatchCurve(jwa.P384)}},
+		{Name: `thumbprint`, Options: []jwk.KeyMatchOption{jwk.WithMatchThumbprint(crypto.SHA256, tp)}, Expected: []string{`ec`}},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			t.Parallel()
+			var kids []string
+			for _, key := range jwk.LookupKeys(set, tc.Options...) {
+				kids = append(kids, key.KeyID())
+			}
+			require.Equal(t, tc.Expected, kids, `matched keys should be as expected`)
+
+			filtered, err := jwk.Filter(set, tc.Options...)
+			require.NoError(t, err, `jwk.Filter should succeed`)
+			require.Equal(t, len(tc.Expected), filtered.Len(), `filtered set should contain the matched keys`)
+		})
+	}
+}
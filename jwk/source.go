@@ -0,0 +1,155 @@
+package jwk
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Loader is responsible for loading a `jwk.Set` from a source other
+// than an HTTP server, such as a file or a secret store. Loaders are
+// specified using `jwk.WithLoader` when registering a URL in a
+// `jwk.Cache`, and are invoked whenever the URL is refreshed.
+type Loader interface {
+	// Load receives the URL that was registered, and returns the `jwk.Set`
+	// to be passed to the `jwk.PostFetcher` (if any) and stored in the cache.
+	Load(string) (Set, error)
+}
+
+// LoaderFunc is a Loader based on a function.
+type LoaderFunc func(string) (Set, error)
+
+func (f LoaderFunc) Load(u string) (Set, error) {
+	return f(u)
+}
+
+// loadedBody is the body of the responses created by loaderClient. It
+// carries the loaded jwk.Set to jwksTransform, which uses it as is
+// instead of parsing the body
+type loadedBody struct {
+	set Set
+}
+
+func (*loadedBody) Read([]byte) (int, error) {
+	return 0, io.EOF
+}
+
+func (*loadedBody) Close() error {
+	return nil
+}
+
+// loaderClient is a HTTPClient that delegates to a Loader, so that
+// non-HTTP sources can be scheduled and refreshed by httprc just like
+// regular HTTP resources
+type loaderClient struct {
+	loader Loader
+}
+
+func (c *loaderClient) Get(u string) (*http.Response, error) {
+	set, err := c.loader.Load(u)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to load jwk.Set from %q: %w`, u, err)
+	}
+	return &http.Response{
+		Status:     http.StatusText(http.StatusOK),
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       &loadedBody{set: set},
+		Request:    &http.Request{Method: http.MethodGet},
+	}, nil
+}
+
+// fileLoader loads jwk.Set objects from "file://" URLs, which may
+// point to either a single file or a directory
+type fileLoader struct {
+	parseOptions []ParseOption
+}
+
+func filePath(u string) (string, error) {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "", fmt.Errorf(`failed to parse url %q: %w`, u, err)
+	}
+	if parsed.Scheme != `file` {
+		return "", fmt.Errorf(`invalid scheme for file source %q`, u)
+	}
+	if parsed.Host != "" && parsed.Host != `localhost` {
+		return "", fmt.Errorf(`file source %q must not specify a remote host`, u)
+	}
+	return filepath.FromSlash(parsed.Path), nil
+}
+
+func (l *fileLoader) Load(u string) (Set, error) {
+	path, err := filePath(u)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to stat %q: %w`, path, err)
+	}
+	if !fi.IsDir() {
+		return l.parseFile(path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read directory %q: %w`, path, err)
+	}
+
+	set := NewSet()
+	for _, entry := range entries {
+		// Skip hidden files, which includes the "..data" directories
+		// that Kubernetes uses to atomically update mounted volumes
+		if strings.HasPrefix(entry.Name(), `.`) {
+			continue
+		}
+
+		name := filepath.Join(path, entry.Name())
+		fi, err := os.Stat(name)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to stat %q: %w`, name, err)
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+
+		src, err := l.parseFile(name)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < src.Len(); i++ {
+			key, _ := src.Key(i)
+			if err := set.AddKey(key); err != nil {
+				return nil, fmt.Errorf(`failed to add key from %q: %w`, name, err)
+			}
+		}
+	}
+	return set, nil
+}
+
+// parseFile parses a file containing either a JWK, a JWKS, or
+// PEM encoded keys and certificates
+func (l *fileLoader) parseFile(name string) (Set, error) {
+	buf, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read %q: %w`, name, err)
+	}
+
+	options := l.parseOptions
+	if bytes.Contains(buf, []byte(`-----BEGIN `)) {
+		options = append(options[:len(options):len(options)], WithPEM(true))
+	}
+
+	set, err := Parse(buf, options...)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse %q: %w`, name, err)
+	}
+	return set, nil
+}
@@ -0,0 +1,260 @@
+package jwk
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+)
+
+// CacheEntry is the unit of data that is persisted by a `jwk.CacheStorage`.
+// It contains the last good `jwk.Set` fetched from a URL, along with the
+// time it was fetched and the HTTP validators that were returned with it.
+type CacheEntry struct {
+	Set          Set
+	FetchedAt    time.Time
+	ETag         string
+	LastModified string
+}
+
+// CacheStorage is a persistent storage for `jwk.Cache`. It allows the
+// contents of the cache to survive restarts of the program.
+//
+// Load should return `nil, nil` if nothing has been stored for the URL.
+//
+// Only the URLs registered using `(*jwk.Cache).Register()` are persisted.
+type CacheStorage interface {
+	Load(string) (*CacheEntry, error)
+	Store(string, *CacheEntry) error
+}
+
+// storedResource sits between httprc and the remote resource for a single
+// URL registered in a `jwk.Cache` that has a `jwk.CacheStorage`. It acts
+// both as the HTTP client, where it sends conditional requests and falls
+// back to the stored entry upon failures, and as the transformer, where it
+// persists the newly fetched jwk.Set.
+type storedResource struct {
+	storage   CacheStorage
+	client    HTTPClient
+	transform *jwksTransform
+
+	mu    sync.RWMutex
+	entry *CacheEntry
+}
+
+type httpDoer interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+func (r *storedResource) current() *CacheEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.entry
+}
+
+func notModified() *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(http.StatusNotModified),
+		StatusCode: http.StatusNotModified,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    &http.Request{Method: http.MethodGet},
+	}
+}
+
+func (r *storedResource) Get(u string) (*http.Response, error) {
+	res, _, _, err := r.getOrFallback(u)
+	return res, err
+}
+
+// getOrFallback fetches `u`, falling back to the stored entry if the
+// remote resource is unavailable. In that case a 304 response is returned
+// along with the status code (if any) and the error that caused the
+// fallback, so that the failure can still be reported.
+func (r *storedResource) getOrFallback(u string) (*http.Response, int, error, error) {
+	entry := r.current()
+
+	var res *http.Response
+	var err error
+	if doer, ok := r.client.(httpDoer); ok {
+		req, rerr := http.NewRequest(http.MethodGet, u, nil)
+		if rerr != nil {
+			return nil, 0, nil, fmt.Errorf(`failed to create request for %q: %w`, u, rerr)
+		}
+		if entry != nil {
+			if entry.ETag != "" {
+				req.Header.Set(`If-None-Match`, entry.ETag)
+			}
+			if entry.LastModified != "" {
+				req.Header.Set(`If-Modified-Since`, entry.LastModified)
+			}
+		}
+		res, err = doer.Do(req)
+	} else {
+		res, err = r.client.Get(u)
+	}
+
+	if entry == nil {
+		return res, 0, nil, err
+	}
+
+	// If the remote resource is unavailable, keep using the last good set
+	if err != nil {
+		return notModified(), 0, err, nil
+	}
+	if res.StatusCode >= http.StatusInternalServerError {
+		_, _ = io.Copy(io.Discard, res.Body)
+		res.Body.Close()
+		return notModified(), res.StatusCode, fmt.Errorf(`failed to fetch %q: %s`, u, res.Status), nil
+	}
+	return res, 0, nil, nil
+}
+
+func (r *storedResource) Transform(u string, res *http.Response) (interface{}, error) {
+	if res.StatusCode == http.StatusNotModified {
+		entry := r.current()
+		if entry == nil {
+			return nil, fmt.Errorf(`received %d for %q, but no stored jwk.Set is available`, res.StatusCode, u)
+		}
+		return entry.Set, nil
+	}
+
+	v, err := r.transform.Transform(u, res)
+	if err != nil {
+		return nil, err
+	}
+
+	//nolint:forcetypeassert
+	entry := &CacheEntry{
+		Set:          v.(Set),
+		FetchedAt:    time.Now(),
+		ETag:         res.Header.Get(`ETag`),
+		LastModified: res.Header.Get(`Last-Modified`),
+	}
+
+	r.mu.Lock()
+	r.entry = entry
+	r.mu.Unlock()
+
+	// Failing to persist the entry does not invalidate the freshly fetched set
+	_ = r.storage.Store(u, entry)
+	return entry.Set, nil
+}
+
+// FileStorage is a `jwk.CacheStorage` that stores each entry as a JSON file
+// in a directory.
+//
+// As the stored JWKS may contain private keys, files are created so that
+// they are only readable by the owner.
+type FileStorage struct {
+	dir string
+}
+
+// NewFileStorage creates a new `jwk.FileStorage` that stores entries in
+// the directory `dir`. The directory is created if it does not exist.
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf(`failed to create directory %q: %w`, dir, err)
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+type fileStorageRecord struct {
+	URL          string          `json:"url"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Set          json.RawMessage `json:"jwks"`
+}
+
+func (s *FileStorage) path(u string) string {
+	sum := sha256.Sum256([]byte(u))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+`.json`)
+}
+
+// Load reads the entry stored for the URL `u`
+func (s *FileStorage) Load(u string) (*CacheEntry, error) {
+	buf, err := os.ReadFile(s.path(u))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf(`failed to read stored entry for %q: %w`, u, err)
+	}
+
+	var rec fileStorageRecord
+	if err := json.Unmarshal(buf, &rec); err != nil {
+		return nil, fmt.Errorf(`failed to parse stored entry for %q: %w`, u, err)
+	}
+	if rec.URL != u {
+		return nil, fmt.Errorf(`stored entry for %q belongs to a different url (%q)`, u, rec.URL)
+	}
+
+	set, err := Parse(rec.Set)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse stored jwk.Set for %q: %w`, u, err)
+	}
+
+	return &CacheEntry{
+		Set:          set,
+		FetchedAt:    rec.FetchedAt,
+		ETag:         rec.ETag,
+		LastModified: rec.LastModified,
+	}, nil
+}
+
+// Store writes the entry for the URL `u`. The file is replaced atomically.
+func (s *FileStorage) Store(u string, entry *CacheEntry) error {
+	set, err := json.Marshal(entry.Set)
+	if err != nil {
+		return fmt.Errorf(`failed to marshal jwk.Set for %q: %w`, u, err)
+	}
+
+	buf, err := json.Marshal(fileStorageRecord{
+		URL:          u,
+		FetchedAt:    entry.FetchedAt,
+		ETag:         entry.ETag,
+		LastModified: entry.LastModified,
+		Set:          set,
+	})
+	if err != nil {
+		return fmt.Errorf(`failed to marshal stored entry for %q: %w`, u, err)
+	}
+
+	if err := writeFile(s.path(u), buf); err != nil {
+		return fmt.Errorf(`failed to store entry for %q: %w`, u, err)
+	}
+	return nil
+}
+
+// writeFile replaces the contents of the file `path` atomically, by
+// writing them to a temporary file in the same directory first. The
+// file is only readable by the owner
+func writeFile(path string, buf []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), `.jwks-*`)
+	if err != nil {
+		return fmt.Errorf(`failed to create temporary file: %w`, err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return fmt.Errorf(`failed to write temporary file: %w`, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf(`failed to write temporary file: %w`, err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf(`failed to rename temporary file: %w`, err)
+	}
+	return nil
+}
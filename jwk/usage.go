@@ -28,3 +28,73 @@ func (k *KeyUsageType) Accept(v interface{}) error {
 
 	return fmt.Errorf("invalid value for key usage type %s", v)
 }
+
+// KeyUsageError is returned when a key is used for an operation that
+// is not permitted by its "use" or "key_ops" fields.
+type KeyUsageError struct {
+	// KeyID is the key ID of the key, if any
+	KeyID string
+	// Operation is the operation that was attempted
+	Operation KeyOperation
+	// Usage and KeyOps are the values of the "use" and "key_ops"
+	// fields of the key
+	Usage  string
+	KeyOps KeyOperationList
+}
+
+func (err *KeyUsageError) Error() string {
+	if err.Usage != "" && err.Usage != usageFor(err.Operation).String() {
+		return fmt.Sprintf(`key %q can not be used to %s: "use" is %q`, err.KeyID, err.Operation, err.Usage)
+	}
+	return fmt.Sprintf(`key %q can not be used to %s: "key_ops" is %q`, err.KeyID, err.Operation, err.KeyOps)
+}
+
+func usageFor(op KeyOperation) KeyUsageType {
+	switch op {
+	case KeyOpSign, KeyOpVerify:
+		return ForSignature
+	default:
+		return ForEncryption
+	}
+}
+
+// CheckKeyUsage checks that the "use" and "key_ops" fields of the key, if
+// specified, permit the operation `op`. The "use" field must be "sig" for
+// the "sign" and "verify" operations, and "enc" for all other operations.
+// The "key_ops" field must contain `op`.
+//
+// `key` may be any kind of key accepted by `jws` and `jwe`, but only
+// `jwk.Key` objects carry usage information, so other keys (such as
+// *rsa.PublicKey) are always permitted.
+//
+// If the operation is not permitted, a `*jwk.KeyUsageError` is returned.
+func CheckKeyUsage(key interface{}, op KeyOperation) error {
+	jwkKey, ok := key.(Key)
+	if !ok {
+		return nil
+	}
+
+	usage := jwkKey.KeyUsage()
+	ops := jwkKey.KeyOps()
+
+	permitted := usage == "" || usage == usageFor(op).String()
+	if permitted && len(ops) > 0 {
+		permitted = false
+		for _, v := range ops {
+			if v == op {
+				permitted = true
+				break
+			}
+		}
+	}
+
+	if permitted {
+		return nil
+	}
+	return &KeyUsageError{
+		KeyID:     jwkKey.KeyID(),
+		Operation: op,
+		Usage:     usage,
+		KeyOps:    ops,
+	}
+}
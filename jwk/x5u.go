@@ -0,0 +1,182 @@
+package jwk
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/lestrrat-go/httprc"
+)
+
+// X509Fetcher is responsible for retrieving a certificate chain from
+// an URL, such as the one found in the `x5u` header. It is the
+// counterpart of `jwk.Fetcher` for PEM encoded certificate chains.
+type X509Fetcher interface {
+	FetchX509(context.Context, string, ...FetchOption) ([]*x509.Certificate, error)
+}
+
+type X509FetchFunc func(context.Context, string, ...FetchOption) ([]*x509.Certificate, error)
+
+func (f X509FetchFunc) FetchX509(ctx context.Context, u string, options ...FetchOption) ([]*x509.Certificate, error) {
+	return f(ctx, u, options...)
+}
+
+// FetchX509 fetches a PEM encoded certificate chain specified by a URL,
+// as described for the `x5u` header in RFC7515 and RFC7517. The first
+// certificate in the returned list is the one containing the key, and
+// each following certificate is expected to certify the previous one.
+//
+// Only the `jwk.WithHTTPClient` and `jwk.WithFetchWhitelist` options
+// are used. Please note that the chain is merely parsed: it is the
+// caller's responsibility to verify it.
+func FetchX509(ctx context.Context, u string, options ...FetchOption) ([]*x509.Certificate, error) {
+	var hrfopts []httprc.FetchOption
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identHTTPClient{}:
+			hrfopts = append(hrfopts, httprc.WithHTTPClient(option.Value().(HTTPClient)))
+		case identFetchWhitelist{}:
+			hrfopts = append(hrfopts, httprc.WithWhitelist(option.Value().(httprc.Whitelist)))
+		}
+	}
+
+	res, err := globalFetcher.Fetch(ctx, u, hrfopts...)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to fetch %q: %w`, u, err)
+	}
+
+	buf, err := io.ReadAll(res.Body)
+	defer res.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read response body for %q: %w`, u, err)
+	}
+
+	return parseX509Chain(buf)
+}
+
+func parseX509Chain(src []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, src = pem.Decode(src)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf(`invalid PEM block type in certificate chain: %q`, block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse certificate #%d: %w`, len(certs), err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf(`no certificates found in PEM encoded certificate chain`)
+	}
+	return certs, nil
+}
+
+type x509Transform struct{}
+
+func (x509Transform) Transform(u string, res *http.Response) (interface{}, error) {
+	buf, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to read response body status: %w`, err)
+	}
+
+	certs, err := parseX509Chain(buf)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse certificate chain at %q: %w`, u, err)
+	}
+	return certs, nil
+}
+
+// RegisterX509 registers a URL pointing to a PEM encoded certificate chain
+// (e.g. the value of a `x5u` header) to be managed by the cache. It works
+// just like `Register`, but the resource is retrieved via `GetX509` instead
+// of `Get`. Options that control parsing of JWKS (such as `jwk.WithPostFetcher`)
+// are ignored.
+func (c *Cache) RegisterX509(u string, options ...RegisterOption) error {
+	params := parseRegisterOptions(options...)
+	return c.register(u, params, params.client, x509Transform{})
+}
+
+// GetX509 returns the certificate chain stored in the cache for a URL
+// registered using `RegisterX509`.
+func (c *Cache) GetX509(ctx context.Context, u string) ([]*x509.Certificate, error) {
+	v, err := c.cache.Get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, ok := v.([]*x509.Certificate)
+	if !ok {
+		return nil, fmt.Errorf(`cached object is not a certificate chain (was %T)`, v)
+	}
+	return certs, nil
+}
+
+type cachedX509Fetcher struct {
+	cache *Cache
+}
+
+// NewCachedX509Fetcher creates a `jwk.X509Fetcher` that retrieves
+// certificate chains through the given `jwk.Cache`. URLs that have not
+// been registered yet are registered using `RegisterX509` upon first use,
+// with the options passed to `FetchX509`.
+//
+// As these URLs usually come from untrusted input such as the `x5u`
+// header, a URL is only registered if it uses the "https" scheme and is
+// allowed by the whitelist specified via `jwk.WithFetchWhitelist`. If the
+// first fetch fails, the URL is unregistered again.
+func NewCachedX509Fetcher(cache *Cache) X509Fetcher {
+	return &cachedX509Fetcher{cache: cache}
+}
+
+func (f *cachedX509Fetcher) FetchX509(ctx context.Context, u string, options ...FetchOption) ([]*x509.Certificate, error) {
+	if f.cache.IsRegistered(u) {
+		return f.cache.GetX509(ctx, u)
+	}
+
+	uo, err := url.Parse(u)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to parse %q: %w`, u, err)
+	}
+	if uo.Scheme != `https` {
+		return nil, fmt.Errorf(`url %q must be HTTPS`, u)
+	}
+
+	var wl Whitelist
+	regopts := make([]RegisterOption, len(options))
+	for i, option := range options {
+		if option.Ident() == (identFetchWhitelist{}) {
+			//nolint:forcetypeassert
+			wl = option.Value().(Whitelist)
+		}
+		regopts[i] = option
+	}
+	if wl != nil && !wl.IsAllowed(u) {
+		return nil, fmt.Errorf(`fetching url %q rejected by whitelist`, u)
+	}
+
+	if err := f.cache.RegisterX509(u, regopts...); err != nil {
+		return nil, fmt.Errorf(`failed to register %q: %w`, u, err)
+	}
+
+	certs, err := f.cache.GetX509(ctx, u)
+	if err != nil {
+		// Do not keep refreshing URLs that never worked
+		_ = f.cache.Unregister(u)
+		return nil, err
+	}
+	return certs, nil
+}
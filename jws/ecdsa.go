@@ -53,15 +53,30 @@ func (es ecdsaSigner) Algorithm() jwa.SignatureAlgorithm {
 }
 
 func (es *ecdsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	w, err := es.NewSignWriter(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, fmt.Errorf(`failed to write payload using ecdsa: %w`, err)
+	}
+	return w.Sign()
+}
+
+func (es *ecdsaSigner) NewSignWriter(key interface{}) (SignWriter, error) {
 	if key == nil {
 		return nil, fmt.Errorf(`missing private key while signing payload`)
 	}
 
-	h := es.hash.New()
-	if _, err := h.Write(payload); err != nil {
-		return nil, fmt.Errorf(`failed to write payload using ecdsa: %w`, err)
-	}
+	return &digestSignWriter{
+		Hash: es.hash.New(),
+		sign: func(digest []byte) ([]byte, error) {
+			return es.signDigest(digest, key)
+		},
+	}, nil
+}
 
+func (es *ecdsaSigner) signDigest(digest []byte, key interface{}) ([]byte, error) {
 	signer, ok := key.(crypto.Signer)
 	if ok {
 		switch key.(type) {
@@ -75,7 +90,7 @@ func (es *ecdsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 	var r, s *big.Int
 	var curveBits int
 	if ok {
-		signed, err := signer.Sign(rand.Reader, h.Sum(nil), es.hash)
+		signed, err := signer.Sign(rand.Reader, digest, es.hash)
 		if err != nil {
 			return nil, err
 		}
@@ -110,7 +125,7 @@ func (es *ecdsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 			return nil, fmt.Errorf(`failed to retrieve ecdsa.PrivateKey out of %T: %w`, key, err)
 		}
 		curveBits = privkey.Curve.Params().BitSize
-		rtmp, stmp, err := ecdsa.Sign(rand.Reader, &privkey, h.Sum(nil))
+		rtmp, stmp, err := ecdsa.Sign(rand.Reader, &privkey, digest)
 		if err != nil {
 			return nil, fmt.Errorf(`failed to sign payload using ecdsa: %w`, err)
 		}
@@ -151,8 +166,19 @@ func (v ecdsaVerifier) Algorithm() jwa.SignatureAlgorithm {
 }
 
 func (v *ecdsaVerifier) Verify(payload []byte, signature []byte, key interface{}) error {
+	w, err := v.NewVerifyWriter(key)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf(`failed to write payload using ecdsa: %w`, err)
+	}
+	return w.Verify(signature)
+}
+
+func (v *ecdsaVerifier) NewVerifyWriter(key interface{}) (VerifyWriter, error) {
 	if key == nil {
-		return fmt.Errorf(`missing public key while verifying payload`)
+		return nil, fmt.Errorf(`missing public key while verifying payload`)
 	}
 
 	var pubkey ecdsa.PublicKey
@@ -164,30 +190,30 @@ func (v *ecdsaVerifier) Verify(payload []byte, signature []byte, key interface{}
 		case *ecdsa.PublicKey:
 			pubkey = *cpub
 		default:
-			return fmt.Errorf(`failed to retrieve ecdsa.PublicKey out of crypto.Signer %T`, key)
+			return nil, fmt.Errorf(`failed to retrieve ecdsa.PublicKey out of crypto.Signer %T`, key)
 		}
 	} else {
 		if err := keyconv.ECDSAPublicKey(&pubkey, key); err != nil {
-			return fmt.Errorf(`failed to retrieve ecdsa.PublicKey out of %T: %w`, key, err)
+			return nil, fmt.Errorf(`failed to retrieve ecdsa.PublicKey out of %T: %w`, key, err)
 		}
 	}
 
-	r := pool.GetBigInt()
-	s := pool.GetBigInt()
-	defer pool.ReleaseBigInt(r)
-	defer pool.ReleaseBigInt(s)
-
-	n := len(signature) / 2
-	r.SetBytes(signature[:n])
-	s.SetBytes(signature[n:])
-
-	h := v.hash.New()
-	if _, err := h.Write(payload); err != nil {
-		return fmt.Errorf(`failed to write payload using ecdsa: %w`, err)
-	}
-
-	if !ecdsa.Verify(&pubkey, h.Sum(nil), r, s) {
-		return fmt.Errorf(`failed to verify signature using ecdsa`)
-	}
-	return nil
+	return &digestVerifyWriter{
+		Hash: v.hash.New(),
+		verify: func(digest, signature []byte) error {
+			r := pool.GetBigInt()
+			s := pool.GetBigInt()
+			defer pool.ReleaseBigInt(r)
+			defer pool.ReleaseBigInt(s)
+
+			n := len(signature) / 2
+			r.SetBytes(signature[:n])
+			s.SetBytes(signature[n:])
+
+			if !ecdsa.Verify(&pubkey, digest, r, s) {
+				return fmt.Errorf(`failed to verify signature using ecdsa`)
+			}
+			return nil
+		},
+	}, nil
 }
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"fmt"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/jwx/v2/internal/keyconv"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 )
@@ -25,17 +26,22 @@ func (s eddsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 		return nil, fmt.Errorf(`missing private key while signing payload`)
 	}
 
-	// The ed25519.PrivateKey object implements crypto.Signer, so we should
-	// simply accept a crypto.Signer here.
+	// The ed25519.PrivateKey and ed448.PrivateKey objects implement
+	// crypto.Signer, so we should simply accept a crypto.Signer here.
 	signer, ok := key.(crypto.Signer)
 	if !ok {
 		// This fallback exists for cases when jwk.Key was passed, or
 		// users gave us a pointer instead of non-pointer, etc.
-		var privkey ed25519.PrivateKey
-		if err := keyconv.Ed25519PrivateKey(&privkey, key); err != nil {
-			return nil, fmt.Errorf(`failed to retrieve ed25519.PrivateKey out of %T: %w`, key, err)
+		var ed25519priv ed25519.PrivateKey
+		if err := keyconv.Ed25519PrivateKey(&ed25519priv, key); err == nil {
+			signer = ed25519priv
+		} else {
+			var ed448priv ed448.PrivateKey
+			if err448 := keyconv.Ed448PrivateKey(&ed448priv, key); err448 != nil {
+				return nil, fmt.Errorf(`failed to retrieve ed25519.PrivateKey or ed448.PrivateKey out of %T: %w`, key, err)
+			}
+			signer = ed448priv
 		}
-		signer = privkey
 	}
 	return signer.Sign(rand.Reader, payload, crypto.Hash(0))
 }
@@ -51,22 +57,33 @@ func (v eddsaVerifier) Verify(payload, signature []byte, key interface{}) (err e
 		return fmt.Errorf(`missing public key while verifying payload`)
 	}
 
-	var pubkey ed25519.PublicKey
-	signer, ok := key.(crypto.Signer)
-	if ok {
-		v := signer.Public()
-		pubkey, ok = v.(ed25519.PublicKey)
-		if !ok {
-			return fmt.Errorf(`expected crypto.Signer.Public() to return ed25519.PublicKey, but got %T`, v)
-		}
+	var pubkey interface{}
+	if signer, ok := key.(crypto.Signer); ok {
+		pubkey = signer.Public()
 	} else {
-		if err := keyconv.Ed25519PublicKey(&pubkey, key); err != nil {
-			return fmt.Errorf(`failed to retrieve ed25519.PublicKey out of %T: %w`, key, err)
+		var ed25519pub ed25519.PublicKey
+		if err := keyconv.Ed25519PublicKey(&ed25519pub, key); err == nil {
+			pubkey = ed25519pub
+		} else {
+			var ed448pub ed448.PublicKey
+			if err448 := keyconv.Ed448PublicKey(&ed448pub, key); err448 != nil {
+				return fmt.Errorf(`failed to retrieve ed25519.PublicKey or ed448.PublicKey out of %T: %w`, key, err)
+			}
+			pubkey = ed448pub
 		}
 	}
 
-	if !ed25519.Verify(pubkey, payload, signature) {
-		return fmt.Errorf(`failed to match EdDSA signature`)
+	switch pubkey := pubkey.(type) {
+	case ed25519.PublicKey:
+		if !ed25519.Verify(pubkey, payload, signature) {
+			return fmt.Errorf(`failed to match EdDSA signature`)
+		}
+	case ed448.PublicKey:
+		if !ed448.Verify(pubkey, payload, signature, "") {
+			return fmt.Errorf(`failed to match EdDSA signature`)
+		}
+	default:
+		return fmt.Errorf(`expected crypto.Signer.Public() to return ed25519.PublicKey or ed448.PublicKey, but got %T`, pubkey)
 	}
 
 	return nil
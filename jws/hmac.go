@@ -12,6 +12,7 @@ import (
 )
 
 var hmacSignFuncs = map[jwa.SignatureAlgorithm]hmacSignFunc{}
+var hmacHashFuncs = map[jwa.SignatureAlgorithm]func() hash.Hash{}
 
 func init() {
 	algs := map[jwa.SignatureAlgorithm]func() hash.Hash{
@@ -22,6 +23,7 @@ func init() {
 
 	for alg, h := range algs {
 		hmacSignFuncs[alg] = makeHMACSignFunc(h)
+		hmacHashFuncs[alg] = h
 	}
 }
 
@@ -29,6 +31,7 @@ func newHMACSigner(alg jwa.SignatureAlgorithm) Signer {
 	return &HMACSigner{
 		alg:  alg,
 		sign: hmacSignFuncs[alg], // we know this will succeed
+		hash: hmacHashFuncs[alg],
 	}
 }
 
@@ -59,6 +62,24 @@ func (s HMACSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 	return s.sign(payload, hmackey)
 }
 
+func (s HMACSigner) NewSignWriter(key interface{}) (SignWriter, error) {
+	var hmackey []byte
+	if err := keyconv.ByteSliceKey(&hmackey, key); err != nil {
+		return nil, fmt.Errorf(`invalid key type %T. []byte is required: %w`, key, err)
+	}
+
+	if len(hmackey) == 0 {
+		return nil, fmt.Errorf(`missing key while signing payload`)
+	}
+
+	return &digestSignWriter{
+		Hash: hmac.New(s.hash, hmackey),
+		sign: func(mac []byte) ([]byte, error) {
+			return mac, nil
+		},
+	}, nil
+}
+
 func newHMACVerifier(alg jwa.SignatureAlgorithm) Verifier {
 	s := newHMACSigner(alg)
 	return &HMACVerifier{signer: s}
@@ -75,3 +96,32 @@ func (v HMACVerifier) Verify(payload, signature []byte, key interface{}) (err er
 	}
 	return nil
 }
+
+func (v HMACVerifier) NewVerifyWriter(key interface{}) (VerifyWriter, error) {
+	ss, ok := v.signer.(StreamSigner)
+	if !ok {
+		return &bufferedVerifyWriter{verifier: v, key: key}, nil
+	}
+
+	w, err := ss.NewSignWriter(key)
+	if err != nil {
+		return nil, fmt.Errorf(`failed to generated signature: %w`, err)
+	}
+	return &hmacVerifyWriter{SignWriter: w}, nil
+}
+
+type hmacVerifyWriter struct {
+	SignWriter
+}
+
+func (w *hmacVerifyWriter) Verify(signature []byte) error {
+	expected, err := w.Sign()
+	if err != nil {
+		return fmt.Errorf(`failed to generated signature: %w`, err)
+	}
+
+	if !hmac.Equal(signature, expected) {
+		return fmt.Errorf(`failed to match hmac signature`)
+	}
+	return nil
+}
@@ -1,6 +1,9 @@
 package jws
 
 import (
+	"hash"
+	"io"
+
 	"github.com/lestrrat-go/iter/mapiter"
 	"github.com/lestrrat-go/jwx/v2/internal/iter"
 	"github.com/lestrrat-go/jwx/v2/jwa"
@@ -82,12 +85,29 @@ type Signer interface {
 	Algorithm() jwa.SignatureAlgorithm
 }
 
+// StreamSigner is an optional interface that Signers may implement
+// in order to generate signatures over content that is fed to them
+// incrementally, such as the payload passed to `jws.SignReader()`.
+type StreamSigner interface {
+	// NewSignWriter creates a SignWriter that generates a signature
+	// using `key` over the content written to it.
+	NewSignWriter(key interface{}) (SignWriter, error)
+}
+
+// SignWriter accumulates the content to be signed.
+type SignWriter interface {
+	io.Writer
+	// Sign returns the signature for the content written so far.
+	Sign() ([]byte, error)
+}
+
 type hmacSignFunc func([]byte, []byte) ([]byte, error)
 
 // HMACSigner uses crypto/hmac to sign the payloads.
 type HMACSigner struct {
 	alg  jwa.SignatureAlgorithm
 	sign hmacSignFunc
+	hash func() hash.Hash
 }
 
 type Verifier interface {
@@ -101,6 +121,23 @@ type Verifier interface {
 	Verify(payload []byte, signature []byte, key interface{}) error
 }
 
+// StreamVerifier is an optional interface that Verifiers may implement
+// in order to verify signatures over content that is fed to them
+// incrementally, such as the payload passed to `jws.VerifyReader()`.
+type StreamVerifier interface {
+	// NewVerifyWriter creates a VerifyWriter that verifies signatures
+	// using `key` over the content written to it.
+	NewVerifyWriter(key interface{}) (VerifyWriter, error)
+}
+
+// VerifyWriter accumulates the content to be verified.
+type VerifyWriter interface {
+	io.Writer
+	// Verify checks whether `signature` is valid for the content
+	// written so far.
+	Verify(signature []byte) error
+}
+
 type HMACVerifier struct {
 	signer Signer
 }
@@ -36,9 +36,11 @@ import (
 	"unicode"
 	"unicode/utf8"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/blackmagic"
 	"github.com/lestrrat-go/jwx/v2/internal/base64"
 	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/internal/keyusage"
 	"github.com/lestrrat-go/jwx/v2/internal/pool"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
@@ -47,6 +49,19 @@ import (
 
 var registry = json.NewRegistry()
 
+var strictKeyUsage keyusage.Setting
+
+// Settings controls global settings that are specific to JWS.
+func Settings(options ...GlobalOption) {
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identStrictKeyUsage{}:
+			strictKeyUsage.Set(option.Value().(bool))
+		}
+	}
+}
+
 type payloadSigner struct {
 	signer    Signer
 	key       interface{}
@@ -70,6 +85,33 @@ func (s *payloadSigner) PublicHeader() Headers {
 	return s.public
 }
 
+// newSignature creates a Signature object with the headers for this
+// signer populated. The signature itself is not computed.
+func (s *payloadSigner) newSignature(detached bool) (*Signature, error) {
+	protected := s.ProtectedHeader()
+	if protected == nil {
+		protected = NewHeaders()
+	}
+
+	if err := protected.Set(AlgorithmKey, s.Algorithm()); err != nil {
+		return nil, fmt.Errorf(`failed to set "alg" header: %w`, err)
+	}
+
+	if key, ok := s.key.(jwk.Key); ok {
+		if kid := key.KeyID(); kid != "" {
+			if err := protected.Set(KeyIDKey, kid); err != nil {
+				return nil, fmt.Errorf(`failed to set "kid" header: %w`, err)
+			}
+		}
+	}
+	return &Signature{
+		headers:   s.PublicHeader(),
+		protected: protected,
+		// cheat. FIXXXXXXMEEEEEE
+		detached: detached,
+	}, nil
+}
+
 var signers = make(map[jwa.SignatureAlgorithm]Signer)
 var muSigner = &sync.Mutex{}
 
@@ -139,11 +181,14 @@ func Sign(payload []byte, options ...SignOption) ([]byte, error) {
 	format := fmtCompact
 	var signers []*payloadSigner
 	var detached bool
+	strict := strictKeyUsage.Get()
 	for _, option := range options {
 		//nolint:forcetypeassert
 		switch option.Ident() {
 		case identSerialization{}:
 			format = option.Value().(int)
+		case identStrictKeyUsage{}:
+			strict = option.Value().(bool)
 		case identKey{}:
 			data := option.Value().(*withKey)
 
@@ -156,6 +201,12 @@ func Sign(payload []byte, options ...SignOption) ([]byte, error) {
 				return nil, fmt.Errorf(`jws.Sign: failed to create signer: %w`, err)
 			}
 			signers = append(signers, signer)
+		case identKeyRing{}:
+			signer, err := option.Value().(*withKeyRing).signer()
+			if err != nil {
+				return nil, fmt.Errorf(`jws.Sign: failed to create signer from jwk.KeyRing: %w`, err)
+			}
+			signers = append(signers, signer)
 		case identDetachedPayload{}:
 			detached = true
 			if payload != nil {
@@ -170,6 +221,14 @@ func Sign(payload []byte, options ...SignOption) ([]byte, error) {
 		return nil, fmt.Errorf(`jws.Sign: no signers available. Specify an alogirthm and akey using jws.WithKey()`)
 	}
 
+	if strict {
+		for i, signer := range signers {
+			if err := jwk.CheckKeyUsage(signer.key, jwk.KeyOpSign); err != nil {
+				return nil, fmt.Errorf(`jws.Sign: invalid key for signer #%d: %w`, i, err)
+			}
+		}
+	}
+
 	// Design note: while we could have easily set format = fmtJSON when
 	// lsigner > 1, I believe the decision to change serialization formats
 	// must be explicitly stated by the caller. Otherwise I'm pretty sure
@@ -190,29 +249,11 @@ func Sign(payload []byte, options ...SignOption) ([]byte, error) {
 
 	result.signatures = make([]*Signature, 0, len(signers))
 	for i, signer := range signers {
-		protected := signer.ProtectedHeader()
-		if protected == nil {
-			protected = NewHeaders()
-		}
-
-		if err := protected.Set(AlgorithmKey, signer.Algorithm()); err != nil {
-			return nil, fmt.Errorf(`failed to set "alg" header: %w`, err)
-		}
-
-		if key, ok := signer.key.(jwk.Key); ok {
-			if kid := key.KeyID(); kid != "" {
-				if err := protected.Set(KeyIDKey, kid); err != nil {
-					return nil, fmt.Errorf(`failed to set "kid" header: %w`, err)
-				}
-			}
-		}
-		sig := &Signature{
-			headers:   signer.PublicHeader(),
-			protected: protected,
-			// cheat. FIXXXXXXMEEEEEE
-			detached: detached,
+		sig, err := signer.newSignature(detached)
+		if err != nil {
+			return nil, err
 		}
-		_, _, err := sig.Sign(payload, signer.signer, signer.key)
+		_, _, err = sig.Sign(payload, signer.signer, signer.key)
 		if err != nil {
 			return nil, fmt.Errorf(`failed to generate signature for signer #%d (alg=%s): %w`, i, signer.Algorithm(), err)
 		}
@@ -252,43 +293,9 @@ var allowNoneWhitelist = jwk.WhitelistFunc(func(string) bool {
 // If you need to access signatures and JOSE headers in a JWS message,
 // use `Parse` function to get `Message` object.
 func Verify(buf []byte, options ...VerifyOption) ([]byte, error) {
-	var dst *Message
-	var detachedPayload []byte
-	var keyProviders []KeyProvider
-	var keyUsed interface{}
-
-	ctx := context.Background()
-
-	//nolint:forcetypeassert
-	for _, option := range options {
-		switch option.Ident() {
-		case identMessage{}:
-			dst = option.Value().(*Message)
-		case identDetachedPayload{}:
-			detachedPayload = option.Value().([]byte)
-		case identKey{}:
-			pair := option.Value().(*withKey)
-			alg, ok := pair.alg.(jwa.SignatureAlgorithm)
-			if !ok {
-				return nil, fmt.Errorf(`WithKey() option must be specified using jwa.SignatureAlgorithm (got %T)`, pair.alg)
-			}
-			keyProviders = append(keyProviders, &staticKeyProvider{
-				alg: alg,
-				key: pair.key,
-			})
-		case identKeyProvider{}:
-			keyProviders = append(keyProviders, option.Value().(KeyProvider))
-		case identKeyUsed{}:
-			keyUsed = option.Value()
-		case identContext{}:
-			ctx = option.Value().(context.Context)
-		default:
-			return nil, fmt.Errorf(`invalid jws.VerifyOption %q passed`, `With`+strings.TrimPrefix(fmt.Sprintf(`%T`, option.Ident()), `jws.ident`))
-		}
-	}
-
-	if len(keyProviders) < 1 {
-		return nil, fmt.Errorf(`jws.Verify: no key providers have been provided (see jws.WithKey(), jws.WithKeySet(), jws.WithVerifyAuto(), and jws.WithKeyProvider()`)
+	vctx, err := newVerifyCtx(options)
+	if err != nil {
+		return nil, fmt.Errorf(`jws.Verify: %w`, err)
 	}
 
 	msg, err := Parse(buf)
@@ -297,12 +304,16 @@ func Verify(buf []byte, options ...VerifyOption) ([]byte, error) {
 	}
 	defer msg.clearRaw()
 
-	if detachedPayload != nil {
+	if err := vctx.verifyCriticalHeaders(msg); err != nil {
+		return nil, err
+	}
+
+	if vctx.detachedPayload != nil {
 		if len(msg.payload) != 0 {
 			return nil, fmt.Errorf(`can't specify detached payload for JWS with payload`)
 		}
 
-		msg.payload = detachedPayload
+		msg.payload = vctx.detachedPayload
 	}
 
 	// Pre-compute the base64 encoded version of payload
@@ -316,32 +327,22 @@ func Verify(buf []byte, options ...VerifyOption) ([]byte, error) {
 	verifyBuf := pool.GetBytesBuffer()
 	defer pool.ReleaseBytesBuffer(verifyBuf)
 
+	var usageErr error
 	for i, sig := range msg.signatures {
 		verifyBuf.Reset()
 
-		var encodedProtectedHeader string
-		if rbp, ok := sig.protected.(interface{ rawBuffer() []byte }); ok {
-			if raw := rbp.rawBuffer(); raw != nil {
-				encodedProtectedHeader = base64.EncodeToString(raw)
-			}
-		}
-
-		if encodedProtectedHeader == "" {
-			protected, err := json.Marshal(sig.protected)
-			if err != nil {
-				return nil, fmt.Errorf(`failed to marshal "protected" for signature #%d: %w`, i+1, err)
-			}
-
-			encodedProtectedHeader = base64.EncodeToString(protected)
+		encodedProtectedHeader, err := sig.encodedProtectedHeader()
+		if err != nil {
+			return nil, fmt.Errorf(`failed to marshal "protected" for signature #%d: %w`, i+1, err)
 		}
 
 		verifyBuf.WriteString(encodedProtectedHeader)
 		verifyBuf.WriteByte('.')
 		verifyBuf.WriteString(payload)
 
-		for i, kp := range keyProviders {
+		for i, kp := range vctx.keyProviders {
 			var sink algKeySink
-			if err := kp.FetchKeys(ctx, &sink, sig, msg); err != nil {
+			if err := kp.FetchKeys(vctx.ctx, &sink, sig, msg); err != nil {
 				return nil, fmt.Errorf(`key provider %d failed: %w`, i, err)
 			}
 
@@ -352,6 +353,13 @@ func Verify(buf []byte, options ...VerifyOption) ([]byte, error) {
 				//nolint:forcetypeassert
 				alg := pair.alg.(jwa.SignatureAlgorithm)
 				key := pair.key
+				if vctx.strictKeyUsage {
+					if err := jwk.CheckKeyUsage(key, jwk.KeyOpVerify); err != nil {
+						usageErr = err
+						continue
+					}
+				}
+
 				verifier, err := NewVerifier(alg)
 				if err != nil {
 					return nil, fmt.Errorf(`failed to create verifier for algorithm %q: %w`, alg, err)
@@ -361,23 +369,117 @@ func Verify(buf []byte, options ...VerifyOption) ([]byte, error) {
 					continue
 				}
 
-				if keyUsed != nil {
-					if err := blackmagic.AssignIfCompatible(keyUsed, key); err != nil {
-						return nil, fmt.Errorf(`failed to assign used key (%T) to %T: %w`, key, keyUsed, err)
+				if vctx.keyUsed != nil {
+					if err := blackmagic.AssignIfCompatible(vctx.keyUsed, key); err != nil {
+						return nil, fmt.Errorf(`failed to assign used key (%T) to %T: %w`, key, vctx.keyUsed, err)
 					}
 				}
 
-				if dst != nil {
-					*(dst) = *msg
+				if pair.verified != nil {
+					pair.verified()
+				}
+
+				if vctx.dst != nil {
+					*(vctx.dst) = *msg
 				}
 
 				return msg.payload, nil
 			}
 		}
 	}
+
+	if usageErr != nil {
+		return nil, fmt.Errorf(`could not verify message using any of the signatures or keys: %w`, usageErr)
+	}
 	return nil, fmt.Errorf(`could not verify message using any of the signatures or keys`)
 }
 
+// verifyCtx holds the information computed from the options
+// passed to `jws.Verify()` or `jws.VerifyReader()`
+type verifyCtx struct {
+	ctx             context.Context
+	dst             *Message
+	detachedPayload []byte
+	keyProviders    []KeyProvider
+	keyUsed         interface{}
+	criticalHeaders map[string]struct{}
+	strictKeyUsage  bool
+}
+
+func newVerifyCtx(options []VerifyOption) (*verifyCtx, error) {
+	vctx := verifyCtx{
+		ctx:            context.Background(),
+		strictKeyUsage: strictKeyUsage.Get(),
+	}
+
+	//nolint:forcetypeassert
+	for _, option := range options {
+		switch option.Ident() {
+		case identMessage{}:
+			vctx.dst = option.Value().(*Message)
+		case identDetachedPayload{}:
+			vctx.detachedPayload = option.Value().([]byte)
+		case identKey{}:
+			pair := option.Value().(*withKey)
+			alg, ok := pair.alg.(jwa.SignatureAlgorithm)
+			if !ok {
+				return nil, fmt.Errorf(`WithKey() option must be specified using jwa.SignatureAlgorithm (got %T)`, pair.alg)
+			}
+			vctx.keyProviders = append(vctx.keyProviders, &staticKeyProvider{
+				alg: alg,
+				key: pair.key,
+			})
+		case identKeyProvider{}:
+			vctx.keyProviders = append(vctx.keyProviders, option.Value().(KeyProvider))
+		case identKeyUsed{}:
+			vctx.keyUsed = option.Value()
+		case identContext{}:
+			vctx.ctx = option.Value().(context.Context)
+		case identCriticalHeaders{}:
+			if vctx.criticalHeaders == nil {
+				vctx.criticalHeaders = make(map[string]struct{})
+			}
+			for _, name := range option.Value().([]string) {
+				vctx.criticalHeaders[name] = struct{}{}
+			}
+		case identStrictKeyUsage{}:
+			vctx.strictKeyUsage = option.Value().(bool)
+		default:
+			return nil, fmt.Errorf(`invalid jws.VerifyOption %q passed`, `With`+strings.TrimPrefix(fmt.Sprintf(`%T`, option.Ident()), `jws.ident`))
+		}
+	}
+
+	if len(vctx.keyProviders) < 1 {
+		return nil, fmt.Errorf(`no key providers have been provided (see jws.WithKey(), jws.WithKeySet(), jws.WithVerifyAuto(), and jws.WithKeyProvider()`)
+	}
+	return &vctx, nil
+}
+
+func (vctx *verifyCtx) verifyCriticalHeaders(msg *Message) error {
+	for i, sig := range msg.signatures {
+		if err := verifyCriticalHeaders(sig, vctx.criticalHeaders); err != nil {
+			return fmt.Errorf(`invalid "crit" header for signature #%d: %w`, i+1, err)
+		}
+	}
+	return nil
+}
+
+// encodedProtectedHeader returns the base64 encoded protected header
+// of the signature, as it appears in the signing input
+func (sig *Signature) encodedProtectedHeader() (string, error) {
+	if rbp, ok := sig.protected.(interface{ rawBuffer() []byte }); ok {
+		if raw := rbp.rawBuffer(); raw != nil {
+			return base64.EncodeToString(raw), nil
+		}
+	}
+
+	protected, err := json.Marshal(sig.protected)
+	if err != nil {
+		return "", err
+	}
+	return base64.EncodeToString(protected), nil
+}
+
 // get the value of b64 header field.
 // If the field does not exist, returns true (default)
 // Otherwise return the value specified by the header field.
@@ -394,6 +496,71 @@ func getB64Value(hdr Headers) bool {
 	return b64
 }
 
+// registeredHeaders lists the header parameter names defined in RFC 7515.
+// These may not be listed in the "crit" header.
+var registeredHeaders = map[string]struct{}{
+	AlgorithmKey:              {},
+	ContentTypeKey:            {},
+	CriticalKey:               {},
+	JWKKey:                    {},
+	JWKSetURLKey:              {},
+	KeyIDKey:                  {},
+	TypeKey:                   {},
+	X509CertChainKey:          {},
+	X509CertThumbprintKey:     {},
+	X509CertThumbprintS256Key: {},
+	X509URLKey:                {},
+}
+
+// builtinCriticalHeaders lists the extension header parameters that
+// this package understands natively.
+var builtinCriticalHeaders = map[string]struct{}{
+	"b64": {},
+}
+
+// verifyCriticalHeaders checks the "crit" header of the given signature
+// as described in RFC 7515 Section 4.1.11. Extensions must either be
+// understood by this package, or explicitly declared by the user in
+// `understood`.
+func verifyCriticalHeaders(sig *Signature, understood map[string]struct{}) error {
+	if hdrs := sig.headers; hdrs != nil {
+		if _, ok := hdrs.Get(CriticalKey); ok {
+			return fmt.Errorf(`"crit" must be placed in the protected header`)
+		}
+	}
+
+	hdrs := sig.protected
+	if hdrs == nil {
+		return nil
+	}
+
+	if _, ok := hdrs.Get(CriticalKey); !ok {
+		return nil
+	}
+
+	crit := hdrs.Critical()
+	if len(crit) == 0 {
+		return fmt.Errorf(`"crit" must not be empty`)
+	}
+
+	for _, name := range crit {
+		if _, ok := registeredHeaders[name]; ok {
+			return fmt.Errorf(`"crit" must not contain registered header parameter %q`, name)
+		}
+
+		_, builtin := builtinCriticalHeaders[name]
+		_, declared := understood[name]
+		if !builtin && !declared {
+			return fmt.Errorf(`unsupported critical extension %q (see jws.WithCriticalHeaders())`, name)
+		}
+
+		if _, ok := hdrs.Get(name); !ok {
+			return fmt.Errorf(`critical extension %q is not present in the protected header`, name)
+		}
+	}
+	return nil
+}
+
 // This is an "optimized" io.ReadAll(). It will attempt to read
 // all of the contents from the reader IF the reader is of a certain
 // concrete type.
@@ -665,6 +832,7 @@ var keyTypeToAlgorithms = make(map[jwa.KeyType][]jwa.SignatureAlgorithm)
 func init() {
 	rawKeyToKeyType[reflect.TypeOf([]byte(nil))] = jwa.OctetSeq
 	rawKeyToKeyType[reflect.TypeOf(ed25519.PublicKey(nil))] = jwa.OKP
+	rawKeyToKeyType[reflect.TypeOf(ed448.PublicKey(nil))] = jwa.OKP
 	rawKeyToKeyType[reflect.TypeOf(rsa.PublicKey{})] = jwa.RSA
 	rawKeyToKeyType[reflect.TypeOf((*rsa.PublicKey)(nil))] = jwa.RSA
 	rawKeyToKeyType[reflect.TypeOf(ecdsa.PublicKey{})] = jwa.EC
@@ -699,7 +867,7 @@ func AlgorithmsForKey(key interface{}) ([]jwa.SignatureAlgorithm, error) {
 		kty = jwa.RSA
 	case ecdsa.PublicKey, *ecdsa.PublicKey, ecdsa.PrivateKey, *ecdsa.PrivateKey:
 		kty = jwa.EC
-	case ed25519.PublicKey, ed25519.PrivateKey, x25519.PublicKey, x25519.PrivateKey:
+	case ed25519.PublicKey, ed25519.PrivateKey, ed448.PublicKey, ed448.PrivateKey, x25519.PublicKey, x25519.PrivateKey:
 		kty = jwa.OKP
 	case []byte:
 		kty = jwa.OctetSeq
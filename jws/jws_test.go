@@ -7,9 +7,15 @@ import (
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha512"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/asn1"
+	"encoding/pem"
+	"errors"
 	"fmt"
 	"io"
 	"math/big"
@@ -18,10 +24,14 @@ import (
 	"os"
 	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/cloudflare/circl/sign/ed448"
 	"github.com/lestrrat-go/httprc"
+	"github.com/lestrrat-go/jwx/v2/cert"
 	"github.com/lestrrat-go/jwx/v2/internal/base64"
 	"github.com/lestrrat-go/jwx/v2/internal/json"
 	"github.com/lestrrat-go/jwx/v2/internal/jwxtest"
@@ -369,6 +379,26 @@ func TestRoundtrip(t *testing.T) {
 			})
 		}
 	})
+	t.Run("EdDSA (Ed448)", func(t *testing.T) {
+		t.Parallel()
+		key, err := jwxtest.GenerateEd448Key()
+		if !assert.NoError(t, err, "ed448 key generated") {
+			return
+		}
+		pubkey := key.Public()
+		jwkKey, _ := jwk.FromRaw(pubkey)
+		keys := map[string]interface{}{
+			"Verify(ed448.Public())": pubkey,
+			"Verify(jwk.Key)":        jwkKey,
+		}
+		for _, alg := range []jwa.SignatureAlgorithm{jwa.EdDSA} {
+			alg := alg
+			t.Run(alg.String(), func(t *testing.T) {
+				t.Parallel()
+				testRoundtrip(t, payload, alg, key, keys)
+			})
+		}
+	})
 }
 
 func TestSignMulti2(t *testing.T) {
@@ -1515,7 +1545,13 @@ func TestGH485(t *testing.T) {
     "signatures": [{"protected": %q, "signature": %q}]
 }`, payload, protected, signature)
 
-	verified, err := jws.Verify([]byte(signed), jws.WithKey(jwa.HS256, []byte("secret")))
+	// "exp" is listed in "crit", so it must be declared as understood
+	_, err := jws.Verify([]byte(signed), jws.WithKey(jwa.HS256, []byte("secret")))
+	if !assert.Error(t, err, `jws.Verify should fail`) {
+		return
+	}
+
+	verified, err := jws.Verify([]byte(signed), jws.WithKey(jwa.HS256, []byte("secret")), jws.WithCriticalHeaders("exp"))
 	if !assert.NoError(t, err, `jws.Verify should succeed`) {
 		return
 	}
@@ -1524,7 +1560,7 @@ func TestGH485(t *testing.T) {
 	}
 
 	compact := strings.Join([]string{protected, payload, signature}, ".")
-	verified, err = jws.Verify([]byte(compact), jws.WithKey(jwa.HS256, []byte("secret")))
+	verified, err = jws.Verify([]byte(compact), jws.WithKey(jwa.HS256, []byte("secret")), jws.WithCriticalHeaders("exp"))
 	if !assert.NoError(t, err, `jws.Verify should succeed`) {
 		return
 	}
@@ -1866,6 +1902,11 @@ func TestAlgorithmsForKey(t *testing.T) {
 			Key:      ed25519.PublicKey(nil),
 			Expected: []jwa.SignatureAlgorithm{jwa.EdDSA},
 		},
+		{
+			Name:     "ed448.PublicKey",
+			Key:      ed448.PublicKey(nil),
+			Expected: []jwa.SignatureAlgorithm{jwa.EdDSA},
+		},
 		{
 			Name:     "x25519.PublicKey",
 			Key:      x25519.PublicKey(nil),
@@ -1919,3 +1960,556 @@ func TestGH681(t *testing.T) {
 		return
 	}
 }
+
+func TestCriticalHeaders(t *testing.T) {
+	key := []byte("secret")
+	sign := func(t *testing.T, hdrs map[string]interface{}, options ...jws.SignOption) []byte {
+		t.Helper()
+		protected := jws.NewHeaders()
+		for k, v := range hdrs {
+			require.NoError(t, protected.Set(k, v), `protected.Set should succeed`)
+		}
+		signed, err := jws.Sign([]byte(examplePayload), append([]jws.SignOption{jws.WithKey(jwa.HS256, key, jws.WithProtectedHeaders(protected))}, options...)...)
+		require.NoError(t, err, `jws.Sign should succeed`)
+		return signed
+	}
+
+	t.Run("Unknown extension", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{
+			jws.CriticalKey: []string{"x-foo"},
+			"x-foo":         "bar",
+		})
+
+		_, err := jws.Verify(signed, jws.WithKey(jwa.HS256, key))
+		require.Error(t, err, `jws.Verify should fail`)
+
+		_, err = jws.Verify(signed, jws.WithKey(jwa.HS256, key), jws.WithCriticalHeaders("x-bar"))
+		require.Error(t, err, `jws.Verify should fail`)
+
+		payload, err := jws.Verify(signed, jws.WithKey(jwa.HS256, key), jws.WithCriticalHeaders("x-bar"), jws.WithCriticalHeaders("x-foo"))
+		require.NoError(t, err, `jws.Verify should succeed`)
+		require.Equal(t, []byte(examplePayload), payload, `payloads should match`)
+	})
+	t.Run("Registered header in crit", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{
+			jws.CriticalKey: []string{jws.KeyIDKey},
+			jws.KeyIDKey:    "foo",
+		})
+
+		_, err := jws.Verify(signed, jws.WithKey(jwa.HS256, key), jws.WithCriticalHeaders(jws.KeyIDKey))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run("Extension missing from header", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{
+			jws.CriticalKey: []string{"x-foo"},
+		})
+
+		_, err := jws.Verify(signed, jws.WithKey(jwa.HS256, key), jws.WithCriticalHeaders("x-foo"))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run("Empty crit", func(t *testing.T) {
+		signed := sign(t, map[string]interface{}{
+			jws.CriticalKey: []string{},
+		})
+
+		_, err := jws.Verify(signed, jws.WithKey(jwa.HS256, key))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run("crit in unprotected header", func(t *testing.T) {
+		public := jws.NewHeaders()
+		require.NoError(t, public.Set(jws.CriticalKey, []string{"x-foo"}), `public.Set should succeed`)
+		require.NoError(t, public.Set("x-foo", "bar"), `public.Set should succeed`)
+
+		signed, err := jws.Sign([]byte(examplePayload), jws.WithKey(jwa.HS256, key, jws.WithPublicHeaders(public)), jws.WithJSON())
+		require.NoError(t, err, `jws.Sign should succeed`)
+
+		_, err = jws.Verify(signed, jws.WithKey(jwa.HS256, key), jws.WithCriticalHeaders("x-foo"))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+}
+
+func TestStream(t *testing.T) {
+	rsakey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+	eckey, err := jwxtest.GenerateEcdsaKey(jwa.P256)
+	require.NoError(t, err, `jwxtest.GenerateEcdsaKey should succeed`)
+	edkey, err := jwxtest.GenerateEd25519Key()
+	require.NoError(t, err, `jwxtest.GenerateEd25519Key should succeed`)
+	symkey := jwxtest.GenerateSymmetricKey()
+
+	payload := make([]byte, 256*1024)
+	for i := range payload {
+		payload[i] = byte(i)
+	}
+
+	testcases := []struct {
+		Alg     jwa.SignatureAlgorithm
+		Private interface{}
+		Public  interface{}
+	}{
+		{Alg: jwa.RS256, Private: rsakey, Public: &rsakey.PublicKey},
+		{Alg: jwa.PS512, Private: rsakey, Public: &rsakey.PublicKey},
+		{Alg: jwa.ES256, Private: eckey, Public: &eckey.PublicKey},
+		{Alg: jwa.HS384, Private: symkey, Public: symkey},
+		{Alg: jwa.EdDSA, Private: edkey, Public: edkey.Public()},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		for _, b64 := range []bool{false, true} {
+			b64 := b64
+			t.Run(fmt.Sprintf("%s/b64=%t", tc.Alg, b64), func(t *testing.T) {
+				hdrs := jws.NewHeaders()
+				if !b64 {
+					require.NoError(t, hdrs.Set("b64", false), `hdrs.Set should succeed`)
+					require.NoError(t, hdrs.Set("crit", []string{"b64"}), `hdrs.Set should succeed`)
+				}
+
+				signed, err := jws.SignReader(bytes.NewReader(payload), jws.WithKey(tc.Alg, tc.Private, jws.WithProtectedHeaders(hdrs)))
+				require.NoError(t, err, `jws.SignReader should succeed`)
+				require.Len(t, bytes.Split(signed, []byte{'.'}), 3, `result should be a compact JWS message`)
+				require.Empty(t, bytes.Split(signed, []byte{'.'})[1], `payload should be detached`)
+
+				require.NoError(t, jws.VerifyReader(signed, bytes.NewReader(payload), jws.WithKey(tc.Alg, tc.Public)), `jws.VerifyReader should succeed`)
+
+				verified, err := jws.Verify(signed, jws.WithKey(tc.Alg, tc.Public), jws.WithDetachedPayload(payload))
+				require.NoError(t, err, `jws.Verify should succeed`)
+				require.Equal(t, payload, verified, `payloads should match`)
+
+				signed, err = jws.Sign(nil, jws.WithKey(tc.Alg, tc.Private, jws.WithProtectedHeaders(hdrs)), jws.WithDetachedPayload(payload))
+				require.NoError(t, err, `jws.Sign should succeed`)
+				require.NoError(t, jws.VerifyReader(signed, bytes.NewReader(payload), jws.WithKey(tc.Alg, tc.Public)), `jws.VerifyReader should succeed`)
+
+				tampered := make([]byte, len(payload))
+				copy(tampered, payload)
+				tampered[len(tampered)-1]++
+				require.Error(t, jws.VerifyReader(signed, bytes.NewReader(tampered), jws.WithKey(tc.Alg, tc.Public)), `jws.VerifyReader should fail`)
+			})
+		}
+	}
+
+	t.Run("Multiple signatures", func(t *testing.T) {
+		hdrs := jws.NewHeaders()
+		require.NoError(t, hdrs.Set("b64", false), `hdrs.Set should succeed`)
+		require.NoError(t, hdrs.Set("crit", []string{"b64"}), `hdrs.Set should succeed`)
+
+		signed, err := jws.SignReader(bytes.NewReader(payload),
+			jws.WithJSON(),
+			jws.WithKey(jwa.RS256, rsakey, jws.WithProtectedHeaders(hdrs)),
+			jws.WithKey(jwa.ES256, eckey, jws.WithProtectedHeaders(hdrs)),
+		)
+		require.NoError(t, err, `jws.SignReader should succeed`)
+
+		var keyUsed interface{}
+		var msg jws.Message
+		require.NoError(t, jws.VerifyReader(signed, bytes.NewReader(payload),
+			jws.WithKey(jwa.HS256, symkey),
+			jws.WithKey(jwa.ES256, &eckey.PublicKey),
+			jws.WithKeyUsed(&keyUsed),
+			jws.WithMessage(&msg),
+		), `jws.VerifyReader should succeed`)
+		require.Equal(t, &eckey.PublicKey, keyUsed, `the ECDSA key should have been used`)
+		require.Len(t, msg.Signatures(), 2, `message should have two signatures`)
+	})
+	t.Run("Invalid options", func(t *testing.T) {
+		_, err := jws.SignReader(bytes.NewReader(payload), jws.WithKey(jwa.HS256, symkey), jws.WithDetachedPayload(payload))
+		require.Error(t, err, `jws.SignReader should fail`)
+
+		signed, err := jws.Sign(payload, jws.WithKey(jwa.HS256, symkey))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		require.Error(t, jws.VerifyReader(signed, bytes.NewReader(payload), jws.WithKey(jwa.HS256, symkey)), `jws.VerifyReader should fail for non-detached messages`)
+	})
+}
+
+type certAndKey struct {
+	cert    *x509.Certificate
+	encoded []byte
+	key     *ecdsa.PrivateKey
+}
+
+var certSerial int64
+
+func issueCert(t *testing.T, template *x509.Certificate, parent *certAndKey) *certAndKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, `ecdsa.GenerateKey should succeed`)
+
+	template.SerialNumber = big.NewInt(atomic.AddInt64(&certSerial, 1))
+	if template.NotBefore.IsZero() {
+		template.NotBefore = time.Now().Add(-time.Hour)
+	}
+	if template.NotAfter.IsZero() {
+		template.NotAfter = time.Now().Add(time.Hour)
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	encoded, err := cert.Create(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err, `cert.Create should succeed`)
+	parsed, err := cert.Parse(encoded)
+	require.NoError(t, err, `cert.Parse should succeed`)
+	return &certAndKey{cert: parsed, encoded: encoded, key: key}
+}
+
+func caTemplate(name string) *x509.Certificate {
+	return &x509.Certificate{
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+}
+
+func TestX509ChainVerification(t *testing.T) {
+	issue := issueCert
+	ca := caTemplate
+
+	root := issue(t, ca(`root`), nil)
+	intermediate := issue(t, ca(`intermediate`), root)
+	leaf := issue(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: `leaf`},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	payload := []byte(`Lorem ipsum`)
+	sign := func(t *testing.T, signer *certAndKey, chain ...*certAndKey) []byte {
+		t.Helper()
+		var c cert.Chain
+		for _, v := range chain {
+			require.NoError(t, c.Add(v.encoded), `c.Add should succeed`)
+		}
+		hdrs := jws.NewHeaders()
+		require.NoError(t, hdrs.Set(jws.X509CertChainKey, &c), `hdrs.Set should succeed`)
+		signed, err := jws.Sign(payload, jws.WithKey(jwa.ES256, signer.key, jws.WithProtectedHeaders(hdrs)))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		return signed
+	}
+
+	signed := sign(t, leaf, leaf, intermediate)
+
+	t.Run(`Verify with chain`, func(t *testing.T) {
+		var chains [][]*x509.Certificate
+		verified, err := jws.Verify(signed, jws.WithX509ChainVerification(roots, jws.WithVerifiedChains(&chains)))
+		require.NoError(t, err, `jws.Verify should succeed`)
+		require.Equal(t, payload, verified, `payload should match`)
+		require.Len(t, chains, 1, `there should be one verified chain`)
+		require.Len(t, chains[0], 3, `chain should contain leaf, intermediate, and root`)
+		require.True(t, chains[0][0].Equal(leaf.cert), `first certificate should be the leaf`)
+		require.True(t, chains[0][2].Equal(root.cert), `last certificate should be the root`)
+	})
+	t.Run(`Verified by another key`, func(t *testing.T) {
+		// the chain is valid, but the message was signed by a different
+		// key that is provided separately
+		other := issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: `other`}}, intermediate)
+		var chains [][]*x509.Certificate
+		verified, err := jws.Verify(sign(t, other, leaf, intermediate),
+			jws.WithX509ChainVerification(roots, jws.WithVerifiedChains(&chains)),
+			jws.WithKey(jwa.ES256, other.key.Public()),
+		)
+		require.NoError(t, err, `jws.Verify should succeed`)
+		require.Equal(t, payload, verified, `payload should match`)
+		require.Empty(t, chains, `no chain should be reported`)
+	})
+	t.Run(`Intermediates from option`, func(t *testing.T) {
+		leafOnly := sign(t, leaf, leaf)
+		_, err := jws.Verify(leafOnly, jws.WithX509ChainVerification(roots))
+		require.Error(t, err, `jws.Verify should fail without intermediate`)
+
+		_, err = jws.Verify(leafOnly, jws.WithX509ChainVerification(roots, jws.WithIntermediates([]*x509.Certificate{intermediate.cert})))
+		require.NoError(t, err, `jws.Verify should succeed`)
+	})
+	t.Run(`Untrusted root`, func(t *testing.T) {
+		other := issue(t, ca(`other`), nil)
+		pool := x509.NewCertPool()
+		pool.AddCert(other.cert)
+		_, err := jws.Verify(signed, jws.WithX509ChainVerification(pool))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`Validity period`, func(t *testing.T) {
+		_, err := jws.Verify(signed, jws.WithX509ChainVerification(roots, jws.WithCertificateTime(time.Now().Add(24*time.Hour))))
+		require.Error(t, err, `jws.Verify should fail for expired certificates`)
+
+		_, err = jws.Verify(signed, jws.WithX509ChainVerification(roots, jws.WithCertificateTime(time.Now().Add(30*time.Minute))))
+		require.NoError(t, err, `jws.Verify should succeed`)
+	})
+	t.Run(`Extended key usage`, func(t *testing.T) {
+		_, err := jws.Verify(signed, jws.WithX509ChainVerification(roots, jws.WithExtKeyUsages([]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth})))
+		require.Error(t, err, `jws.Verify should fail`)
+
+		_, err = jws.Verify(signed, jws.WithX509ChainVerification(roots, jws.WithExtKeyUsages([]x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning})))
+		require.NoError(t, err, `jws.Verify should succeed`)
+	})
+	t.Run(`Key usage`, func(t *testing.T) {
+		encLeaf := issue(t, &x509.Certificate{
+			Subject:  pkix.Name{CommonName: `encryption`},
+			KeyUsage: x509.KeyUsageKeyAgreement,
+		}, intermediate)
+		_, err := jws.Verify(sign(t, encLeaf, encLeaf, intermediate), jws.WithX509ChainVerification(roots))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`Signed by a different key`, func(t *testing.T) {
+		other := issue(t, &x509.Certificate{Subject: pkix.Name{CommonName: `other`}}, intermediate)
+		_, err := jws.Verify(sign(t, other, leaf, intermediate), jws.WithX509ChainVerification(roots))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`No x5c`, func(t *testing.T) {
+		plain, err := jws.Sign(payload, jws.WithKey(jwa.ES256, leaf.key))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		_, err = jws.Verify(plain, jws.WithX509ChainVerification(roots))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+}
+
+func TestX509URLVerification(t *testing.T) {
+	root := issueCert(t, caTemplate(`root`), nil)
+	intermediate := issueCert(t, caTemplate(`intermediate`), root)
+	leaf := issueCert(t, &x509.Certificate{
+		Subject:  pkix.Name{CommonName: `leaf`},
+		KeyUsage: x509.KeyUsageDigitalSignature,
+	}, intermediate)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(root.cert)
+
+	var pemChain bytes.Buffer
+	for _, c := range []*certAndKey{leaf, intermediate} {
+		require.NoError(t, pem.Encode(&pemChain, &pem.Block{Type: `CERTIFICATE`, Bytes: c.cert.Raw}), `pem.Encode should succeed`)
+	}
+
+	var requests int64
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		w.Header().Set(`Content-Type`, `application/pem-certificate-chain`)
+		_, _ = w.Write(pemChain.Bytes())
+	}))
+	defer srv.Close()
+
+	payload := []byte(`Lorem ipsum`)
+	sign := func(t *testing.T, u string) []byte {
+		t.Helper()
+		hdrs := jws.NewHeaders()
+		require.NoError(t, hdrs.Set(jws.X509URLKey, u), `hdrs.Set should succeed`)
+		signed, err := jws.Sign(payload, jws.WithKey(jwa.ES256, leaf.key, jws.WithProtectedHeaders(hdrs)))
+		require.NoError(t, err, `jws.Sign should succeed`)
+		return signed
+	}
+	signed := sign(t, srv.URL+`/chain.pem`)
+	fetchOptions := []jwk.FetchOption{
+		jwk.WithHTTPClient(srv.Client()),
+		jwk.WithFetchWhitelist(jwk.InsecureWhitelist{}),
+	}
+
+	t.Run(`Verify`, func(t *testing.T) {
+		var chains [][]*x509.Certificate
+		verified, err := jws.Verify(signed, jws.WithX509URLVerification(nil, roots,
+			jws.WithFetchOptions(fetchOptions),
+			jws.WithVerifiedChains(&chains),
+		))
+		require.NoError(t, err, `jws.Verify should succeed`)
+		require.Equal(t, payload, verified, `payload should match`)
+		require.Len(t, chains, 1, `there should be one verified chain`)
+		require.Len(t, chains[0], 3, `chain should contain leaf, intermediate, and root`)
+	})
+	t.Run(`No whitelist`, func(t *testing.T) {
+		_, err := jws.Verify(signed, jws.WithX509URLVerification(nil, roots,
+			jws.WithFetchOptions([]jwk.FetchOption{jwk.WithHTTPClient(srv.Client())}),
+		))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`Untrusted root`, func(t *testing.T) {
+		_, err := jws.Verify(signed, jws.WithX509URLVerification(nil, x509.NewCertPool(),
+			jws.WithFetchOptions(fetchOptions),
+		))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`Non-HTTPS URL`, func(t *testing.T) {
+		_, err := jws.Verify(sign(t, `http://example.com/chain.pem`), jws.WithX509URLVerification(nil, roots,
+			jws.WithFetchOptions(fetchOptions),
+		))
+		require.Error(t, err, `jws.Verify should fail`)
+	})
+	t.Run(`Cached`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		f := jwk.NewCachedX509Fetcher(jwk.NewCache(ctx))
+		before := atomic.LoadInt64(&requests)
+		for i := 0; i < 3; i++ {
+			_, err := jws.Verify(signed, jws.WithX509URLVerification(f, roots,
+				jws.WithFetchOptions(fetchOptions),
+			))
+			require.NoError(t, err, `jws.Verify should succeed`)
+		}
+		require.Equal(t, before+1, atomic.LoadInt64(&requests), `certificate chain should be fetched only once`)
+	})
+	t.Run(`Cached URLs are checked before registration`, func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		c := jwk.NewCache(ctx)
+		f := jwk.NewCachedX509Fetcher(c)
+
+		// rejected by the default whitelist of jws.WithX509URLVerification()
+		u := srv.URL + `/other.pem`
+		_, err := jws.Verify(sign(t, u), jws.WithX509URLVerification(f, roots))
+		require.Error(t, err, `jws.Verify should fail`)
+		require.False(t, c.IsRegistered(u), `url should not be registered`)
+
+		_, err = f.FetchX509(ctx, `http://example.com/chain.pem`, fetchOptions...)
+		require.Error(t, err, `FetchX509 should fail`)
+		require.False(t, c.IsRegistered(`http://example.com/chain.pem`), `url should not be registered`)
+
+		// the first fetch fails, as the default client does not trust the server
+		_, err = f.FetchX509(ctx, u, jwk.WithFetchWhitelist(jwk.InsecureWhitelist{}))
+		require.Error(t, err, `FetchX509 should fail`)
+		require.False(t, c.IsRegistered(u), `url should be unregistered`)
+	})
+}
+
+func TestKeySetRefreshOnUnknownKeyID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	pubset := jwk.NewSet()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set(`Content-Type`, `application/json`)
+		_ = json.NewEncoder(w).Encode(pubset)
+	}))
+	defer srv.Close()
+	rotate := func(t *testing.T, key jwk.Key) {
+		t.Helper()
+		pubkey, err := key.PublicKey()
+		require.NoError(t, err, `key.PublicKey should succeed`)
+		mu.Lock()
+		defer mu.Unlock()
+		require.NoError(t, pubset.AddKey(pubkey), `pubset.AddKey should succeed`)
+	}
+
+	key1, err := jwxtest.GenerateRsaJwkWithKeyID(`key-1`)
+	require.NoError(t, err, `jwxtest.GenerateRsaJwkWithKeyID should succeed`)
+	rotate(t, key1)
+
+	c := jwk.NewCache(ctx, jwk.WithRefreshCooldown(time.Nanosecond))
+	require.NoError(t, c.Register(srv.URL, jwk.WithRefreshInterval(time.Hour)), `c.Register should succeed`)
+	_, err = c.Refresh(ctx, srv.URL)
+	require.NoError(t, err, `c.Refresh should succeed`)
+
+	payload := []byte(`Lorem ipsum`)
+	for _, multiple := range []bool{false, true} {
+		multiple := multiple
+		t.Run(fmt.Sprintf(`multipleKeysPerKeyID=%t`, multiple), func(t *testing.T) {
+			key, err := jwxtest.GenerateRsaJwkWithKeyID(fmt.Sprintf(`rotated-%t`, multiple))
+			require.NoError(t, err, `jwxtest.GenerateRsaJwkWithKeyID should succeed`)
+			rotate(t, key)
+			signed, err := jws.Sign(payload, jws.WithKey(jwa.RS256, key))
+			require.NoError(t, err, `jws.Sign should succeed`)
+
+			_, err = jws.Verify(signed, jws.WithKeySet(jwk.NewCachedSet(c, srv.URL), jws.WithMultipleKeysPerKeyID(multiple)))
+			require.Error(t, err, `jws.Verify should fail without refresh`)
+
+			verified, err := jws.Verify(signed, jws.WithKeySet(jwk.NewCachedSet(c, srv.URL, jwk.WithRefreshOnUnknownKeyID(true)), jws.WithMultipleKeysPerKeyID(multiple)))
+			require.NoError(t, err, `jws.Verify should succeed`)
+			require.Equal(t, payload, verified, `payload should match`)
+		})
+	}
+}
+
+func TestStrictKeyUsage(t *testing.T) {
+	payload := []byte(`Lorem ipsum`)
+	newKey := func(t *testing.T, usage jwk.KeyUsageType, ops ...jwk.KeyOperation) jwk.Key {
+		t.Helper()
+		key, err := jwxtest.GenerateRsaJwk()
+		require.NoError(t, err, `jwxtest.GenerateRsaJwk should succeed`)
+		require.NoError(t, key.Set(jwk.KeyIDKey, `key-1`), `key.Set should succeed`)
+		if usage != "" {
+			require.NoError(t, key.Set(jwk.KeyUsageKey, usage), `key.Set should succeed`)
+		}
+		if len(ops) > 0 {
+			require.NoError(t, key.Set(jwk.KeyOpsKey, jwk.KeyOperationList(ops)), `key.Set should succeed`)
+		}
+		return key
+	}
+
+	t.Run("Sign", func(t *testing.T) {
+		key := newKey(t, jwk.ForEncryption)
+		_, err := jws.Sign(payload, jws.WithKey(jwa.RS256, key))
+		require.NoError(t, err, `jws.Sign should succeed without strict mode`)
+
+		_, err = jws.Sign(payload, jws.WithKey(jwa.RS256, key), jws.WithStrictKeyUsage(true))
+		require.Error(t, err, `jws.Sign should fail in strict mode`)
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `error should be a *jwk.KeyUsageError`)
+		require.Equal(t, `key-1`, usageErr.KeyID, `KeyID should match`)
+		require.Equal(t, jwk.KeyOpSign, usageErr.Operation, `Operation should match`)
+
+		_, err = jws.SignReader(bytes.NewReader(payload), jws.WithKey(jwa.RS256, key), jws.WithStrictKeyUsage(true))
+		require.True(t, errors.As(err, &usageErr), `jws.SignReader should fail with a *jwk.KeyUsageError`)
+
+		key = newKey(t, jwk.ForSignature, jwk.KeyOpVerify)
+		_, err = jws.Sign(payload, jws.WithKey(jwa.RS256, key), jws.WithStrictKeyUsage(true))
+		require.True(t, errors.As(err, &usageErr), `jws.Sign should fail when "key_ops" does not contain "sign"`)
+
+		key = newKey(t, jwk.ForSignature, jwk.KeyOpSign)
+		_, err = jws.Sign(payload, jws.WithKey(jwa.RS256, key), jws.WithStrictKeyUsage(true))
+		require.NoError(t, err, `jws.Sign should succeed with a permitted key`)
+	})
+	t.Run("Verify", func(t *testing.T) {
+		key := newKey(t, "")
+		signed, err := jws.Sign(payload, jws.WithKey(jwa.RS256, key))
+		require.NoError(t, err, `jws.Sign should succeed`)
+
+		pubkey, err := key.PublicKey()
+		require.NoError(t, err, `key.PublicKey should succeed`)
+		require.NoError(t, pubkey.Set(jwk.KeyUsageKey, jwk.ForEncryption), `pubkey.Set should succeed`)
+
+		_, err = jws.Verify(signed, jws.WithKey(jwa.RS256, pubkey))
+		require.NoError(t, err, `jws.Verify should succeed without strict mode`)
+
+		_, err = jws.Verify(signed, jws.WithKey(jwa.RS256, pubkey), jws.WithStrictKeyUsage(true))
+		require.Error(t, err, `jws.Verify should fail in strict mode`)
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `error should be a *jwk.KeyUsageError`)
+		require.Equal(t, jwk.KeyOpVerify, usageErr.Operation, `Operation should match`)
+
+		// keys marked for encryption are not even considered by jws.WithKeySet(),
+		// so use "key_ops" to exclude the key
+		setkey, err := key.PublicKey()
+		require.NoError(t, err, `key.PublicKey should succeed`)
+		require.NoError(t, setkey.Set(jwk.AlgorithmKey, jwa.RS256), `setkey.Set should succeed`)
+		require.NoError(t, setkey.Set(jwk.KeyOpsKey, jwk.KeyOperationList{jwk.KeyOpEncrypt}), `setkey.Set should succeed`)
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(setkey), `set.AddKey should succeed`)
+		_, err = jws.Verify(signed, jws.WithKeySet(set), jws.WithStrictKeyUsage(true))
+		require.True(t, errors.As(err, &usageErr), `jws.Verify with a key set should fail with a *jwk.KeyUsageError`)
+
+		detached, err := jws.SignReader(bytes.NewReader(payload), jws.WithKey(jwa.RS256, key))
+		require.NoError(t, err, `jws.SignReader should succeed`)
+		err = jws.VerifyReader(detached, bytes.NewReader(payload), jws.WithKey(jwa.RS256, pubkey), jws.WithStrictKeyUsage(true))
+		require.True(t, errors.As(err, &usageErr), `jws.VerifyReader should fail with a *jwk.KeyUsageError`)
+
+		require.NoError(t, pubkey.Set(jwk.KeyUsageKey, jwk.ForSignature), `pubkey.Set should succeed`)
+		_, err = jws.Verify(signed, jws.WithKey(jwa.RS256, pubkey), jws.WithStrictKeyUsage(true))
+		require.NoError(t, err, `jws.Verify should succeed with a permitted key`)
+	})
+	t.Run("Settings", func(t *testing.T) {
+		jws.Settings(jws.WithStrictKeyUsage(true))
+		defer jws.Settings(jws.WithStrictKeyUsage(false))
+
+		key := newKey(t, jwk.ForEncryption)
+		_, err := jws.Sign(payload, jws.WithKey(jwa.RS256, key))
+		var usageErr *jwk.KeyUsageError
+		require.True(t, errors.As(err, &usageErr), `jws.Sign should fail when strict mode is enabled globally`)
+
+		_, err = jws.Sign(payload, jws.WithKey(jwa.RS256, key), jws.WithStrictKeyUsage(false))
+		require.NoError(t, err, `jws.WithStrictKeyUsage(false) should override the global setting`)
+	})
+}
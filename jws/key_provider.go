@@ -2,10 +2,13 @@ package jws
 
 import (
 	"context"
+	"crypto/x509"
 	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
+	"github.com/lestrrat-go/jwx/v2/cert"
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 )
@@ -78,6 +81,9 @@ type KeySink interface {
 type algKeyPair struct {
 	alg jwa.KeyAlgorithm
 	key interface{}
+	// verified, if non-nil, is called when key has been used to
+	// successfully verify the message
+	verified func()
 }
 
 type algKeySink struct {
@@ -86,11 +92,22 @@ type algKeySink struct {
 }
 
 func (s *algKeySink) Key(alg jwa.SignatureAlgorithm, key interface{}) {
+	s.verifiedKey(alg, key, nil)
+}
+
+func (s *algKeySink) verifiedKey(alg jwa.SignatureAlgorithm, key interface{}, verified func()) {
 	s.mu.Lock()
-	s.list = append(s.list, algKeyPair{alg, key})
+	s.list = append(s.list, algKeyPair{alg, key, verified})
 	s.mu.Unlock()
 }
 
+// verifiedKeySink is implemented by the sinks used in `jws.Verify()` and
+// `jws.VerifyReader()`. It allows a key provider to find out whether a
+// key it has provided was actually used to verify the message.
+type verifiedKeySink interface {
+	verifiedKey(jwa.SignatureAlgorithm, interface{}, func())
+}
+
 type staticKeyProvider struct {
 	alg jwa.SignatureAlgorithm
 	key interface{}
@@ -109,8 +126,11 @@ type keySetProvider struct {
 	multipleKeysPerKeyID bool // true if we should attempt to match multiple keys per key ID. if false we assume that only one key exists for a given key ID
 }
 
+// signatureKeys matches the keys that may be used for signatures
+var signatureKeys = jwk.NewKeyMatcher(jwk.WithMatchKeyUsage(jwk.ForSignature))
+
 func (kp *keySetProvider) selectKey(sink KeySink, key jwk.Key, sig *Signature, _ *Message) error {
-	if usage := key.KeyUsage(); usage != "" && usage != jwk.ForSignature.String() {
+	if !signatureKeys.Match(key) {
 		return nil
 	}
 
@@ -150,6 +170,18 @@ func (kp *keySetProvider) selectKey(sink KeySink, key jwk.Key, sig *Signature, _
 	return nil
 }
 
+func (kp *keySetProvider) selectKeysWithKeyID(sink KeySink, kid string, sig *Signature, msg *Message) bool {
+	var ok bool
+	for _, key := range jwk.LookupKeys(kp.set, jwk.WithMatchKeyID(kid), jwk.WithMatchKeyUsage(jwk.ForSignature)) {
+		if err := kp.selectKey(sink, key, sig, msg); err != nil {
+			continue
+		}
+		ok = true
+		// continue processing so that we try all keys with the same key ID
+	}
+	return ok
+}
+
 func (kp *keySetProvider) FetchKeys(_ context.Context, sink KeySink, sig *Signature, msg *Message) error {
 	if kp.requireKid {
 		wantedKid := sig.ProtectedHeaders().KeyID()
@@ -181,28 +213,20 @@ func (kp *keySetProvider) FetchKeys(_ context.Context, sink KeySink, sig *Signat
 
 		// if multipleKeysPerKeyID is true, we attempt all keys whose key ID matches
 		// the wantedKey
-		var ok bool
-		for i := 0; i < kp.set.Len(); i++ {
-			key, _ := kp.set.Key(i)
-			if key.KeyID() != wantedKid {
-				continue
-			}
-
-			if err := kp.selectKey(sink, key, sig, msg); err != nil {
-				continue
-			}
-			ok = true
-			// continue processing so that we try all keys with the same key ID
+		if kp.selectKeysWithKeyID(sink, wantedKid, sig, msg) {
+			return nil
 		}
-		if !ok {
-			return fmt.Errorf(`failed to find key with key ID %q in key set`, wantedKid)
+
+		// Give sets that can refresh themselves upon unknown key IDs
+		// (i.e. jwk.CachedSet) a chance to do so, and try again
+		if _, ok := kp.set.LookupKeyID(wantedKid); ok && kp.selectKeysWithKeyID(sink, wantedKid, sig, msg) {
+			return nil
 		}
-		return nil
+		return fmt.Errorf(`failed to find key with key ID %q in key set`, wantedKid)
 	}
 
 	// Otherwise just try all keys
-	for i := 0; i < kp.set.Len(); i++ {
-		key, _ := kp.set.Key(i)
+	for _, key := range signatureKeys.Select(kp.set) {
 		if err := kp.selectKey(sink, key, sig, msg); err != nil {
 			continue
 		}
@@ -266,6 +290,141 @@ func (kp jkuProvider) FetchKeys(ctx context.Context, sink KeySink, sig *Signatur
 	return nil
 }
 
+type x5cProvider struct {
+	roots          *x509.CertPool
+	intermediates  []*x509.Certificate
+	keyUsages      []x509.ExtKeyUsage
+	currentTime    time.Time
+	verifiedChains *[][]*x509.Certificate
+}
+
+func (kp *x5cProvider) FetchKeys(_ context.Context, sink KeySink, sig *Signature, _ *Message) error {
+	chain := sig.ProtectedHeaders().X509CertChain()
+	if chain == nil || chain.Len() == 0 {
+		if hdrs := sig.PublicHeaders(); hdrs != nil {
+			chain = hdrs.X509CertChain()
+		}
+	}
+	if chain == nil || chain.Len() == 0 {
+		return fmt.Errorf(`use of x509 chain verification requires that the signature contain a "x5c" field`)
+	}
+
+	hdrAlg := sig.ProtectedHeaders().Algorithm()
+	if hdrAlg == "" {
+		return fmt.Errorf(`use of x509 chain verification requires that the protected header contain an "alg" field`)
+	}
+
+	certs, err := parseChain(chain)
+	if err != nil {
+		return err
+	}
+	return kp.verifyChain(sink, hdrAlg, certs)
+}
+
+// verifyChain verifies the certificate chain `certs`, whose first element
+// is the leaf certificate, and sends the leaf certificate's public key
+// to the sink
+func (kp *x5cProvider) verifyChain(sink KeySink, hdrAlg jwa.SignatureAlgorithm, certs []*x509.Certificate) error {
+	leaf := certs[0]
+	// If the key usage extension is present, the leaf certificate must
+	// be allowed to create signatures
+	if leaf.KeyUsage != 0 && leaf.KeyUsage&(x509.KeyUsageDigitalSignature|x509.KeyUsageContentCommitment) == 0 {
+		return fmt.Errorf(`leaf certificate is not valid for digital signatures`)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, c := range certs[1:] {
+		intermediates.AddCert(c)
+	}
+	for _, c := range kp.intermediates {
+		intermediates.AddCert(c)
+	}
+
+	keyUsages := kp.keyUsages
+	if len(keyUsages) == 0 {
+		keyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageAny}
+	}
+
+	verified, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         kp.roots,
+		Intermediates: intermediates,
+		CurrentTime:   kp.currentTime,
+		KeyUsages:     keyUsages,
+	})
+	if err != nil {
+		return fmt.Errorf(`failed to verify certificate chain: %w`, err)
+	}
+
+	algs, err := AlgorithmsForKey(leaf.PublicKey)
+	if err != nil {
+		return fmt.Errorf(`failed to get a list of signature methods for leaf certificate key (%T): %w`, leaf.PublicKey, err)
+	}
+
+	for _, alg := range algs {
+		if alg != hdrAlg {
+			continue
+		}
+		// The chain is only reported once the signature has been
+		// verified using the leaf certificate's key
+		if vs, ok := sink.(verifiedKeySink); ok && kp.verifiedChains != nil {
+			vs.verifiedKey(alg, leaf.PublicKey, func() {
+				*kp.verifiedChains = verified
+			})
+			return nil
+		}
+		sink.Key(alg, leaf.PublicKey)
+		return nil
+	}
+	return fmt.Errorf(`algorithm %q in the message can not be used with the leaf certificate key (%T)`, hdrAlg, leaf.PublicKey)
+}
+
+type x5uProvider struct {
+	x5cProvider
+	fetcher jwk.X509Fetcher
+	options []jwk.FetchOption
+}
+
+func (kp *x5uProvider) FetchKeys(ctx context.Context, sink KeySink, sig *Signature, _ *Message) error {
+	u := sig.ProtectedHeaders().X509URL()
+	if u == "" {
+		return fmt.Errorf(`use of x509 URL verification requires that the protected header contain a "x5u" field`)
+	}
+	uo, err := url.Parse(u)
+	if err != nil {
+		return fmt.Errorf(`failed to parse "x5u": %w`, err)
+	}
+	if uo.Scheme != "https" {
+		return fmt.Errorf(`url in "x5u" must be HTTPS`)
+	}
+
+	hdrAlg := sig.ProtectedHeaders().Algorithm()
+	if hdrAlg == "" {
+		return fmt.Errorf(`use of x509 URL verification requires that the protected header contain an "alg" field`)
+	}
+
+	certs, err := kp.fetcher.FetchX509(ctx, u, kp.options...)
+	if err != nil {
+		return fmt.Errorf(`failed to fetch certificate chain from %q: %w`, u, err)
+	}
+	if len(certs) == 0 {
+		return fmt.Errorf(`no certificates found at %q`, u)
+	}
+	return kp.verifyChain(sink, hdrAlg, certs)
+}
+
+func parseChain(chain *cert.Chain) ([]*x509.Certificate, error) {
+	certs := make([]*x509.Certificate, chain.Len())
+	for i := 0; i < chain.Len(); i++ {
+		der, _ := chain.Get(i)
+		c, err := cert.Parse(der)
+		if err != nil {
+			return nil, fmt.Errorf(`failed to parse certificate #%d in "x5c": %w`, i, err)
+		}
+		certs[i] = c
+	}
+	return certs, nil
+}
+
 // KeyProviderFunc is a type of KeyProvider that is implemented by
 // a single function. You can use this to create ad-hoc `KeyProvider`
 // instances.
@@ -1,6 +1,10 @@
 package jws
 
 import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
 	"github.com/lestrrat-go/jwx/v2/jwa"
 	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/option"
@@ -110,6 +114,56 @@ func WithKey(alg jwa.KeyAlgorithm, key interface{}, options ...WithKeySuboption)
 	}
 }
 
+type identKeyRing struct{}
+
+type withKeyRing struct {
+	ring      *jwk.KeyRing
+	protected Headers
+	public    Headers
+}
+
+// signer creates a signer for the key that is currently active in the key ring
+func (w *withKeyRing) signer() (*payloadSigner, error) {
+	key, err := w.ring.ActiveKey()
+	if err != nil {
+		return nil, fmt.Errorf(`failed to obtain active key: %w`, err)
+	}
+
+	alg, ok := key.Algorithm().(jwa.SignatureAlgorithm)
+	if !ok {
+		return nil, fmt.Errorf(`active key %q must have a signature algorithm in its "alg" field (got %q)`, key.KeyID(), key.Algorithm())
+	}
+	return makeSigner(alg, key, w.public, w.protected)
+}
+
+// WithKeyRing specifies that the message should be signed using the key
+// that is currently active in the `jwk.KeyRing`. The algorithm is taken
+// from the "alg" field of the key, and the "kid" header is set to the
+// key ID of the key. The suboptions are the same as those for `jws.WithKey()`.
+//
+// To verify messages signed using a key ring, pass the set returned by
+// `(*jwk.KeyRing).PublicSet()` to `jws.WithKeySet()`.
+func WithKeyRing(ring *jwk.KeyRing, options ...WithKeySuboption) SignOption {
+	var protected, public Headers
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identProtectedHeaders{}:
+			protected = option.Value().(Headers)
+		case identPublicHeaders{}:
+			public = option.Value().(Headers)
+		}
+	}
+
+	return &signOption{
+		option.New(identKeyRing{}, &withKeyRing{
+			ring:      ring,
+			protected: protected,
+			public:    public,
+		}),
+	}
+}
+
 // WithKeySet specifies a JWKS (jwk.Set) to use for verification.
 //
 // By default both `alg` and `kid` fields in the JWS _and_ the
@@ -117,6 +171,10 @@ func WithKey(alg jwa.KeyAlgorithm, key interface{}, options ...WithKeySuboption)
 //
 // The behavior can be tweaked by using the `jws.WithKeySetSuboption`
 // suboption types.
+//
+// If the set is a `jwk.CachedSet` created with `jwk.WithRefreshOnUnknownKeyID(true)`,
+// a `kid` that is not found in the set causes the underlying `jwk.Cache`
+// to be refreshed (subject to its cooldown period) before giving up.
 func WithKeySet(set jwk.Set, options ...WithKeySetSuboption) VerifyOption {
 	requireKid := true
 	var useDefault, inferAlgorithm, multipleKeysPerKeyID bool
@@ -157,3 +215,98 @@ func WithVerifyAuto(f jwk.Fetcher, options ...jwk.FetchOption) VerifyOption {
 		options: options,
 	})
 }
+
+// WithX509ChainVerification specifies that the JWS message should be verified
+// using the public key of the leaf certificate found in the "x5c" header,
+// after the certificate chain has been validated against the given trust
+// anchors.
+//
+// The first certificate in "x5c" is treated as the leaf certificate, and the
+// rest are used as candidate intermediates when building the chain. The
+// validity periods of all certificates in the chain, and their extended key
+// usages (see `jws.WithExtKeyUsages()`) are checked. If the leaf certificate
+// contains the key usage extension, it must permit digital signatures.
+// The message's "alg" header must be a signature algorithm that can be used
+// with the leaf certificate's public key.
+//
+// If `roots` is nil, the system certificate pool is used.
+//
+// The verified chain can be obtained by specifying the
+// `jws.WithVerifiedChains()` suboption.
+func WithX509ChainVerification(roots *x509.CertPool, options ...WithX509ChainVerificationSuboption) VerifyOption {
+	kp, _ := newX5CProvider(roots, options...)
+	return WithKeyProvider(kp)
+}
+
+func newX5CProvider(roots *x509.CertPool, options ...WithX509ChainVerificationSuboption) (*x5cProvider, []jwk.FetchOption) {
+	kp := &x5cProvider{roots: roots}
+	var fetchOptions []jwk.FetchOption
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identIntermediates{}:
+			kp.intermediates = option.Value().([]*x509.Certificate)
+		case identExtKeyUsages{}:
+			kp.keyUsages = option.Value().([]x509.ExtKeyUsage)
+		case identCertificateTime{}:
+			kp.currentTime = option.Value().(time.Time)
+		case identVerifiedChains{}:
+			kp.verifiedChains = option.Value().(*[][]*x509.Certificate)
+		case identFetchOptions{}:
+			fetchOptions = append(fetchOptions, option.Value().([]jwk.FetchOption)...)
+		}
+	}
+	return kp, fetchOptions
+}
+
+// WithX509URLVerification is similar to `jws.WithX509ChainVerification()`,
+// but the certificate chain is retrieved from the URL in the "x5u" header
+// instead of the "x5c" header. The resource must be a list of PEM encoded
+// certificates, with the leaf certificate first. Only HTTPS URLs are allowed.
+//
+// The first argument should either be `nil`, or your custom jwk.X509Fetcher
+// object, which tells how the certificate chain should be fetched. Leaving it
+// to `nil` is equivalent to specifying that `jwk.FetchX509` should be used.
+// In order to cache the certificate chains, use the object returned by
+// `jwk.NewCachedX509Fetcher()`.
+//
+// Just like `jws.WithVerifyAuto()`, all fetching is disabled unless you
+// explicitly whitelist urls using the `jws.WithFetchOptions()` suboption:
+//
+//	jws.Verify(data, jws.WithX509URLVerification(nil, roots,
+//	  jws.WithFetchOptions([]jwk.FetchOption{jwk.WithFetchWhitelist(...)}),
+//	))
+//
+// The rest of the suboptions work the same as `jws.WithX509ChainVerification()`
+func WithX509URLVerification(f jwk.X509Fetcher, roots *x509.CertPool, options ...WithX509ChainVerificationSuboption) VerifyOption {
+	if f == nil {
+		f = jwk.X509FetchFunc(jwk.FetchX509)
+	}
+
+	kp, fetchOptions := newX5CProvider(roots, options...)
+
+	// the option MUST start with a "disallow no whitelist" to force
+	// users provide a whitelist
+	fetchOptions = append([]jwk.FetchOption{jwk.WithFetchWhitelist(allowNoneWhitelist)}, fetchOptions...)
+
+	return WithKeyProvider(&x5uProvider{
+		x5cProvider: *kp,
+		fetcher:     f,
+		options:     fetchOptions,
+	})
+}
+
+// WithCriticalHeaders specifies the names of the JWS extension header
+// parameters that the caller understands and processes. When a JWS
+// message lists header parameter names in its "crit" header,
+// `jws.Verify()` will fail unless every one of those names has been
+// declared using this option.
+//
+// The "b64" extension (RFC 7797) is understood by this library, and
+// therefore never needs to be specified.
+//
+// This option may be specified multiple times, in which case the names
+// are accumulated.
+func WithCriticalHeaders(names ...string) VerifyOption {
+	return &verifyOption{option.New(identCriticalHeaders{}, names)}
+}
@@ -4,8 +4,11 @@ package jws
 
 import (
 	"context"
+	"crypto/x509"
 	"io/fs"
+	"time"
 
+	"github.com/lestrrat-go/jwx/v2/jwk"
 	"github.com/lestrrat-go/option"
 )
 
@@ -23,6 +26,37 @@ type compactOption struct {
 
 func (*compactOption) compactOption() {}
 
+// GlobalSignVerifyOption describes options that can be passed to `jws.Verify`, `jws.Sign`,
+// as well as `jws.Settings()`
+type GlobalSignVerifyOption interface {
+	Option
+	globalOption()
+	signOption()
+	verifyOption()
+}
+
+type globalSignVerifyOption struct {
+	Option
+}
+
+func (*globalSignVerifyOption) globalOption() {}
+
+func (*globalSignVerifyOption) signOption() {}
+
+func (*globalSignVerifyOption) verifyOption() {}
+
+// GlobalOption describes options that can be passed to `jws.Settings()`
+type GlobalOption interface {
+	Option
+	globalOption()
+}
+
+type globalOption struct {
+	Option
+}
+
+func (*globalOption) globalOption() {}
+
 // ReadFileOption is a type of `Option` that can be passed to `jwe.Parse`
 type ParseOption interface {
 	Option
@@ -123,11 +157,29 @@ type withKeySuboption struct {
 
 func (*withKeySuboption) withKeySuboption() {}
 
+// WithX509ChainVerificationSuboption is a suboption passed to the
+// `jws.WithX509ChainVerification()` option
+type WithX509ChainVerificationSuboption interface {
+	Option
+	withX509ChainVerificationSuboption()
+}
+
+type withX509ChainVerificationSuboption struct {
+	Option
+}
+
+func (*withX509ChainVerificationSuboption) withX509ChainVerificationSuboption() {}
+
+type identCertificateTime struct{}
 type identContext struct{}
+type identCriticalHeaders struct{}
 type identDetached struct{}
 type identDetachedPayload struct{}
+type identExtKeyUsages struct{}
 type identFS struct{}
+type identFetchOptions struct{}
 type identInferAlgorithmFromKey struct{}
+type identIntermediates struct{}
 type identKey struct{}
 type identKeyProvider struct{}
 type identKeyUsed struct{}
@@ -138,12 +190,22 @@ type identProtectedHeaders struct{}
 type identPublicHeaders struct{}
 type identRequireKid struct{}
 type identSerialization struct{}
+type identStrictKeyUsage struct{}
 type identUseDefault struct{}
+type identVerifiedChains struct{}
+
+func (identCertificateTime) String() string {
+	return "WithCertificateTime"
+}
 
 func (identContext) String() string {
 	return "WithContext"
 }
 
+func (identCriticalHeaders) String() string {
+	return "WithCriticalHeaders"
+}
+
 func (identDetached) String() string {
 	return "WithDetached"
 }
@@ -152,14 +214,26 @@ func (identDetachedPayload) String() string {
 	return "WithDetachedPayload"
 }
 
+func (identExtKeyUsages) String() string {
+	return "WithExtKeyUsages"
+}
+
 func (identFS) String() string {
 	return "WithFS"
 }
 
+func (identFetchOptions) String() string {
+	return "WithFetchOptions"
+}
+
 func (identInferAlgorithmFromKey) String() string {
 	return "WithInferAlgorithmFromKey"
 }
 
+func (identIntermediates) String() string {
+	return "WithIntermediates"
+}
+
 func (identKey) String() string {
 	return "WithKey"
 }
@@ -200,10 +274,25 @@ func (identSerialization) String() string {
 	return "WithSerialization"
 }
 
+func (identStrictKeyUsage) String() string {
+	return "WithStrictKeyUsage"
+}
+
 func (identUseDefault) String() string {
 	return "WithUseDefault"
 }
 
+func (identVerifiedChains) String() string {
+	return "WithVerifiedChains"
+}
+
+// WithCertificateTime specifies the time at which the validity periods
+// of the certificates in the chain are checked by
+// `jws.WithX509ChainVerification()`. By default the current time is used.
+func WithCertificateTime(v time.Time) WithX509ChainVerificationSuboption {
+	return &withX509ChainVerificationSuboption{option.New(identCertificateTime{}, v)}
+}
+
 func WithContext(v context.Context) VerifyOption {
 	return &verifyOption{option.New(identContext{}, v)}
 }
@@ -226,11 +315,33 @@ func WithDetachedPayload(v []byte) SignVerifyOption {
 	return &signVerifyOption{option.New(identDetachedPayload{}, v)}
 }
 
+// WithExtKeyUsages specifies the list of extended key usages that the
+// certificates in the chain must be valid for, when verifying a JWS
+// message using `jws.WithX509ChainVerification()`. Any one of the
+// values is accepted.
+//
+// By default `x509.ExtKeyUsageAny` is used, which accepts certificates
+// regardless of their extended key usage.
+func WithExtKeyUsages(v []x509.ExtKeyUsage) WithX509ChainVerificationSuboption {
+	return &withX509ChainVerificationSuboption{option.New(identExtKeyUsages{}, v)}
+}
+
 // WithFS specifies the source `fs.FS` object to read the file from.
 func WithFS(v fs.FS) ReadFileOption {
 	return &readFileOption{option.New(identFS{}, v)}
 }
 
+// WithFetchOptions specifies the options that are passed to the
+// `jwk.X509Fetcher` when `jws.WithX509URLVerification()` retrieves the
+// certificate chain pointed by the "x5u" header. Use this to specify
+// `jwk.WithFetchWhitelist()`, which is required in order to fetch
+// anything at all.
+//
+// It has no effect when used with `jws.WithX509ChainVerification()`.
+func WithFetchOptions(v []jwk.FetchOption) WithX509ChainVerificationSuboption {
+	return &withX509ChainVerificationSuboption{option.New(identFetchOptions{}, v)}
+}
+
 // WithInferAlgorithmFromKey specifies whether the JWS signing algorithm name
 // should be inferred by looking at the provided key, in case the JWS
 // message or the key does not have a proper `alg` header.
@@ -249,6 +360,14 @@ func WithInferAlgorithmFromKey(v bool) WithKeySetSuboption {
 	return &withKeySetSuboption{option.New(identInferAlgorithmFromKey{}, v)}
 }
 
+// WithIntermediates specifies a list of intermediate certificates that
+// may be used by `jws.WithX509ChainVerification()` to build a chain from
+// the leaf certificate to one of the trust anchors, in addition to the
+// certificates found in the "x5c" header.
+func WithIntermediates(v []*x509.Certificate) WithX509ChainVerificationSuboption {
+	return &withX509ChainVerificationSuboption{option.New(identIntermediates{}, v)}
+}
+
 func WithKeyProvider(v KeyProvider) VerifyOption {
 	return &verifyOption{option.New(identKeyProvider{}, v)}
 }
@@ -323,9 +442,36 @@ func WithCompact() SignOption {
 	return &signOption{option.New(identSerialization{}, fmtCompact)}
 }
 
+// WithStrictKeyUsage specifies whether the "use" and "key_ops" fields of
+// `jwk.Key` objects should be enforced. When enabled, `jws.Sign()` fails
+// if a key does not permit the "sign" operation, and `jws.Verify()` skips
+// keys that do not permit the "verify" operation. In both cases the error
+// returned can be inspected using `errors.As()` with a `*jwk.KeyUsageError`.
+// Fields that are not specified in the key do not restrict its usage.
+//
+// Keys that are not `jwk.Key` objects (e.g. `*rsa.PrivateKey`) are not affected.
+//
+// When passed to `jws.Settings()`, it changes the default value, which
+// is false.
+func WithStrictKeyUsage(v bool) GlobalSignVerifyOption {
+	return &globalSignVerifyOption{option.New(identStrictKeyUsage{}, v)}
+}
+
 // WithUseDefault specifies that if and only if a jwk.Key contains
 // exactly one jwk.Key, that tkey should be used.
 // (I think this should be removed)
 func WithUseDefault(v bool) WithKeySetSuboption {
 	return &withKeySetSuboption{option.New(identUseDefault{}, v)}
 }
+
+// WithVerifiedChains specifies a location where the certificate chains
+// that were built and verified by `jws.WithX509ChainVerification()`
+// should be stored. Each chain starts with the leaf certificate, and
+// ends with one of the trust anchors.
+//
+// The chains are only stored once the message has been verified using
+// the key of the leaf certificate. If the message is verified using
+// a key from another source, nothing is stored.
+func WithVerifiedChains(v *[][]*x509.Certificate) WithX509ChainVerificationSuboption {
+	return &withX509ChainVerificationSuboption{option.New(identVerifiedChains{}, v)}
+}
@@ -9,11 +9,16 @@ import (
 )
 
 func TestOptionIdent(t *testing.T) {
+	require.Equal(t, "WithCertificateTime", identCertificateTime{}.String())
 	require.Equal(t, "WithContext", identContext{}.String())
+	require.Equal(t, "WithCriticalHeaders", identCriticalHeaders{}.String())
 	require.Equal(t, "WithDetached", identDetached{}.String())
 	require.Equal(t, "WithDetachedPayload", identDetachedPayload{}.String())
+	require.Equal(t, "WithExtKeyUsages", identExtKeyUsages{}.String())
 	require.Equal(t, "WithFS", identFS{}.String())
+	require.Equal(t, "WithFetchOptions", identFetchOptions{}.String())
 	require.Equal(t, "WithInferAlgorithmFromKey", identInferAlgorithmFromKey{}.String())
+	require.Equal(t, "WithIntermediates", identIntermediates{}.String())
 	require.Equal(t, "WithKey", identKey{}.String())
 	require.Equal(t, "WithKeyProvider", identKeyProvider{}.String())
 	require.Equal(t, "WithKeyUsed", identKeyUsed{}.String())
@@ -24,5 +29,7 @@ func TestOptionIdent(t *testing.T) {
 	require.Equal(t, "WithPublicHeaders", identPublicHeaders{}.String())
 	require.Equal(t, "WithRequireKid", identRequireKid{}.String())
 	require.Equal(t, "WithSerialization", identSerialization{}.String())
+	require.Equal(t, "WithStrictKeyUsage", identStrictKeyUsage{}.String())
 	require.Equal(t, "WithUseDefault", identUseDefault{}.String())
+	require.Equal(t, "WithVerifiedChains", identVerifiedChains{}.String())
 }
@@ -72,6 +72,17 @@ func (rs *rsaSigner) Algorithm() jwa.SignatureAlgorithm {
 }
 
 func (rs *rsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
+	w, err := rs.NewSignWriter(key)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return nil, fmt.Errorf(`failed to write payload to hash: %w`, err)
+	}
+	return w.Sign()
+}
+
+func (rs *rsaSigner) NewSignWriter(key interface{}) (SignWriter, error) {
 	if key == nil {
 		return nil, fmt.Errorf(`missing private key while signing payload`)
 	}
@@ -85,17 +96,18 @@ func (rs *rsaSigner) Sign(payload []byte, key interface{}) ([]byte, error) {
 		signer = &privkey
 	}
 
-	h := rs.hash.New()
-	if _, err := h.Write(payload); err != nil {
-		return nil, fmt.Errorf(`failed to write payload to hash: %w`, err)
-	}
-	if rs.pss {
-		return signer.Sign(rand.Reader, h.Sum(nil), &rsa.PSSOptions{
-			Hash:       rs.hash,
-			SaltLength: rsa.PSSSaltLengthEqualsHash,
-		})
-	}
-	return signer.Sign(rand.Reader, h.Sum(nil), rs.hash)
+	return &digestSignWriter{
+		Hash: rs.hash.New(),
+		sign: func(digest []byte) ([]byte, error) {
+			if rs.pss {
+				return signer.Sign(rand.Reader, digest, &rsa.PSSOptions{
+					Hash:       rs.hash,
+					SaltLength: rsa.PSSSaltLengthEqualsHash,
+				})
+			}
+			return signer.Sign(rand.Reader, digest, rs.hash)
+		},
+	}, nil
 }
 
 type rsaVerifier struct {
@@ -109,8 +121,19 @@ func newRSAVerifier(alg jwa.SignatureAlgorithm) Verifier {
 }
 
 func (rv *rsaVerifier) Verify(payload, signature []byte, key interface{}) error {
+	w, err := rv.NewVerifyWriter(key)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(payload); err != nil {
+		return fmt.Errorf(`failed to write payload to hash: %w`, err)
+	}
+	return w.Verify(signature)
+}
+
+func (rv *rsaVerifier) NewVerifyWriter(key interface{}) (VerifyWriter, error) {
 	if key == nil {
-		return fmt.Errorf(`missing public key while verifying payload`)
+		return nil, fmt.Errorf(`missing public key while verifying payload`)
 	}
 
 	var pubkey rsa.PublicKey
@@ -122,21 +145,21 @@ func (rv *rsaVerifier) Verify(payload, signature []byte, key interface{}) error
 		case *rsa.PublicKey:
 			pubkey = *cpub
 		default:
-			return fmt.Errorf(`failed to retrieve rsa.PublicKey out of crypto.Signer %T`, key)
+			return nil, fmt.Errorf(`failed to retrieve rsa.PublicKey out of crypto.Signer %T`, key)
 		}
 	} else {
 		if err := keyconv.RSAPublicKey(&pubkey, key); err != nil {
-			return fmt.Errorf(`failed to retrieve rsa.PublicKey out of %T: %w`, key, err)
+			return nil, fmt.Errorf(`failed to retrieve rsa.PublicKey out of %T: %w`, key, err)
 		}
 	}
 
-	h := rv.hash.New()
-	if _, err := h.Write(payload); err != nil {
-		return fmt.Errorf(`failed to write payload to hash: %w`, err)
-	}
-
-	if rv.pss {
-		return rsa.VerifyPSS(&pubkey, rv.hash, h.Sum(nil), signature, nil)
-	}
-	return rsa.VerifyPKCS1v15(&pubkey, rv.hash, h.Sum(nil), signature)
+	return &digestVerifyWriter{
+		Hash: rv.hash.New(),
+		verify: func(digest, signature []byte) error {
+			if rv.pss {
+				return rsa.VerifyPSS(&pubkey, rv.hash, digest, signature, nil)
+			}
+			return rsa.VerifyPKCS1v15(&pubkey, rv.hash, digest, signature)
+		},
+	}, nil
 }
@@ -0,0 +1,362 @@
+package jws
+
+import (
+	"bytes"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/lestrrat-go/blackmagic"
+	"github.com/lestrrat-go/jwx/v2/internal/base64"
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+)
+
+// digestSignWriter computes the digest of the content written to it,
+// and signs the digest using `sign`
+type digestSignWriter struct {
+	hash.Hash
+	sign func([]byte) ([]byte, error)
+}
+
+func (w *digestSignWriter) Sign() ([]byte, error) {
+	return w.sign(w.Sum(nil))
+}
+
+// digestVerifyWriter computes the digest of the content written to it,
+// and verifies the signature against the digest using `verify`
+type digestVerifyWriter struct {
+	hash.Hash
+	verify func([]byte, []byte) error
+}
+
+func (w *digestVerifyWriter) Verify(signature []byte) error {
+	return w.verify(w.Sum(nil), signature)
+}
+
+// bufferedSignWriter is used for Signers that do not implement
+// StreamSigner. The content is accumulated in memory, and passed
+// to the Signer all at once
+type bufferedSignWriter struct {
+	bytes.Buffer
+	signer Signer
+	key    interface{}
+}
+
+func (w *bufferedSignWriter) Sign() ([]byte, error) {
+	return w.signer.Sign(w.Bytes(), w.key)
+}
+
+// bufferedVerifyWriter is used for Verifiers that do not implement
+// StreamVerifier. The content is accumulated in memory, and passed
+// to the Verifier all at once
+type bufferedVerifyWriter struct {
+	bytes.Buffer
+	verifier Verifier
+	key      interface{}
+}
+
+func (w *bufferedVerifyWriter) Verify(signature []byte) error {
+	return w.verifier.Verify(w.Bytes(), signature, w.key)
+}
+
+func newSignWriter(signer Signer, key interface{}) (SignWriter, error) {
+	if ss, ok := signer.(StreamSigner); ok {
+		return ss.NewSignWriter(key)
+	}
+	return &bufferedSignWriter{signer: signer, key: key}, nil
+}
+
+func newVerifyWriter(verifier Verifier, key interface{}) (VerifyWriter, error) {
+	if sv, ok := verifier.(StreamVerifier); ok {
+		return sv.NewVerifyWriter(key)
+	}
+	return &bufferedVerifyWriter{verifier: verifier, key: key}, nil
+}
+
+// signingInput sets up `w` to receive the signing input for `sig`.
+// The encoded protected header is written to `w`, and the returned
+// writer should be used to write the payload. If the returned
+// io.Closer is non-nil, it must be closed after the payload
+// has been written.
+func signingInput(w io.Writer, sig *Signature) (io.Writer, io.Closer, error) {
+	encoded, err := sig.encodedProtectedHeader()
+	if err != nil {
+		return nil, nil, fmt.Errorf(`failed to marshal "protected": %w`, err)
+	}
+
+	if _, err := io.WriteString(w, encoded+"."); err != nil {
+		return nil, nil, fmt.Errorf(`failed to write protected header: %w`, err)
+	}
+
+	if getB64Value(sig.protected) {
+		enc := base64.NewEncoder(w)
+		return enc, enc, nil
+	}
+	return w, nil, nil
+}
+
+// SignReader is the streaming version of `jws.Sign()`. The payload is
+// read from `payload` until io.EOF, and is fed to the signers
+// incrementally, so that the entire payload never needs to be held
+// in memory.
+//
+// The payload is NOT included in the result: the returned JWS message
+// always has a detached payload (RFC 7515 Appendix F). This is usually
+// used along with the "b64" header set to false (RFC 7797), in which
+// case the signing input contains the payload as is. Use
+// `jws.VerifyReader()` to verify the resulting message.
+//
+// `SignReader` accepts the same options as `jws.Sign()`, except for
+// `jws.WithDetachedPayload()`.
+//
+// The signers for RSA, ECDSA and HMAC algorithms compute the signature
+// incrementally. Signers that do not implement `jws.StreamSigner`,
+// including the one for EdDSA (which can not sign a pre-computed digest),
+// still work, but the payload is buffered in memory.
+func SignReader(payload io.Reader, options ...SignOption) ([]byte, error) {
+	format := fmtCompact
+	var signers []*payloadSigner
+	strict := strictKeyUsage.Get()
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identSerialization{}:
+			format = option.Value().(int)
+		case identStrictKeyUsage{}:
+			strict = option.Value().(bool)
+		case identKey{}:
+			data := option.Value().(*withKey)
+
+			alg, ok := data.alg.(jwa.SignatureAlgorithm)
+			if !ok {
+				return nil, fmt.Errorf(`jws.SignReader: expected algorithm to be of type jwa.SignatureAlgorithm but got (%[1]q, %[1]T)`, data.alg)
+			}
+			signer, err := makeSigner(alg, data.key, data.public, data.protected)
+			if err != nil {
+				return nil, fmt.Errorf(`jws.SignReader: failed to create signer: %w`, err)
+			}
+			signers = append(signers, signer)
+		case identKeyRing{}:
+			signer, err := option.Value().(*withKeyRing).signer()
+			if err != nil {
+				return nil, fmt.Errorf(`jws.SignReader: failed to create signer from jwk.KeyRing: %w`, err)
+			}
+			signers = append(signers, signer)
+		case identDetachedPayload{}:
+			return nil, fmt.Errorf(`jws.SignReader: jws.WithDetachedPayload() can not be used with jws.SignReader()`)
+		}
+	}
+
+	lsigner := len(signers)
+	if lsigner == 0 {
+		return nil, fmt.Errorf(`jws.SignReader: no signers available. Specify an alogirthm and akey using jws.WithKey()`)
+	}
+
+	if strict {
+		for i, signer := range signers {
+			if err := jwk.CheckKeyUsage(signer.key, jwk.KeyOpSign); err != nil {
+				return nil, fmt.Errorf(`jws.SignReader: invalid key for signer #%d: %w`, i, err)
+			}
+		}
+	}
+
+	if format == fmtCompact && lsigner != 1 {
+		return nil, fmt.Errorf(`jws.SignReader: cannot have multiple signers (keys) specified for compact serialization. Use only one jws.WithKey()`)
+	}
+
+	var result Message
+	result.signatures = make([]*Signature, 0, lsigner)
+
+	sws := make([]SignWriter, 0, lsigner)
+	dsts := make([]io.Writer, 0, lsigner)
+	var closers []io.Closer
+	for i, signer := range signers {
+		sig, err := signer.newSignature(true)
+		if err != nil {
+			return nil, fmt.Errorf(`jws.SignReader: %w`, err)
+		}
+
+		sw, err := newSignWriter(signer.signer, signer.key)
+		if err != nil {
+			return nil, fmt.Errorf(`jws.SignReader: failed to create signer #%d (alg=%s): %w`, i, signer.Algorithm(), err)
+		}
+
+		dst, closer, err := signingInput(sw, sig)
+		if err != nil {
+			return nil, fmt.Errorf(`jws.SignReader: failed to generate signing input for signer #%d: %w`, i, err)
+		}
+		if closer != nil {
+			closers = append(closers, closer)
+		}
+
+		result.signatures = append(result.signatures, sig)
+		sws = append(sws, sw)
+		dsts = append(dsts, dst)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(dsts...), payload); err != nil {
+		return nil, fmt.Errorf(`jws.SignReader: failed to read payload: %w`, err)
+	}
+
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return nil, fmt.Errorf(`jws.SignReader: failed to encode payload: %w`, err)
+		}
+	}
+
+	for i, sw := range sws {
+		signature, err := sw.Sign()
+		if err != nil {
+			return nil, fmt.Errorf(`jws.SignReader: failed to generate signature for signer #%d (alg=%s): %w`, i, signers[i].Algorithm(), err)
+		}
+		result.signatures[i].signature = signature
+	}
+
+	switch format {
+	case fmtJSON:
+		return json.Marshal(result)
+	case fmtJSONPretty:
+		return json.MarshalIndent(result, "", "  ")
+	case fmtCompact:
+		return Compact(&result, WithDetached(true))
+	default:
+		return nil, fmt.Errorf(`jws.SignReader: invalid serialization format`)
+	}
+}
+
+// VerifyReader is the streaming version of `jws.Verify()`. It verifies
+// the JWS message `buf` with a detached payload, such as those created
+// by `jws.SignReader()`, against the payload read from `payload`.
+//
+// The payload is read only once, and is fed to the verifiers
+// incrementally, so that the entire payload never needs to be held
+// in memory. Because of this, every combination of signature and
+// candidate key is computed in parallel while the payload is being
+// read. Verifiers that do not implement `jws.StreamVerifier`, including
+// the one for EdDSA, buffer the payload in memory.
+//
+// `VerifyReader` accepts the same options as `jws.Verify()`, except
+// for `jws.WithDetachedPayload()`. Since the payload is not held in memory,
+// the message stored via `jws.WithMessage()` does not contain the payload.
+func VerifyReader(buf []byte, payload io.Reader, options ...VerifyOption) error {
+	vctx, err := newVerifyCtx(options)
+	if err != nil {
+		return fmt.Errorf(`jws.VerifyReader: %w`, err)
+	}
+
+	if vctx.detachedPayload != nil {
+		return fmt.Errorf(`jws.VerifyReader: jws.WithDetachedPayload() can not be used with jws.VerifyReader()`)
+	}
+
+	msg, err := Parse(buf)
+	if err != nil {
+		return fmt.Errorf(`jws.VerifyReader: failed to parse jws: %w`, err)
+	}
+	defer msg.clearRaw()
+
+	if err := vctx.verifyCriticalHeaders(msg); err != nil {
+		return fmt.Errorf(`jws.VerifyReader: %w`, err)
+	}
+
+	if len(msg.payload) != 0 {
+		return fmt.Errorf(`jws.VerifyReader: can't verify detached payload for JWS with payload`)
+	}
+
+	type candidate struct {
+		sig      *Signature
+		key      interface{}
+		vw       VerifyWriter
+		verified func()
+	}
+
+	var candidates []candidate
+	var dsts []io.Writer
+	var closers []io.Closer
+	var usageErr error
+	for i, sig := range msg.signatures {
+		for j, kp := range vctx.keyProviders {
+			var sink algKeySink
+			if err := kp.FetchKeys(vctx.ctx, &sink, sig, msg); err != nil {
+				return fmt.Errorf(`jws.VerifyReader: key provider %d failed: %w`, j, err)
+			}
+
+			for _, pair := range sink.list {
+				//nolint:forcetypeassert
+				alg := pair.alg.(jwa.SignatureAlgorithm)
+				if vctx.strictKeyUsage {
+					if err := jwk.CheckKeyUsage(pair.key, jwk.KeyOpVerify); err != nil {
+						usageErr = err
+						continue
+					}
+				}
+
+				verifier, err := NewVerifier(alg)
+				if err != nil {
+					return fmt.Errorf(`jws.VerifyReader: failed to create verifier for algorithm %q: %w`, alg, err)
+				}
+
+				vw, err := newVerifyWriter(verifier, pair.key)
+				if err != nil {
+					// the key can not be used with this algorithm
+					continue
+				}
+
+				dst, closer, err := signingInput(vw, sig)
+				if err != nil {
+					return fmt.Errorf(`jws.VerifyReader: failed to generate signing input for signature #%d: %w`, i+1, err)
+				}
+				if closer != nil {
+					closers = append(closers, closer)
+				}
+
+				candidates = append(candidates, candidate{sig: sig, key: pair.key, vw: vw, verified: pair.verified})
+				dsts = append(dsts, dst)
+			}
+		}
+	}
+
+	if len(candidates) == 0 {
+		if usageErr != nil {
+			return fmt.Errorf(`jws.VerifyReader: could not verify message using any of the signatures or keys: %w`, usageErr)
+		}
+		return fmt.Errorf(`jws.VerifyReader: could not verify message using any of the signatures or keys`)
+	}
+
+	if _, err := io.Copy(io.MultiWriter(dsts...), payload); err != nil {
+		return fmt.Errorf(`jws.VerifyReader: failed to read payload: %w`, err)
+	}
+
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf(`jws.VerifyReader: failed to encode payload: %w`, err)
+		}
+	}
+
+	for _, c := range candidates {
+		if err := c.vw.Verify(c.sig.signature); err != nil {
+			continue
+		}
+
+		if vctx.keyUsed != nil {
+			if err := blackmagic.AssignIfCompatible(vctx.keyUsed, c.key); err != nil {
+				return fmt.Errorf(`jws.VerifyReader: failed to assign used key (%T) to %T: %w`, c.key, vctx.keyUsed, err)
+			}
+		}
+
+		if c.verified != nil {
+			c.verified()
+		}
+
+		if vctx.dst != nil {
+			*(vctx.dst) = *msg
+		}
+		return nil
+	}
+
+	if usageErr != nil {
+		return fmt.Errorf(`jws.VerifyReader: could not verify message using any of the signatures or keys: %w`, usageErr)
+	}
+	return fmt.Errorf(`jws.VerifyReader: could not verify message using any of the signatures or keys`)
+}
@@ -0,0 +1,233 @@
+package jwt
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwa"
+	"github.com/lestrrat-go/jwx/v2/jwk"
+	"github.com/lestrrat-go/jwx/v2/jws"
+)
+
+// IssuerKeyProvider is a `jws.KeyProvider` that selects the keys used to
+// verify a JWT based on its (as of yet unverified) `iss` claim, using a
+// trusted mapping from issuers to key sets. This is useful when tokens from
+// multiple identity providers must be accepted.
+//
+// Issuers are registered using `AddKeySet()` or `AddCache()`. Tokens from
+// issuers that have not been registered are rejected.
+//
+// An issuer may contain placeholders in the form of `{name}`, which are
+// replaced by the value of the claim `name` in the token before it is
+// compared to the `iss` claim. For example, to accept tokens from any
+// Azure AD tenant, where `tid` claim contains the tenant ID, you would write
+//
+//	p.AddCache(`https://login.microsoftonline.com/{tid}/v2.0`, cache, jwksURL)
+//
+// The key set for a particular issuer is chosen using the same rules as
+// `jws.WithKeySet()`.
+//
+// Pass the provider to `jwt.Parse()` using `jwt.WithIssuerKeyProvider()`,
+// which additionally checks that the `iss` claim in the verified token
+// matches the issuer that was used to select the keys.
+type IssuerKeyProvider struct {
+	mu      sync.RWMutex
+	issuers map[string]*issuerKeySource
+}
+
+type issuerKeySource struct {
+	set     jwk.Set
+	cache   *jwk.Cache
+	url     string
+	options []jws.WithKeySetSuboption
+}
+
+func (src *issuerKeySource) keySet(ctx context.Context) (jwk.Set, error) {
+	if src.cache == nil {
+		return src.set, nil
+	}
+	return src.cache.Get(ctx, src.url)
+}
+
+// NewIssuerKeyProvider creates a new, empty IssuerKeyProvider.
+func NewIssuerKeyProvider() *IssuerKeyProvider {
+	return &IssuerKeyProvider{
+		issuers: make(map[string]*issuerKeySource),
+	}
+}
+
+// AddKeySet registers `set` as the key set to be used to verify tokens
+// whose `iss` claim matches `issuer`. The suboptions are passed to
+// `jws.WithKeySet()`.
+func (p *IssuerKeyProvider) AddKeySet(issuer string, set jwk.Set, options ...jws.WithKeySetSuboption) *IssuerKeyProvider {
+	p.mu.Lock()
+	p.issuers[issuer] = &issuerKeySource{set: set, options: options}
+	p.mu.Unlock()
+	return p
+}
+
+// AddCache registers the key set stored in `cache` under `u` as the key
+// set to be used to verify tokens whose `iss` claim matches `issuer`.
+// `u` must already be registered in `cache` using either `Register()` or
+// `RegisterIssuer()`. The suboptions are passed to `jws.WithKeySet()`.
+func (p *IssuerKeyProvider) AddCache(issuer string, cache *jwk.Cache, u string, options ...jws.WithKeySetSuboption) *IssuerKeyProvider {
+	p.mu.Lock()
+	p.issuers[issuer] = &issuerKeySource{cache: cache, url: u, options: options}
+	p.mu.Unlock()
+	return p
+}
+
+// Remove removes the issuer from the provider
+func (p *IssuerKeyProvider) Remove(issuer string) {
+	p.mu.Lock()
+	delete(p.issuers, issuer)
+	p.mu.Unlock()
+}
+
+// lookup returns the registered issuer matching the `iss` claim in
+// `claims`, along with its key source
+func (p *IssuerKeyProvider) lookup(claims map[string]interface{}) (string, *issuerKeySource, error) {
+	iss, ok := claims[IssuerKey].(string)
+	if !ok || iss == "" {
+		return "", nil, fmt.Errorf(`token does not contain a valid %q claim`, IssuerKey)
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if src, ok := p.issuers[iss]; ok {
+		return iss, src, nil
+	}
+
+	for pattern, src := range p.issuers {
+		if !strings.Contains(pattern, `{`) {
+			continue
+		}
+		expanded, ok := expandIssuer(pattern, claims)
+		if ok && expanded == iss {
+			return iss, src, nil
+		}
+	}
+	return "", nil, fmt.Errorf(`unknown issuer %q`, iss)
+}
+
+func expandIssuer(pattern string, claims map[string]interface{}) (string, bool) {
+	var sb strings.Builder
+	for {
+		i := strings.IndexByte(pattern, '{')
+		if i < 0 {
+			sb.WriteString(pattern)
+			return sb.String(), true
+		}
+		j := strings.IndexByte(pattern[i:], '}')
+		if j < 0 {
+			return "", false
+		}
+		v, ok := claims[pattern[i+1:i+j]].(string)
+		if !ok || v == "" || strings.ContainsAny(v, "/?#") {
+			return "", false
+		}
+		sb.WriteString(pattern[:i])
+		sb.WriteString(v)
+		pattern = pattern[i+j+1:]
+	}
+}
+
+func (p *IssuerKeyProvider) fetchKeys(ctx context.Context, sink jws.KeySink, sig *jws.Signature, msg *jws.Message) (string, error) {
+	var claims map[string]interface{}
+	if err := json.Unmarshal(msg.Payload(), &claims); err != nil {
+		return "", fmt.Errorf(`failed to parse token claims: %w`, err)
+	}
+
+	iss, src, err := p.lookup(claims)
+	if err != nil {
+		return "", err
+	}
+
+	set, err := src.keySet(ctx)
+	if err != nil {
+		return "", fmt.Errorf(`failed to retrieve key set for issuer %q: %w`, iss, err)
+	}
+
+	//nolint:forcetypeassert
+	kp := jws.WithKeySet(set, src.options...).Value().(jws.KeyProvider)
+	if err := kp.FetchKeys(ctx, sink, sig, msg); err != nil {
+		return "", fmt.Errorf(`failed to fetch keys for issuer %q: %w`, iss, err)
+	}
+	return iss, nil
+}
+
+// FetchKeys implements the `jws.KeyProvider` interface
+func (p *IssuerKeyProvider) FetchKeys(ctx context.Context, sink jws.KeySink, sig *jws.Signature, msg *jws.Message) error {
+	_, err := p.fetchKeys(ctx, sink, sig, msg)
+	return err
+}
+
+// issuerRouting wraps an IssuerKeyProvider for a single call to
+// `jwt.Parse()`, and remembers the issuer that keys were selected for,
+// along with the keys themselves
+type issuerRouting struct {
+	provider *IssuerKeyProvider
+	mu       sync.Mutex
+	issuer   string
+	keys     []interface{}
+	// keyUsed receives the key that verified the token (jws.WithKeyUsed)
+	keyUsed interface{}
+}
+
+// issuerKeySink records the keys passed to the underlying sink
+type issuerKeySink struct {
+	jws.KeySink
+	keys []interface{}
+}
+
+func (s *issuerKeySink) Key(alg jwa.SignatureAlgorithm, key interface{}) {
+	s.keys = append(s.keys, key)
+	s.KeySink.Key(alg, key)
+}
+
+func (r *issuerRouting) FetchKeys(ctx context.Context, sink jws.KeySink, sig *jws.Signature, msg *jws.Message) error {
+	rsink := issuerKeySink{KeySink: sink}
+	iss, err := r.provider.fetchKeys(ctx, &rsink, sig, msg)
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.issuer = iss
+	r.keys = append(r.keys, rsink.keys...)
+	r.mu.Unlock()
+	return nil
+}
+
+func (r *issuerRouting) verifyIssuer(t Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// The provider must have selected the keys for an issuer, and the
+	// token must have been verified using one of them. Otherwise the
+	// token may have been verified using keys from another source
+	if r.issuer == "" || !r.selected(r.keyUsed) {
+		return fmt.Errorf(`token was not verified using keys selected by the issuer key provider`)
+	}
+	if t.Issuer() != r.issuer {
+		return fmt.Errorf(`%q claim in the verified token (%q) does not match the issuer used to select keys (%q)`, IssuerKey, t.Issuer(), r.issuer)
+	}
+	return nil
+}
+
+func (r *issuerRouting) selected(key interface{}) bool {
+	if key == nil {
+		return false
+	}
+	for _, v := range r.keys {
+		// keys in a jwk.Set are pointers, so they can be compared by identity.
+		// Other types (e.g. []byte) can not be compared, and are never selected
+		if reflect.TypeOf(v).Comparable() && reflect.TypeOf(v) == reflect.TypeOf(key) && v == key {
+			return true
+		}
+	}
+	return false
+}
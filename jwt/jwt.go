@@ -11,6 +11,7 @@ import (
 
 	"github.com/lestrrat-go/jwx/v2"
 	"github.com/lestrrat-go/jwx/v2/internal/json"
+	"github.com/lestrrat-go/jwx/v2/jwe"
 	"github.com/lestrrat-go/jwx/v2/jws"
 	"github.com/lestrrat-go/jwx/v2/jwt/internal/types"
 )
@@ -91,9 +92,12 @@ func ParseString(s string, options ...ParseOption) (Token, error) {
 // Parse parses the JWT token payload and creates a new `jwt.Token` object.
 // The token must be encoded in either JSON format or compact format.
 //
-// This function can only work with either raw JWT (JSON) and JWS (Compact or JSON).
-// If you need JWE support on top of it, you will need to rollout your
-// own workaround.
+// This function can work with raw JWT (JSON) and JWS (Compact or JSON).
+// JWE-wrapped (nested) JWTs are also accepted when the keys to decrypt
+// them are specified using the jwt.WithDecryptKey(alg, key),
+// jwt.WithDecryptKeySet(jwk.Set), or jwt.WithDecryptKeyProvider(provider)
+// options. In that case the token is decrypted first, and the
+// enclosed payload is then verified and validated as described below.
 //
 // If the token is signed and you want to verify the payload matches the signature,
 // you must pass the jwt.WithKey(alg, key) or jwt.WithKeySet(jwk.Set) option.
@@ -149,6 +153,8 @@ type parseCtx struct {
 	token            Token
 	validateOpts     []ValidateOption
 	verifyOpts       []jws.VerifyOption
+	decryptOpts      []jwe.DecryptOption
+	issuerRouting    *issuerRouting
 	localReg         *json.Registry
 	pedantic         bool
 	skipVerification bool
@@ -168,6 +174,7 @@ func parseBytes(data []byte, options ...ParseOption) (Token, error) {
 	verification := true
 
 	var verifyOpts []Option
+	var decryptOpts []Option
 	for _, o := range options {
 		if v, ok := o.(ValidateOption); ok {
 			ctx.validateOpts = append(ctx.validateOpts, v)
@@ -178,6 +185,11 @@ func parseBytes(data []byte, options ...ParseOption) (Token, error) {
 		switch o.Ident() {
 		case identKey{}, identKeySet{}, identVerifyAuto{}, identKeyProvider{}:
 			verifyOpts = append(verifyOpts, o)
+		case identIssuerKeyProvider{}:
+			ctx.issuerRouting = &issuerRouting{provider: o.Value().(*IssuerKeyProvider)}
+			verifyOpts = append(verifyOpts, WithKeyProvider(ctx.issuerRouting))
+		case identDecryptKey{}, identDecryptKeySet{}, identDecryptKeyProvider{}:
+			decryptOpts = append(decryptOpts, o)
 		case identToken{}:
 			token, ok := o.Value().(Token)
 			if !ok {
@@ -210,6 +222,17 @@ func parseBytes(data []byte, options ...ParseOption) (Token, error) {
 			return nil, fmt.Errorf(`jwt.Parse: failed to convert options into jws.VerifyOption: %w`, err)
 		}
 		ctx.verifyOpts = converted
+		if ctx.issuerRouting != nil {
+			ctx.verifyOpts = append(ctx.verifyOpts, jws.WithKeyUsed(&ctx.issuerRouting.keyUsed))
+		}
+	}
+
+	if len(decryptOpts) > 0 {
+		converted, err := toDecryptOptions(decryptOpts...)
+		if err != nil {
+			return nil, fmt.Errorf(`jwt.Parse: failed to convert options into jwe.DecryptOption: %w`, err)
+		}
+		ctx.decryptOpts = converted
 	}
 
 	data = bytes.TrimSpace(data)
@@ -243,6 +266,13 @@ func parse(ctx *parseCtx, data []byte) (Token, error) {
 	// If cty = `JWT`, we expect this to be a nested structure
 	var expectNested bool
 
+	// Set once the JWS signature has been verified, so that a payload
+	// enclosed in a JWE is not accepted without verification
+	var verified bool
+
+	// Set while the payload being processed was enclosed in a JWE
+	var encrypted bool
+
 OUTER:
 	for i := 0; i < maxDecodeLevels; i++ {
 		switch kind := jwx.GuessFormat(payload); kind {
@@ -253,13 +283,14 @@ OUTER:
 				}
 			}
 
-			if i == 0 {
-				// We were NOT enveloped in other formats
-				if !ctx.skipVerification {
-					if _, _, err := verifyJWS(ctx, payload); err != nil {
-						return nil, err
-					}
+			// If we were NOT enveloped in other formats, or the enveloping
+			// JWE did not contain a JWS, we still need to verify
+			if !verified && !ctx.skipVerification {
+				_, state, err := verifyJWS(ctx, payload)
+				if err != nil {
+					return nil, err
 				}
+				verified = state != _JwsVerifySkipped
 			}
 
 			break OUTER
@@ -270,19 +301,21 @@ OUTER:
 				return nil, fmt.Errorf(`invalid JWT`)
 			}
 
-			if i == 0 {
-				// We were NOT enveloped in other formats
-				if !ctx.skipVerification {
-					if _, _, err := verifyJWS(ctx, payload); err != nil {
-						return nil, err
-					}
+			// If we were NOT enveloped in other formats, or the enveloping
+			// JWE did not contain a JWS, we still need to verify
+			if !verified && !ctx.skipVerification {
+				_, state, err := verifyJWS(ctx, payload)
+				if err != nil {
+					return nil, err
 				}
+				verified = state != _JwsVerifySkipped
 			}
 			break OUTER
 		case jwx.JWS:
 			// Food for thought: This is going to break if you have multiple layers of
 			// JWS enveloping using different keys. It is highly unlikely use case,
 			// but it might happen.
+			encrypted = false
 
 			// skipVerification should only be set to true by us. It's used
 			// when we just want to parse the JWT out of a payload
@@ -297,6 +330,7 @@ OUTER:
 
 				if state != _JwsVerifySkipped {
 					payload = v
+					verified = true
 
 					// We only check for cty and typ if the pedantic flag is enabled
 					if !ctx.pedantic {
@@ -314,17 +348,47 @@ OUTER:
 			}
 
 			// No verification.
-			m, err := jws.Parse(data)
+			m, err := jws.Parse(payload)
 			if err != nil {
 				return nil, fmt.Errorf(`invalid jws message: %w`, err)
 			}
 			payload = m.Payload()
+		case jwx.JWE:
+			// A JWE directly enclosing another JWE can not carry a signature
+			// at the innermost level within our decode limit, and anybody
+			// with the recipient's public key can create one
+			if encrypted {
+				return nil, fmt.Errorf(`jwt.Parse: a JWE must not be nested directly in another JWE`)
+			}
+
+			if len(ctx.decryptOpts) == 0 {
+				return nil, fmt.Errorf(`jwt.Parse: token is encrypted, but no keys for decryption are provided (use jwt.WithDecryptKey() or its siblings)`)
+			}
+
+			msg := jwe.NewMessage()
+			decrypted, err := jwe.Decrypt(payload, append(ctx.decryptOpts, jwe.WithMessage(msg))...)
+			if err != nil {
+				return nil, fmt.Errorf(`jwt.Parse: failed to decrypt token: %w`, err)
+			}
+			payload = decrypted
+			encrypted = true
+
+			if ctx.pedantic && msg.ProtectedHeaders().ContentType() == "JWT" {
+				expectNested = true
+				continue OUTER
+			}
 		default:
 			return nil, fmt.Errorf(`unsupported format (layer: #%d)`, i+1)
 		}
 		expectNested = false
 	}
 
+	// Regardless of how the layers were processed, the token must have
+	// been verified unless verification was explicitly disabled
+	if !verified && !ctx.skipVerification && len(ctx.verifyOpts) > 0 {
+		return nil, fmt.Errorf(`jwt.Parse: token was not verified`)
+	}
+
 	if ctx.token == nil {
 		ctx.token = New()
 	}
@@ -343,6 +407,12 @@ OUTER:
 		return nil, fmt.Errorf(`failed to parse token: %w`, err)
 	}
 
+	if ctx.issuerRouting != nil {
+		if err := ctx.issuerRouting.verifyIssuer(ctx.token); err != nil {
+			return nil, err
+		}
+	}
+
 	if ctx.validate {
 		if err := Validate(ctx.token, ctx.validateOpts...); err != nil {
 			return nil, err
@@ -1486,6 +1486,256 @@ func TestSerializer(t *testing.T) {
 	})
 }
 
+func TestParseNested(t *testing.T) {
+	signKey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+	encKey, err := jwxtest.GenerateRsaKey()
+	require.NoError(t, err, `jwxtest.GenerateRsaKey should succeed`)
+
+	tok := jwt.New()
+	require.NoError(t, tok.Set(jwt.IssuerKey, `github.com/lestrrat-go/jwx`), `tok.Set should succeed`)
+	require.NoError(t, tok.Set(jwt.ExpirationKey, time.Now().Add(time.Hour)), `tok.Set should succeed`)
+
+	serialized, err := jwt.NewSerializer().
+		Sign(jwt.WithKey(jwa.RS256, signKey)).
+		Encrypt(jwt.WithKey(jwa.RSA_OAEP, &encKey.PublicKey)).
+		Serialize(tok)
+	require.NoError(t, err, `Serialize should succeed`)
+
+	t.Run(`WithDecryptKey`, func(t *testing.T) {
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`WithDecryptKeySet`, func(t *testing.T) {
+		key, err := jwk.FromRaw(encKey)
+		require.NoError(t, err, `jwk.FromRaw should succeed`)
+		require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RSA_OAEP), `key.Set should succeed`)
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(key), `set.AddKey should succeed`)
+
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecryptKeySet(set, jwe.WithRequireKid(false)),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`WithDecryptKeyProvider`, func(t *testing.T) {
+		kp := jwe.KeyProviderFunc(func(_ context.Context, sink jwe.KeySink, _ jwe.Recipient, _ *jwe.Message) error {
+			sink.Key(jwa.RSA_OAEP, encKey)
+			return nil
+		})
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecryptKeyProvider(kp),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`ParseRequest`, func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, `https://github.com/lestrrat-go/jwx`, nil)
+		require.NoError(t, err, `http.NewRequest should succeed`)
+		req.Header.Set(`Authorization`, `Bearer `+string(serialized))
+
+		parsed, err := jwt.ParseRequest(req,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.NoError(t, err, `jwt.ParseRequest should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`Pedantic`, func(t *testing.T) {
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+			jwt.WithPedantic(true),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`No decryption key`, func(t *testing.T) {
+		_, err := jwt.Parse(serialized, jwt.WithKey(jwa.RS256, &signKey.PublicKey))
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Wrong decryption key`, func(t *testing.T) {
+		_, err := jwt.Parse(serialized,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, signKey),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Wrong verification key`, func(t *testing.T) {
+		_, err := jwt.Parse(serialized,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithKey(jwa.RS256, &encKey.PublicKey),
+		)
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Skip verification`, func(t *testing.T) {
+		parsed, err := jwt.Parse(serialized,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithVerify(false),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`Encrypted but not signed`, func(t *testing.T) {
+		encrypted, err := jwt.NewSerializer().
+			Encrypt(jwt.WithKey(jwa.RSA_OAEP, &encKey.PublicKey)).
+			Serialize(tok)
+		require.NoError(t, err, `Serialize should succeed`)
+
+		_, err = jwt.Parse(encrypted,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+		)
+		require.Error(t, err, `jwt.Parse should fail when a verification key is given`)
+
+		parsed, err := jwt.Parse(encrypted,
+			jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+			jwt.WithVerify(false),
+		)
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.True(t, jwt.Equal(tok, parsed), `tokens should be equal`)
+	})
+	t.Run(`Encrypted twice`, func(t *testing.T) {
+		// Anybody with the recipient's public key can create this token,
+		// so it must never be accepted as verified
+		forged := jwt.New()
+		require.NoError(t, forged.Set(jwt.SubjectKey, `admin`), `forged.Set should succeed`)
+
+		inner, err := jwt.NewSerializer().
+			Encrypt(jwt.WithKey(jwa.RSA_OAEP, &encKey.PublicKey)).
+			Serialize(forged)
+		require.NoError(t, err, `Serialize should succeed`)
+		hdrs := jwe.NewHeaders()
+		require.NoError(t, hdrs.Set(jwe.ContentTypeKey, `JWT`), `hdrs.Set should succeed`)
+		outer, err := jwe.Encrypt(inner, jwe.WithKey(jwa.RSA_OAEP, &encKey.PublicKey), jwe.WithProtectedHeaders(hdrs))
+		require.NoError(t, err, `jwe.Encrypt should succeed`)
+
+		for _, pedantic := range []bool{false, true} {
+			parsed, err := jwt.Parse(outer,
+				jwt.WithDecryptKey(jwa.RSA_OAEP, encKey),
+				jwt.WithKey(jwa.RS256, &signKey.PublicKey),
+				jwt.WithPedantic(pedantic),
+			)
+			require.Error(t, err, `jwt.Parse should fail (pedantic = %t)`, pedantic)
+			require.Nil(t, parsed, `jwt.Parse should not return a token (pedantic = %t)`, pedantic)
+		}
+	})
+}
+
+func TestIssuerKeyProvider(t *testing.T) {
+	newKey := func(t *testing.T, kid string) (jwk.Key, jwk.Set) {
+		t.Helper()
+		key, err := jwxtest.GenerateRsaJwk()
+		require.NoError(t, err, `jwxtest.GenerateRsaJwk should succeed`)
+		require.NoError(t, key.Set(jwk.KeyIDKey, kid), `key.Set should succeed`)
+		require.NoError(t, key.Set(jwk.AlgorithmKey, jwa.RS256), `key.Set should succeed`)
+		pubkey, err := key.PublicKey()
+		require.NoError(t, err, `key.PublicKey should succeed`)
+		set := jwk.NewSet()
+		require.NoError(t, set.AddKey(pubkey), `set.AddKey should succeed`)
+		return key, set
+	}
+	sign := func(t *testing.T, key jwk.Key, claims map[string]interface{}) []byte {
+		t.Helper()
+		tok := jwt.New()
+		for k, v := range claims {
+			require.NoError(t, tok.Set(k, v), `tok.Set should succeed`)
+		}
+		signed, err := jwt.Sign(tok, jwt.WithKey(jwa.RS256, key))
+		require.NoError(t, err, `jwt.Sign should succeed`)
+		return signed
+	}
+
+	keyA, setA := newKey(t, `a`)
+	keyB, setB := newKey(t, `b`)
+	keyTenant, setTenant := newKey(t, `tenant`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set(`Content-Type`, `application/json`)
+		_ = json.NewEncoder(w).Encode(setB)
+	}))
+	defer srv.Close()
+	cache := jwk.NewCache(ctx)
+	require.NoError(t, cache.Register(srv.URL), `cache.Register should succeed`)
+
+	p := jwt.NewIssuerKeyProvider().
+		AddKeySet(`https://a.example.com`, setA).
+		AddCache(`https://b.example.com`, cache, srv.URL).
+		AddKeySet(`https://login.example.com/{tid}/v2.0`, setTenant)
+
+	t.Run(`Static key set`, func(t *testing.T) {
+		tok, err := jwt.Parse(sign(t, keyA, map[string]interface{}{jwt.IssuerKey: `https://a.example.com`}), jwt.WithIssuerKeyProvider(p))
+		require.NoError(t, err, `jwt.Parse should succeed`)
+		require.Equal(t, `https://a.example.com`, tok.Issuer(), `issuer should match`)
+	})
+	t.Run(`Cached key set`, func(t *testing.T) {
+		_, err := jwt.Parse(sign(t, keyB, map[string]interface{}{jwt.IssuerKey: `https://b.example.com`}), jwt.WithIssuerKeyProvider(p))
+		require.NoError(t, err, `jwt.Parse should succeed`)
+	})
+	t.Run(`Placeholder`, func(t *testing.T) {
+		_, err := jwt.Parse(sign(t, keyTenant, map[string]interface{}{
+			jwt.IssuerKey: `https://login.example.com/1234/v2.0`,
+			`tid`:         `1234`,
+		}), jwt.WithIssuerKeyProvider(p))
+		require.NoError(t, err, `jwt.Parse should succeed`)
+
+		_, err = jwt.Parse(sign(t, keyTenant, map[string]interface{}{
+			jwt.IssuerKey: `https://login.example.com/1234/v2.0`,
+			`tid`:         `5678`,
+		}), jwt.WithIssuerKeyProvider(p))
+		require.Error(t, err, `jwt.Parse should fail when tid does not match`)
+	})
+	t.Run(`Key from another issuer`, func(t *testing.T) {
+		_, err := jwt.Parse(sign(t, keyB, map[string]interface{}{jwt.IssuerKey: `https://a.example.com`}), jwt.WithIssuerKeyProvider(p))
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Unknown issuer`, func(t *testing.T) {
+		_, err := jwt.Parse(sign(t, keyA, map[string]interface{}{jwt.IssuerKey: `https://unknown.example.com`}), jwt.WithIssuerKeyProvider(p))
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Missing issuer`, func(t *testing.T) {
+		_, err := jwt.Parse(sign(t, keyA, map[string]interface{}{jwt.SubjectKey: `foo`}), jwt.WithIssuerKeyProvider(p))
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Unknown issuer verified by another key`, func(t *testing.T) {
+		pubkey, err := keyA.PublicKey()
+		require.NoError(t, err, `keyA.PublicKey should succeed`)
+
+		// jwt.WithKey() is consulted first, so the issuer key provider is never used
+		_, err = jwt.Parse(sign(t, keyA, map[string]interface{}{jwt.IssuerKey: `https://unknown.example.com`}),
+			jwt.WithKey(jwa.RS256, pubkey),
+			jwt.WithIssuerKeyProvider(p),
+		)
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Known issuer verified by another key`, func(t *testing.T) {
+		pubkey, err := keyB.PublicKey()
+		require.NoError(t, err, `keyB.PublicKey should succeed`)
+
+		// The issuer key provider is consulted first, but its keys do not verify the token
+		_, err = jwt.Parse(sign(t, keyB, map[string]interface{}{jwt.IssuerKey: `https://a.example.com`}),
+			jwt.WithIssuerKeyProvider(p),
+			jwt.WithKey(jwa.RS256, pubkey),
+		)
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+	t.Run(`Removed issuer`, func(t *testing.T) {
+		p := jwt.NewIssuerKeyProvider().AddKeySet(`https://a.example.com`, setA)
+		p.Remove(`https://a.example.com`)
+		_, err := jwt.Parse(sign(t, keyA, map[string]interface{}{jwt.IssuerKey: `https://a.example.com`}), jwt.WithIssuerKeyProvider(p))
+		require.Error(t, err, `jwt.Parse should fail`)
+	})
+}
+
 func TestFractional(t *testing.T) {
 	t.Run("FormatPrecision", func(t *testing.T) {
 		var nd types.NumericDate
@@ -1597,3 +1847,38 @@ func TestFractional(t *testing.T) {
 		jwt.Settings(jwt.WithNumericDateParsePrecision(0))
 	})
 }
+
+func TestWithKeyRing(t *testing.T) {
+	kr, err := jwk.NewKeyRing(jwk.NewKeyGenerator(jwa.EC, jwk.WithKeyAlgorithm(jwa.ES256)))
+	require.NoError(t, err, `jwk.NewKeyRing should succeed`)
+
+	tok, err := jwt.NewBuilder().Issuer(`github.com/lestrrat-go/jwx`).Build()
+	require.NoError(t, err, `jwt.NewBuilder should succeed`)
+
+	for _, rotate := range []bool{false, true} {
+		if rotate {
+			require.NoError(t, kr.Rotate(), `kr.Rotate should succeed`)
+		}
+
+		active, err := kr.ActiveKey()
+		require.NoError(t, err, `kr.ActiveKey should succeed`)
+
+		signed, err := jwt.Sign(tok, jwt.WithKeyRing(kr))
+		require.NoError(t, err, `jwt.Sign should succeed`)
+
+		msg, err := jws.Parse(signed)
+		require.NoError(t, err, `jws.Parse should succeed`)
+		require.Equal(t, active.KeyID(), msg.Signatures()[0].ProtectedHeaders().KeyID(), `"kid" should be the key ID of the active key`)
+		require.Equal(t, jwa.ES256, msg.Signatures()[0].ProtectedHeaders().Algorithm(), `"alg" should be taken from the active key`)
+
+		pubset, err := kr.PublicSet()
+		require.NoError(t, err, `kr.PublicSet should succeed`)
+		_, err = jwt.Parse(signed, jwt.WithKeySet(pubset))
+		require.NoError(t, err, `jwt.Parse should succeed`)
+	}
+
+	kr, err = jwk.NewKeyRing(jwk.NewKeyGenerator(jwa.EC))
+	require.NoError(t, err, `jwk.NewKeyRing should succeed`)
+	_, err = jwt.Sign(tok, jwt.WithKeyRing(kr))
+	require.Error(t, err, `jwt.Sign should fail if the active key does not have "alg"`)
+}
@@ -11,7 +11,10 @@ import (
 	"github.com/lestrrat-go/option"
 )
 
+type identDecryptKey struct{}
+type identDecryptKeySet struct{}
 type identKey struct{}
+type identKeyRing struct{}
 type identKeySet struct{}
 type identTypedClaim struct{}
 type identVerifyAuto struct{}
@@ -33,6 +36,9 @@ func toSignOptions(options ...Option) ([]jws.SignOption, error) {
 			}
 
 			soptions = append(soptions, jws.WithKey(wk.alg, wk.key, wksoptions...))
+		case identKeyRing{}:
+			wkr := option.Value().(*withKeyRing) // this always succeeds
+			soptions = append(soptions, jws.WithKeyRing(wkr.ring, wkr.options...))
 		}
 	}
 	return soptions, nil
@@ -103,6 +109,28 @@ func toVerifyOptions(options ...Option) ([]jws.VerifyOption, error) {
 	return voptions, nil
 }
 
+func toDecryptOptions(options ...Option) ([]jwe.DecryptOption, error) {
+	var doptions []jwe.DecryptOption
+	for _, option := range options {
+		//nolint:forcetypeassert
+		switch option.Ident() {
+		case identDecryptKey{}:
+			wk := option.Value().(*withDecryptKey) // this always succeeds
+			doptions = append(doptions, jwe.WithKey(wk.alg, wk.key, wk.options...))
+		case identDecryptKeySet{}:
+			wks := option.Value().(*withDecryptKeySet) // this always succeeds
+			doptions = append(doptions, jwe.WithKeySet(wks.set, wks.options...))
+		case identDecryptKeyProvider{}:
+			kp, ok := option.Value().(jwe.KeyProvider)
+			if !ok {
+				return nil, fmt.Errorf(`expected jwe.KeyProvider, got %T`, option.Value())
+			}
+			doptions = append(doptions, jwe.WithKeyProvider(kp))
+		}
+	}
+	return doptions, nil
+}
+
 type withKey struct {
 	alg     jwa.KeyAlgorithm
 	key     interface{}
@@ -128,6 +156,21 @@ func WithKey(alg jwa.KeyAlgorithm, key interface{}, suboptions ...Option) SignEn
 	})}
 }
 
+type withKeyRing struct {
+	ring    *jwk.KeyRing
+	options []jws.WithKeySuboption
+}
+
+// WithKeyRing specifies that the token should be signed using the key
+// that is currently active in the `jwk.KeyRing`. See `jws.WithKeyRing()`
+// for details.
+func WithKeyRing(ring *jwk.KeyRing, suboptions ...jws.WithKeySuboption) SignOption {
+	return &signOption{option.New(identKeyRing{}, &withKeyRing{
+		ring:    ring,
+		options: suboptions,
+	})}
+}
+
 type withKeySet struct {
 	set     jwk.Set
 	options []interface{}
@@ -164,6 +207,54 @@ func WithKeySet(set jwk.Set, options ...interface{}) ParseOption {
 	})}
 }
 
+type withDecryptKey struct {
+	alg     jwa.KeyEncryptionAlgorithm
+	key     interface{}
+	options []jwe.WithKeySuboption
+}
+
+// WithDecryptKey specifies the key to be used to decrypt a JWE-wrapped
+// (nested) JWT in `jwt.Parse()` and its siblings.
+//
+// When this option (or `jwt.WithDecryptKeySet()`/`jwt.WithDecryptKeyProvider()`)
+// is given, `jwt.Parse()` accepts tokens in JWE format. The token is first
+// decrypted, and then the enclosed payload -- usually a JWS, as indicated by the
+// `cty: JWT` header -- is verified and validated as usual. The verification keys
+// are still specified using `jwt.WithKey()` and friends, so both halves of a
+// sign-then-encrypt token produced by `jwt.NewSerializer().Sign(...).Encrypt(...)`
+// can be processed in one call:
+//
+//	jwt.Parse(data,
+//	  jwt.WithDecryptKey(jwa.RSA_OAEP, encPrivKey),
+//	  jwt.WithKey(jwa.RS256, signPubKey),
+//	)
+//
+// If you want to accept tokens that are only encrypted but not signed,
+// you must explicitly specify `jwt.WithVerify(false)`.
+func WithDecryptKey(alg jwa.KeyEncryptionAlgorithm, key interface{}, suboptions ...jwe.WithKeySuboption) ParseOption {
+	return &parseOption{option.New(identDecryptKey{}, &withDecryptKey{
+		alg:     alg,
+		key:     key,
+		options: suboptions,
+	})}
+}
+
+type withDecryptKeySet struct {
+	set     jwk.Set
+	options []jwe.WithKeySetSuboption
+}
+
+// WithDecryptKeySet specifies that a JWE-wrapped (nested) JWT should be
+// decrypted using one of the keys in the given key set. See `jwe.WithKeySet()`
+// for how the keys are matched against the message, and `jwt.WithDecryptKey()`
+// for how nested tokens are processed.
+func WithDecryptKeySet(set jwk.Set, options ...jwe.WithKeySetSuboption) ParseOption {
+	return &parseOption{option.New(identDecryptKeySet{}, &withDecryptKeySet{
+		set:     set,
+		options: options,
+	})}
+}
+
 // WithIssuer specifies that expected issuer value. If not specified,
 // the value of issuer is not verified at all.
 func WithIssuer(s string) ValidateOption {
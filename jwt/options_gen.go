@@ -125,11 +125,13 @@ func (*validateOption) validateOption() {}
 type identAcceptableSkew struct{}
 type identClock struct{}
 type identContext struct{}
+type identDecryptKeyProvider struct{}
 type identEncryptOption struct{}
 type identFS struct{}
 type identFlattenAudience struct{}
 type identFormKey struct{}
 type identHeaderKey struct{}
+type identIssuerKeyProvider struct{}
 type identKeyProvider struct{}
 type identNumericDateFormatPrecision struct{}
 type identNumericDateParsePedantic struct{}
@@ -154,6 +156,10 @@ func (identContext) String() string {
 	return "WithContext"
 }
 
+func (identDecryptKeyProvider) String() string {
+	return "WithDecryptKeyProvider"
+}
+
 func (identEncryptOption) String() string {
 	return "WithEncryptOption"
 }
@@ -174,6 +180,10 @@ func (identHeaderKey) String() string {
 	return "WithHeaderKey"
 }
 
+func (identIssuerKeyProvider) String() string {
+	return "WithIssuerKeyProvider"
+}
+
 func (identKeyProvider) String() string {
 	return "WithKeyProvider"
 }
@@ -240,6 +250,14 @@ func WithContext(v context.Context) ValidateOption {
 	return &validateOption{option.New(identContext{}, v)}
 }
 
+// WithDecryptKeyProvider allows users to specify an object to provide keys to
+// decrypt JWE-wrapped (nested) tokens using arbitrary code. Please read the
+// documentation for `jwe.KeyProvider` in the `jwe` package for details on
+// how this works.
+func WithDecryptKeyProvider(v jwe.KeyProvider) ParseOption {
+	return &parseOption{option.New(identDecryptKeyProvider{}, v)}
+}
+
 // WithEncryptOption provides an escape hatch for cases where extra options to
 // `(jws.Serializer).Encrypt()` must be specified when usng `jwt.Sign()`. Normally you do not
 // need to use this.
@@ -281,6 +299,17 @@ func WithHeaderKey(v string) ParseOption {
 	return &parseOption{option.New(identHeaderKey{}, v)}
 }
 
+// WithIssuerKeyProvider specifies that the keys used to verify the token
+// should be selected based on its `iss` claim, using the given
+// `jwt.IssuerKeyProvider`. Tokens from unknown issuers are rejected, and
+// after the signature has been verified the `iss` claim is checked again
+// to make sure that it matches the issuer that was used to select the keys.
+// Tokens that were verified using keys from other sources, such as
+// `jwt.WithKey()`, are rejected as well.
+func WithIssuerKeyProvider(v *IssuerKeyProvider) ParseOption {
+	return &parseOption{option.New(identIssuerKeyProvider{}, v)}
+}
+
 // WithKeyProvider allows users to specify an object to provide keys to
 // sign/verify tokens using arbitrary code. Please read the documentation
 // for `jws.KeyProvider` in the `jws` package for details on how this works.
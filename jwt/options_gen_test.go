@@ -12,11 +12,13 @@ func TestOptionIdent(t *testing.T) {
 	require.Equal(t, "WithAcceptableSkew", identAcceptableSkew{}.String())
 	require.Equal(t, "WithClock", identClock{}.String())
 	require.Equal(t, "WithContext", identContext{}.String())
+	require.Equal(t, "WithDecryptKeyProvider", identDecryptKeyProvider{}.String())
 	require.Equal(t, "WithEncryptOption", identEncryptOption{}.String())
 	require.Equal(t, "WithFS", identFS{}.String())
 	require.Equal(t, "WithFlattenAudience", identFlattenAudience{}.String())
 	require.Equal(t, "WithFormKey", identFormKey{}.String())
 	require.Equal(t, "WithHeaderKey", identHeaderKey{}.String())
+	require.Equal(t, "WithIssuerKeyProvider", identIssuerKeyProvider{}.String())
 	require.Equal(t, "WithKeyProvider", identKeyProvider{}.String())
 	require.Equal(t, "WithNumericDateFormatPrecision", identNumericDateFormatPrecision{}.String())
 	require.Equal(t, "WithNumericDateParsePedantic", identNumericDateParsePedantic{}.String())
@@ -73,6 +73,16 @@ func _main() error {
 					value:   `A256GCM`,
 					comment: `AES-GCM (256)`,
 				},
+				{
+					name:    `C20P`,
+					value:   `C20P`,
+					comment: `ChaCha20-Poly1305`,
+				},
+				{
+					name:    `XC20P`,
+					value:   `XC20P`,
+					comment: `XChaCha20-Poly1305`,
+				},
 			},
 		},
 		{
@@ -249,6 +259,16 @@ func _main() error {
 					value:   "RSA-OAEP-256",
 					comment: `RSA-OAEP-SHA256`,
 				},
+				{
+					name:    `RSA_OAEP_384`,
+					value:   "RSA-OAEP-384",
+					comment: `RSA-OAEP-SHA384`,
+				},
+				{
+					name:    `RSA_OAEP_512`,
+					value:   "RSA-OAEP-512",
+					comment: `RSA-OAEP-SHA512`,
+				},
 				{
 					name:    `A128KW`,
 					value:   "A128KW",
@@ -289,6 +309,66 @@ func _main() error {
 					value:   "ECDH-ES+A256KW",
 					comment: `ECDH-ES + AES key wrap (256)`,
 				},
+				{
+					name:    `ECDH_1PU`,
+					value:   "ECDH-1PU",
+					comment: `ECDH-1PU`,
+				},
+				{
+					name:    `ECDH_1PU_A128KW`,
+					value:   "ECDH-1PU+A128KW",
+					comment: `ECDH-1PU + AES key wrap (128)`,
+				},
+				{
+					name:    `ECDH_1PU_A192KW`,
+					value:   "ECDH-1PU+A192KW",
+					comment: `ECDH-1PU + AES key wrap (192)`,
+				},
+				{
+					name:    `ECDH_1PU_A256KW`,
+					value:   "ECDH-1PU+A256KW",
+					comment: `ECDH-1PU + AES key wrap (256)`,
+				},
+				{
+					name:    `HPKE_0`,
+					value:   "HPKE-0",
+					comment: `HPKE DHKEM(P-256, HKDF-SHA256), HKDF-SHA256, AES-128-GCM`,
+				},
+				{
+					name:    `HPKE_1`,
+					value:   "HPKE-1",
+					comment: `HPKE DHKEM(P-384, HKDF-SHA384), HKDF-SHA384, AES-256-GCM`,
+				},
+				{
+					name:    `HPKE_2`,
+					value:   "HPKE-2",
+					comment: `HPKE DHKEM(P-521, HKDF-SHA512), HKDF-SHA512, AES-256-GCM`,
+				},
+				{
+					name:    `HPKE_3`,
+					value:   "HPKE-3",
+					comment: `HPKE DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, AES-128-GCM`,
+				},
+				{
+					name:    `HPKE_4`,
+					value:   "HPKE-4",
+					comment: `HPKE DHKEM(X25519, HKDF-SHA256), HKDF-SHA256, ChaCha20Poly1305`,
+				},
+				{
+					name:    `HPKE_5`,
+					value:   "HPKE-5",
+					comment: `HPKE DHKEM(X448, HKDF-SHA512), HKDF-SHA512, AES-256-GCM`,
+				},
+				{
+					name:    `HPKE_6`,
+					value:   "HPKE-6",
+					comment: `HPKE DHKEM(X448, HKDF-SHA512), HKDF-SHA512, ChaCha20Poly1305`,
+				},
+				{
+					name:    `HPKE_7`,
+					value:   "HPKE-7",
+					comment: `HPKE DHKEM(P-256, HKDF-SHA256), HKDF-SHA256, AES-256-GCM`,
+				},
 				{
 					name:    `A128GCMKW`,
 					value:   "A128GCMKW",
@@ -0,0 +1,91 @@
+package x448_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/lestrrat-go/jwx/v2/x448"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateKey(t *testing.T) {
+	t.Run("x448.GenerateKey(nil)", func(t *testing.T) {
+		_, _, err := x448.GenerateKey(nil)
+		if !assert.NoError(t, err, `x448.GenerateKey should work even if argument is nil`) {
+			return
+		}
+	})
+	t.Run("x448.NewKeyFromSeed(wrongSeedLength)", func(t *testing.T) {
+		dummy := make([]byte, x448.SeedSize-1)
+		_, err := x448.NewKeyFromSeed(dummy)
+		if !assert.Error(t, err, `wrong seed size should result in error`) {
+			return
+		}
+	})
+}
+
+func TestNewKeyFromSeed(t *testing.T) {
+	// These test vectors are from RFC7748 Section 6.2
+	const alicePrivHex = `9a8f4925d1519f5775cf46b04b5800d4ee9ee8bae8bc5565d498c28dd9c9baf574a9419744897391006382a6f127ab1d9ac2d8c0a598726b`
+	const alicePubHex = `9b08f7cc31b7e3e67d22d5aea121074a273bd2b83de09c63faa73d2c22c5d9bbc836647241d953d40c5b12da88120d53177f80e532c41fa0`
+	const bobPrivHex = `1c306a7ac2a0e2e0990b294470cba339e6453772b075811d8fad0d1d6927c120bb5ee8972b0d3e21374c9c921b09d1b0366f10b65173992d`
+	const bobPubHex = `3eb7a829b0cd20f5bcfc0b599b6feccf6da4627107bdb0d4f345b43027d8b972fc3e34fb4232a13ca706dcb57aec3dae07bdc1c67bf33609`
+	const sharedHex = `07fff4181ac6cc95ec1c16a94a0f74d12da232ce40a77552281d282bb60c0b56fd2464c335543936521c24403085d59a449a5037514a879d`
+
+	alicePrivSeed, err := hex.DecodeString(alicePrivHex)
+	if !assert.NoError(t, err, `alice seed decoded`) {
+		return
+	}
+	alicePriv, err := x448.NewKeyFromSeed(alicePrivSeed)
+	if !assert.NoError(t, err, `alice private key`) {
+		return
+	}
+
+	alicePub := alicePriv.Public().(x448.PublicKey)
+	if !assert.Equal(t, hex.EncodeToString(alicePub), alicePubHex, `alice public key`) {
+		return
+	}
+
+	bobPrivSeed, err := hex.DecodeString(bobPrivHex)
+	if !assert.NoError(t, err, `bob seed decoded`) {
+		return
+	}
+	bobPriv, err := x448.NewKeyFromSeed(bobPrivSeed)
+	if !assert.NoError(t, err, `bob private key`) {
+		return
+	}
+
+	bobPub := bobPriv.Public().(x448.PublicKey)
+	if !assert.Equal(t, hex.EncodeToString(bobPub), bobPubHex, `bob public key`) {
+		return
+	}
+
+	if !assert.True(t, bobPriv.Equal(bobPriv), `bobPriv should equal bobPriv`) {
+		return
+	}
+	if !assert.True(t, bobPub.Equal(bobPub), `bobPub should equal bobPub`) {
+		return
+	}
+	if !assert.False(t, bobPriv.Equal(bobPub), `bobPriv should NOT equal bobPub`) {
+		return
+	}
+	if !assert.False(t, bobPub.Equal(bobPriv), `bobPub should NOT equal bobPriv`) {
+		return
+	}
+
+	aliceShared, err := x448.X448(alicePriv.Seed(), bobPub)
+	if !assert.NoError(t, err, `x448.X448 (alice) should succeed`) {
+		return
+	}
+	if !assert.Equal(t, hex.EncodeToString(aliceShared), sharedHex, `alice shared secret`) {
+		return
+	}
+
+	bobShared, err := x448.X448(bobPriv.Seed(), alicePub)
+	if !assert.NoError(t, err, `x448.X448 (bob) should succeed`) {
+		return
+	}
+	if !assert.Equal(t, hex.EncodeToString(bobShared), sharedHex, `bob shared secret`) {
+		return
+	}
+}